@@ -0,0 +1,87 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+)
+
+type CreateDepotRequest struct {
+	Name    string  `json:"name" binding:"required,max=100"`
+	Address string  `json:"address"`
+	Lat     float64 `json:"lat" binding:"min=-90,max=90"`
+	Lng     float64 `json:"lng" binding:"min=-180,max=180"`
+}
+
+type DepotResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Lat       float64   `json:"lat"`
+	Lng       float64   `json:"lng"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newDepotResponse(depot db.Depot) DepotResponse {
+	return DepotResponse{
+		ID:        depot.ID,
+		Name:      depot.Name,
+		Address:   depot.Address.String,
+		Lat:       depot.Lat,
+		Lng:       depot.Lng,
+		CreatedAt: depot.CreatedAt,
+	}
+}
+
+func (server *Server) CreateDepot(ctx *gin.Context) {
+	var req CreateDepotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	depot, err := server.store.CreateDepot(ctx, db.CreateDepotParams{
+		ID:      uuid.New(),
+		Name:    req.Name,
+		Address: sql.NullString{String: req.Address, Valid: req.Address != ""},
+		Lat:     req.Lat,
+		Lng:     req.Lng,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newDepotResponse(depot))
+}
+
+type ListDepotsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (server *Server) ListDepots(ctx *gin.Context) {
+	var req ListDepotsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	depots, err := server.store.ListDepots(ctx, db.ListDepotsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]DepotResponse, 0, len(depots))
+	for _, depot := range depots {
+		response = append(response, newDepotResponse(depot))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
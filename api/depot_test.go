@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomDepot() db.Depot {
+	return db.Depot{
+		ID:        uuid.New(),
+		Name:      util.RandomString(8),
+		Address:   sql.NullString{String: util.RandomString(12), Valid: true},
+		Lat:       6.5244,
+		Lng:       3.3792,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestCreateDepot(t *testing.T) {
+	depot := randomDepot()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		role          util.Role
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": depot.Name, "address": depot.Address.String, "lat": depot.Lat, "lng": depot.Lng},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateDepot(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateDepotParams) (db.Depot, error) {
+						require.NotEqual(t, uuid.Nil, arg.ID)
+						require.Equal(t, depot.Name, arg.Name)
+						require.Equal(t, depot.Address, arg.Address)
+						require.Equal(t, depot.Lat, arg.Lat)
+						return depot, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got DepotResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, depot.ID, got.ID)
+				require.Equal(t, depot.Name, got.Name)
+			},
+		},
+		{
+			name: "MissingName",
+			body: gin.H{"lat": depot.Lat, "lng": depot.Lng},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateDepot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidLat",
+			body: gin.H{"name": depot.Name, "lat": 91, "lng": depot.Lng},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateDepot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DriverForbidden",
+			body: gin.H{"name": depot.Name, "lat": depot.Lat, "lng": depot.Lng},
+			role: util.RoleDriver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateDepot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"name": depot.Name, "lat": depot.Lat, "lng": depot.Lng},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateDepot(gomock.Any(), gomock.Any()).Times(1).Return(db.Depot{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/depots", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListDepots(t *testing.T) {
+	depots := []db.Depot{randomDepot(), randomDepot()}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDepots(gomock.Any(), gomock.Eq(db.ListDepotsParams{Limit: 5, Offset: 5})).
+					Times(1).
+					Return(depots, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got []DepotResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 2)
+				require.Equal(t, depots[1].ID, got[1].ID)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=500",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDepots(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDepots(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/admin/depots?%s", tc.query), nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/deviation"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/tracking"
+)
+
+type DeviationEventResponse struct {
+	ID         int64     `json:"id"`
+	RouteID    uuid.UUID `json:"route_id"`
+	Transition string    `json:"transition"`
+	DistanceM  float64   `json:"distance_m"`
+	// LocationID is the first position on the new side of the corridor, left
+	// out once it is deleted
+	LocationID *int64    `json:"location_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func newDeviationEventResponse(event db.DeviationEvent) DeviationEventResponse {
+	response := DeviationEventResponse{
+		ID:         event.ID,
+		RouteID:    event.RouteID,
+		Transition: event.Transition,
+		DistanceM:  event.DistanceM,
+		OccurredAt: event.OccurredAt,
+	}
+	if event.LocationID.Valid {
+		response.LocationID = &event.LocationID.Int64
+	}
+	return response
+}
+
+type ListDeviationEventsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListDeviationEvents lists the times the driver left the route's planned
+// path and got back on it, latest first, to its driver and to admins.
+func (server *Server) ListDeviationEvents(ctx *gin.Context) {
+	route, ok := server.getReadableRoute(ctx)
+	if !ok {
+		return
+	}
+	var req ListDeviationEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	events, err := server.store.ListDeviationEvents(ctx, db.ListDeviationEventsParams{
+		RouteID: route.ID,
+		Limit:   req.PageSize,
+		Offset:  (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]DeviationEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, newDeviationEventResponse(event))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// evaluateDeviation checks the recent positions of an in-progress route,
+// which must be ordered oldest first, against the path it was planned along
+// and records the deviations they confirm. Routes planned without a path,
+// which is all of them when no road network is loaded, are left alone: the
+// server reports that at startup.
+func (server *Server) evaluateDeviation(ctx *gin.Context, route db.Route, recent []db.RouteLocation) error {
+	planned, err := server.store.GetRoutePath(ctx, route.ID)
+	if err == sql.ErrNoRows {
+		// the route wasn't planned along roads
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var path deviation.Path
+	if err := json.Unmarshal(planned.Points, &path); err != nil {
+		return err
+	}
+
+	state := deviation.State{Since: route.StartedAt.Time}
+	latest, err := server.store.GetLatestDeviationEvent(ctx, route.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		state.OffRoute = latest.Transition == string(deviation.OffRoute)
+		state.Since = latest.OccurredAt
+	}
+
+	pings := make([]deviation.Ping, 0, len(recent))
+	for _, location := range recent {
+		pings = append(pings, deviation.Ping{
+			ID:         location.ID,
+			Point:      eta.Point{Lat: location.Lat, Lng: location.Lng},
+			AccuracyM:  location.AccuracyM.Float64,
+			RecordedAt: location.RecordedAt,
+		})
+	}
+	for _, change := range server.deviations.Detect(path, state, pings) {
+		if err := server.recordDeviationEvent(ctx, route, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plannedPath is the way a trip from origin through points, in order, follows
+// the fastest roads, encoded to be stored with the route. It is nil when no
+// road network is loaded or it doesn't cover every leg: a straight corridor
+// would flag drivers keeping to the roads as off route, so such trips have no
+// path to check positions against.
+func (server *Server) plannedPath(origin eta.Point, points []eta.Point, class eta.VehicleClass) json.RawMessage {
+	if server.roads == nil {
+		return nil
+	}
+	path := deviation.Path{origin}
+	from := origin
+	for _, point := range points {
+		leg, err := server.roads.Route(from, point, class)
+		if err != nil {
+			return nil
+		}
+		path = append(path, leg.Points[1:]...)
+		from = point
+	}
+	// only coordinates that aren't finite fail to encode, and no road leads there
+	encoded, err := json.Marshal(path)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+func (server *Server) recordDeviationEvent(ctx *gin.Context, route db.Route, change deviation.Deviation) error {
+	event, err := server.store.CreateDeviationEvent(ctx, db.CreateDeviationEventParams{
+		RouteID:    route.ID,
+		Transition: string(change.Transition),
+		DistanceM:  math.Round(change.DistanceM),
+		LocationID: sql.NullInt64{Int64: change.Ping.ID, Valid: true},
+		OccurredAt: change.Ping.RecordedAt,
+	})
+	if err != nil {
+		return err
+	}
+	server.publish(tracking.EventDeviation, route, newDeviationEventResponse(event))
+	return nil
+}
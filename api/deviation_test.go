@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/deviation"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func expectLatestDeviationEvent(store *mockdb.MockStore, route db.Route, event db.DeviationEvent, err error) {
+	store.EXPECT().GetLatestDeviationEvent(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(event, err)
+}
+
+func TestRecordLocationDeviation(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	now := time.Now().UTC().Truncate(time.Second)
+
+	route := randomRoute(t, vehicle)
+	route.Status = string(util.RouteInProgress)
+	route.StartedAt = sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+
+	stops := randomRouteStops(route, 2)
+	stops[0].Status = string(util.StopCompleted)
+
+	// a few kilometres east of the way from the origin through the stops
+	lat, lng := route.OriginLat+0.03, route.OriginLng+0.05
+	// halfway along the straight way from the origin to the destination
+	midLat, midLng := (route.OriginLat+route.DestinationLat)/2, (route.OriginLng+route.DestinationLng)/2
+
+	offRoute := db.DeviationEvent{
+		ID:         1,
+		RouteID:    route.ID,
+		Transition: string(deviation.OffRoute),
+		DistanceM:  3000,
+		OccurredAt: now.Add(-10 * time.Minute),
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore) gin.H
+	}{
+		{
+			name: "LeaveRoute",
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(route, lat, lng, 0, now, 6)
+				body := expectPingsStored(store, route, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(stops, nil)
+				expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+
+				expectRoutePath(t, store, route, stops...)
+				expectLatestDeviationEvent(store, route, db.DeviationEvent{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateDeviationEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateDeviationEventParams) (db.DeviationEvent, error) {
+						require.Equal(t, route.ID, arg.RouteID)
+						require.Equal(t, string(deviation.OffRoute), arg.Transition)
+						require.Equal(t, locations[0].ID, arg.LocationID.Int64)
+						require.Equal(t, locations[0].RecordedAt, arg.OccurredAt)
+						require.Greater(t, arg.DistanceM, deviation.DefaultThresholdM)
+						return db.DeviationEvent{ID: 1, RouteID: route.ID, Transition: arg.Transition, DistanceM: arg.DistanceM}, nil
+					})
+				// the live estimate plans the rest of the trip from where the driver is
+				expectEtaRefresh(store, route, vehicle, locations[5], stops...)
+				return body
+			},
+		},
+		{
+			name: "StillOffRoute",
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(route, lat, lng, 0, now, 2)
+				body := expectPingsStored(store, route, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(stops, nil)
+				expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+
+				expectRoutePath(t, store, route, stops...)
+				expectLatestDeviationEvent(store, route, offRoute, nil)
+				store.EXPECT().CreateDeviationEvent(gomock.Any(), gomock.Any()).Times(0)
+				expectEtaRefresh(store, route, vehicle, locations[1], stops...)
+				return body
+			},
+		},
+		{
+			name: "BackOnRoute",
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(route, midLat, midLng, 0, now, 6)
+				body := expectPingsStored(store, route, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+
+				expectRoutePath(t, store, route)
+				expectLatestDeviationEvent(store, route, offRoute, nil)
+				store.EXPECT().
+					CreateDeviationEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateDeviationEventParams) (db.DeviationEvent, error) {
+						require.Equal(t, string(deviation.OnRoute), arg.Transition)
+						require.Equal(t, locations[0].ID, arg.LocationID.Int64)
+						require.Less(t, arg.DistanceM, 1.0)
+						return db.DeviationEvent{ID: 2, RouteID: route.ID, Transition: arg.Transition}, nil
+					})
+				expectEtaRefresh(store, route, vehicle, locations[5])
+				return body
+			},
+		},
+		{
+			name: "ShortDetour",
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(route, lat, lng, 0, now, 3)
+				body := expectPingsStored(store, route, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(stops, nil)
+				expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectDeviationCheck(t, store, route, stops...)
+				expectEtaRefresh(store, route, vehicle, locations[2], stops...)
+				return body
+			},
+		},
+		{
+			name: "NotPlannedAlongRoads",
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(route, lat, lng, 0, now, 6)
+				body := expectPingsStored(store, route, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(stops, nil)
+				expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+
+				// with no path to hold the driver to, the detour goes unflagged
+				store.EXPECT().GetRoutePath(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.RoutePath{}, sql.ErrNoRows)
+				store.EXPECT().GetLatestDeviationEvent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateDeviationEvent(gomock.Any(), gomock.Any()).Times(0)
+				expectEtaRefresh(store, route, vehicle, locations[5], stops...)
+				return body
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			body := tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/locations/batch", route.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+// TestGetRouteEtaWhileOffRoute checks that the ETA read back while the driver
+// is off the route is the one stored and published from their position.
+func TestGetRouteEtaWhileOffRoute(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	now := time.Now().UTC().Truncate(time.Second)
+
+	route := randomRoute(t, vehicle)
+	route.Status = string(util.RouteInProgress)
+	route.StartedAt = sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+
+	stops := randomRouteStops(route, 2)
+	stops[0].Status = string(util.StopCompleted)
+	locations := pingsNear(route, route.OriginLat+0.03, route.OriginLng+0.05, 0, now, 6)
+	latest := locations[len(locations)-1]
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(2).Return(route, nil)
+	store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).AnyTimes().Return(stops, nil)
+	store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).AnyTimes().Return(vehicle, nil)
+	body := expectPingsStored(store, route, locations)
+	expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+	expectRoutePath(t, store, route, stops...)
+	expectLatestDeviationEvent(store, route, db.DeviationEvent{}, sql.ErrNoRows)
+	store.EXPECT().
+		CreateDeviationEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.DeviationEvent{ID: 1, RouteID: route.ID, Transition: string(deviation.OffRoute)}, nil)
+
+	store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(2).Return(latest, nil)
+	store.EXPECT().
+		ListRouteLocationsSince(gomock.Any(), gomock.Eq(db.ListRouteLocationsSinceParams{
+			RouteID:    route.ID,
+			RecordedAt: latest.RecordedAt.Add(-eta.DefaultSpeedWindow),
+		})).
+		Times(2).
+		Return(locations, nil)
+	var stored db.UpdateRouteEtaParams
+	store.EXPECT().
+		UpdateRouteEta(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.UpdateRouteEtaParams) (db.Route, error) {
+			stored = arg
+			return route, nil
+		})
+	store.EXPECT().CreateRouteEtaHistory(gomock.Any(), gomock.Any()).Times(1).Return(db.RouteEtaHistory{}, nil)
+
+	server := NewTestServer(t, store)
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	url := fmt.Sprintf("/routes/%s/locations/batch", route.ID)
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/routes/%s/eta", route.ID), nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	got := requireBodyEta(t, recorder)
+	require.Equal(t, etaSourceLive, got.Source)
+	require.Equal(t, stored.RemainingDistanceKm.Float64, got.RemainingDistanceKm)
+	require.Equal(t, stored.RemainingDurationMin.Float64, got.RemainingDurationMin)
+	require.WithinDuration(t, stored.EtaAt.Time, got.PredictedArrivalAt, time.Second)
+}
+
+func TestListDeviationEvents(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	events := []db.DeviationEvent{
+		{
+			ID:         2,
+			RouteID:    route.ID,
+			Transition: string(deviation.OnRoute),
+			DistanceM:  40,
+			LocationID: sql.NullInt64{Int64: 42, Valid: true},
+			OccurredAt: time.Now().Add(-time.Minute).UTC().Truncate(time.Second),
+		},
+		{
+			ID:         1,
+			RouteID:    route.ID,
+			Transition: string(deviation.OffRoute),
+			DistanceM:  310,
+			OccurredAt: time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		userID        uuid.UUID
+		role          util.Role
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			userID: user.ID,
+			query:  "page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().
+					ListDeviationEvents(gomock.Any(), gomock.Eq(db.ListDeviationEventsParams{RouteID: route.ID, Limit: 5, Offset: 5})).
+					Times(1).
+					Return(events, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []DeviationEventResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 2)
+				require.Equal(t, "on_route", got[0].Transition)
+				require.Equal(t, 40.0, got[0].DistanceM)
+				require.Equal(t, int64(42), *got[0].LocationID)
+				require.WithinDuration(t, events[0].OccurredAt, got[0].OccurredAt, time.Second)
+				require.Equal(t, "off_route", got[1].Transition)
+				require.Nil(t, got[1].LocationID)
+			},
+		},
+		{
+			name:   "InvalidPageSize",
+			userID: user.ID,
+			query:  "page_id=1&page_size=100",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListDeviationEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "Admin",
+			userID: uuid.New(),
+			role:   util.RoleAdmin,
+			query:  "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListDeviationEvents(gomock.Any(), gomock.Any()).Times(1).Return([]db.DeviationEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UnauthorizedUser",
+			userID: uuid.New(),
+			query:  "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListDeviationEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			userID: user.ID,
+			query:  "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListDeviationEvents(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s/deviations?%s", route.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			role := util.RoleDriver
+			if tc.role != "" {
+				role = tc.role
+			}
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.userID, role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
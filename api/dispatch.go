@@ -0,0 +1,223 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/optimize"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+// maxDispatchShipments bounds how many shipments a single plan takes on.
+const maxDispatchShipments = 500
+
+var errShipmentsPlanned = errors.New("shipments were planned by another request, try again")
+
+type PlanDispatchRequest struct {
+	DepotID     string    `json:"depot_id" binding:"required,uuid"`
+	DepartureAt time.Time `json:"departure_at" binding:"required"`
+	// Time spent handing over each shipment
+	ServiceTimeMin float64 `json:"service_time_min" binding:"min=0,max=120"`
+	// Seed of the solver's local search, the same seed gives the same plan
+	Seed int64 `json:"seed"`
+}
+
+type DispatchRouteResponse struct {
+	Route  RouteResponse       `json:"route"`
+	Stops  []RouteStopResponse `json:"stops"`
+	LoadKg float64             `json:"load_kg"`
+}
+
+type DispatchPlanResponse struct {
+	Routes []DispatchRouteResponse `json:"routes"`
+	// Shipments no vehicle had room for, left for a later plan
+	Unassigned []uuid.UUID `json:"unassigned"`
+	DistanceKm float64     `json:"distance_km"`
+	LateStops  int         `json:"late_stops"`
+}
+
+// PlanDispatch splits the unplanned shipments due in the 24 hours after the
+// departure over the vehicles free to take a route, up to the capacity of
+// each, and stores a pending route per vehicle used. Routes leave the depot at the departure, deliver
+// their shipments in order and return to the depot.
+func (server *Server) PlanDispatch(ctx *gin.Context) {
+	var req PlanDispatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	depot, err := server.store.GetDepot(ctx, uuid.MustParse(req.DepotID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	vehicles, err := server.store.ListFleetVehicles(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	shipments, err := server.store.ListUnplannedShipments(ctx, db.ListUnplannedShipmentsParams{
+		Since:        req.DepartureAt,
+		Until:        req.DepartureAt.Add(24 * time.Hour),
+		MaxShipments: maxDispatchShipments,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	problem := optimize.FleetProblem{
+		Deliveries: make([]optimize.Delivery, 0, len(shipments)),
+		Vehicles:   make([]optimize.Vehicle, 0, len(vehicles)),
+		Departure:  req.DepartureAt,
+		Seed:       req.Seed,
+	}
+	// travel times are those of the heaviest vehicle, so windows hold for any
+	// vehicle a shipment ends up on
+	var maxCapacity int32
+	for _, vehicle := range vehicles {
+		problem.Vehicles = append(problem.Vehicles, optimize.Vehicle{CapacityKg: float64(vehicle.Capacity.Int32)})
+		if vehicle.Capacity.Int32 > maxCapacity {
+			maxCapacity = vehicle.Capacity.Int32
+		}
+	}
+	points := make([]eta.Point, 0, len(shipments)+1)
+	points = append(points, eta.Point{Lat: depot.Lat, Lng: depot.Lng})
+	for _, shipment := range shipments {
+		points = append(points, eta.Point{Lat: shipment.DropoffLat, Lng: shipment.DropoffLng})
+		problem.Deliveries = append(problem.Deliveries, optimize.Delivery{
+			Stop: optimize.Stop{
+				ServiceMin:  req.ServiceTimeMin,
+				WindowStart: shipment.WindowStart.Time,
+				WindowEnd:   shipment.WindowEnd.Time,
+			},
+			WeightKg: shipment.WeightKg,
+		})
+	}
+	class := eta.ClassForCapacity(maxCapacity)
+	roads := server.roadEstimator()
+	problem.Matrix = optimize.BuildMatrix(points, func(from, to eta.Point) (float64, float64) {
+		leg := roads.Estimate(from, to, class)
+		return leg.DistanceKm, leg.DurationMin
+	})
+
+	plan, err := optimize.SolveFleet(problem)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := DispatchPlanResponse{
+		Routes:     make([]DispatchRouteResponse, 0, len(plan.Trips)),
+		Unassigned: make([]uuid.UUID, 0, len(plan.Unassigned)),
+		LateStops:  plan.LateStops,
+	}
+	for _, i := range plan.Unassigned {
+		response.Unassigned = append(response.Unassigned, shipments[i].ID)
+	}
+	if len(plan.Trips) == 0 {
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+
+	arg := db.CreateDispatchPlanTxParams{Routes: make([]db.DispatchRoute, 0, len(plan.Trips))}
+	schedules := make([][]eta.StopEta, 0, len(plan.Trips))
+	for _, trip := range plan.Trips {
+		route, schedule := server.dispatchRoute(depot, vehicles[trip.Vehicle], shipments, trip.Order, req)
+		arg.Routes = append(arg.Routes, route)
+		schedules = append(schedules, schedule)
+	}
+
+	result, err := server.store.CreateDispatchPlanTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrShipmentPlanned) {
+			ctx.JSON(http.StatusConflict, errorResponse(errShipmentsPlanned))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	for r, created := range result.Routes {
+		stops := make([]RouteStopResponse, 0, len(created.Stops))
+		for s, stop := range created.Stops {
+			stopResponse := newRouteStopResponse(stop)
+			stopResponse.EstimatedArrivalAt = &schedules[r][s].ArrivalAt
+			stopResponse.EstimatedDepartureAt = &schedules[r][s].DepartureAt
+			stops = append(stops, stopResponse)
+		}
+		response.Routes = append(response.Routes, DispatchRouteResponse{
+			Route:  newRouteResponse(created.Route),
+			Stops:  stops,
+			LoadKg: plan.Trips[r].LoadKg,
+		})
+		response.DistanceKm += created.Route.EstimatedDistanceKm.Float64
+	}
+	response.DistanceKm = roundKm(response.DistanceKm)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// dispatchRoute drafts the pending route of vehicle delivering the shipments
+// at order from the depot and back, planned like any route with stops.
+// It also returns the predicted visit of every stop.
+func (server *Server) dispatchRoute(depot db.Depot, vehicle db.Vehicle, shipments []db.Shipment, order []int, req PlanDispatchRequest) (db.DispatchRoute, []eta.StopEta) {
+	stops := make([]db.NewRouteStop, 0, len(order))
+	waypoints := make([]eta.Stop, 0, len(order)+1)
+	for _, i := range order {
+		shipment := shipments[i]
+		stops = append(stops, db.NewRouteStop{
+			ShipmentID:     uuid.NullUUID{UUID: shipment.ID, Valid: true},
+			Lat:            shipment.DropoffLat,
+			Lng:            shipment.DropoffLng,
+			Address:        shipment.DropoffAddress,
+			ServiceTimeMin: req.ServiceTimeMin,
+			WindowStart:    shipment.WindowStart,
+			WindowEnd:      shipment.WindowEnd,
+		})
+		waypoints = append(waypoints, eta.Stop{
+			Point:      eta.Point{Lat: shipment.DropoffLat, Lng: shipment.DropoffLng},
+			ServiceMin: req.ServiceTimeMin,
+			NotBefore:  shipment.WindowStart.Time,
+		})
+	}
+
+	depotPoint := eta.Point{Lat: depot.Lat, Lng: depot.Lng}
+	waypoints = append(waypoints, eta.Stop{Point: depotPoint})
+	plan := server.planTrip(depotPoint, req.DepartureAt, waypoints, vehicle, vehicle.DriverID)
+	p10, p50, p90 := server.durationQuantiles(plan.Estimate)
+
+	route := db.DispatchRoute{
+		Route: db.CreateRouteParams{
+			ID:                   uuid.New(),
+			DriverID:             vehicle.DriverID,
+			VehicleID:            vehicle.ID,
+			OriginAddress:        depot.Address,
+			OriginLat:            depot.Lat,
+			OriginLng:            depot.Lng,
+			DestinationAddress:   depot.Address,
+			DestinationLat:       depot.Lat,
+			DestinationLng:       depot.Lng,
+			EstimatedDistanceKm:  sql.NullFloat64{Float64: plan.Estimate.DistanceKm, Valid: true},
+			EstimatedDurationMin: sql.NullFloat64{Float64: plan.Estimate.DurationMin, Valid: true},
+			BaselineDurationMin:  sql.NullFloat64{Float64: plan.BaselineDurationMin, Valid: true},
+			DurationP10Min:       p10,
+			DurationP50Min:       p50,
+			DurationP90Min:       p90,
+			Status:               string(util.RoutePending),
+		},
+		Stops: stops,
+		Path:  plan.Path,
+	}
+	return route, plan.Schedule[:len(order)]
+}
@@ -0,0 +1,340 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// storedDispatchPlan stands in for CreateDispatchPlanTx, storing the plan
+// the way the database would.
+func storedDispatchPlan(_ interface{}, arg db.CreateDispatchPlanTxParams) (db.CreateDispatchPlanTxResult, error) {
+	var result db.CreateDispatchPlanTxResult
+	for _, planned := range arg.Routes {
+		route := db.Route{
+			ID:                   planned.Route.ID,
+			DriverID:             planned.Route.DriverID,
+			VehicleID:            planned.Route.VehicleID,
+			OriginLat:            planned.Route.OriginLat,
+			OriginLng:            planned.Route.OriginLng,
+			DestinationLat:       planned.Route.DestinationLat,
+			DestinationLng:       planned.Route.DestinationLng,
+			OriginAddress:        planned.Route.OriginAddress,
+			DestinationAddress:   planned.Route.DestinationAddress,
+			EstimatedDistanceKm:  planned.Route.EstimatedDistanceKm,
+			EstimatedDurationMin: planned.Route.EstimatedDurationMin,
+			BaselineDurationMin:  planned.Route.BaselineDurationMin,
+			Status:               planned.Route.Status,
+		}
+		stops := make([]db.RouteStop, 0, len(planned.Stops))
+		for i, stop := range planned.Stops {
+			stops = append(stops, db.RouteStop{
+				ID:             int64(i + 1),
+				RouteID:        route.ID,
+				ShipmentID:     stop.ShipmentID,
+				Sequence:       int32(i + 1),
+				Lat:            stop.Lat,
+				Lng:            stop.Lng,
+				Address:        stop.Address,
+				ServiceTimeMin: stop.ServiceTimeMin,
+				Status:         string(util.StopPending),
+				WindowStart:    stop.WindowStart,
+				WindowEnd:      stop.WindowEnd,
+			})
+		}
+		result.Routes = append(result.Routes, db.DispatchRouteResult{Route: route, Stops: stops})
+	}
+	return result, nil
+}
+
+func TestPlanDispatch(t *testing.T) {
+	depot := randomDepot()
+	departure := time.Now().UTC().Truncate(time.Second)
+
+	vehicles := []db.Vehicle{RandomVehicle(t), RandomVehicle(t)}
+	vehicles[0].Capacity = sql.NullInt32{Int32: 60, Valid: true}
+	vehicles[1].Capacity = sql.NullInt32{Int32: 40, Valid: true}
+
+	// spread around the depot, together just filling both vehicles
+	shipments := make([]db.Shipment, 4)
+	for i, weight := range []float64{30, 30, 20, 20} {
+		shipments[i] = randomShipment(t, uuid.New())
+		shipments[i].DropoffLat = depot.Lat + 0.02*float64(i%2*2-1)
+		shipments[i].DropoffLng = depot.Lng + 0.01*float64(i)
+		shipments[i].WeightKg = weight
+	}
+	tooHeavy := append([]db.Shipment{}, shipments...)
+	tooHeavy[0].WeightKg = 100
+
+	body := gin.H{"depot_id": depot.ID, "departure_at": departure, "service_time_min": 5, "seed": 3}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		role          util.Role
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: body,
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(depot, nil)
+				store.EXPECT().ListFleetVehicles(gomock.Any()).Times(1).Return(vehicles, nil)
+				store.EXPECT().
+					ListUnplannedShipments(gomock.Any(), gomock.Eq(db.ListUnplannedShipmentsParams{
+						Since:        departure,
+						Until:        departure.Add(24 * time.Hour),
+						MaxShipments: maxDispatchShipments,
+					})).
+					Times(1).
+					Return(shipments, nil)
+				store.EXPECT().
+					CreateDispatchPlanTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx interface{}, arg db.CreateDispatchPlanTxParams) (db.CreateDispatchPlanTxResult, error) {
+						require.Len(t, arg.Routes, 2)
+						planned := make(map[uuid.UUID]bool)
+						for _, route := range arg.Routes {
+							require.Equal(t, depot.Lat, route.Route.OriginLat)
+							require.Equal(t, depot.Lat, route.Route.DestinationLat)
+							require.Equal(t, string(util.RoutePending), route.Route.Status)
+							require.Greater(t, route.Route.EstimatedDistanceKm.Float64, 0.0)
+							for _, stop := range route.Stops {
+								require.Equal(t, 5.0, stop.ServiceTimeMin)
+								planned[stop.ShipmentID.UUID] = true
+							}
+						}
+						require.Len(t, planned, len(shipments))
+						return storedDispatchPlan(ctx, arg)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyDispatchPlan(t, recorder)
+				require.Len(t, got.Routes, 2)
+				require.Empty(t, got.Unassigned)
+				require.Zero(t, got.LateStops)
+
+				byVehicle := map[uuid.UUID]db.Vehicle{vehicles[0].ID: vehicles[0], vehicles[1].ID: vehicles[1]}
+				var loadKg, distanceKm float64
+				for _, route := range got.Routes {
+					vehicle := byVehicle[route.Route.VehicleID]
+					require.Equal(t, vehicle.DriverID, route.Route.DriverID)
+					require.LessOrEqual(t, route.LoadKg, float64(vehicle.Capacity.Int32))
+					loadKg += route.LoadKg
+					distanceKm += route.Route.EstimatedDistanceKm
+					for _, stop := range route.Stops {
+						require.NotNil(t, stop.EstimatedArrivalAt)
+						require.True(t, stop.EstimatedArrivalAt.After(departure))
+					}
+				}
+				require.Equal(t, 100.0, loadKg)
+				require.InDelta(t, distanceKm, got.DistanceKm, 0.01)
+			},
+		},
+		{
+			name: "Unassigned",
+			body: body,
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(depot, nil)
+				store.EXPECT().ListFleetVehicles(gomock.Any()).Times(1).Return(vehicles, nil)
+				store.EXPECT().ListUnplannedShipments(gomock.Any(), gomock.Any()).Times(1).Return(tooHeavy, nil)
+				store.EXPECT().CreateDispatchPlanTx(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(storedDispatchPlan)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyDispatchPlan(t, recorder)
+				require.Equal(t, []uuid.UUID{tooHeavy[0].ID}, got.Unassigned)
+			},
+		},
+		{
+			name: "NoShipments",
+			body: body,
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(depot, nil)
+				store.EXPECT().ListFleetVehicles(gomock.Any()).Times(1).Return(vehicles, nil)
+				store.EXPECT().ListUnplannedShipments(gomock.Any(), gomock.Any()).Times(1).Return([]db.Shipment{}, nil)
+				store.EXPECT().CreateDispatchPlanTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyDispatchPlan(t, recorder)
+				require.Empty(t, got.Routes)
+				require.Empty(t, got.Unassigned)
+			},
+		},
+		{
+			name: "ShipmentPlanned",
+			body: body,
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(depot, nil)
+				store.EXPECT().ListFleetVehicles(gomock.Any()).Times(1).Return(vehicles, nil)
+				store.EXPECT().ListUnplannedShipments(gomock.Any(), gomock.Any()).Times(1).Return(shipments, nil)
+				store.EXPECT().CreateDispatchPlanTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateDispatchPlanTxResult{}, db.ErrShipmentPlanned)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "DepotNotFound",
+			body: body,
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(db.Depot{}, sql.ErrNoRows)
+				store.EXPECT().ListUnplannedShipments(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MissingDeparture",
+			body: gin.H{"depot_id": depot.ID},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidDepotID",
+			body: gin.H{"depot_id": "not-a-uuid", "departure_at": departure},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DriverForbidden",
+			body: body,
+			role: util.RoleDriver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body,
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(depot, nil)
+				store.EXPECT().ListFleetVehicles(gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+				store.EXPECT().CreateDispatchPlanTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/dispatch", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestPlanDispatchCorrectionModel(t *testing.T) {
+	depot := randomDepot()
+	departure := time.Now().UTC().Truncate(time.Second)
+	vehicle := RandomVehicle(t)
+	vehicle.Capacity = sql.NullInt32{Int32: 60, Valid: true}
+	shipments := []db.Shipment{randomShipment(t, uuid.New()), randomShipment(t, uuid.New())}
+	for i := range shipments {
+		shipments[i].DropoffLat = depot.Lat + 0.02*float64(i+1)
+		shipments[i].DropoffLng = depot.Lng + 0.01
+		shipments[i].WeightKg = 10
+		shipments[i].WindowStart = sql.NullTime{}
+		shipments[i].WindowEnd = sql.NullTime{}
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetDepot(gomock.Any(), gomock.Eq(depot.ID)).Times(1).Return(depot, nil)
+	store.EXPECT().ListFleetVehicles(gomock.Any()).Times(1).Return([]db.Vehicle{vehicle}, nil)
+	store.EXPECT().ListUnplannedShipments(gomock.Any(), gomock.Any()).Times(1).Return(shipments, nil)
+	store.EXPECT().
+		CreateDispatchPlanTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx interface{}, arg db.CreateDispatchPlanTxParams) (db.CreateDispatchPlanTxResult, error) {
+			require.Len(t, arg.Routes, 1)
+			// the whole round trip, deliveries included, takes the driver twice as long
+			route := arg.Routes[0].Route
+			require.Greater(t, route.BaselineDurationMin.Float64, 10.0)
+			require.InEpsilon(t, 2*route.BaselineDurationMin.Float64, route.EstimatedDurationMin.Float64, 0.1)
+			return storedDispatchPlan(ctx, arg)
+		})
+
+	server := NewTestServer(t, store)
+	server.correction = trainCorrectionModel(t, eta.CorrectionFeatures{
+		DistanceKm:   10,
+		Departure:    departure,
+		DriverID:     vehicle.DriverID.String(),
+		VehicleModel: vehicle.Model.String,
+		Origin:       eta.Point{Lat: depot.Lat, Lng: depot.Lng},
+		Destination:  eta.Point{Lat: depot.Lat, Lng: depot.Lng},
+	}, 30)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"depot_id": depot.ID, "departure_at": departure, "service_time_min": 5})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/admin/dispatch", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func requireBodyDispatchPlan(t *testing.T, recorder *httptest.ResponseRecorder) DispatchPlanResponse {
+	var got DispatchPlanResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	return got
+}
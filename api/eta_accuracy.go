@@ -0,0 +1,303 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+const (
+	accuracyDateFormat = "2006-01-02"
+	// defaultAccuracyDays is how many days, today included, accuracy is
+	// reported over when the request leaves out from.
+	defaultAccuracyDays = 30
+)
+
+var errInvalidAccuracyRange = errors.New("to must not be before from")
+
+type ETAAccuracyRequest struct {
+	// From and To are the first and last days routes were completed on, in
+	// the speed profile time zone. To is today and From 30 days before when
+	// left out.
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type ETAAccuracyRange struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone"`
+}
+
+// ETAAccuracyStats are the errors of the estimates of completed routes:
+// actual minus estimated duration, as a percentage of the actual duration
+// for the pct ones. A positive bias means routes take longer than estimated.
+type ETAAccuracyStats struct {
+	Trips             int64   `json:"trips"`
+	MeanAbsErrorMin   float64 `json:"mean_abs_error_min"`
+	MedianAbsErrorMin float64 `json:"median_abs_error_min"`
+	P90AbsErrorMin    float64 `json:"p90_abs_error_min"`
+	MeanAbsErrorPct   float64 `json:"mean_abs_error_pct"`
+	MedianAbsErrorPct float64 `json:"median_abs_error_pct"`
+	P90AbsErrorPct    float64 `json:"p90_abs_error_pct"`
+	BiasMin           float64 `json:"bias_min"`
+}
+
+func newETAAccuracyStats(trips int64, meanMin, medianMin, p90Min, meanPct, medianPct, p90Pct, biasMin float64) ETAAccuracyStats {
+	round := func(value float64) float64 {
+		return math.Round(value*100) / 100
+	}
+	return ETAAccuracyStats{
+		Trips:             trips,
+		MeanAbsErrorMin:   round(meanMin),
+		MedianAbsErrorMin: round(medianMin),
+		P90AbsErrorMin:    round(p90Min),
+		MeanAbsErrorPct:   round(meanPct),
+		MedianAbsErrorPct: round(medianPct),
+		P90AbsErrorPct:    round(p90Pct),
+		BiasMin:           round(biasMin),
+	}
+}
+
+type ETAAccuracyResponse struct {
+	ETAAccuracyRange
+	ETAAccuracyStats
+}
+
+type DriverETAAccuracy struct {
+	DriverID   uuid.UUID `json:"driver_id"`
+	DriverName string    `json:"driver_name"`
+	ETAAccuracyStats
+}
+
+type DriverETAAccuracyResponse struct {
+	ETAAccuracyRange
+	Drivers []DriverETAAccuracy `json:"drivers"`
+}
+
+type VehicleETAAccuracy struct {
+	VehicleID    uuid.UUID `json:"vehicle_id"`
+	LicensePlate string    `json:"license_plate"`
+	Model        string    `json:"model"`
+	ETAAccuracyStats
+}
+
+type VehicleETAAccuracyResponse struct {
+	ETAAccuracyRange
+	Vehicles []VehicleETAAccuracy `json:"vehicles"`
+}
+
+type HourETAAccuracy struct {
+	// Hour is the hour of the day routes started in, in the range's time zone
+	Hour int32 `json:"hour"`
+	ETAAccuracyStats
+}
+
+type HourETAAccuracyResponse struct {
+	ETAAccuracyRange
+	Hours []HourETAAccuracy `json:"hours"`
+}
+
+type DistanceBandETAAccuracy struct {
+	MinDistanceKm float64 `json:"min_distance_km"`
+	// MaxDistanceKm is left out for the last band, which has no end
+	MaxDistanceKm *float64 `json:"max_distance_km,omitempty"`
+	ETAAccuracyStats
+}
+
+type DistanceBandETAAccuracyResponse struct {
+	ETAAccuracyRange
+	DistanceBands []DistanceBandETAAccuracy `json:"distance_bands"`
+}
+
+// bindAccuracyRange binds the days of an accuracy request, returning them
+// and the times routes must be completed in, or responds with why it can't.
+func (server *Server) bindAccuracyRange(ctx *gin.Context) (ETAAccuracyRange, time.Time, time.Time, bool) {
+	var req ETAAccuracyRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return ETAAccuracyRange{}, time.Time{}, time.Time{}, false
+	}
+
+	now := time.Now().In(server.profileLocation)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, server.profileLocation)
+	if req.To != "" {
+		to, _ = time.ParseInLocation(accuracyDateFormat, req.To, server.profileLocation)
+	}
+	from := to.AddDate(0, 0, 1-defaultAccuracyDays)
+	if req.From != "" {
+		from, _ = time.ParseInLocation(accuracyDateFormat, req.From, server.profileLocation)
+	}
+	if to.Before(from) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidAccuracyRange))
+		return ETAAccuracyRange{}, time.Time{}, time.Time{}, false
+	}
+
+	days := ETAAccuracyRange{
+		From:     from.Format(accuracyDateFormat),
+		To:       to.Format(accuracyDateFormat),
+		Timezone: server.profileLocation.String(),
+	}
+	return days, from, to.AddDate(0, 0, 1), true
+}
+
+// GetETAAccuracy reports the errors of the estimates of the routes completed
+// over a range of days.
+func (server *Server) GetETAAccuracy(ctx *gin.Context) {
+	days, from, to, ok := server.bindAccuracyRange(ctx)
+	if !ok {
+		return
+	}
+
+	row, err := server.store.GetETAAccuracy(ctx, db.GetETAAccuracyParams{
+		CompletedFrom: from,
+		CompletedTo:   to,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, ETAAccuracyResponse{
+		ETAAccuracyRange: days,
+		ETAAccuracyStats: newETAAccuracyStats(row.Trips, row.MeanAbsErrorMin, row.MedianAbsErrorMin, row.P90AbsErrorMin,
+			row.MeanAbsErrorPct, row.MedianAbsErrorPct, row.P90AbsErrorPct, row.BiasMin),
+	})
+}
+
+// ListETAAccuracyByDriver reports estimate errors by driver, those with the
+// most routes first.
+func (server *Server) ListETAAccuracyByDriver(ctx *gin.Context) {
+	days, from, to, ok := server.bindAccuracyRange(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := server.store.ListETAAccuracyByDriver(ctx, db.ListETAAccuracyByDriverParams{
+		CompletedFrom: from,
+		CompletedTo:   to,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := DriverETAAccuracyResponse{
+		ETAAccuracyRange: days,
+		Drivers:          make([]DriverETAAccuracy, 0, len(rows)),
+	}
+	for _, row := range rows {
+		response.Drivers = append(response.Drivers, DriverETAAccuracy{
+			DriverID:   row.DriverID,
+			DriverName: row.DriverName,
+			ETAAccuracyStats: newETAAccuracyStats(row.Trips, row.MeanAbsErrorMin, row.MedianAbsErrorMin, row.P90AbsErrorMin,
+				row.MeanAbsErrorPct, row.MedianAbsErrorPct, row.P90AbsErrorPct, row.BiasMin),
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ListETAAccuracyByVehicle reports estimate errors by vehicle, those with
+// the most routes first.
+func (server *Server) ListETAAccuracyByVehicle(ctx *gin.Context) {
+	days, from, to, ok := server.bindAccuracyRange(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := server.store.ListETAAccuracyByVehicle(ctx, db.ListETAAccuracyByVehicleParams{
+		CompletedFrom: from,
+		CompletedTo:   to,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := VehicleETAAccuracyResponse{
+		ETAAccuracyRange: days,
+		Vehicles:         make([]VehicleETAAccuracy, 0, len(rows)),
+	}
+	for _, row := range rows {
+		response.Vehicles = append(response.Vehicles, VehicleETAAccuracy{
+			VehicleID:    row.VehicleID,
+			LicensePlate: row.LicensePlate,
+			Model:        row.VehicleModel,
+			ETAAccuracyStats: newETAAccuracyStats(row.Trips, row.MeanAbsErrorMin, row.MedianAbsErrorMin, row.P90AbsErrorMin,
+				row.MeanAbsErrorPct, row.MedianAbsErrorPct, row.P90AbsErrorPct, row.BiasMin),
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ListETAAccuracyByHour reports estimate errors by the hour of the day
+// routes started in. Hours without routes are left out.
+func (server *Server) ListETAAccuracyByHour(ctx *gin.Context) {
+	days, from, to, ok := server.bindAccuracyRange(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := server.store.ListETAAccuracyByHour(ctx, db.ListETAAccuracyByHourParams{
+		Timezone:      days.Timezone,
+		CompletedFrom: from,
+		CompletedTo:   to,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := HourETAAccuracyResponse{
+		ETAAccuracyRange: days,
+		Hours:            make([]HourETAAccuracy, 0, len(rows)),
+	}
+	for _, row := range rows {
+		response.Hours = append(response.Hours, HourETAAccuracy{
+			Hour: row.Hour,
+			ETAAccuracyStats: newETAAccuracyStats(row.Trips, row.MeanAbsErrorMin, row.MedianAbsErrorMin, row.P90AbsErrorMin,
+				row.MeanAbsErrorPct, row.MedianAbsErrorPct, row.P90AbsErrorPct, row.BiasMin),
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ListETAAccuracyByDistanceBand reports estimate errors by band of estimated
+// distance. Bands without routes are left out.
+func (server *Server) ListETAAccuracyByDistanceBand(ctx *gin.Context) {
+	days, from, to, ok := server.bindAccuracyRange(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := server.store.ListETAAccuracyByDistanceBand(ctx, db.ListETAAccuracyByDistanceBandParams{
+		CompletedFrom: from,
+		CompletedTo:   to,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	response := DistanceBandETAAccuracyResponse{
+		ETAAccuracyRange: days,
+		DistanceBands:    make([]DistanceBandETAAccuracy, 0, len(rows)),
+	}
+	for _, row := range rows {
+		band := DistanceBandETAAccuracy{
+			MinDistanceKm: row.MinDistanceKm,
+			ETAAccuracyStats: newETAAccuracyStats(row.Trips, row.MeanAbsErrorMin, row.MedianAbsErrorMin, row.P90AbsErrorMin,
+				row.MeanAbsErrorPct, row.MedianAbsErrorPct, row.P90AbsErrorPct, row.BiasMin),
+		}
+		for _, start := range eta.DistanceBandsKm {
+			if start > row.MinDistanceKm {
+				end := start
+				band.MaxDistanceKm = &end
+				break
+			}
+		}
+		response.DistanceBands = append(response.DistanceBands, band)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetETAAccuracy(t *testing.T) {
+	row := db.GetETAAccuracyRow{
+		Trips:             12,
+		MeanAbsErrorMin:   6.255,
+		MedianAbsErrorMin: 5,
+		P90AbsErrorMin:    11.5,
+		MeanAbsErrorPct:   14.2,
+		MedianAbsErrorPct: 12,
+		P90AbsErrorPct:    30.75,
+		BiasMin:           -2.5,
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		role          util.Role
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: url.Values{"from": {"2024-03-01"}, "to": {"2024-03-07"}},
+			role:  util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetETAAccuracy(gomock.Any(), gomock.Eq(db.GetETAAccuracyParams{
+						CompletedFrom: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+						CompletedTo:   time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+					})).
+					Times(1).
+					Return(row, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got ETAAccuracyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, ETAAccuracyRange{From: "2024-03-01", To: "2024-03-07", Timezone: "UTC"}, got.ETAAccuracyRange)
+				require.Equal(t, int64(12), got.Trips)
+				require.Equal(t, 6.26, got.MeanAbsErrorMin)
+				require.Equal(t, 30.75, got.P90AbsErrorPct)
+				require.Equal(t, -2.5, got.BiasMin)
+			},
+		},
+		{
+			name:  "DefaultRange",
+			query: url.Values{},
+			role:  util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetETAAccuracy(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.GetETAAccuracyParams) (db.GetETAAccuracyRow, error) {
+						// the last 30 days, today included
+						require.Equal(t, defaultAccuracyDays*24*time.Hour, arg.CompletedTo.Sub(arg.CompletedFrom))
+						require.True(t, arg.CompletedTo.After(time.Now()))
+						require.True(t, arg.CompletedTo.Add(-24*time.Hour).Before(time.Now()))
+						return db.GetETAAccuracyRow{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got ETAAccuracyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, time.Now().UTC().Format(accuracyDateFormat), got.To)
+				require.Zero(t, got.Trips)
+			},
+		},
+		{
+			name:  "InvalidDate",
+			query: url.Values{"from": {"03/01/2024"}},
+			role:  util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetETAAccuracy(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "ReversedRange",
+			query: url.Values{"from": {"2024-03-07"}, "to": {"2024-03-01"}},
+			role:  util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetETAAccuracy(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "Forbidden",
+			query: url.Values{},
+			role:  util.RoleDriver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetETAAccuracy(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: url.Values{},
+			role:  util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetETAAccuracy(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetETAAccuracyRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/eta_accuracy?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListETAAccuracySlices(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	query := url.Values{"from": {"2024-03-01"}, "to": {"2024-03-07"}}
+	driverID := uuid.New()
+	vehicleID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		path          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Drivers",
+			path: "/admin/eta_accuracy/drivers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListETAAccuracyByDriver(gomock.Any(), gomock.Eq(db.ListETAAccuracyByDriverParams{CompletedFrom: from, CompletedTo: to})).
+					Times(1).
+					Return([]db.ListETAAccuracyByDriverRow{{DriverID: driverID, DriverName: "Ada", Trips: 4, BiasMin: 3}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got DriverETAAccuracyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "2024-03-01", got.From)
+				require.Len(t, got.Drivers, 1)
+				require.Equal(t, driverID, got.Drivers[0].DriverID)
+				require.Equal(t, "Ada", got.Drivers[0].DriverName)
+				require.Equal(t, int64(4), got.Drivers[0].Trips)
+				require.Equal(t, 3.0, got.Drivers[0].BiasMin)
+			},
+		},
+		{
+			name: "Vehicles",
+			path: "/admin/eta_accuracy/vehicles",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListETAAccuracyByVehicle(gomock.Any(), gomock.Eq(db.ListETAAccuracyByVehicleParams{CompletedFrom: from, CompletedTo: to})).
+					Times(1).
+					Return([]db.ListETAAccuracyByVehicleRow{{VehicleID: vehicleID, LicensePlate: "LAG-123", VehicleModel: "Sprinter", Trips: 2}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got VehicleETAAccuracyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got.Vehicles, 1)
+				require.Equal(t, vehicleID, got.Vehicles[0].VehicleID)
+				require.Equal(t, "LAG-123", got.Vehicles[0].LicensePlate)
+				require.Equal(t, "Sprinter", got.Vehicles[0].Model)
+			},
+		},
+		{
+			name: "Hours",
+			path: "/admin/eta_accuracy/hours",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListETAAccuracyByHour(gomock.Any(), gomock.Eq(db.ListETAAccuracyByHourParams{Timezone: "UTC", CompletedFrom: from, CompletedTo: to})).
+					Times(1).
+					Return([]db.ListETAAccuracyByHourRow{{Hour: 8, Trips: 5}, {Hour: 17, Trips: 3}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got HourETAAccuracyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "UTC", got.Timezone)
+				require.Len(t, got.Hours, 2)
+				require.Equal(t, int32(17), got.Hours[1].Hour)
+				require.Equal(t, int64(3), got.Hours[1].Trips)
+			},
+		},
+		{
+			name: "DistanceBands",
+			path: "/admin/eta_accuracy/distance_bands",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListETAAccuracyByDistanceBand(gomock.Any(), gomock.Eq(db.ListETAAccuracyByDistanceBandParams{CompletedFrom: from, CompletedTo: to})).
+					Times(1).
+					Return([]db.ListETAAccuracyByDistanceBandRow{{MinDistanceKm: 5, Trips: 7}, {MinDistanceKm: 100, Trips: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got DistanceBandETAAccuracyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got.DistanceBands, 2)
+				require.Equal(t, 5.0, got.DistanceBands[0].MinDistanceKm)
+				require.NotNil(t, got.DistanceBands[0].MaxDistanceKm)
+				require.Equal(t, 20.0, *got.DistanceBands[0].MaxDistanceKm)
+				// the last band has no end
+				require.Nil(t, got.DistanceBands[1].MaxDistanceKm)
+			},
+		},
+		{
+			name: "InternalError",
+			path: "/admin/eta_accuracy/drivers",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListETAAccuracyByDriver(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.path+"?"+query.Encode(), nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
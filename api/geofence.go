@@ -0,0 +1,312 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/geofence"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+type GeofenceEventResponse struct {
+	ID         int64     `json:"id"`
+	RouteID    uuid.UUID `json:"route_id"`
+	Place      string    `json:"place"`
+	StopID     *int64    `json:"stop_id,omitempty"`
+	Transition string    `json:"transition"`
+	// LocationID is the first position on the new side of the fence, left
+	// out once it is deleted
+	LocationID *int64    `json:"location_id,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func newGeofenceEventResponse(event db.GeofenceEvent) GeofenceEventResponse {
+	response := GeofenceEventResponse{
+		ID:         event.ID,
+		RouteID:    event.RouteID,
+		Place:      event.Place,
+		Transition: event.Transition,
+		OccurredAt: event.OccurredAt,
+	}
+	if event.StopID.Valid {
+		response.StopID = &event.StopID.Int64
+	}
+	if event.LocationID.Valid {
+		response.LocationID = &event.LocationID.Int64
+	}
+	return response
+}
+
+type ListGeofenceEventsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListGeofenceEvents lists the arrivals and departures detected along the
+// route, latest first, to its driver and to admins.
+func (server *Server) ListGeofenceEvents(ctx *gin.Context) {
+	route, ok := server.getReadableRoute(ctx)
+	if !ok {
+		return
+	}
+	var req ListGeofenceEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	events, err := server.store.ListGeofenceEvents(ctx, db.ListGeofenceEventsParams{
+		RouteID: route.ID,
+		Limit:   req.PageSize,
+		Offset:  (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]GeofenceEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, newGeofenceEventResponse(event))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// geofenceRadius returns radius, or the configured default when it's null.
+func (server *Server) geofenceRadius(radius sql.NullFloat64) float64 {
+	if radius.Valid {
+		return radius.Float64
+	}
+	if server.config.GeofenceRadiusM > 0 {
+		return server.config.GeofenceRadiusM
+	}
+	return geofence.DefaultRadiusM
+}
+
+// evaluateGeofences checks the route's recent positions, which must be
+// ordered oldest first, against the geofences around its places. The
+// arrivals and departures they confirm are recorded and move the route along
+// on the driver's behalf: leaving the origin starts the route, arriving at a
+// stop and leaving it again completes it, and arriving at the destination
+// with every stop done with completes the route. It returns the route as it
+// is afterwards.
+func (server *Server) evaluateGeofences(ctx *gin.Context, route db.Route, recent []db.RouteLocation) (db.Route, error) {
+	pings := make([]geofence.Ping, 0, len(recent))
+	for _, location := range recent {
+		pings = append(pings, geofence.Ping{
+			ID:         location.ID,
+			Point:      eta.Point{Lat: location.Lat, Lng: location.Lng},
+			AccuracyM:  location.AccuracyM.Float64,
+			RecordedAt: location.RecordedAt,
+		})
+	}
+
+	// stops and the destination only count once the trip has started
+	since := route.StartedAt.Time
+	if util.RouteStatus(route.Status) == util.RoutePending {
+		var err error
+		route, since, err = server.detectOriginDeparture(ctx, route, pings)
+		if err != nil {
+			return route, err
+		}
+	}
+	if util.RouteStatus(route.Status) != util.RouteInProgress {
+		return route, nil
+	}
+
+	stopsDone, doneAt, err := server.detectStopVisits(ctx, route, pings, since)
+	if err != nil {
+		return route, err
+	}
+	return server.detectDestinationArrival(ctx, route, pings, since, stopsDone, doneAt)
+}
+
+// detectOriginDeparture starts a pending route once its driver is seen at
+// the origin and then leaving it, and returns when they left.
+func (server *Server) detectOriginDeparture(ctx *gin.Context, route db.Route, pings []geofence.Ping) (db.Route, time.Time, error) {
+	origin := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+	fence, err := server.placeFence(ctx, route, util.GeofenceOrigin, origin, route.OriginGeofenceRadiusM, time.Time{})
+	if err != nil {
+		return route, time.Time{}, err
+	}
+
+	var departed time.Time
+	for _, crossing := range server.geofences.Detect(fence, pings) {
+		if err := server.recordGeofenceEvent(ctx, route, util.GeofenceOrigin, sql.NullInt64{}, crossing); err != nil {
+			return route, departed, err
+		}
+		if crossing.Transition == geofence.Departure && util.RouteStatus(route.Status) == util.RoutePending {
+			departed = crossing.Ping.RecordedAt
+			route, err = server.advanceRoute(ctx, route, util.RouteInProgress, departed)
+			if err != nil {
+				return route, departed, err
+			}
+		}
+	}
+	return route, departed, nil
+}
+
+// detectStopVisits marks the route's stops arrived at and completed as the
+// driver enters and leaves their geofences. It reports whether every stop is
+// done with, and when the last of them was.
+func (server *Server) detectStopVisits(ctx *gin.Context, route db.Route, pings []geofence.Ping, since time.Time) (bool, time.Time, error) {
+	stops, err := server.store.ListRouteStops(ctx, route.ID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	done := true
+	var doneAt time.Time
+	for _, stop := range stops {
+		if util.StopStatus(stop.Status).IsTerminal() {
+			if stop.DepartedAt.Time.After(doneAt) {
+				doneAt = stop.DepartedAt.Time
+			}
+			continue
+		}
+		fence := geofence.Fence{
+			Center:  eta.Point{Lat: stop.Lat, Lng: stop.Lng},
+			RadiusM: server.geofenceRadius(stop.GeofenceRadiusM),
+			Since:   since,
+		}
+		if util.StopStatus(stop.Status) == util.StopArrived {
+			fence.Inside = true
+			fence.Since = stop.ArrivedAt.Time
+		}
+
+		for _, crossing := range server.geofences.Detect(fence, pings) {
+			stopID := sql.NullInt64{Int64: stop.ID, Valid: true}
+			if err := server.recordGeofenceEvent(ctx, route, util.GeofenceStop, stopID, crossing); err != nil {
+				return false, time.Time{}, err
+			}
+			next := util.StopArrived
+			if crossing.Transition == geofence.Departure {
+				next = util.StopCompleted
+			}
+			if !util.StopStatus(stop.Status).CanTransitionTo(next) {
+				continue
+			}
+			stop, err = server.advanceStop(ctx, route, stop, next, crossing.Ping.RecordedAt)
+			if err != nil {
+				return false, time.Time{}, err
+			}
+		}
+		if !util.StopStatus(stop.Status).IsTerminal() {
+			done = false
+		} else if stop.DepartedAt.Time.After(doneAt) {
+			doneAt = stop.DepartedAt.Time
+		}
+	}
+	return done, doneAt, nil
+}
+
+// detectDestinationArrival completes the route once the driver is at its
+// destination with every stop done with, whether they just arrived or
+// finished the last stop, at stopsDoneAt, after arriving. The route is
+// completed as of whichever came last.
+func (server *Server) detectDestinationArrival(ctx *gin.Context, route db.Route, pings []geofence.Ping, since time.Time, stopsDone bool, stopsDoneAt time.Time) (db.Route, error) {
+	destination := eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng}
+	fence, err := server.placeFence(ctx, route, util.GeofenceDestination, destination, route.DestinationGeofenceRadiusM, since)
+	if err != nil {
+		return route, err
+	}
+
+	inside, arrivedAt := fence.Inside, fence.Since
+	for _, crossing := range server.geofences.Detect(fence, pings) {
+		if err := server.recordGeofenceEvent(ctx, route, util.GeofenceDestination, sql.NullInt64{}, crossing); err != nil {
+			return route, err
+		}
+		inside, arrivedAt = crossing.Transition == geofence.Arrival, crossing.Ping.RecordedAt
+	}
+	if !inside || !stopsDone {
+		return route, nil
+	}
+	if stopsDoneAt.After(arrivedAt) {
+		arrivedAt = stopsDoneAt
+	}
+	return server.advanceRoute(ctx, route, util.RouteCompleted, arrivedAt)
+}
+
+// placeFence returns the fence around the route's origin or destination,
+// with the driver on the side the latest event there left them, and outside
+// since since before any.
+func (server *Server) placeFence(ctx *gin.Context, route db.Route, place util.GeofencePlace, center eta.Point, radius sql.NullFloat64, since time.Time) (geofence.Fence, error) {
+	fence := geofence.Fence{
+		Center:  center,
+		RadiusM: server.geofenceRadius(radius),
+		Since:   since,
+	}
+	latest, err := server.store.GetLatestGeofenceEvent(ctx, db.GetLatestGeofenceEventParams{
+		RouteID: route.ID,
+		Place:   string(place),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fence, nil
+		}
+		return fence, err
+	}
+	fence.Inside = latest.Transition == string(geofence.Arrival)
+	fence.Since = latest.OccurredAt
+	return fence, nil
+}
+
+func (server *Server) recordGeofenceEvent(ctx *gin.Context, route db.Route, place util.GeofencePlace, stopID sql.NullInt64, crossing geofence.Crossing) error {
+	event, err := server.store.CreateGeofenceEvent(ctx, db.CreateGeofenceEventParams{
+		RouteID:    route.ID,
+		Place:      string(place),
+		StopID:     stopID,
+		Transition: string(crossing.Transition),
+		LocationID: sql.NullInt64{Int64: crossing.Ping.ID, Valid: true},
+		OccurredAt: crossing.Ping.RecordedAt,
+	})
+	if err != nil {
+		return err
+	}
+	server.publish(tracking.EventGeofence, route, newGeofenceEventResponse(event))
+	return nil
+}
+
+// advanceRoute moves the route to status on its driver's behalf, as of at,
+// when the position that moved it was recorded. A route another request moved
+// first, or whose vehicle is busy on another route, is left as it is for the
+// driver to sort out.
+func (server *Server) advanceRoute(ctx *gin.Context, route db.Route, status util.RouteStatus, at time.Time) (db.Route, error) {
+	updated, err := server.transitionRoute(ctx, route, status, at)
+	if err != nil {
+		if err == sql.ErrNoRows || errors.Is(err, db.ErrRouteStatusChanged) || errors.Is(err, db.ErrVehicleInUse) {
+			return route, nil
+		}
+		return route, err
+	}
+	server.publish(tracking.EventStatus, updated, newRouteResponse(updated))
+	return updated, nil
+}
+
+// advanceStop moves the stop to status on the driver's behalf as of at,
+// leaving a stop another request moved first as it is.
+func (server *Server) advanceStop(ctx *gin.Context, route db.Route, stop db.RouteStop, status util.StopStatus, at time.Time) (db.RouteStop, error) {
+	updated, err := server.store.TransitionRouteStopStatus(ctx, db.TransitionRouteStopStatusParams{
+		ToStatus:      string(status),
+		At:            at,
+		FailureReason: stop.FailureReason,
+		ID:            stop.ID,
+		RouteID:       route.ID,
+		FromStatus:    stop.Status,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return stop, nil
+		}
+		return stop, err
+	}
+	server.publish(tracking.EventStop, route, newRouteStopResponse(updated))
+	return updated, nil
+}
@@ -0,0 +1,515 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/deviation"
+	"github.com/joekings2k/logistics-eta/geofence"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// pingsNear are positions of the route reported every 30 seconds until
+// now, metresNorth of lat, lng.
+func pingsNear(route db.Route, lat, lng, metresNorth float64, now time.Time, n int) []db.RouteLocation {
+	locations := make([]db.RouteLocation, n)
+	for i := range locations {
+		locations[i] = db.RouteLocation{
+			ID:         int64(100 + i),
+			RouteID:    route.ID,
+			DriverID:   route.DriverID,
+			Lat:        lat + metresNorth/111195,
+			Lng:        lng,
+			RecordedAt: now.Add(-time.Duration(n-1-i) * 30 * time.Second),
+		}
+	}
+	return locations
+}
+
+// expectPingsStored stubs storing locations and checking them against the
+// route's geofences, and returns the batch request reporting them.
+func expectPingsStored(store *mockdb.MockStore, route db.Route, locations []db.RouteLocation) gin.H {
+	pings := make([]gin.H, 0, len(locations))
+	arg := db.CreateRouteLocationsTxParams{}
+	for _, location := range locations {
+		pings = append(pings, gin.H{"lat": location.Lat, "lng": location.Lng, "recorded_at": location.RecordedAt})
+		arg.Locations = append(arg.Locations, db.CreateRouteLocationParams{
+			RouteID:    route.ID,
+			DriverID:   route.DriverID,
+			Lat:        location.Lat,
+			Lng:        location.Lng,
+			RecordedAt: location.RecordedAt,
+		})
+	}
+	store.EXPECT().
+		CreateRouteLocationsTx(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(db.CreateRouteLocationsTxResult{Locations: locations}, nil)
+	store.EXPECT().
+		ListRouteLocationsSince(gomock.Any(), gomock.Eq(db.ListRouteLocationsSinceParams{
+			RouteID:    route.ID,
+			RecordedAt: locations[0].RecordedAt.Add(-deviation.DefaultDwell),
+		})).
+		Times(1).
+		Return(locations, nil)
+	return gin.H{"locations": pings}
+}
+
+func expectGeofenceEvent(store *mockdb.MockStore, route db.Route, place util.GeofencePlace, stopID int64, transition geofence.Transition, first db.RouteLocation) {
+	arg := db.CreateGeofenceEventParams{
+		RouteID:    route.ID,
+		Place:      string(place),
+		Transition: string(transition),
+		LocationID: sql.NullInt64{Int64: first.ID, Valid: true},
+		OccurredAt: first.RecordedAt,
+	}
+	if stopID != 0 {
+		arg.StopID = sql.NullInt64{Int64: stopID, Valid: true}
+	}
+	store.EXPECT().
+		CreateGeofenceEvent(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(db.GeofenceEvent{ID: 1, RouteID: route.ID, Place: arg.Place, StopID: arg.StopID, Transition: arg.Transition, OccurredAt: arg.OccurredAt}, nil)
+}
+
+func expectLatestGeofenceEvent(store *mockdb.MockStore, route db.Route, place util.GeofencePlace, event db.GeofenceEvent, err error) {
+	store.EXPECT().
+		GetLatestGeofenceEvent(gomock.Any(), gomock.Eq(db.GetLatestGeofenceEventParams{
+			RouteID: route.ID,
+			Place:   string(place),
+		})).
+		Times(1).
+		Return(event, err)
+}
+
+func TestRecordLocationGeofences(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pending := randomRoute(t, vehicle)
+	started := pending
+	started.Status = string(util.RouteInProgress)
+	started.StartedAt = sql.NullTime{Time: now.Add(-30 * time.Minute), Valid: true}
+	completed := started
+	completed.Status = string(util.RouteCompleted)
+	completed.CompletedAt = sql.NullTime{Time: now, Valid: true}
+
+	stop := randomRouteStops(started, 1)[0]
+	arrived := stop
+	arrived.Status = string(util.StopArrived)
+	arrived.ArrivedAt = sql.NullTime{Time: now.Add(-10 * time.Minute), Valid: true}
+	left := arrived
+	left.Status = string(util.StopCompleted)
+	left.DepartedAt = sql.NullTime{Time: now.Add(-5 * time.Minute), Valid: true}
+
+	// the driver was seen at the origin before the new positions
+	atOrigin := db.GeofenceEvent{
+		RouteID:    pending.ID,
+		Place:      string(util.GeofenceOrigin),
+		Transition: string(geofence.Arrival),
+		OccurredAt: now.Add(-20 * time.Minute),
+	}
+
+	testCases := []struct {
+		name       string
+		route      db.Route
+		buildStubs func(store *mockdb.MockStore) gin.H
+	}{
+		{
+			name:  "LeaveOrigin",
+			route: pending,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(pending, pending.OriginLat, pending.OriginLng, 500, now, 4)
+				body := expectPingsStored(store, pending, locations)
+				expectLatestGeofenceEvent(store, pending, util.GeofenceOrigin, atOrigin, nil)
+				expectGeofenceEvent(store, pending, util.GeofenceOrigin, 0, geofence.Departure, locations[0])
+				store.EXPECT().
+					StartRouteTx(gomock.Any(), gomock.Eq(db.StartRouteTxParams{RouteID: pending.ID, FromStatus: pending.Status, At: locations[0].RecordedAt})).
+					Times(1).
+					Return(db.StartRouteTxResult{Route: started}, nil)
+				// the stops and destination are checked as soon as the route starts
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return([]db.RouteStop{stop}, nil)
+				expectLatestGeofenceEvent(store, pending, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectDeviationCheck(t, store, started, stop)
+				expectEtaRefresh(store, started, vehicle, locations[3], stop)
+				return body
+			},
+		},
+		{
+			name:  "WaitAtOrigin",
+			route: pending,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				// GPS noise just beyond the radius doesn't start the route
+				locations := pingsNear(pending, pending.OriginLat, pending.OriginLng, geofence.DefaultRadiusM+50, now, 4)
+				body := expectPingsStored(store, pending, locations)
+				expectLatestGeofenceEvent(store, pending, util.GeofenceOrigin, atOrigin, nil)
+				store.EXPECT().CreateGeofenceEvent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(0)
+				return body
+			},
+		},
+		{
+			name:  "VehicleInUse",
+			route: pending,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(pending, pending.OriginLat, pending.OriginLng, 500, now, 4)
+				body := expectPingsStored(store, pending, locations)
+				expectLatestGeofenceEvent(store, pending, util.GeofenceOrigin, atOrigin, nil)
+				expectGeofenceEvent(store, pending, util.GeofenceOrigin, 0, geofence.Departure, locations[0])
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(1).Return(db.StartRouteTxResult{}, db.ErrVehicleInUse)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(0)
+				return body
+			},
+		},
+		{
+			name:  "ArriveAtStop",
+			route: started,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(started, stop.Lat, stop.Lng, 20, now, 3)
+				body := expectPingsStored(store, started, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(started.ID)).Times(1).Return([]db.RouteStop{stop}, nil)
+				expectGeofenceEvent(store, started, util.GeofenceStop, stop.ID, geofence.Arrival, locations[0])
+				store.EXPECT().
+					TransitionRouteStopStatus(gomock.Any(), gomock.Eq(db.TransitionRouteStopStatusParams{
+						ToStatus:   string(util.StopArrived),
+						At:         locations[0].RecordedAt,
+						ID:         stop.ID,
+						RouteID:    started.ID,
+						FromStatus: string(util.StopPending),
+					})).
+					Times(1).
+					Return(arrived, nil)
+				expectLatestGeofenceEvent(store, started, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectDeviationCheck(t, store, started, arrived)
+				expectEtaRefresh(store, started, vehicle, locations[2], arrived)
+				return body
+			},
+		},
+		{
+			name:  "ArriveAtStopReportedLate",
+			route: started,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				// positions buffered offline are uploaded 20 minutes after
+				// the driver reached the stop
+				locations := pingsNear(started, stop.Lat, stop.Lng, 20, now.Add(-20*time.Minute), 3)
+				body := expectPingsStored(store, started, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(started.ID)).Times(1).Return([]db.RouteStop{stop}, nil)
+				expectGeofenceEvent(store, started, util.GeofenceStop, stop.ID, geofence.Arrival, locations[0])
+				store.EXPECT().
+					TransitionRouteStopStatus(gomock.Any(), gomock.Eq(db.TransitionRouteStopStatusParams{
+						ToStatus:   string(util.StopArrived),
+						At:         now.Add(-21 * time.Minute),
+						ID:         stop.ID,
+						RouteID:    started.ID,
+						FromStatus: string(util.StopPending),
+					})).
+					Times(1).
+					Return(arrived, nil)
+				expectLatestGeofenceEvent(store, started, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectDeviationCheck(t, store, started, arrived)
+				expectEtaRefresh(store, started, vehicle, locations[2], arrived)
+				return body
+			},
+		},
+		{
+			name:  "LeaveStop",
+			route: started,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(started, stop.Lat, stop.Lng, 500, now, 3)
+				body := expectPingsStored(store, started, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(started.ID)).Times(1).Return([]db.RouteStop{arrived}, nil)
+				expectGeofenceEvent(store, started, util.GeofenceStop, stop.ID, geofence.Departure, locations[0])
+				store.EXPECT().
+					TransitionRouteStopStatus(gomock.Any(), gomock.Eq(db.TransitionRouteStopStatusParams{
+						ToStatus:   string(util.StopCompleted),
+						At:         locations[0].RecordedAt,
+						ID:         stop.ID,
+						RouteID:    started.ID,
+						FromStatus: string(util.StopArrived),
+					})).
+					Times(1).
+					Return(left, nil)
+				expectLatestGeofenceEvent(store, started, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectDeviationCheck(t, store, started, left)
+				expectEtaRefresh(store, started, vehicle, locations[2], left)
+				return body
+			},
+		},
+		{
+			name:  "ArriveAtDestination",
+			route: started,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(started, started.DestinationLat, started.DestinationLng, 0, now, 3)
+				body := expectPingsStored(store, started, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(started.ID)).Times(1).Return([]db.RouteStop{left}, nil)
+				expectLatestGeofenceEvent(store, started, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectGeofenceEvent(store, started, util.GeofenceDestination, 0, geofence.Arrival, locations[0])
+				store.EXPECT().
+					CompleteRouteTx(gomock.Any(), gomock.Eq(db.CompleteRouteTxParams{RouteID: started.ID, FromStatus: started.Status, At: locations[0].RecordedAt})).
+					Times(1).
+					Return(db.CompleteRouteTxResult{Route: completed}, nil)
+				// a completed route has no live ETA
+				store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(0)
+				return body
+			},
+		},
+		{
+			name:  "ArriveAtDestinationWithStopsLeft",
+			route: started,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				locations := pingsNear(started, started.DestinationLat, started.DestinationLng, 0, now, 3)
+				body := expectPingsStored(store, started, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(started.ID)).Times(1).Return([]db.RouteStop{stop}, nil)
+				expectLatestGeofenceEvent(store, started, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+				expectGeofenceEvent(store, started, util.GeofenceDestination, 0, geofence.Arrival, locations[0])
+				store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				expectDeviationCheck(t, store, started, stop)
+				expectEtaRefresh(store, started, vehicle, locations[2], stop)
+				return body
+			},
+		},
+		{
+			name:  "FinishLastStopAtDestination",
+			route: started,
+			buildStubs: func(store *mockdb.MockStore) gin.H {
+				// the driver arrived earlier and finished a stop by hand since
+				locations := pingsNear(started, started.DestinationLat, started.DestinationLng, 0, now, 3)
+				body := expectPingsStored(store, started, locations)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(started.ID)).Times(1).Return([]db.RouteStop{left}, nil)
+				expectLatestGeofenceEvent(store, started, util.GeofenceDestination, db.GeofenceEvent{
+					RouteID:    started.ID,
+					Place:      string(util.GeofenceDestination),
+					Transition: string(geofence.Arrival),
+					OccurredAt: now.Add(-10 * time.Minute),
+				}, nil)
+				store.EXPECT().CreateGeofenceEvent(gomock.Any(), gomock.Any()).Times(0)
+				// the route is done once the stop is, after the arrival
+				store.EXPECT().
+					CompleteRouteTx(gomock.Any(), gomock.Eq(db.CompleteRouteTxParams{RouteID: started.ID, FromStatus: started.Status, At: left.DepartedAt.Time})).
+					Times(1).
+					Return(db.CompleteRouteTxResult{Route: completed}, nil)
+				return body
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(tc.route.ID)).Times(1).Return(tc.route, nil)
+			body := tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/locations/batch", tc.route.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+func TestRecordLocationGeofenceRadius(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// a wide yard at the destination
+	route := randomRoute(t, vehicle)
+	route.Status = string(util.RouteInProgress)
+	route.StartedAt = sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+	route.DestinationGeofenceRadiusM = sql.NullFloat64{Float64: 600, Valid: true}
+	completed := route
+	completed.Status = string(util.RouteCompleted)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+	locations := pingsNear(route, route.DestinationLat, route.DestinationLng, 400, now, 3)
+	body := expectPingsStored(store, route, locations)
+	store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+	expectLatestGeofenceEvent(store, route, util.GeofenceDestination, db.GeofenceEvent{}, sql.ErrNoRows)
+	expectGeofenceEvent(store, route, util.GeofenceDestination, 0, geofence.Arrival, locations[0])
+	store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CompleteRouteTxResult{Route: completed}, nil)
+
+	server := NewTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/routes/%s/locations/batch", route.ID)
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestListGeofenceEvents(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	events := []db.GeofenceEvent{
+		{
+			ID:         2,
+			RouteID:    route.ID,
+			Place:      string(util.GeofenceStop),
+			StopID:     sql.NullInt64{Int64: 7, Valid: true},
+			Transition: string(geofence.Arrival),
+			LocationID: sql.NullInt64{Int64: 42, Valid: true},
+			OccurredAt: time.Now().Add(-time.Minute).UTC().Truncate(time.Second),
+		},
+		{
+			ID:         1,
+			RouteID:    route.ID,
+			Place:      string(util.GeofenceOrigin),
+			Transition: string(geofence.Departure),
+			OccurredAt: time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		userID        uuid.UUID
+		role          util.Role
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			userID: user.ID,
+			query:  "page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().
+					ListGeofenceEvents(gomock.Any(), gomock.Eq(db.ListGeofenceEventsParams{RouteID: route.ID, Limit: 5, Offset: 5})).
+					Times(1).
+					Return(events, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []GeofenceEventResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 2)
+				require.Equal(t, "stop", got[0].Place)
+				require.Equal(t, int64(7), *got[0].StopID)
+				require.Equal(t, int64(42), *got[0].LocationID)
+				require.Equal(t, "arrival", got[0].Transition)
+				require.WithinDuration(t, events[0].OccurredAt, got[0].OccurredAt, time.Second)
+				require.Equal(t, "origin", got[1].Place)
+				require.Nil(t, got[1].StopID)
+				require.Nil(t, got[1].LocationID)
+			},
+		},
+		{
+			name:   "InvalidPageSize",
+			userID: user.ID,
+			query:  "page_id=1&page_size=100",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListGeofenceEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "Admin",
+			userID: uuid.New(),
+			role:   util.RoleAdmin,
+			query:  "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListGeofenceEvents(gomock.Any(), gomock.Any()).Times(1).Return([]db.GeofenceEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UnauthorizedUser",
+			userID: uuid.New(),
+			query:  "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListGeofenceEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			userID: user.ID,
+			query:  "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().ListGeofenceEvents(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s/geofence_events?%s", route.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			role := util.RoleDriver
+			if tc.role != "" {
+				role = tc.role
+			}
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.userID, role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -15,6 +15,7 @@ func NewTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
 		TokenSymmetricKey: 		util.RandomString(32) ,
 		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
 	}
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
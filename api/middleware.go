@@ -5,19 +5,68 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
 )
 
 
 
+// errNotAccessToken rejects refresh tokens presented as bearer tokens: they
+// live much longer than access tokens and are only good for renewing them.
+var errNotAccessToken = errors.New("token is not an access token")
+
 const (
 	authorizationHeaderKey 	= "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	accessTokenQueryKey     = "access_token"
 )
 
+// tokenFromQuery lets clients that cannot set headers, such as browser
+// EventSource and WebSocket, pass the access token as a query parameter. It
+// must run before authMiddleware, and a header always takes precedence.
+func tokenFromQuery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken := ctx.Query(accessTokenQueryKey)
+		if accessToken != "" && ctx.GetHeader(authorizationHeaderKey) == "" {
+			ctx.Request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+		}
+		ctx.Next()
+	}
+}
+
+// accessLogFormatter formats the request log like gin's default logger, with
+// the access token tracking streams take in the query string redacted, so
+// the logs can't be used to follow anyone's stream.
+func accessLogFormatter(param gin.LogFormatterParams) string {
+	if query := param.Request.URL.Query(); query.Has(accessTokenQueryKey) {
+		query.Set(accessTokenQueryKey, "REDACTED")
+		param.Path = param.Request.URL.Path + "?" + query.Encode()
+	}
+
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
 func authMiddleware(tokenMaker token.Maker ) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
@@ -45,8 +94,28 @@ func authMiddleware(tokenMaker token.Maker ) gin.HandlerFunc {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
+		if payload.Type != token.AccessToken {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errNotAccessToken))
+			return
+		}
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 
-}
\ No newline at end of file
+}
+
+// requireRoles only lets requests through whose token carries one of roles.
+// It must run after authMiddleware.
+func requireRoles(roles ...util.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, role := range roles {
+			if authPayload.Role == role {
+				ctx.Next()
+				return
+			}
+		}
+		err := fmt.Errorf("role %q is not allowed to access this resource", authPayload.Role)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +9,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
 	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,10 +23,12 @@ func addAuthorization(
 	request *http.Request, 
 	tokenMaker token.Maker, authorizationType string, 
 	userID uuid.UUID, 
+	role util.Role,
 	duration time.Duration) {
-	token, err := tokenMaker.CreateToken(userID, duration)
+	token, payload, err := tokenMaker.CreateToken(userID, role, token.AccessToken, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
@@ -40,7 +46,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(
-					t, request, tokenMaker, authorizationTypeBearer, user.ID, time.Minute)
+					t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -58,7 +64,7 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "UnsupportedAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(
-					t, request, tokenMaker, "unsupported", user.ID, time.Minute)
+					t, request, tokenMaker, "unsupported", user.ID, util.RoleDriver, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -68,17 +74,29 @@ func TestAuthMiddleware(t *testing.T) {
 			name: "InvalidAuthorizationFormat",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(
-					t, request, tokenMaker, "", user.ID, time.Minute)
+					t, request, tokenMaker, "", user.ID, util.RoleDriver, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				refreshToken, _, err := tokenMaker.CreateToken(user.ID, util.RoleDriver, token.RefreshToken, time.Minute)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, refreshToken))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errNotAccessToken.Error())
+			},
+		},
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuthorization(
-					t, request, tokenMaker, authorizationTypeBearer, user.ID, -time.Minute)
+					t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, -time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -110,4 +128,194 @@ func TestAuthMiddleware(t *testing.T) {
 
 
 		})}
-}
\ No newline at end of file
+}
+
+func TestRequireRoles(t *testing.T) {
+	user, _ := randomUser(t)
+	testCases := []struct {
+		name          string
+		role          util.Role
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "AllowedRole",
+			role: util.RoleAdmin,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherAllowedRole",
+			role: util.RoleDriver,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ForbiddenRole",
+			role: util.RoleCustomer,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "MissingRole",
+			role: "",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := NewTestServer(t, nil)
+
+			rolePath := "/role"
+			server.router.GET(
+				rolePath,
+				authMiddleware(server.tokenMaker),
+				requireRoles(util.RoleAdmin, util.RoleDriver),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, rolePath, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGroupPermissions(t *testing.T) {
+	// every group registered by the router must be declared, and the admin
+	// group must stay closed to everyone else
+	NewTestServer(t, nil)
+	require.Equal(t, []util.Role{util.RoleAdmin}, groupPermissions["/admin"])
+	require.NotContains(t, groupPermissions["/vehicles"], util.RoleCustomer)
+
+	require.Panics(t, func() {
+		restrictedGroup(&gin.New().RouterGroup, "/undeclared")
+	})
+}
+
+func TestRestrictedGroup(t *testing.T) {
+	user, _ := randomUser(t)
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Admin",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleAdmin, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DriverForbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "CustomerForbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleCustomer, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := NewTestServer(t, nil)
+
+			// a stub endpoint stands in for the admin handlers
+			adminRoute := restrictedGroup(&server.router.RouterGroup, "/admin", authMiddleware(server.tokenMaker))
+			adminRoute.GET("/stub", func(ctx *gin.Context) {
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/admin/stub", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAccessLogRedactsToken(t *testing.T) {
+	var logs bytes.Buffer
+	writer := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	defer func() { gin.DefaultWriter = writer }()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+	accessToken := newAccessToken(t, server.tokenMaker, uuid.New())
+
+	testCases := []struct {
+		name      string
+		query     string
+		logged    string
+		notLogged string
+	}{
+		{
+			name:      "QueryToken",
+			query:     "?access_token=" + accessToken + "&last_event_id=7",
+			logged:    "/tracking/drivers/invalid/events?access_token=REDACTED&last_event_id=7",
+			notLogged: accessToken,
+		},
+		{
+			name:   "NoQueryToken",
+			query:  "?last_event_id=7",
+			logged: "/tracking/drivers/invalid/events?last_event_id=7",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/tracking/drivers/invalid/events"+tc.query, nil)
+			require.NoError(t, err)
+			server.router.ServeHTTP(recorder, request)
+
+			require.Contains(t, logs.String(), tc.logged)
+			if tc.notLogged != "" {
+				require.NotContains(t, logs.String(), tc.notLogged)
+			}
+		})
+	}
+}
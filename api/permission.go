@@ -0,0 +1,32 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+// groupPermissions lists the roles allowed to call each protected endpoint
+// group. Ownership of individual records is still checked by the handlers.
+var groupPermissions = map[string][]util.Role{
+	"/admin":     {util.RoleAdmin},
+	"/sessions":  {util.RoleAdmin, util.RoleDriver, util.RoleCustomer},
+	"/users/me":  {util.RoleAdmin, util.RoleDriver, util.RoleCustomer},
+	"/vehicles":  {util.RoleAdmin, util.RoleDriver},
+	"/routes":    {util.RoleAdmin, util.RoleDriver},
+	"/tracking":  {util.RoleAdmin, util.RoleDriver, util.RoleCustomer},
+	"/shipments": {util.RoleAdmin, util.RoleCustomer},
+}
+
+// restrictedGroup creates the endpoint group at path, guarded by the roles
+// declared for it in groupPermissions. A group without an entry is a
+// programming error, so it panics at startup instead of serving unguarded
+// endpoints.
+func restrictedGroup(parent *gin.RouterGroup, path string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	roles, ok := groupPermissions[path]
+	if !ok {
+		panic(fmt.Sprintf("no permissions declared for endpoint group %s", path))
+	}
+	return parent.Group(path, append(handlers, requireRoles(roles...))...)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+// learnedIntervals is the error distribution prediction intervals currently
+// follow.
+type learnedIntervals struct {
+	distribution *eta.ErrorDistribution
+	samples      int
+	learnedAt    time.Time
+}
+
+// errorDistribution returns the current error distribution, or nil before
+// one is learned.
+func (server *Server) errorDistribution() *eta.ErrorDistribution {
+	if learned := server.intervals.Load(); learned != nil {
+		return learned.distribution
+	}
+	return nil
+}
+
+// durationQuantiles returns the quantiles of the duration of a trip
+// estimated at estimate, null while there's no history to derive them from.
+func (server *Server) durationQuantiles(estimate eta.Estimate) (p10, p50, p90 sql.NullFloat64) {
+	interval, ok := server.errorDistribution().Interval(estimate)
+	if !ok {
+		return
+	}
+	return sql.NullFloat64{Float64: interval.P10Min, Valid: true},
+		sql.NullFloat64{Float64: interval.P50Min, Valid: true},
+		sql.NullFloat64{Float64: interval.P90Min, Valid: true}
+}
+
+// LearnPredictionIntervals learns how far off the estimates of the routes
+// completed over the speed profile window were, and derives the intervals
+// of every route planned from then on from it.
+func (server *Server) LearnPredictionIntervals(ctx context.Context) error {
+	window := server.config.ETAProfileWindow
+	if window == 0 {
+		window = defaultProfileWindow
+	}
+	now := time.Now()
+	rows, err := server.store.ListCorrectionSamples(ctx, db.ListCorrectionSamplesParams{
+		Since:      now.Add(-window),
+		MaxSamples: maxProfileSamples,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list completed routes: %w", err)
+	}
+
+	samples := make([]eta.IntervalSample, 0, len(rows))
+	for _, row := range rows {
+		samples = append(samples, eta.IntervalSample{
+			DistanceKm:   row.EstimatedDistanceKm,
+			EstimatedMin: row.EstimatedDurationMin,
+			ActualMin:    row.ActualDurationMin,
+		})
+	}
+	server.intervals.Store(&learnedIntervals{
+		distribution: eta.LearnErrorDistribution(samples),
+		samples:      len(samples),
+		learnedAt:    now,
+	})
+	return nil
+}
+
+type PredictionIntervalBandResponse struct {
+	MinDistanceKm float64 `json:"min_distance_km"`
+	// MaxDistanceKm is left out for the last band, which has no end
+	MaxDistanceKm *float64 `json:"max_distance_km,omitempty"`
+	Samples       int      `json:"samples"`
+	// Learned is false for bands with too few routes of their own, which
+	// use the ratios of every route
+	Learned bool `json:"learned"`
+	// the ratios of actual to estimated duration, left out until enough
+	// routes are completed
+	P10Ratio *float64 `json:"p10_ratio,omitempty"`
+	P50Ratio *float64 `json:"p50_ratio,omitempty"`
+	P90Ratio *float64 `json:"p90_ratio,omitempty"`
+}
+
+type PredictionIntervalsResponse struct {
+	Samples   int                              `json:"samples"`
+	LearnedAt *time.Time                       `json:"learned_at,omitempty"`
+	Bands     []PredictionIntervalBandResponse `json:"bands"`
+}
+
+func newPredictionIntervalsResponse(learned *learnedIntervals) PredictionIntervalsResponse {
+	if learned == nil {
+		learned = &learnedIntervals{distribution: eta.LearnErrorDistribution(nil)}
+	}
+	response := PredictionIntervalsResponse{
+		Samples: learned.samples,
+		Bands:   make([]PredictionIntervalBandResponse, 0, len(eta.DistanceBandsKm)),
+	}
+	if !learned.learnedAt.IsZero() {
+		response.LearnedAt = &learned.learnedAt
+	}
+	for _, band := range learned.distribution.Bands() {
+		bandResponse := PredictionIntervalBandResponse{
+			MinDistanceKm: band.MinDistanceKm,
+			Samples:       band.Samples,
+			Learned:       band.Learned,
+		}
+		if band.MaxDistanceKm > 0 {
+			bandResponse.MaxDistanceKm = &band.MaxDistanceKm
+		}
+		if band.P50Ratio > 0 {
+			bandResponse.P10Ratio = &band.P10Ratio
+			bandResponse.P50Ratio = &band.P50Ratio
+			bandResponse.P90Ratio = &band.P90Ratio
+		}
+		response.Bands = append(response.Bands, bandResponse)
+	}
+	return response
+}
+
+// GetPredictionIntervals returns the ratios prediction intervals currently
+// scale estimates by.
+func (server *Server) GetPredictionIntervals(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, newPredictionIntervalsResponse(server.intervals.Load()))
+}
+
+// RelearnPredictionIntervals learns the error distribution again from the
+// latest completed routes and returns it.
+func (server *Server) RelearnPredictionIntervals(ctx *gin.Context) {
+	if err := server.LearnPredictionIntervals(ctx); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newPredictionIntervalsResponse(server.intervals.Load()))
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// lateSamples are n completed 10 km trips estimated at 20 minutes that took
+// 1 to 100% longer, evenly.
+func lateSamples(n int) []db.ListCorrectionSamplesRow {
+	samples := make([]db.ListCorrectionSamplesRow, n)
+	for i := range samples {
+		samples[i] = db.ListCorrectionSamplesRow{
+			StartedAt:            rushHour.AddDate(0, 0, -i),
+			EstimatedDistanceKm:  10,
+			EstimatedDurationMin: 20,
+			BaselineDurationMin:  20,
+			ActualDurationMin:    20 * (1 + float64(i+1)/float64(n)),
+		}
+	}
+	return samples
+}
+
+func requireBodyPredictionIntervals(t *testing.T, recorder *httptest.ResponseRecorder) PredictionIntervalsResponse {
+	var got PredictionIntervalsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Len(t, got.Bands, len(eta.DistanceBandsKm))
+	return got
+}
+
+func TestGetPredictionIntervals(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := NewTestServer(t, store)
+
+	request, err := http.NewRequest(http.MethodGet, "/admin/prediction_intervals", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// nothing learned yet, so routes get no intervals
+	got := requireBodyPredictionIntervals(t, recorder)
+	require.Nil(t, got.LearnedAt)
+	require.Zero(t, got.Samples)
+	for _, band := range got.Bands {
+		require.False(t, band.Learned)
+		require.Nil(t, band.P50Ratio)
+	}
+	require.Equal(t, 5.0, *got.Bands[0].MaxDistanceKm)
+	require.Nil(t, got.Bands[len(got.Bands)-1].MaxDistanceKm)
+}
+
+func TestRelearnPredictionIntervals(t *testing.T) {
+	testCases := []struct {
+		name          string
+		role          util.Role
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListCorrectionSamples(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListCorrectionSamplesParams) ([]db.ListCorrectionSamplesRow, error) {
+						require.WithinDuration(t, time.Now().Add(-defaultProfileWindow), arg.Since, time.Minute)
+						require.Equal(t, int32(maxProfileSamples), arg.MaxSamples)
+						return lateSamples(101), nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyPredictionIntervals(t, recorder)
+				require.Equal(t, 101, got.Samples)
+				require.NotNil(t, got.LearnedAt)
+
+				band := got.Bands[eta.DistanceBand(10)]
+				require.Equal(t, 101, band.Samples)
+				require.True(t, band.Learned)
+				require.InDelta(t, 1.11, *band.P10Ratio, 0.01)
+				require.InDelta(t, 1.505, *band.P50Ratio, 0.01)
+				require.InDelta(t, 1.901, *band.P90Ratio, 0.01)
+
+				// the other bands fall back to every trip's ratios
+				require.False(t, got.Bands[0].Learned)
+				require.Equal(t, *band.P50Ratio, *got.Bands[0].P50Ratio)
+			},
+		},
+		{
+			name: "Forbidden",
+			role: util.RoleDriver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListCorrectionSamples(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListCorrectionSamples(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/prediction_intervals/learn", nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateRoutePredictionInterval(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListCorrectionSamples(gomock.Any(), gomock.Any()).Times(1).Return(lateSamples(101), nil)
+	store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+	store.EXPECT().
+		CreateRoute(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateRouteParams) (db.Route, error) {
+			require.True(t, arg.DurationP10Min.Valid)
+			require.True(t, arg.DurationP50Min.Valid)
+			require.True(t, arg.DurationP90Min.Valid)
+			// routes ran later than estimated, so even the 10th percentile is
+			// later than the estimate
+			require.Greater(t, arg.DurationP10Min.Float64, arg.EstimatedDurationMin.Float64)
+			require.Greater(t, arg.DurationP50Min.Float64, arg.DurationP10Min.Float64)
+			require.Greater(t, arg.DurationP90Min.Float64, arg.DurationP50Min.Float64)
+
+			route.EstimatedDurationMin = arg.EstimatedDurationMin
+			route.DurationP10Min = arg.DurationP10Min
+			route.DurationP50Min = arg.DurationP50Min
+			route.DurationP90Min = arg.DurationP90Min
+			return route, nil
+		})
+
+	server := NewTestServer(t, store)
+	require.NoError(t, server.LearnPredictionIntervals(context.Background()))
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"vehicle_id":      vehicle.ID,
+		"origin_lat":      route.OriginLat,
+		"origin_lng":      route.OriginLng,
+		"destination_lat": route.DestinationLat,
+		"destination_lng": route.DestinationLng,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/routes/create", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got RouteResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.NotNil(t, got.DurationP10Min)
+	require.NotNil(t, got.DurationP50Min)
+	require.NotNil(t, got.DurationP90Min)
+	require.Equal(t, route.DurationP50Min.Float64, *got.DurationP50Min)
+}
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/lib/pq"
+)
+
+var (
+	errRouteNotOwned      = errors.New("route doesn't belong to the authenticated user")
+	errVehicleNotOwned    = errors.New("vehicle doesn't belong to the authenticated user")
+	errRouteStatusChanged = errors.New("route status was changed by another request")
+)
+
+type CreateRouteRequest struct {
+	VehicleID          string  `json:"vehicle_id" binding:"required,uuid"`
+	OriginAddress      string  `json:"origin_address"`
+	OriginLat          float64 `json:"origin_lat" binding:"min=-90,max=90"`
+	OriginLng          float64 `json:"origin_lng" binding:"min=-180,max=180"`
+	DestinationAddress string  `json:"destination_address"`
+	DestinationLat     float64 `json:"destination_lat" binding:"min=-90,max=90"`
+	DestinationLng     float64 `json:"destination_lng" binding:"min=-180,max=180"`
+	// DepartureAt is when the driver leaves the origin, now when left out.
+	DepartureAt *time.Time `json:"departure_at"`
+	// Radii of the geofences around the origin and destination, the
+	// configured default when left out
+	OriginGeofenceRadiusM      *float64 `json:"origin_geofence_radius_m" binding:"omitempty,gt=0,max=5000"`
+	DestinationGeofenceRadiusM *float64 `json:"destination_geofence_radius_m" binding:"omitempty,gt=0,max=5000"`
+}
+
+type RouteResponse struct {
+	ID                         uuid.UUID  `json:"id"`
+	DriverID                   uuid.UUID  `json:"driver_id"`
+	VehicleID                  uuid.UUID  `json:"vehicle_id"`
+	OriginAddress              string     `json:"origin_address"`
+	OriginLat                  float64    `json:"origin_lat"`
+	OriginLng                  float64    `json:"origin_lng"`
+	DestinationAddress         string     `json:"destination_address"`
+	DestinationLat             float64    `json:"destination_lat"`
+	DestinationLng             float64    `json:"destination_lng"`
+	EstimatedDistanceKm        float64    `json:"estimated_distance_km"`
+	EstimatedDurationMin       float64    `json:"estimated_duration_min"`
+	DurationP10Min             *float64   `json:"duration_p10_min,omitempty"`
+	DurationP50Min             *float64   `json:"duration_p50_min,omitempty"`
+	DurationP90Min             *float64   `json:"duration_p90_min,omitempty"`
+	OriginGeofenceRadiusM      *float64   `json:"origin_geofence_radius_m,omitempty"`
+	DestinationGeofenceRadiusM *float64   `json:"destination_geofence_radius_m,omitempty"`
+	ActualDurationMin          float64    `json:"actual_duration_min"`
+	Status                     string     `json:"status"`
+	StartedAt                  *time.Time `json:"started_at,omitempty"`
+	CompletedAt                *time.Time `json:"completed_at,omitempty"`
+	CancelledAt                *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt                  time.Time  `json:"created_at"`
+	UpdatedAt                  time.Time  `json:"updated_at"`
+}
+
+func newRouteResponse(route db.Route) RouteResponse {
+	return RouteResponse{
+		ID:                         route.ID,
+		DriverID:                   route.DriverID,
+		VehicleID:                  route.VehicleID,
+		OriginAddress:              route.OriginAddress.String,
+		OriginLat:                  route.OriginLat,
+		OriginLng:                  route.OriginLng,
+		DestinationAddress:         route.DestinationAddress.String,
+		DestinationLat:             route.DestinationLat,
+		DestinationLng:             route.DestinationLng,
+		EstimatedDistanceKm:        route.EstimatedDistanceKm.Float64,
+		EstimatedDurationMin:       route.EstimatedDurationMin.Float64,
+		DurationP10Min:             nullFloatPtr(route.DurationP10Min),
+		DurationP50Min:             nullFloatPtr(route.DurationP50Min),
+		DurationP90Min:             nullFloatPtr(route.DurationP90Min),
+		OriginGeofenceRadiusM:      nullFloatPtr(route.OriginGeofenceRadiusM),
+		DestinationGeofenceRadiusM: nullFloatPtr(route.DestinationGeofenceRadiusM),
+		ActualDurationMin:          route.ActualDurationMin.Float64,
+		Status:                     route.Status,
+		StartedAt:                  nullTimePtr(route.StartedAt),
+		CompletedAt:                nullTimePtr(route.CompletedAt),
+		CancelledAt:                nullTimePtr(route.CancelledAt),
+		CreatedAt:                  route.CreatedAt.Time,
+		UpdatedAt:                  route.UpdatedAt.Time,
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (server *Server) CreateRoute(ctx *gin.Context) {
+	var req CreateRouteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	vehicle, err := server.store.GetVehicleByID(ctx, uuid.MustParse(req.VehicleID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if vehicle.DriverID != authPayload.UserID {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errVehicleNotOwned))
+		return
+	}
+
+	departure := time.Now()
+	if req.DepartureAt != nil {
+		departure = *req.DepartureAt
+	}
+	origin := eta.Point{Lat: req.OriginLat, Lng: req.OriginLng}
+	destination := eta.Point{Lat: req.DestinationLat, Lng: req.DestinationLng}
+	class := eta.ClassForCapacity(vehicle.Capacity.Int32)
+	baseline := server.roadEstimator().Estimate(origin, destination, class)
+	estimate := server.correctEstimate(baseline, eta.CorrectionFeatures{
+		DistanceKm:   baseline.DistanceKm,
+		Departure:    departure,
+		DriverID:     authPayload.UserID.String(),
+		VehicleModel: vehicle.Model.String,
+		Origin:       origin,
+		Destination:  destination,
+	})
+
+	p10, p50, p90 := server.durationQuantiles(estimate)
+
+	arg := db.CreateRouteParams{
+		ID:                         uuid.New(),
+		DriverID:                   authPayload.UserID,
+		VehicleID:                  vehicle.ID,
+		OriginAddress:              sql.NullString{String: req.OriginAddress, Valid: req.OriginAddress != ""},
+		OriginLat:                  req.OriginLat,
+		OriginLng:                  req.OriginLng,
+		DestinationAddress:         sql.NullString{String: req.DestinationAddress, Valid: req.DestinationAddress != ""},
+		DestinationLat:             req.DestinationLat,
+		DestinationLng:             req.DestinationLng,
+		EstimatedDistanceKm:        sql.NullFloat64{Float64: estimate.DistanceKm, Valid: true},
+		EstimatedDurationMin:       sql.NullFloat64{Float64: estimate.DurationMin, Valid: true},
+		BaselineDurationMin:        sql.NullFloat64{Float64: baseline.DurationMin, Valid: true},
+		DurationP10Min:             p10,
+		DurationP50Min:             p50,
+		DurationP90Min:             p90,
+		OriginGeofenceRadiusM:      floatPtrToNull(req.OriginGeofenceRadiusM),
+		DestinationGeofenceRadiusM: floatPtrToNull(req.DestinationGeofenceRadiusM),
+		Status:                     string(util.RoutePending),
+	}
+
+	route, err := server.store.CreateRoute(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the route stands without its path, only its deviations go unchecked
+	if path := server.plannedPath(origin, []eta.Point{destination}, class); path != nil {
+		err = server.store.UpsertRoutePath(ctx, db.UpsertRoutePathParams{RouteID: route.ID, Points: path})
+		if err != nil {
+			ctx.Error(err)
+		}
+	}
+	ctx.JSON(http.StatusOK, newRouteResponse(route))
+}
+
+// roadEstimator returns the free-flow estimator, whose legs follow the
+// fastest roads when a road network is loaded and covers them, and the
+// straight line otherwise.
+func (server *Server) roadEstimator() *eta.Estimator {
+	if server.roads == nil {
+		return server.estimator
+	}
+	return server.estimator.WithRoads(func(origin, destination eta.Point, class eta.VehicleClass) (eta.Estimate, bool) {
+		path, err := server.roads.Route(origin, destination, class)
+		if err != nil {
+			return eta.Estimate{}, false
+		}
+		return path.Estimate(), true
+	})
+}
+
+// correctEstimate adjusts the free-flow baseline of a trip for how long
+// trips like it take: by the correction model when one is loaded, and by
+// the speed profile otherwise.
+func (server *Server) correctEstimate(baseline eta.Estimate, features eta.CorrectionFeatures) eta.Estimate {
+	if server.correction != nil {
+		return server.correction.Correct(baseline, features)
+	}
+	return server.speedProfile().Adjust(baseline, features.Origin, features.Departure)
+}
+
+// tripPlan is the planned drive of a route through its stops.
+type tripPlan struct {
+	// Schedule holds the predicted visit of every waypoint, the destination last
+	Schedule            []eta.StopEta
+	Estimate            eta.Estimate
+	BaselineDurationMin float64
+	// Path is the roads the trip follows, nil when it can't be planned along roads
+	Path json.RawMessage
+}
+
+// planTrip plans the drive of driverID in vehicle from origin through
+// waypoints, the last of which is the destination, leaving at departure.
+// The trip as a whole is corrected by the model when one is loaded, like a
+// route without stops; otherwise each leg is adjusted by the speed profile
+// at the time it starts.
+func (server *Server) planTrip(origin eta.Point, departure time.Time, waypoints []eta.Stop, vehicle db.Vehicle, driverID uuid.UUID) tripPlan {
+	class := eta.ClassForCapacity(vehicle.Capacity.Int32)
+	baseline := server.roadEstimator().Schedule(origin, departure, waypoints, class)
+	end := baseline[len(baseline)-1]
+	points := make([]eta.Point, 0, len(waypoints))
+	for _, waypoint := range waypoints {
+		points = append(points, waypoint.Point)
+	}
+	plan := tripPlan{
+		BaselineDurationMin: end.ArrivalAt.Sub(departure).Minutes(),
+		Path:                server.plannedPath(origin, points, class),
+	}
+
+	if server.correction != nil {
+		plan.Schedule = baseline
+		plan.Estimate = server.correction.Correct(eta.Estimate{
+			DistanceKm:  end.DistanceKm,
+			DurationMin: plan.BaselineDurationMin,
+		}, eta.CorrectionFeatures{
+			DistanceKm:   end.DistanceKm,
+			Departure:    departure,
+			DriverID:     driverID.String(),
+			VehicleModel: vehicle.Model.String,
+			Origin:       origin,
+			Destination:  waypoints[len(waypoints)-1].Point,
+		})
+		return plan
+	}
+
+	plan.Schedule = server.trafficEstimator().Schedule(origin, departure, waypoints, class)
+	end = plan.Schedule[len(plan.Schedule)-1]
+	plan.Estimate = eta.Estimate{DistanceKm: end.DistanceKm, DurationMin: end.ArrivalAt.Sub(departure).Minutes()}
+	return plan
+}
+
+type RouteIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// getOwnedRoute loads the route named by the :id path parameter and makes sure
+// it belongs to the authenticated user. It writes the error response itself and
+// reports whether the handler may continue.
+func (server *Server) getOwnedRoute(ctx *gin.Context) (db.Route, bool) {
+	return server.loadRoute(ctx, false)
+}
+
+// getReadableRoute is getOwnedRoute for endpoints that only read the route,
+// which admins may also call for routes they dispatched or oversee.
+func (server *Server) getReadableRoute(ctx *gin.Context) (db.Route, bool) {
+	return server.loadRoute(ctx, true)
+}
+
+func (server *Server) loadRoute(ctx *gin.Context, adminAllowed bool) (db.Route, bool) {
+	var req RouteIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Route{}, false
+	}
+
+	route, err := server.store.GetRouteByID(ctx, uuid.MustParse(req.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.Route{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.Route{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if adminAllowed && authPayload.Role == util.RoleAdmin {
+		return route, true
+	}
+	if route.DriverID != authPayload.UserID {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errRouteNotOwned))
+		return db.Route{}, false
+	}
+	return route, true
+}
+
+func (server *Server) GetRoute(ctx *gin.Context) {
+	route, ok := server.getReadableRoute(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newRouteResponse(route))
+}
+
+type ListRoutesRequest struct {
+	Status   string `form:"status" binding:"omitempty,route_status"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (server *Server) ListRoutes(ctx *gin.Context) {
+	var req ListRoutesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var (
+		routes []db.Route
+		err    error
+	)
+	if req.Status == "" {
+		routes, err = server.store.GetRoutesByDriverID(ctx, db.GetRoutesByDriverIDParams{
+			DriverID: authPayload.UserID,
+			Limit:    req.PageSize,
+			Offset:   (req.PageID - 1) * req.PageSize,
+		})
+	} else {
+		routes, err = server.store.ListRoutesByDriverAndStatus(ctx, db.ListRoutesByDriverAndStatusParams{
+			DriverID: authPayload.UserID,
+			Status:   req.Status,
+			Limit:    req.PageSize,
+			Offset:   (req.PageID - 1) * req.PageSize,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]RouteResponse, 0, len(routes))
+	for _, route := range routes {
+		response = append(response, newRouteResponse(route))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type UpdateRouteStatusRequest struct {
+	Status string `json:"status" binding:"required,route_status"`
+}
+
+func (server *Server) UpdateRouteStatus(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	var req UpdateRouteStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := util.ValidateRouteTransition(util.RouteStatus(route.Status), util.RouteStatus(req.Status))
+	if err != nil {
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	route, err = server.transitionRoute(ctx, route, util.RouteStatus(req.Status), time.Now())
+	if err != nil {
+		switch {
+		// the row no longer has the status we validated against, so another
+		// request moved the route first
+		case err == sql.ErrNoRows, errors.Is(err, db.ErrRouteStatusChanged):
+			ctx.JSON(http.StatusConflict, errorResponse(errRouteStatusChanged))
+		case errors.Is(err, db.ErrVehicleInUse):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+	response := newRouteResponse(route)
+	server.publish(tracking.EventStatus, route, response)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// transitionRoute moves the route to status as of at. Starting and completing
+// a route claim and release its vehicle, so they run in a transaction that
+// locks it.
+func (server *Server) transitionRoute(ctx *gin.Context, route db.Route, status util.RouteStatus, at time.Time) (db.Route, error) {
+	switch status {
+	case util.RouteInProgress:
+		result, err := server.store.StartRouteTx(ctx, db.StartRouteTxParams{RouteID: route.ID, FromStatus: route.Status, At: at})
+		return result.Route, err
+	case util.RouteCompleted:
+		result, err := server.store.CompleteRouteTx(ctx, db.CompleteRouteTxParams{RouteID: route.ID, FromStatus: route.Status, At: at})
+		return result.Route, err
+	default:
+		return server.store.TransitionRouteStatus(ctx, db.TransitionRouteStatusParams{
+			ID:         route.ID,
+			FromStatus: route.Status,
+			ToStatus:   string(status),
+			At:         at,
+		})
+	}
+}
+
+type UpdateRouteActualDurationRequest struct {
+	ActualDurationMin float64 `json:"actual_duration_min" binding:"required,gt=0"`
+}
+
+func (server *Server) UpdateRouteActualDuration(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	var req UpdateRouteActualDurationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateRouteActualDurationParams{
+		ID:                route.ID,
+		ActualDurationMin: sql.NullFloat64{Float64: req.ActualDurationMin, Valid: true},
+	}
+	route, err := server.store.UpdateRouteActualDuration(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newRouteResponse(route))
+}
+
+func (server *Server) DeleteRoute(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	if err := server.store.DeleteRoute(ctx, route.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "route deleted"})
+}
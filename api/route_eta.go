@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+const (
+	etaSourcePlanned   = "planned"
+	etaSourceLive      = "live"
+	etaSourceCompleted = "completed"
+)
+
+var errRouteCancelled = errors.New("route was cancelled and has no ETA")
+
+type RouteEtaResponse struct {
+	RouteID              uuid.UUID  `json:"route_id"`
+	Status               string     `json:"status"`
+	Source               string     `json:"source"`
+	RemainingDistanceKm  float64    `json:"remaining_distance_km"`
+	RemainingDurationMin float64    `json:"remaining_duration_min"`
+	PredictedArrivalAt   time.Time  `json:"predicted_arrival_at"`
+	ArrivalP10At         *time.Time `json:"arrival_p10_at,omitempty"`
+	ArrivalP50At         *time.Time `json:"arrival_p50_at,omitempty"`
+	ArrivalP90At         *time.Time `json:"arrival_p90_at,omitempty"`
+	Confidence           float64    `json:"confidence"`
+	ComputedAt           time.Time  `json:"computed_at"`
+}
+
+// RouteEtaEvent is a live ETA update published to the route's followers: the
+// route's ETA, and its stops with the visits predicted for those still ahead.
+type RouteEtaEvent struct {
+	RouteEtaResponse
+	Stops []RouteStopResponse `json:"stops"`
+}
+
+func (server *Server) GetRouteEta(ctx *gin.Context) {
+	route, ok := server.getReadableRoute(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := server.currentRouteEta(ctx, route, time.Now())
+	if err != nil {
+		if err == errRouteCancelled {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// currentRouteEta works out the route's arrival as of now: the live estimate
+// while it is under way, the planned trip before that and the recorded arrival
+// once it is completed. Cancelled routes have no ETA and yield errRouteCancelled.
+// Arrival quantiles come from the ones planned with the route, and from those
+// of similar routes for live estimates; they're left out when there are none.
+func (server *Server) currentRouteEta(ctx context.Context, route db.Route, now time.Time) (RouteEtaResponse, error) {
+	response := RouteEtaResponse{
+		RouteID:    route.ID,
+		Status:     route.Status,
+		ComputedAt: now,
+	}
+
+	switch util.RouteStatus(route.Status) {
+	case util.RouteCancelled:
+		return response, errRouteCancelled
+	case util.RouteCompleted:
+		response.Source = etaSourceCompleted
+		response.PredictedArrivalAt = route.CompletedAt.Time
+		response.Confidence = 1
+	case util.RouteInProgress:
+		stops, err := server.store.ListRouteStops(ctx, route.ID)
+		if err != nil {
+			return response, err
+		}
+		estimate, _, err := server.estimateFromPositions(ctx, route, stops, now)
+		if err == nil {
+			response = server.liveEta(response, estimate, now)
+			break
+		}
+		if err != sql.ErrNoRows {
+			return response, err
+		}
+		// no position reported yet, so the planned trip started when the route did
+		response = plannedEta(response, route, route.StartedAt.Time, now)
+	default:
+		response = plannedEta(response, route, now, now)
+	}
+	return response, nil
+}
+
+func plannedEta(response RouteEtaResponse, route db.Route, departure time.Time, now time.Time) RouteEtaResponse {
+	duration := time.Duration(route.EstimatedDurationMin.Float64 * float64(time.Minute))
+	arrival := departure.Add(duration)
+	if arrival.Before(now) {
+		arrival = now
+	}
+
+	response.Source = etaSourcePlanned
+	response.RemainingDistanceKm = route.EstimatedDistanceKm.Float64
+	response.RemainingDurationMin = math.Max(arrival.Sub(now).Minutes(), 0)
+	response.PredictedArrivalAt = arrival
+	response.Confidence = eta.PlannedConfidence
+	if route.DurationP10Min.Valid && route.DurationP50Min.Valid && route.DurationP90Min.Valid {
+		response = withArrivalQuantiles(response, departure, eta.Interval{
+			P10Min: route.DurationP10Min.Float64,
+			P50Min: route.DurationP50Min.Float64,
+			P90Min: route.DurationP90Min.Float64,
+		}, now)
+	}
+	return response
+}
+
+// liveEta fills response in with the live estimate of the remaining trip,
+// scaled to quantiles by how far off the estimates of similar routes were.
+func (server *Server) liveEta(response RouteEtaResponse, estimate eta.LiveEstimate, now time.Time) RouteEtaResponse {
+	response.Source = etaSourceLive
+	response.RemainingDistanceKm = estimate.RemainingDistanceKm
+	response.RemainingDurationMin = estimate.RemainingDurationMin
+	response.PredictedArrivalAt = estimate.ArrivalAt
+	response.Confidence = estimate.Confidence
+
+	interval, ok := server.errorDistribution().Interval(eta.Estimate{
+		DistanceKm:  estimate.RemainingDistanceKm,
+		DurationMin: estimate.RemainingDurationMin,
+	})
+	if ok {
+		departure := estimate.ArrivalAt.Add(-time.Duration(estimate.RemainingDurationMin * float64(time.Minute)))
+		response = withArrivalQuantiles(response, departure, interval, now)
+	}
+	return response
+}
+
+// withArrivalQuantiles sets the arrival quantiles of a trip leaving at
+// departure, none of them before now.
+func withArrivalQuantiles(response RouteEtaResponse, departure time.Time, interval eta.Interval, now time.Time) RouteEtaResponse {
+	arrival := func(durationMin float64) *time.Time {
+		at := departure.Add(time.Duration(durationMin * float64(time.Minute)))
+		if at.Before(now) {
+			at = now
+		}
+		return &at
+	}
+	response.ArrivalP10At = arrival(interval.P10Min)
+	response.ArrivalP50At = arrival(interval.P50Min)
+	response.ArrivalP90At = arrival(interval.P90Min)
+	return response
+}
+
+type RouteEtaHistoryResponse struct {
+	ID                   int64     `json:"id"`
+	LocationID           *int64    `json:"location_id,omitempty"`
+	RemainingDistanceKm  float64   `json:"remaining_distance_km"`
+	RemainingDurationMin float64   `json:"remaining_duration_min"`
+	PredictedArrivalAt   time.Time `json:"predicted_arrival_at"`
+	Confidence           float64   `json:"confidence"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+type ListRouteEtaHistoryRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (server *Server) ListRouteEtaHistory(ctx *gin.Context) {
+	route, ok := server.getReadableRoute(ctx)
+	if !ok {
+		return
+	}
+	var req ListRouteEtaHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	history, err := server.store.ListRouteEtaHistory(ctx, db.ListRouteEtaHistoryParams{
+		RouteID: route.ID,
+		Limit:   req.PageSize,
+		Offset:  (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]RouteEtaHistoryResponse, 0, len(history))
+	for _, entry := range history {
+		item := RouteEtaHistoryResponse{
+			ID:                   entry.ID,
+			RemainingDistanceKm:  entry.RemainingDistanceKm,
+			RemainingDurationMin: entry.RemainingDurationMin,
+			PredictedArrivalAt:   entry.EtaAt,
+			Confidence:           entry.Confidence,
+			CreatedAt:            entry.CreatedAt,
+		}
+		if entry.LocationID.Valid {
+			item.LocationID = &entry.LocationID.Int64
+		}
+		response = append(response, item)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// refreshRouteEta recomputes the live ETA of an in-progress route and stores
+// it on the route along with a history entry.
+func (server *Server) refreshRouteEta(ctx context.Context, route db.Route) (db.Route, error) {
+	stops, err := server.store.ListRouteStops(ctx, route.ID)
+	if err != nil {
+		return route, err
+	}
+	estimate, latest, err := server.estimateFromPositions(ctx, route, stops, time.Now())
+	if err != nil {
+		return route, err
+	}
+	return server.saveRouteEta(ctx, route, stops, estimate, latest)
+}
+
+// saveRouteEta stores estimate, made from the route's latest position
+// through stops, as its ETA along with a history entry and publishes it.
+func (server *Server) saveRouteEta(ctx context.Context, route db.Route, stops []db.RouteStop, estimate eta.LiveEstimate, latest db.RouteLocation) (db.Route, error) {
+	route, err := server.store.UpdateRouteEta(ctx, db.UpdateRouteEtaParams{
+		ID:                   route.ID,
+		RemainingDistanceKm:  sql.NullFloat64{Float64: estimate.RemainingDistanceKm, Valid: true},
+		RemainingDurationMin: sql.NullFloat64{Float64: estimate.RemainingDurationMin, Valid: true},
+		EtaAt:                sql.NullTime{Time: estimate.ArrivalAt, Valid: true},
+		EtaConfidence:        sql.NullFloat64{Float64: estimate.Confidence, Valid: true},
+	})
+	if err != nil {
+		return route, err
+	}
+
+	now := time.Now()
+	_, ahead := remainingStops(route, stops)
+	server.publish(tracking.EventEta, route, RouteEtaEvent{
+		RouteEtaResponse: server.liveEta(RouteEtaResponse{
+			RouteID:    route.ID,
+			Status:     route.Status,
+			ComputedAt: now,
+		}, estimate, now),
+		Stops: newScheduledStopsResponse(stops, stopSchedule{ahead: ahead, visits: estimate.Stops}),
+	})
+
+	_, err = server.store.CreateRouteEtaHistory(ctx, db.CreateRouteEtaHistoryParams{
+		RouteID:              route.ID,
+		LocationID:           sql.NullInt64{Int64: latest.ID, Valid: true},
+		RemainingDistanceKm:  estimate.RemainingDistanceKm,
+		RemainingDurationMin: estimate.RemainingDurationMin,
+		EtaAt:                estimate.ArrivalAt,
+		Confidence:           estimate.Confidence,
+	})
+	return route, err
+}
+
+// estimateFromPositions estimates the remaining trip of an in-progress route
+// from its latest reported positions through what is left of stops, its
+// stops in sequence, to the destination. It returns sql.ErrNoRows when the
+// route has no positions yet.
+func (server *Server) estimateFromPositions(ctx context.Context, route db.Route, stops []db.RouteStop, now time.Time) (eta.LiveEstimate, db.RouteLocation, error) {
+	latest, err := server.store.GetLatestRouteLocation(ctx, route.ID)
+	if err != nil {
+		return eta.LiveEstimate{}, db.RouteLocation{}, err
+	}
+
+	window := server.config.ETASpeedWindow
+	if window <= 0 {
+		window = eta.DefaultSpeedWindow
+	}
+	locations, err := server.store.ListRouteLocationsSince(ctx, db.ListRouteLocationsSinceParams{
+		RouteID:    route.ID,
+		RecordedAt: latest.RecordedAt.Add(-window),
+	})
+	if err != nil {
+		return eta.LiveEstimate{}, latest, err
+	}
+
+	vehicle, err := server.store.GetVehicleByID(ctx, route.VehicleID)
+	if err != nil {
+		return eta.LiveEstimate{}, latest, err
+	}
+
+	waypoints, _ := remainingStops(route, stops)
+	estimate, err := server.roadEstimator().Recalculate(
+		waypoints,
+		eta.ClassForCapacity(vehicle.Capacity.Int32),
+		fixesFromLocations(locations),
+		now,
+	)
+	return estimate, latest, err
+}
+
+// remainingStops lists what is left of the trip through stops, the route's
+// stops in sequence: the stop the driver is being served at, which they
+// leave once its service time has passed, the stops still pending and the
+// route's destination last. Every stop comes with its index in stops.
+func remainingStops(route db.Route, stops []db.RouteStop) ([]eta.Stop, []int) {
+	var waypoints []eta.Stop
+	var ahead []int
+	for i, stop := range stops {
+		switch util.StopStatus(stop.Status) {
+		case util.StopArrived:
+			ahead = append(ahead, i)
+			waypoints = append(waypoints, eta.Stop{
+				Point:     eta.Point{Lat: stop.Lat, Lng: stop.Lng},
+				NotBefore: stop.ArrivedAt.Time.Add(time.Duration(stop.ServiceTimeMin * float64(time.Minute))),
+			})
+		case util.StopPending:
+			ahead = append(ahead, i)
+			waypoints = append(waypoints, eta.Stop{
+				Point:      eta.Point{Lat: stop.Lat, Lng: stop.Lng},
+				ServiceMin: stop.ServiceTimeMin,
+				NotBefore:  stop.WindowStart.Time,
+			})
+		}
+	}
+	waypoints = append(waypoints, eta.Stop{Point: eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng}})
+	return waypoints, ahead
+}
+
+func fixesFromLocations(locations []db.RouteLocation) []eta.Fix {
+	fixes := make([]eta.Fix, 0, len(locations))
+	for _, location := range locations {
+		fixes = append(fixes, eta.Fix{
+			Point:      eta.Point{Lat: location.Lat, Lng: location.Lng},
+			SpeedKmh:   location.SpeedKmh.Float64,
+			HasSpeed:   location.SpeedKmh.Valid,
+			RecordedAt: location.RecordedAt,
+		})
+	}
+	return fixes
+}
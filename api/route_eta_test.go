@@ -0,0 +1,317 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRouteEta(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+
+	pending := randomRoute(t, vehicle)
+
+	inProgress := pending
+	inProgress.Status = string(util.RouteInProgress)
+	inProgress.StartedAt = sql.NullTime{Time: time.Now().Add(-10 * time.Minute), Valid: true}
+
+	completed := inProgress
+	completed.Status = string(util.RouteCompleted)
+	completed.CompletedAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	cancelled := pending
+	cancelled.Status = string(util.RouteCancelled)
+
+	withQuantiles := pending
+	withQuantiles.DurationP10Min = sql.NullFloat64{Float64: pending.EstimatedDurationMin.Float64 * 0.9, Valid: true}
+	withQuantiles.DurationP50Min = sql.NullFloat64{Float64: pending.EstimatedDurationMin.Float64 * 1.1, Valid: true}
+	withQuantiles.DurationP90Min = sql.NullFloat64{Float64: pending.EstimatedDurationMin.Float64 * 1.5, Valid: true}
+
+	latest := randomRouteLocation(inProgress, time.Now().Add(-10*time.Second))
+
+	// a drop at the destination itself that takes half an hour to unload
+	unloading := randomRouteStops(inProgress, 1)
+	unloading[0].Lat, unloading[0].Lng = inProgress.DestinationLat, inProgress.DestinationLng
+	unloading[0].ServiceTimeMin = 30
+
+	testCases := []struct {
+		name          string
+		route         db.Route
+		buildStubs    func(store *mockdb.MockStore, route db.Route)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Pending",
+			route: pending,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyEta(t, recorder)
+				require.Equal(t, etaSourcePlanned, got.Source)
+				require.Equal(t, pending.EstimatedDistanceKm.Float64, got.RemainingDistanceKm)
+				require.InDelta(t, pending.EstimatedDurationMin.Float64, got.RemainingDurationMin, 0.1)
+				require.Equal(t, eta.PlannedConfidence, got.Confidence)
+				require.Nil(t, got.ArrivalP10At)
+				require.Nil(t, got.ArrivalP90At)
+			},
+		},
+		{
+			name:  "PendingWithQuantiles",
+			route: withQuantiles,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyEta(t, recorder)
+				require.Equal(t, etaSourcePlanned, got.Source)
+				require.NotNil(t, got.ArrivalP10At)
+				require.NotNil(t, got.ArrivalP50At)
+				require.NotNil(t, got.ArrivalP90At)
+				require.True(t, got.ArrivalP10At.Before(got.PredictedArrivalAt))
+				require.True(t, got.ArrivalP50At.After(got.PredictedArrivalAt))
+				require.True(t, got.ArrivalP90At.After(*got.ArrivalP50At))
+
+				spread := time.Duration((withQuantiles.DurationP90Min.Float64 - withQuantiles.DurationP10Min.Float64) * float64(time.Minute))
+				require.WithinDuration(t, got.ArrivalP10At.Add(spread), *got.ArrivalP90At, time.Second)
+			},
+		},
+		{
+			name:  "InProgressLive",
+			route: inProgress,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(latest, nil)
+				store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Eq(db.ListRouteLocationsSinceParams{
+					RouteID:    route.ID,
+					RecordedAt: latest.RecordedAt.Add(-eta.DefaultSpeedWindow),
+				})).Times(1).Return([]db.RouteLocation{latest}, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				// reading the ETA never writes it
+				store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRouteEtaHistory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyEta(t, recorder)
+				require.Equal(t, etaSourceLive, got.Source)
+				require.Greater(t, got.RemainingDistanceKm, 0.0)
+				require.Greater(t, got.Confidence, 0.0)
+				require.True(t, got.PredictedArrivalAt.After(time.Now()))
+			},
+		},
+		{
+			name:  "InProgressLiveThroughStops",
+			route: inProgress,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(unloading, nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(latest, nil)
+				store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Any()).Times(1).Return([]db.RouteLocation{latest}, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyEta(t, recorder)
+				require.Equal(t, etaSourceLive, got.Source)
+				// the destination is only reached once the pending stop is served
+				require.Greater(t, got.RemainingDurationMin, unloading[0].ServiceTimeMin)
+				require.True(t, got.PredictedArrivalAt.After(time.Now().Add(30*time.Minute)))
+			},
+		},
+		{
+			name:  "InProgressWithoutPositions",
+			route: inProgress,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.RouteLocation{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyEta(t, recorder)
+				require.Equal(t, etaSourcePlanned, got.Source)
+				expected := inProgress.StartedAt.Time.Add(time.Duration(inProgress.EstimatedDurationMin.Float64 * float64(time.Minute)))
+				if expected.Before(time.Now()) {
+					require.InDelta(t, 0, got.RemainingDurationMin, 0.1)
+					return
+				}
+				require.WithinDuration(t, expected, got.PredictedArrivalAt, time.Second)
+			},
+		},
+		{
+			name:  "Completed",
+			route: completed,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyEta(t, recorder)
+				require.Equal(t, etaSourceCompleted, got.Source)
+				require.Zero(t, got.RemainingDistanceKm)
+				require.WithinDuration(t, completed.CompletedAt.Time, got.PredictedArrivalAt, time.Second)
+			},
+		},
+		{
+			name:  "Cancelled",
+			route: cancelled,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			route: inProgress,
+			buildStubs: func(store *mockdb.MockStore, route db.Route) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.RouteLocation{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(tc.route.ID)).Times(1).Return(tc.route, nil)
+			tc.buildStubs(store, tc.route)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s/eta", tc.route.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListRouteEtaHistory(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	history := []db.RouteEtaHistory{
+		{
+			ID:                   2,
+			RouteID:              route.ID,
+			LocationID:           sql.NullInt64{Int64: 9, Valid: true},
+			RemainingDistanceKm:  3.2,
+			RemainingDurationMin: 6.4,
+			EtaAt:                time.Now().Add(6 * time.Minute),
+			Confidence:           0.8,
+		},
+		{
+			ID:                   1,
+			RouteID:              route.ID,
+			RemainingDistanceKm:  5.1,
+			RemainingDurationMin: 10.2,
+			EtaAt:                time.Now().Add(10 * time.Minute),
+			Confidence:           0.6,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListRouteEtaHistoryParams{RouteID: route.ID, Limit: 5, Offset: 0}
+				store.EXPECT().ListRouteEtaHistory(gomock.Any(), gomock.Eq(arg)).Times(1).Return(history, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []RouteEtaHistoryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, len(history))
+				require.NotNil(t, got[0].LocationID)
+				require.Equal(t, int64(9), *got[0].LocationID)
+				require.Nil(t, got[1].LocationID)
+				require.Equal(t, history[1].Confidence, got[1].Confidence)
+			},
+		},
+		{
+			name:  "InvalidPage",
+			query: "page_id=0&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteEtaHistory(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteEtaHistory(gomock.Any(), gomock.Any()).Times(1).Return([]db.RouteEtaHistory{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s/eta/history?%s", route.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyEta(t *testing.T, recorder *httptest.ResponseRecorder) RouteEtaResponse {
+	var got RouteEtaResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	return got
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+// maxClockSkew is how far into the future a device timestamp may be before
+// the ping is rejected.
+const maxClockSkew = time.Minute
+
+var errRouteFinished = errors.New("locations can only be recorded for routes pending or in progress")
+
+type LocationPing struct {
+	Lat        float64   `json:"lat" binding:"min=-90,max=90"`
+	Lng        float64   `json:"lng" binding:"min=-180,max=180"`
+	SpeedKmh   *float64  `json:"speed_kmh" binding:"omitempty,min=0"`
+	HeadingDeg *float64  `json:"heading_deg" binding:"omitempty,min=0,lt=360"`
+	AccuracyM  *float64  `json:"accuracy_m" binding:"omitempty,min=0"`
+	RecordedAt time.Time `json:"recorded_at" binding:"required"`
+}
+
+type RecordLocationsRequest struct {
+	Locations []LocationPing `json:"locations" binding:"required,min=1,max=500,dive"`
+}
+
+type RouteLocationResponse struct {
+	ID         int64     `json:"id"`
+	RouteID    uuid.UUID `json:"route_id"`
+	Lat        float64   `json:"lat"`
+	Lng        float64   `json:"lng"`
+	SpeedKmh   *float64  `json:"speed_kmh,omitempty"`
+	HeadingDeg *float64  `json:"heading_deg,omitempty"`
+	AccuracyM  *float64  `json:"accuracy_m,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func newRouteLocationResponse(location db.RouteLocation) RouteLocationResponse {
+	return RouteLocationResponse{
+		ID:         location.ID,
+		RouteID:    location.RouteID,
+		Lat:        location.Lat,
+		Lng:        location.Lng,
+		SpeedKmh:   nullFloatPtr(location.SpeedKmh),
+		HeadingDeg: nullFloatPtr(location.HeadingDeg),
+		AccuracyM:  nullFloatPtr(location.AccuracyM),
+		RecordedAt: location.RecordedAt,
+	}
+}
+
+func nullFloatPtr(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+func floatPtrToNull(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+func (server *Server) RecordLocation(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	var req LocationPing
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	locations, ok := server.recordLocations(ctx, route, []LocationPing{req})
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newRouteLocationResponse(locations[0]))
+}
+
+func (server *Server) RecordLocationBatch(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	var req RecordLocationsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	locations, ok := server.recordLocations(ctx, route, req.Locations)
+	if !ok {
+		return
+	}
+	response := make([]RouteLocationResponse, 0, len(locations))
+	for _, location := range locations {
+		response = append(response, newRouteLocationResponse(location))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// recordLocations validates and stores pings for a pending or in-progress
+// route in the order they were recorded on the device, all or none of them,
+// and publishes them once stored. It then checks them against the route's
+// geofences and, once it is under way, its planned path. Pings before the
+// route starts let leaving the origin start it. It writes the error response
+// itself and reports whether the handler may continue.
+func (server *Server) recordLocations(ctx *gin.Context, route db.Route, pings []LocationPing) ([]db.RouteLocation, bool) {
+	if util.RouteStatus(route.Status).IsTerminal() {
+		ctx.JSON(http.StatusConflict, errorResponse(errRouteFinished))
+		return nil, false
+	}
+
+	now := time.Now()
+	for _, ping := range pings {
+		if ping.RecordedAt.After(now.Add(maxClockSkew)) {
+			err := fmt.Errorf("recorded_at %s is in the future", ping.RecordedAt.Format(time.RFC3339))
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return nil, false
+		}
+	}
+	sort.SliceStable(pings, func(i, j int) bool {
+		return pings[i].RecordedAt.Before(pings[j].RecordedAt)
+	})
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateRouteLocationsTxParams{
+		Locations: make([]db.CreateRouteLocationParams, 0, len(pings)),
+	}
+	for _, ping := range pings {
+		arg.Locations = append(arg.Locations, db.CreateRouteLocationParams{
+			RouteID:    route.ID,
+			DriverID:   authPayload.UserID,
+			Lat:        ping.Lat,
+			Lng:        ping.Lng,
+			SpeedKmh:   floatPtrToNull(ping.SpeedKmh),
+			HeadingDeg: floatPtrToNull(ping.HeadingDeg),
+			AccuracyM:  floatPtrToNull(ping.AccuracyM),
+			RecordedAt: ping.RecordedAt,
+		})
+	}
+	result, err := server.store.CreateRouteLocationsTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	locations := result.Locations
+	for _, location := range locations {
+		server.publish(tracking.EventPosition, route, newRouteLocationResponse(location))
+	}
+
+	// the pings are already stored, so failing to act on them must not fail
+	// the request. Positions stored before them may be part of a crossing or
+	// deviation they confirm.
+	recent, err := server.store.ListRouteLocationsSince(ctx, db.ListRouteLocationsSinceParams{
+		RouteID:    route.ID,
+		RecordedAt: locations[0].RecordedAt.Add(-max(server.geofences.Dwell(), server.deviations.Dwell())),
+	})
+	if err != nil {
+		ctx.Error(err)
+	}
+	if len(recent) > 0 {
+		if route, err = server.evaluateGeofences(ctx, route, recent); err != nil {
+			ctx.Error(err)
+		}
+		if util.RouteStatus(route.Status) == util.RouteInProgress {
+			if err := server.evaluateDeviation(ctx, route, recent); err != nil {
+				ctx.Error(err)
+			}
+		}
+	}
+	if util.RouteStatus(route.Status) == util.RouteInProgress {
+		if _, err := server.refreshRouteEta(ctx, route); err != nil {
+			ctx.Error(err)
+		}
+	}
+	return locations, true
+}
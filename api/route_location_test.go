@@ -0,0 +1,455 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/deviation"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomRouteLocation(route db.Route, recordedAt time.Time) db.RouteLocation {
+	return db.RouteLocation{
+		ID:         util.RandomInt(1, 1000),
+		RouteID:    route.ID,
+		DriverID:   route.DriverID,
+		Lat:        route.OriginLat,
+		Lng:        route.OriginLng,
+		SpeedKmh:   sql.NullFloat64{Float64: float64(util.RandomInt(0, 80)), Valid: true},
+		RecordedAt: recordedAt,
+	}
+}
+
+// expectGeofenceChecks stubs checking recent against the geofences of an
+// in-progress route without stops, which the driver is nowhere near the
+// destination of.
+func expectGeofenceChecks(store *mockdb.MockStore, route db.Route, recent ...db.RouteLocation) {
+	store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Any()).Times(1).Return(recent, nil)
+	store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+	store.EXPECT().
+		GetLatestGeofenceEvent(gomock.Any(), gomock.Eq(db.GetLatestGeofenceEventParams{
+			RouteID: route.ID,
+			Place:   string(util.GeofenceDestination),
+		})).
+		Times(1).
+		Return(db.GeofenceEvent{}, sql.ErrNoRows)
+	store.EXPECT().CreateGeofenceEvent(gomock.Any(), gomock.Any()).Times(0)
+}
+
+// expectRoutePath stubs loading the path route was planned along, which
+// stands in for the roads by running straight from the origin through stops
+// to the destination.
+func expectRoutePath(t *testing.T, store *mockdb.MockStore, route db.Route, stops ...db.RouteStop) {
+	path := deviation.Path{{Lat: route.OriginLat, Lng: route.OriginLng}}
+	for _, stop := range stops {
+		path = append(path, eta.Point{Lat: stop.Lat, Lng: stop.Lng})
+	}
+	path = append(path, eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng})
+	points, err := json.Marshal(path)
+	require.NoError(t, err)
+	store.EXPECT().
+		GetRoutePath(gomock.Any(), gomock.Eq(route.ID)).
+		Times(1).
+		Return(db.RoutePath{RouteID: route.ID, Points: points}, nil)
+}
+
+// expectDeviationCheck stubs checking the positions of an in-progress route
+// against its planned path through stops, which the driver hasn't left.
+func expectDeviationCheck(t *testing.T, store *mockdb.MockStore, route db.Route, stops ...db.RouteStop) {
+	expectRoutePath(t, store, route, stops...)
+	store.EXPECT().GetLatestDeviationEvent(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.DeviationEvent{}, sql.ErrNoRows)
+	store.EXPECT().CreateDeviationEvent(gomock.Any(), gomock.Any()).Times(0)
+}
+
+// expectEtaRefresh stubs the ETA refresh that follows every successful
+// ingestion, from latest through stops.
+func expectEtaRefresh(store *mockdb.MockStore, route db.Route, vehicle db.Vehicle, latest db.RouteLocation, stops ...db.RouteStop) {
+	store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(stops, nil)
+	store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(latest, nil)
+	store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Any()).Times(1).Return([]db.RouteLocation{latest}, nil)
+	store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+	store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(1).Return(route, nil)
+	store.EXPECT().CreateRouteEtaHistory(gomock.Any(), gomock.Any()).Times(1).Return(db.RouteEtaHistory{}, nil)
+}
+
+func TestRecordLocation(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	route.Status = string(util.RouteInProgress)
+
+	recordedAt := time.Now().Add(-time.Second).UTC().Truncate(time.Second)
+	location := randomRouteLocation(route, recordedAt)
+
+	testCases := []struct {
+		name          string
+		userID        uuid.UUID
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"speed_kmh":   location.SpeedKmh.Float64,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateRouteLocationParams{
+					RouteID:    route.ID,
+					DriverID:   user.ID,
+					Lat:        location.Lat,
+					Lng:        location.Lng,
+					SpeedKmh:   location.SpeedKmh,
+					RecordedAt: recordedAt,
+				}
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Eq(db.CreateRouteLocationsTxParams{Locations: []db.CreateRouteLocationParams{arg}})).Times(1).Return(db.CreateRouteLocationsTxResult{Locations: []db.RouteLocation{location}}, nil)
+				expectGeofenceChecks(store, route, location)
+				expectDeviationCheck(t, store, route)
+				expectEtaRefresh(store, route, vehicle, location)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got RouteLocationResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, location.ID, got.ID)
+				require.Equal(t, route.ID, got.RouteID)
+				require.Equal(t, location.Lat, got.Lat)
+				require.Equal(t, location.Lng, got.Lng)
+				require.NotNil(t, got.SpeedKmh)
+				require.Nil(t, got.HeadingDeg)
+			},
+		},
+		{
+			name:   "EtaRefreshFails",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateRouteLocationsTxResult{Locations: []db.RouteLocation{location}}, nil)
+				expectGeofenceChecks(store, route, location)
+				expectDeviationCheck(t, store, route)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.RouteLocation{}, sql.ErrConnDone)
+				store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "GeofencingFails",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateRouteLocationsTxResult{Locations: []db.RouteLocation{location}}, nil)
+				store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+				expectEtaRefresh(store, route, vehicle, location)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			// the route isn't started yet, so its ETA isn't live either
+			name:   "RoutePending",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				pending := route
+				pending.Status = string(util.RoutePending)
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateRouteLocationsTxResult{Locations: []db.RouteLocation{location}}, nil)
+				store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Any()).Times(1).Return([]db.RouteLocation{location}, nil)
+				store.EXPECT().
+					GetLatestGeofenceEvent(gomock.Any(), gomock.Eq(db.GetLatestGeofenceEventParams{
+						RouteID: route.ID,
+						Place:   string(util.GeofenceOrigin),
+					})).
+					Times(1).
+					Return(db.GeofenceEvent{}, sql.ErrNoRows)
+				store.EXPECT().CreateGeofenceEvent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateRouteEta(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "RouteCompleted",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				completed := route
+				completed.Status = string(util.RouteCompleted)
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(completed, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "UnauthorizedUser",
+			userID: uuid.New(),
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidHeading",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"heading_deg": 400,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "FutureTimestamp",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": time.Now().Add(time.Hour),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			userID: user.ID,
+			body: gin.H{
+				"lat":         location.Lat,
+				"lng":         location.Lng,
+				"recorded_at": recordedAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateRouteLocationsTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/locations", route.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.userID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRecordLocationBatch(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	route.Status = string(util.RouteInProgress)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := randomRouteLocation(route, now.Add(-2*time.Minute))
+	second := randomRouteLocation(route, now.Add(-time.Minute))
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"locations": []gin.H{
+					{"lat": second.Lat, "lng": second.Lng, "recorded_at": second.RecordedAt},
+					{"lat": first.Lat, "lng": first.Lng, "recorded_at": first.RecordedAt},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				// pings are stored oldest first regardless of request order
+				arg := db.CreateRouteLocationsTxParams{
+					Locations: []db.CreateRouteLocationParams{
+						{RouteID: route.ID, DriverID: user.ID, Lat: first.Lat, Lng: first.Lng, RecordedAt: first.RecordedAt},
+						{RouteID: route.ID, DriverID: user.ID, Lat: second.Lat, Lng: second.Lng, RecordedAt: second.RecordedAt},
+					},
+				}
+				store.EXPECT().
+					CreateRouteLocationsTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CreateRouteLocationsTxResult{Locations: []db.RouteLocation{first, second}}, nil)
+				// crossings and deviations are looked for from the longer of their
+				// dwells before the oldest new ping
+				store.EXPECT().
+					ListRouteLocationsSince(gomock.Any(), gomock.Eq(db.ListRouteLocationsSinceParams{
+						RouteID:    route.ID,
+						RecordedAt: first.RecordedAt.Add(-deviation.DefaultDwell),
+					})).
+					Times(1).
+					Return([]db.RouteLocation{first, second}, nil)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				store.EXPECT().GetLatestGeofenceEvent(gomock.Any(), gomock.Any()).Times(1).Return(db.GeofenceEvent{}, sql.ErrNoRows)
+				expectDeviationCheck(t, store, route)
+				expectEtaRefresh(store, route, vehicle, second)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []RouteLocationResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 2)
+				require.Equal(t, first.ID, got[0].ID)
+				require.Equal(t, second.ID, got[1].ID)
+			},
+		},
+		{
+			name: "EmptyBatch",
+			body: gin.H{"locations": []gin.H{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsertFails",
+			body: gin.H{
+				"locations": []gin.H{
+					{"lat": first.Lat, "lng": first.Lng, "recorded_at": first.RecordedAt},
+					{"lat": second.Lat, "lng": second.Lng, "recorded_at": second.RecordedAt},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateRouteLocationsTxResult{}, sql.ErrConnDone)
+				// none of the batch was stored, so there is nothing to act on
+				store.EXPECT().ListRouteLocationsSince(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPing",
+			body: gin.H{
+				"locations": []gin.H{
+					{"lat": first.Lat, "lng": first.Lng, "recorded_at": first.RecordedAt},
+					{"lat": 95.0, "lng": second.Lng, "recorded_at": second.RecordedAt},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().CreateRouteLocationsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/locations/batch", route.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
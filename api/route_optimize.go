@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/optimize"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+type OptimizeRouteStopsRequest struct {
+	// DepartureAt is when the driver leaves the origin, now when left out.
+	// Stop windows are checked against it.
+	DepartureAt *time.Time `json:"departure_at"`
+}
+
+type OptimizeRouteStopsResponse struct {
+	Route RouteResponse       `json:"route"`
+	Stops []RouteStopResponse `json:"stops"`
+	// Reordered is false when the stops were already in the best order found
+	Reordered        bool    `json:"reordered"`
+	DistanceBeforeKm float64 `json:"distance_before_km"`
+	DistanceAfterKm  float64 `json:"distance_after_km"`
+	// LateStops counts the stops that can't be reached within their window
+	LateStops int `json:"late_stops"`
+}
+
+// OptimizeRouteStops reorders the stops of a route that hasn't started yet
+// into the shortest trip from the origin to the destination that serves each
+// stop within its window, and replans the route along it.
+func (server *Server) OptimizeRouteStops(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	// the body is optional
+	var req OptimizeRouteStopsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if util.RouteStatus(route.Status) != util.RoutePending {
+		ctx.JSON(http.StatusConflict, errorResponse(errRouteStopsLocked))
+		return
+	}
+	departure := time.Now()
+	if req.DepartureAt != nil {
+		departure = *req.DepartureAt
+	}
+
+	stops, err := server.store.ListRouteStops(ctx, route.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	vehicle, err := server.store.GetVehicleByID(ctx, route.VehicleID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	class := eta.ClassForCapacity(vehicle.Capacity.Int32)
+	points := make([]eta.Point, 0, len(stops)+2)
+	points = append(points, eta.Point{Lat: route.OriginLat, Lng: route.OriginLng})
+	problem := optimize.Problem{
+		Stops:     make([]optimize.Stop, 0, len(stops)),
+		FixedEnd:  true,
+		Departure: departure,
+	}
+	current := make([]int, 0, len(stops))
+	for i, stop := range stops {
+		points = append(points, eta.Point{Lat: stop.Lat, Lng: stop.Lng})
+		problem.Stops = append(problem.Stops, optimize.Stop{
+			ServiceMin:  stop.ServiceTimeMin,
+			WindowStart: stop.WindowStart.Time,
+			WindowEnd:   stop.WindowEnd.Time,
+		})
+		current = append(current, i)
+	}
+	points = append(points, eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng})
+	roads := server.roadEstimator()
+	problem.Matrix = optimize.BuildMatrix(points, func(from, to eta.Point) (float64, float64) {
+		leg := roads.Estimate(from, to, class)
+		return leg.DistanceKm, leg.DurationMin
+	})
+
+	before, err := optimize.Evaluate(problem, current)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	solution, err := optimize.Solve(problem)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := OptimizeRouteStopsResponse{DistanceBeforeKm: roundKm(before.DistanceKm)}
+	if solution.Better(before) {
+		reordered := make([]db.NewRouteStop, 0, len(stops))
+		for _, i := range solution.Order {
+			stop := stops[i]
+			reordered = append(reordered, db.NewRouteStop{
+				ShipmentID:     stop.ShipmentID,
+				Lat:            stop.Lat,
+				Lng:            stop.Lng,
+				Address:        stop.Address,
+				ServiceTimeMin: stop.ServiceTimeMin,
+				WindowStart:    stop.WindowStart,
+				WindowEnd:      stop.WindowEnd,
+			})
+		}
+		result, ok := server.saveRouteStops(ctx, route, vehicle, reordered, departure)
+		if !ok {
+			return
+		}
+		route, stops = result.Route, result.Stops
+		response.Reordered = true
+	} else {
+		solution = before
+	}
+	response.DistanceAfterKm = roundKm(solution.DistanceKm)
+	response.LateStops = solution.LateStops
+
+	response.Route = newRouteResponse(route)
+	response.Stops, err = server.stopEtas(ctx, route, stops, departure)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*100) / 100
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// stopsAlongRoute places a stop at each fraction of the way from the route's
+// origin to its destination, in the order given.
+func stopsAlongRoute(route db.Route, fractions ...float64) []db.RouteStop {
+	stops := randomRouteStops(route, len(fractions))
+	for i, fraction := range fractions {
+		stops[i].Lat = route.OriginLat + (route.DestinationLat-route.OriginLat)*fraction
+		stops[i].Lng = route.OriginLng + (route.DestinationLng-route.OriginLng)*fraction
+	}
+	return stops
+}
+
+// replacedStops stands in for ReplaceRouteStopsTx, storing the new stops in
+// the order they are given.
+func replacedStops(t *testing.T, route db.Route, want []float64) func(_ interface{}, arg db.ReplaceRouteStopsTxParams) (db.ReplaceRouteStopsTxResult, error) {
+	return func(_ interface{}, arg db.ReplaceRouteStopsTxParams) (db.ReplaceRouteStopsTxResult, error) {
+		require.Equal(t, route.ID, arg.RouteID)
+		require.Equal(t, route.Status, arg.FromStatus)
+		require.Len(t, arg.Stops, len(want))
+
+		stored := stopsAlongRoute(route, want...)
+		for i, stop := range arg.Stops {
+			require.InDelta(t, stored[i].Lat, stop.Lat, 1e-9)
+			stored[i].ShipmentID = stop.ShipmentID
+			stored[i].Address = stop.Address
+			stored[i].WindowStart = stop.WindowStart
+			stored[i].WindowEnd = stop.WindowEnd
+		}
+
+		planned := route
+		planned.EstimatedDistanceKm = sql.NullFloat64{Float64: arg.EstimatedDistanceKm, Valid: true}
+		planned.EstimatedDurationMin = sql.NullFloat64{Float64: arg.EstimatedDurationMin, Valid: true}
+		return db.ReplaceRouteStopsTxResult{Route: planned, Stops: stored}, nil
+	}
+}
+
+func TestOptimizeRouteStops(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	inProgress := route
+	inProgress.Status = string(util.RouteInProgress)
+
+	departure := time.Now().Add(time.Hour).Truncate(time.Second)
+	shuffled := stopsAlongRoute(route, 0.8, 0.2, 0.5)
+	urgent := stopsAlongRoute(route, 0.2, 0.5, 0.8)
+	urgent[1].WindowEnd = sql.NullTime{Time: departure.Add(time.Minute), Valid: true}
+
+	testCases := []struct {
+		name          string
+		route         db.Route
+		body          interface{}
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			route: route,
+			body:  gin.H{"departure_at": departure},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(shuffled, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(2).Return(vehicle, nil)
+				store.EXPECT().
+					ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(replacedStops(t, route, []float64{0.2, 0.5, 0.8}))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyOptimized(t, recorder)
+				require.True(t, got.Reordered)
+				require.Less(t, got.DistanceAfterKm, got.DistanceBeforeKm)
+				require.Zero(t, got.LateStops)
+				require.InDelta(t, got.DistanceAfterKm, got.Route.EstimatedDistanceKm, 0.05)
+				require.Len(t, got.Stops, 3)
+				require.True(t, got.Stops[0].EstimatedArrivalAt.After(departure))
+			},
+		},
+		{
+			name:  "AlreadyOptimal",
+			route: route,
+			body:  nil,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(stopsAlongRoute(route, 0.2, 0.5, 0.8), nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(2).Return(vehicle, nil)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyOptimized(t, recorder)
+				require.False(t, got.Reordered)
+				require.Equal(t, got.DistanceBeforeKm, got.DistanceAfterKm)
+				require.Len(t, got.Stops, 3)
+			},
+		},
+		{
+			name:  "TimeWindow",
+			route: route,
+			body:  gin.H{"departure_at": departure},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(urgent, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(2).Return(vehicle, nil)
+				// the stop closing first is served first, then the trip doubles back
+				store.EXPECT().
+					ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(replacedStops(t, route, []float64{0.5, 0.2, 0.8}))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyOptimized(t, recorder)
+				require.True(t, got.Reordered)
+				require.Greater(t, got.DistanceAfterKm, got.DistanceBeforeKm)
+				require.Equal(t, 1, got.LateStops)
+				require.NotNil(t, got.Stops[0].WindowEnd)
+			},
+		},
+		{
+			name:  "RouteStarted",
+			route: inProgress,
+			body:  nil,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:  "RouteStatusChanged",
+			route: route,
+			body:  nil,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(shuffled, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReplaceRouteStopsTxResult{}, db.ErrRouteStatusChanged)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidDeparture",
+			route: route,
+			body:  gin.H{"departure_at": "tomorrow"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			route: route,
+			body:  nil,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(nil, sql.ErrConnDone)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(tc.route.ID)).Times(1).Return(tc.route, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var data []byte
+			if tc.body != nil {
+				var err error
+				data, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			url := fmt.Sprintf("/routes/%s/optimize", tc.route.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyOptimized(t *testing.T, recorder *httptest.ResponseRecorder) OptimizeRouteStopsResponse {
+	var got OptimizeRouteStopsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	return got
+}
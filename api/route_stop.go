@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/lib/pq"
+)
+
+var (
+	errRouteStopsLocked   = errors.New("stops can only be changed before the route starts")
+	errStopsNotInProgress = errors.New("stops can only be updated on routes in progress")
+	errStopStatusChanged  = errors.New("stop status was changed by another request")
+	errStopWindow         = errors.New("stop window_end must be after window_start")
+)
+
+type RouteStopRequest struct {
+	ShipmentID     string     `json:"shipment_id" binding:"omitempty,uuid"`
+	Address        string     `json:"address"`
+	Lat            float64    `json:"lat" binding:"min=-90,max=90"`
+	Lng            float64    `json:"lng" binding:"min=-180,max=180"`
+	ServiceTimeMin float64    `json:"service_time_min" binding:"min=0,max=480"`
+	WindowStart    *time.Time `json:"window_start"`
+	WindowEnd      *time.Time `json:"window_end"`
+	// GeofenceRadiusM is the configured default when left out
+	GeofenceRadiusM *float64 `json:"geofence_radius_m" binding:"omitempty,gt=0,max=5000"`
+}
+
+type ReplaceRouteStopsRequest struct {
+	Stops []RouteStopRequest `json:"stops" binding:"required,max=100,dive"`
+}
+
+type RouteStopResponse struct {
+	ID              int64      `json:"id"`
+	RouteID         uuid.UUID  `json:"route_id"`
+	ShipmentID      *uuid.UUID `json:"shipment_id,omitempty"`
+	Sequence        int32      `json:"sequence"`
+	Address         string     `json:"address"`
+	Lat             float64    `json:"lat"`
+	Lng             float64    `json:"lng"`
+	ServiceTimeMin  float64    `json:"service_time_min"`
+	Status          string     `json:"status"`
+	ArrivedAt       *time.Time `json:"arrived_at,omitempty"`
+	DepartedAt      *time.Time `json:"departed_at,omitempty"`
+	FailureReason   string     `json:"failure_reason,omitempty"`
+	WindowStart     *time.Time `json:"window_start,omitempty"`
+	WindowEnd       *time.Time `json:"window_end,omitempty"`
+	GeofenceRadiusM *float64   `json:"geofence_radius_m,omitempty"`
+	// Predicted visit, left out once it has actually happened
+	EstimatedArrivalAt   *time.Time `json:"estimated_arrival_at,omitempty"`
+	EstimatedDepartureAt *time.Time `json:"estimated_departure_at,omitempty"`
+}
+
+func newRouteStopResponse(stop db.RouteStop) RouteStopResponse {
+	response := RouteStopResponse{
+		ID:              stop.ID,
+		RouteID:         stop.RouteID,
+		Sequence:        stop.Sequence,
+		Address:         stop.Address.String,
+		Lat:             stop.Lat,
+		Lng:             stop.Lng,
+		ServiceTimeMin:  stop.ServiceTimeMin,
+		Status:          stop.Status,
+		ArrivedAt:       nullTimePtr(stop.ArrivedAt),
+		DepartedAt:      nullTimePtr(stop.DepartedAt),
+		FailureReason:   stop.FailureReason.String,
+		WindowStart:     nullTimePtr(stop.WindowStart),
+		WindowEnd:       nullTimePtr(stop.WindowEnd),
+		GeofenceRadiusM: nullFloatPtr(stop.GeofenceRadiusM),
+	}
+	if stop.ShipmentID.Valid {
+		response.ShipmentID = &stop.ShipmentID.UUID
+	}
+	return response
+}
+
+type RouteStopsResponse struct {
+	Route RouteResponse       `json:"route"`
+	Stops []RouteStopResponse `json:"stops"`
+}
+
+// ReplaceRouteStops sets the ordered stops of a route that hasn't started yet
+// and replans the trip from the origin through every stop to the destination.
+func (server *Server) ReplaceRouteStops(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	var req ReplaceRouteStopsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	for _, stop := range req.Stops {
+		if stop.WindowStart != nil && stop.WindowEnd != nil && !stop.WindowEnd.After(*stop.WindowStart) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errStopWindow))
+			return
+		}
+	}
+	if util.RouteStatus(route.Status) != util.RoutePending {
+		ctx.JSON(http.StatusConflict, errorResponse(errRouteStopsLocked))
+		return
+	}
+
+	vehicle, err := server.store.GetVehicleByID(ctx, route.VehicleID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	stops := make([]db.NewRouteStop, 0, len(req.Stops))
+	for _, stop := range req.Stops {
+		newStop := db.NewRouteStop{
+			Lat:             stop.Lat,
+			Lng:             stop.Lng,
+			Address:         sql.NullString{String: stop.Address, Valid: stop.Address != ""},
+			ServiceTimeMin:  stop.ServiceTimeMin,
+			WindowStart:     nullTimeFromPtr(stop.WindowStart),
+			WindowEnd:       nullTimeFromPtr(stop.WindowEnd),
+			GeofenceRadiusM: floatPtrToNull(stop.GeofenceRadiusM),
+		}
+		if stop.ShipmentID != "" {
+			newStop.ShipmentID = uuid.NullUUID{UUID: uuid.MustParse(stop.ShipmentID), Valid: true}
+		}
+		stops = append(stops, newStop)
+	}
+
+	result, ok := server.saveRouteStops(ctx, route, vehicle, stops, time.Now())
+	if !ok {
+		return
+	}
+
+	response, err := server.stopEtas(ctx, result.Route, result.Stops, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, RouteStopsResponse{
+		Route: newRouteResponse(result.Route),
+		Stops: response,
+	})
+}
+
+// saveRouteStops replaces the stops of a pending route with stops, in
+// visiting order, and replans the trip from the origin through every stop to
+// the destination, leaving at departure. On failure it writes the error
+// response and returns false.
+func (server *Server) saveRouteStops(ctx *gin.Context, route db.Route, vehicle db.Vehicle, stops []db.NewRouteStop, departure time.Time) (db.ReplaceRouteStopsTxResult, bool) {
+	waypoints := make([]eta.Stop, 0, len(stops)+1)
+	for _, stop := range stops {
+		waypoints = append(waypoints, eta.Stop{
+			Point:      eta.Point{Lat: stop.Lat, Lng: stop.Lng},
+			ServiceMin: stop.ServiceTimeMin,
+			NotBefore:  stop.WindowStart.Time,
+		})
+	}
+
+	// the trip ends at the destination, after the last stop
+	waypoints = append(waypoints, eta.Stop{Point: eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng}})
+	origin := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+	plan := server.planTrip(origin, departure, waypoints, vehicle, route.DriverID)
+	p10, p50, p90 := server.durationQuantiles(plan.Estimate)
+
+	result, err := server.store.ReplaceRouteStopsTx(ctx, db.ReplaceRouteStopsTxParams{
+		RouteID:              route.ID,
+		FromStatus:           route.Status,
+		Stops:                stops,
+		EstimatedDistanceKm:  plan.Estimate.DistanceKm,
+		EstimatedDurationMin: plan.Estimate.DurationMin,
+		BaselineDurationMin:  plan.BaselineDurationMin,
+		DurationP10Min:       p10,
+		DurationP50Min:       p50,
+		DurationP90Min:       p90,
+		Path:                 plan.Path,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRouteStatusChanged) {
+			ctx.JSON(http.StatusConflict, errorResponse(errRouteStopsLocked))
+			return result, false
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return result, false
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return result, false
+	}
+	return result, true
+}
+
+// ListRouteStops lists the route's stops in visiting order along with the
+// predicted visit of every stop still ahead.
+func (server *Server) ListRouteStops(ctx *gin.Context) {
+	route, ok := server.getReadableRoute(ctx)
+	if !ok {
+		return
+	}
+
+	stops, err := server.store.ListRouteStops(ctx, route.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response, err := server.stopEtas(ctx, route, stops, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type RouteStopIDRequest struct {
+	StopID int64 `uri:"stop_id" binding:"required,min=1"`
+}
+
+type UpdateRouteStopStatusRequest struct {
+	Status        string `json:"status" binding:"required,stop_status"`
+	FailureReason string `json:"failure_reason" binding:"required_if=Status failed,max=500"`
+}
+
+// UpdateRouteStopStatus records the driver arriving at, completing or failing
+// a stop of a route in progress.
+func (server *Server) UpdateRouteStopStatus(ctx *gin.Context) {
+	route, ok := server.getOwnedRoute(ctx)
+	if !ok {
+		return
+	}
+	var uri RouteStopIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req UpdateRouteStopStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if util.RouteStatus(route.Status) != util.RouteInProgress {
+		ctx.JSON(http.StatusConflict, errorResponse(errStopsNotInProgress))
+		return
+	}
+
+	stop, err := server.store.GetRouteStop(ctx, db.GetRouteStopParams{ID: uri.StopID, RouteID: route.ID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = util.ValidateStopTransition(util.StopStatus(stop.Status), util.StopStatus(req.Status))
+	if err != nil {
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	arg := db.TransitionRouteStopStatusParams{
+		ToStatus:      req.Status,
+		At:            time.Now(),
+		FailureReason: stop.FailureReason,
+		ID:            stop.ID,
+		RouteID:       route.ID,
+		FromStatus:    stop.Status,
+	}
+	if util.StopStatus(req.Status) == util.StopFailed {
+		arg.FailureReason = sql.NullString{String: req.FailureReason, Valid: true}
+	}
+	stop, err = server.store.TransitionRouteStopStatus(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errStopStatusChanged))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := newRouteStopResponse(stop)
+	server.publish(tracking.EventStop, route, response)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// stopEtas predicts the visit of every stop that is still ahead, as
+// scheduled by scheduleStops. Completed and failed stops keep their recorded
+// times only.
+func (server *Server) stopEtas(ctx context.Context, route db.Route, stops []db.RouteStop, now time.Time) ([]RouteStopResponse, error) {
+	schedule, err := server.scheduleStops(ctx, route, stops, now)
+	if err != nil {
+		return nil, err
+	}
+	return newScheduledStopsResponse(stops, schedule), nil
+}
+
+// newScheduledStopsResponse returns stops with the visits schedule predicts
+// for those still ahead.
+func newScheduledStopsResponse(stops []db.RouteStop, schedule stopSchedule) []RouteStopResponse {
+	response := make([]RouteStopResponse, 0, len(stops))
+	for _, stop := range stops {
+		response = append(response, newRouteStopResponse(stop))
+	}
+	for j, i := range schedule.ahead {
+		if util.StopStatus(stops[i].Status) == util.StopPending {
+			response[i].EstimatedArrivalAt = &schedule.visits[j].ArrivalAt
+		}
+		response[i].EstimatedDepartureAt = &schedule.visits[j].DepartureAt
+	}
+	return response
+}
+
+// stopSchedule is the predicted visit of every stop still ahead on a route.
+type stopSchedule struct {
+	// ahead holds the index in the route's stops of each visit.
+	ahead      []int
+	visits     []eta.StopEta
+	source     string
+	confidence float64
+}
+
+// scheduleStops predicts the visit of every stop still ahead on an unfinished
+// route. Once the route is under way they come with its live estimate from
+// the driver's latest reported position; until a position is reported they're
+// scheduled in sequence from the stop the driver is at, else the last stop
+// they left, and before the route starts, its origin.
+func (server *Server) scheduleStops(ctx context.Context, route db.Route, stops []db.RouteStop, now time.Time) (stopSchedule, error) {
+	switch util.RouteStatus(route.Status) {
+	case util.RouteCompleted, util.RouteCancelled:
+		return stopSchedule{}, nil
+	}
+	waypoints, ahead := remainingStops(route, stops)
+	if len(ahead) == 0 {
+		return stopSchedule{}, nil
+	}
+
+	if util.RouteStatus(route.Status) == util.RouteInProgress {
+		estimate, _, err := server.estimateFromPositions(ctx, route, stops, now)
+		if err == nil {
+			return stopSchedule{
+				ahead:      ahead,
+				visits:     estimate.Stops,
+				source:     etaSourceLive,
+				confidence: estimate.Confidence,
+			}, nil
+		}
+		if err != sql.ErrNoRows {
+			return stopSchedule{}, err
+		}
+	}
+
+	start := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+	for _, stop := range stops {
+		status := util.StopStatus(stop.Status)
+		if status == util.StopArrived || status.IsTerminal() && stop.DepartedAt.Valid {
+			start = eta.Point{Lat: stop.Lat, Lng: stop.Lng}
+		}
+	}
+	vehicle, err := server.store.GetVehicleByID(ctx, route.VehicleID)
+	if err != nil {
+		return stopSchedule{}, err
+	}
+	return stopSchedule{
+		ahead:      ahead,
+		visits:     server.trafficEstimator().Schedule(start, now, waypoints, eta.ClassForCapacity(vehicle.Capacity.Int32)),
+		source:     etaSourcePlanned,
+		confidence: eta.PlannedConfidence,
+	}, nil
+}
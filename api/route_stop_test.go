@@ -0,0 +1,629 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/deviation"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func randomRouteStops(route db.Route, n int) []db.RouteStop {
+	stops := make([]db.RouteStop, 0, n)
+	for i := 0; i < n; i++ {
+		stops = append(stops, db.RouteStop{
+			ID:             int64(i + 1),
+			RouteID:        route.ID,
+			Sequence:       int32(i + 1),
+			Lat:            route.OriginLat + 0.01*float64(i+1),
+			Lng:            route.OriginLng + 0.01*float64(i+1),
+			Address:        sql.NullString{String: util.RandomString(10), Valid: true},
+			ServiceTimeMin: 5,
+			Status:         string(util.StopPending),
+		})
+	}
+	return stops
+}
+
+func TestReplaceRouteStops(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	stops := randomRouteStops(route, 2)
+	shipmentID := uuid.New()
+	stops[1].ShipmentID = uuid.NullUUID{UUID: shipmentID, Valid: true}
+
+	inProgress := route
+	inProgress.Status = string(util.RouteInProgress)
+
+	body := gin.H{"stops": []gin.H{
+		{"lat": stops[0].Lat, "lng": stops[0].Lng, "address": stops[0].Address.String, "service_time_min": 5},
+		{"lat": stops[1].Lat, "lng": stops[1].Lng, "address": stops[1].Address.String, "service_time_min": 5, "shipment_id": shipmentID},
+	}}
+
+	testCases := []struct {
+		name          string
+		route         db.Route
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			route: route,
+			body:  body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(2).Return(vehicle, nil)
+				store.EXPECT().
+					ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ReplaceRouteStopsTxParams) (db.ReplaceRouteStopsTxResult, error) {
+						require.Equal(t, route.ID, arg.RouteID)
+						require.Equal(t, route.Status, arg.FromStatus)
+						require.Len(t, arg.Stops, 2)
+						require.False(t, arg.Stops[0].ShipmentID.Valid)
+						require.Equal(t, shipmentID, arg.Stops[1].ShipmentID.UUID)
+						// the plan covers the detour through both stops and their service time
+						require.Greater(t, arg.EstimatedDistanceKm, 0.0)
+						require.Greater(t, arg.EstimatedDurationMin, 10.0)
+
+						planned := route
+						planned.EstimatedDistanceKm = sql.NullFloat64{Float64: arg.EstimatedDistanceKm, Valid: true}
+						planned.EstimatedDurationMin = sql.NullFloat64{Float64: arg.EstimatedDurationMin, Valid: true}
+						return db.ReplaceRouteStopsTxResult{Route: planned, Stops: stops}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got RouteStopsResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, route.ID, got.Route.ID)
+				require.Len(t, got.Stops, 2)
+				require.NotNil(t, got.Stops[0].EstimatedArrivalAt)
+				require.True(t, got.Stops[1].EstimatedArrivalAt.After(*got.Stops[0].EstimatedDepartureAt))
+				require.Equal(t, shipmentID, *got.Stops[1].ShipmentID)
+			},
+		},
+		{
+			name:  "RouteStarted",
+			route: inProgress,
+			body:  body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:  "RouteStatusChanged",
+			route: route,
+			body:  body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReplaceRouteStopsTxResult{}, db.ErrRouteStatusChanged)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:  "UnknownShipment",
+			route: route,
+			body:  body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReplaceRouteStopsTxResult{}, &pq.Error{Code: "23503"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidStop",
+			route: route,
+			body:  gin.H{"stops": []gin.H{{"lat": 95, "lng": 3.4}}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidWindow",
+			route: route,
+			body: gin.H{"stops": []gin.H{{
+				"lat":          stops[0].Lat,
+				"lng":          stops[0].Lng,
+				"window_start": time.Now().Add(2 * time.Hour),
+				"window_end":   time.Now().Add(time.Hour),
+			}}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingStops",
+			route: route,
+			body:  gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			route: route,
+			body:  body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReplaceRouteStopsTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(tc.route.ID)).Times(1).Return(tc.route, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/stops", tc.route.ID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReplaceRouteStopsRoadNetwork(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	roads := testRoads(t, route)
+
+	origin := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+	destination := eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng}
+	path, err := roads.Route(origin, destination, eta.ClassForCapacity(vehicle.Capacity.Int32))
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+	store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).AnyTimes().Return(vehicle, nil)
+	store.EXPECT().
+		ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.ReplaceRouteStopsTxParams) (db.ReplaceRouteStopsTxResult, error) {
+			// the leg from the stop at the origin goes around the corner
+			require.InDelta(t, path.DistanceKm, arg.EstimatedDistanceKm, 0.01)
+			require.Greater(t, arg.EstimatedDistanceKm, eta.HaversineKm(origin, destination))
+			require.InDelta(t, path.Estimate().DurationMin, arg.BaselineDurationMin, 0.01)
+
+			var stored deviation.Path
+			require.NoError(t, json.Unmarshal(arg.Path, &stored))
+			require.Equal(t, origin, stored[0])
+			require.Equal(t, path.Points[1:], []eta.Point(stored[len(stored)-len(path.Points)+1:]))
+			return db.ReplaceRouteStopsTxResult{Route: route, Stops: randomRouteStops(route, 1)}, nil
+		})
+
+	server := NewTestServer(t, store)
+	server.roads = roads
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"stops": []gin.H{{"lat": origin.Lat, "lng": origin.Lng}}})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/routes/%s/stops", route.ID)
+	request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestReplaceRouteStopsCorrectionModel(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	stops := randomRouteStops(route, 2)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+	store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).AnyTimes().Return(vehicle, nil)
+	store.EXPECT().
+		ReplaceRouteStopsTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.ReplaceRouteStopsTxParams) (db.ReplaceRouteStopsTxResult, error) {
+			// the whole trip, stops included, takes the driver twice as long
+			require.Greater(t, arg.BaselineDurationMin, 10.0)
+			require.InEpsilon(t, 2*arg.BaselineDurationMin, arg.EstimatedDurationMin, 0.1)
+			return db.ReplaceRouteStopsTxResult{Route: route, Stops: stops}, nil
+		})
+
+	server := NewTestServer(t, store)
+	server.correction = trainCorrectionModel(t, eta.CorrectionFeatures{
+		DistanceKm:   10,
+		Departure:    time.Now(),
+		DriverID:     user.ID.String(),
+		VehicleModel: vehicle.Model.String,
+		Origin:       eta.Point{Lat: route.OriginLat, Lng: route.OriginLng},
+		Destination:  eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng},
+	}, 30)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"stops": []gin.H{
+		{"lat": stops[0].Lat, "lng": stops[0].Lng, "service_time_min": 5},
+		{"lat": stops[1].Lat, "lng": stops[1].Lng, "service_time_min": 5},
+	}})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/routes/%s/stops", route.ID)
+	request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestListRouteStops(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	inProgress := route
+	inProgress.Status = string(util.RouteInProgress)
+	inProgress.StartedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+
+	completed := inProgress
+	completed.Status = string(util.RouteCompleted)
+
+	// the driver finished the first stop and is standing at the second
+	visited := randomRouteStops(inProgress, 3)
+	visited[0].Status = string(util.StopCompleted)
+	visited[0].ArrivedAt = sql.NullTime{Time: time.Now().Add(-40 * time.Minute), Valid: true}
+	visited[0].DepartedAt = sql.NullTime{Time: time.Now().Add(-35 * time.Minute), Valid: true}
+	visited[1].Status = string(util.StopArrived)
+	visited[1].ArrivedAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	testCases := []struct {
+		name          string
+		route         db.Route
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Pending",
+			route: route,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(randomRouteStops(route, 3), nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyStops(t, recorder)
+				require.Len(t, got, 3)
+				for i := 1; i < len(got); i++ {
+					require.Equal(t, 5*time.Minute, got[i].EstimatedDepartureAt.Sub(*got[i].EstimatedArrivalAt))
+					require.True(t, got[i].EstimatedArrivalAt.After(*got[i-1].EstimatedDepartureAt))
+				}
+			},
+		},
+		{
+			name:  "InProgress",
+			route: inProgress,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(visited, nil)
+				store.EXPECT().GetLatestRouteLocation(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.RouteLocation{}, sql.ErrNoRows)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodyStops(t, recorder)
+				require.Len(t, got, 3)
+
+				require.Nil(t, got[0].EstimatedArrivalAt)
+				require.Nil(t, got[0].EstimatedDepartureAt)
+				require.NotNil(t, got[0].DepartedAt)
+
+				// still being served, so it is left once the service time is up
+				require.Nil(t, got[1].EstimatedArrivalAt)
+				require.WithinDuration(t, visited[1].ArrivedAt.Time.Add(5*time.Minute), *got[1].EstimatedDepartureAt, time.Second)
+
+				require.True(t, got[2].EstimatedArrivalAt.After(*got[1].EstimatedDepartureAt))
+			},
+		},
+		{
+			name:  "Completed",
+			route: completed,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(visited, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				for _, stop := range requireBodyStops(t, recorder) {
+					require.Nil(t, stop.EstimatedArrivalAt)
+				}
+			},
+		},
+		{
+			name:  "NoStops",
+			route: route,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, requireBodyStops(t, recorder))
+			},
+		},
+		{
+			name:  "InternalError",
+			route: route,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(tc.route.ID)).Times(1).Return(tc.route, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s/stops", tc.route.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateRouteStopStatus(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+
+	route := randomRoute(t, vehicle)
+	route.Status = string(util.RouteInProgress)
+	pending := route
+	pending.Status = string(util.RoutePending)
+
+	stop := randomRouteStops(route, 1)[0]
+	arrived := stop
+	arrived.Status = string(util.StopArrived)
+	arrived.ArrivedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	completed := arrived
+	completed.Status = string(util.StopCompleted)
+
+	testCases := []struct {
+		name          string
+		route         db.Route
+		stopID        string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Arrived",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "arrived"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Eq(db.GetRouteStopParams{ID: stop.ID, RouteID: route.ID})).Times(1).Return(stop, nil)
+				store.EXPECT().TransitionRouteStopStatus(gomock.Any(), EqTransitionParams(db.TransitionRouteStopStatusParams{
+					ToStatus:   "arrived",
+					ID:         stop.ID,
+					RouteID:    route.ID,
+					FromStatus: "pending",
+				})).Times(1).Return(arrived, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got RouteStopResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, string(util.StopArrived), got.Status)
+				require.NotNil(t, got.ArrivedAt)
+			},
+		},
+		{
+			name:   "Failed",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "failed", "failure_reason": "nobody home"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(1).Return(arrived, nil)
+				store.EXPECT().TransitionRouteStopStatus(gomock.Any(), EqTransitionParams(db.TransitionRouteStopStatusParams{
+					ToStatus:      "failed",
+					FailureReason: sql.NullString{String: "nobody home", Valid: true},
+					ID:            stop.ID,
+					RouteID:       route.ID,
+					FromStatus:    "arrived",
+				})).Times(1).Return(arrived, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "FailedWithoutReason",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "failed"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidStatus",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "skipped"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidStopID",
+			route:  route,
+			stopID: "0",
+			body:   gin.H{"status": "arrived"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "RouteNotStarted",
+			route:  pending,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "arrived"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "StopNotFound",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "arrived"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(1).Return(db.RouteStop{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidTransition",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "arrived"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(1).Return(completed, nil)
+				store.EXPECT().TransitionRouteStopStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "ConcurrentUpdate",
+			route:  route,
+			stopID: fmt.Sprint(stop.ID),
+			body:   gin.H{"status": "completed"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteStop(gomock.Any(), gomock.Any()).Times(1).Return(stop, nil)
+				store.EXPECT().TransitionRouteStopStatus(gomock.Any(), gomock.Any()).Times(1).Return(db.RouteStop{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(tc.route.ID)).Times(1).Return(tc.route, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/stops/%s/status", tc.route.ID, tc.stopID)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyStops(t *testing.T, recorder *httptest.ResponseRecorder) []RouteStopResponse {
+	var got []RouteStopResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	return got
+}
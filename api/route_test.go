@@ -0,0 +1,1231 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/routing"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomRoute(t *testing.T, vehicle db.Vehicle) db.Route {
+	return db.Route{
+		ID:                   uuid.New(),
+		DriverID:             vehicle.DriverID,
+		VehicleID:            vehicle.ID,
+		OriginAddress:        sql.NullString{String: util.RandomString(10), Valid: true},
+		OriginLat:            6.5244,
+		OriginLng:            3.3792,
+		DestinationAddress:   sql.NullString{String: util.RandomString(10), Valid: true},
+		DestinationLat:       6.6018,
+		DestinationLng:       3.3515,
+		EstimatedDistanceKm:  sql.NullFloat64{Float64: float64(util.RandomInt(1, 50)), Valid: true},
+		EstimatedDurationMin: sql.NullFloat64{Float64: float64(util.RandomInt(5, 120)), Valid: true},
+		Status:               string(util.RoutePending),
+	}
+}
+
+type eqCreateRouteParamsMatcher struct {
+	arg db.CreateRouteParams
+}
+
+func (e eqCreateRouteParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateRouteParams)
+	if !ok {
+		return false
+	}
+	if arg.ID == uuid.Nil {
+		return false
+	}
+	e.arg.ID = arg.ID
+	return reflect.DeepEqual(e.arg, arg)
+}
+
+func (e eqCreateRouteParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v", e.arg)
+}
+
+func EqCreateRouteParams(arg db.CreateRouteParams) gomock.Matcher {
+	return eqCreateRouteParamsMatcher{arg}
+}
+
+// eqTransitionParamsMatcher matches route and stop transition params made
+// by hand, stamped with the time the request came in.
+type eqTransitionParamsMatcher struct {
+	arg interface{}
+}
+
+func (e eqTransitionParamsMatcher) Matches(x interface{}) bool {
+	got := reflect.ValueOf(x)
+	if got.Type() != reflect.TypeOf(e.arg) {
+		return false
+	}
+	at, ok := got.FieldByName("At").Interface().(time.Time)
+	if !ok || at.After(time.Now()) || time.Since(at) > time.Minute {
+		return false
+	}
+	want := reflect.New(got.Type()).Elem()
+	want.Set(reflect.ValueOf(e.arg))
+	want.FieldByName("At").Set(reflect.ValueOf(at))
+	return reflect.DeepEqual(want.Interface(), x)
+}
+
+func (e eqTransitionParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v at about now", e.arg)
+}
+
+func EqTransitionParams(arg interface{}) gomock.Matcher {
+	return eqTransitionParamsMatcher{arg}
+}
+
+func TestCreateRoute(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	estimator, err := eta.NewEstimator(0, nil)
+	require.NoError(t, err)
+	estimate := estimator.Estimate(
+		eta.Point{Lat: route.OriginLat, Lng: route.OriginLng},
+		eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng},
+		eta.ClassForCapacity(vehicle.Capacity.Int32),
+	)
+
+	body := gin.H{
+		"vehicle_id":          vehicle.ID,
+		"origin_address":      route.OriginAddress.String,
+		"origin_lat":          route.OriginLat,
+		"origin_lng":          route.OriginLng,
+		"destination_address": route.DestinationAddress.String,
+		"destination_lat":     route.DestinationLat,
+		"destination_lng":     route.DestinationLng,
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateRouteParams{
+					DriverID:             user.ID,
+					VehicleID:            vehicle.ID,
+					OriginAddress:        route.OriginAddress,
+					OriginLat:            route.OriginLat,
+					OriginLng:            route.OriginLng,
+					DestinationAddress:   route.DestinationAddress,
+					DestinationLat:       route.DestinationLat,
+					DestinationLng:       route.DestinationLng,
+					EstimatedDistanceKm:  sql.NullFloat64{Float64: estimate.DistanceKm, Valid: true},
+					EstimatedDurationMin: sql.NullFloat64{Float64: estimate.DurationMin, Valid: true},
+					BaselineDurationMin:  sql.NullFloat64{Float64: estimate.DurationMin, Valid: true},
+					Status:               string(util.RoutePending),
+				}
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().
+					CreateRoute(gomock.Any(), EqCreateRouteParams(arg)).
+					Times(1).
+					Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, route)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "VehicleNotFound",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Any()).Times(1).Return(db.Vehicle{}, sql.ErrNoRows)
+				store.EXPECT().CreateRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "VehicleNotOwned",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().CreateRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCoordinates",
+			body: gin.H{
+				"vehicle_id":      vehicle.ID,
+				"origin_lat":      120.0,
+				"origin_lng":      route.OriginLng,
+				"destination_lat": route.DestinationLat,
+				"destination_lng": route.DestinationLng,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+				store.EXPECT().CreateRoute(gomock.Any(), gomock.Any()).Times(1).Return(db.Route{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/routes/create"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// testRoads is a road network of two primary roads meeting at a right angle,
+// from the origin of route due north and then west to its destination.
+func testRoads(t *testing.T, route db.Route) *routing.Graph {
+	builder := routing.NewBuilder()
+	builder.AddWay([]int64{1, 2, 3}, map[string]string{"highway": "primary"})
+	builder.AddNode(1, route.OriginLat, route.OriginLng)
+	builder.AddNode(2, route.DestinationLat, route.OriginLng)
+	builder.AddNode(3, route.DestinationLat, route.DestinationLng)
+	roads, err := builder.Build()
+	require.NoError(t, err)
+	return roads
+}
+
+func TestCreateRouteRoadNetwork(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	roads := testRoads(t, route)
+
+	origin := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+	class := eta.ClassForCapacity(vehicle.Capacity.Int32)
+	estimator, err := eta.NewEstimator(0, nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		destination eta.Point
+		expected    func(t *testing.T, destination eta.Point) eta.Estimate
+		// the road path is stored to check the driver's positions against
+		storesPath bool
+	}{
+		{
+			name:        "RoadPath",
+			destination: eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng},
+			storesPath:  true,
+			expected: func(t *testing.T, destination eta.Point) eta.Estimate {
+				path, err := roads.Route(origin, destination, class)
+				require.NoError(t, err)
+				// around the corner rather than straight there
+				require.Greater(t, path.DistanceKm, eta.HaversineKm(origin, destination))
+				return path.Estimate()
+			},
+		},
+		{
+			name:        "NotCovered",
+			destination: eta.Point{Lat: 7.1, Lng: 3.9},
+			expected: func(t *testing.T, destination eta.Point) eta.Estimate {
+				return estimator.Estimate(origin, destination, class)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			estimate := tc.expected(t, tc.destination)
+			arg := db.CreateRouteParams{
+				DriverID:             user.ID,
+				VehicleID:            vehicle.ID,
+				OriginLat:            route.OriginLat,
+				OriginLng:            route.OriginLng,
+				DestinationLat:       tc.destination.Lat,
+				DestinationLng:       tc.destination.Lng,
+				EstimatedDistanceKm:  sql.NullFloat64{Float64: estimate.DistanceKm, Valid: true},
+				EstimatedDurationMin: sql.NullFloat64{Float64: estimate.DurationMin, Valid: true},
+				BaselineDurationMin:  sql.NullFloat64{Float64: estimate.DurationMin, Valid: true},
+				Status:               string(util.RoutePending),
+			}
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			store.EXPECT().CreateRoute(gomock.Any(), EqCreateRouteParams(arg)).Times(1).Return(route, nil)
+			if tc.storesPath {
+				path, err := roads.Route(origin, tc.destination, class)
+				require.NoError(t, err)
+				points, err := json.Marshal(path.Points)
+				require.NoError(t, err)
+				store.EXPECT().
+					UpsertRoutePath(gomock.Any(), gomock.Eq(db.UpsertRoutePathParams{RouteID: route.ID, Points: points})).
+					Times(1).
+					Return(nil)
+			} else {
+				store.EXPECT().UpsertRoutePath(gomock.Any(), gomock.Any()).Times(0)
+			}
+
+			server := NewTestServer(t, store)
+			server.roads = roads
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"vehicle_id":      vehicle.ID,
+				"origin_lat":      route.OriginLat,
+				"origin_lng":      route.OriginLng,
+				"destination_lat": tc.destination.Lat,
+				"destination_lng": tc.destination.Lng,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/routes/create", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
+
+// trainCorrectionModel trains a model on trips like features whose driver
+// has always taken twice the free-flow estimate of baselineMin.
+func trainCorrectionModel(t *testing.T, features eta.CorrectionFeatures, baselineMin float64) *eta.CorrectionModel {
+	samples := make([]eta.CorrectionSample, eta.MinTrainingSamples*5)
+	for i := range samples {
+		samples[i] = eta.CorrectionSample{
+			CorrectionFeatures: features,
+			BaselineMin:        baselineMin,
+			PlannedMin:         baselineMin,
+			ActualMin:          baselineMin * 2,
+		}
+		samples[i].Departure = features.Departure.AddDate(0, 0, -i)
+	}
+	model, err := eta.TrainCorrectionModel(samples, time.UTC)
+	require.NoError(t, err)
+	require.InDelta(t, 2, model.Factor(features), 0.1)
+	return model
+}
+
+func TestCreateRouteCorrectionModel(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	origin := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+	destination := eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng}
+	departure := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+
+	estimator, err := eta.NewEstimator(0, nil)
+	require.NoError(t, err)
+	baseline := estimator.Estimate(origin, destination, eta.ClassForCapacity(vehicle.Capacity.Int32))
+
+	features := eta.CorrectionFeatures{
+		DistanceKm:   baseline.DistanceKm,
+		Departure:    departure,
+		DriverID:     user.ID.String(),
+		VehicleModel: vehicle.Model.String,
+		Origin:       origin,
+		Destination:  destination,
+	}
+	model := trainCorrectionModel(t, features, baseline.DurationMin)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+	store.EXPECT().
+		CreateRoute(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateRouteParams) (db.Route, error) {
+			require.Equal(t, baseline.DurationMin, arg.BaselineDurationMin.Float64)
+			require.Equal(t, model.Correct(baseline, features), eta.Estimate{
+				DistanceKm:  arg.EstimatedDistanceKm.Float64,
+				DurationMin: arg.EstimatedDurationMin.Float64,
+			})
+			return route, nil
+		})
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	require.NoError(t, model.Save(path))
+	server, err := NewServer(util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		ETAModelFile:        path,
+	}, store)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"vehicle_id":      vehicle.ID,
+		"origin_lat":      route.OriginLat,
+		"origin_lng":      route.OriginLng,
+		"destination_lat": route.DestinationLat,
+		"destination_lng": route.DestinationLng,
+		"departure_at":    departure,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/routes/create", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	_, err = NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		ETAModelFile:      filepath.Join(t.TempDir(), "missing.json"),
+	}, store)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGetRoute(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	testCases := []struct {
+		name          string
+		routeID       string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			routeID: route.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, route)
+			},
+		},
+		{
+			name:    "Admin",
+			routeID: route.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, route)
+			},
+		},
+		{
+			name:    "NotFound",
+			routeID: route.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.Route{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "UnauthorizedUser",
+			routeID: route.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:    "InvalidID",
+			routeID: "not-a-uuid",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:    "InternalError",
+			routeID: route.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(1).Return(db.Route{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s", tc.routeID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminRouteAccess(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	// admins read routes they don't drive, such as those they dispatched,
+	// but only the driver changes them
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		buildStubs func(store *mockdb.MockStore)
+		wantStatus int
+	}{
+		{
+			name:       "GetRoute",
+			method:     http.MethodGet,
+			path:       "",
+			buildStubs: func(store *mockdb.MockStore) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "GetRouteEta",
+			method:     http.MethodGet,
+			path:       "/eta",
+			buildStubs: func(store *mockdb.MockStore) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "ListRouteEtaHistory",
+			method: http.MethodGet,
+			path:   "/eta/history?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteEtaHistory(gomock.Any(), gomock.Any()).Times(1).Return([]db.RouteEtaHistory{}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "ListRouteStops",
+			method: http.MethodGet,
+			path:   "/stops",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(randomRouteStops(route, 2), nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "DeleteRoute",
+			method: http.MethodDelete,
+			path:   "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s%s", route.ID, tc.path)
+			request, err := http.NewRequest(tc.method, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantStatus, recorder.Code)
+		})
+	}
+}
+
+func TestListRoutes(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+
+	n := 5
+	routes := make([]db.Route, n)
+	for i := 0; i < n; i++ {
+		routes[i] = randomRoute(t, vehicle)
+	}
+
+	type Query struct {
+		status   string
+		pageID   int
+		pageSize int
+	}
+
+	testCases := []struct {
+		name          string
+		query         Query
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: Query{pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.GetRoutesByDriverIDParams{
+					DriverID: user.ID,
+					Limit:    int32(n),
+					Offset:   0,
+				}
+				store.EXPECT().GetRoutesByDriverID(gomock.Any(), gomock.Eq(arg)).Times(1).Return(routes, nil)
+				store.EXPECT().ListRoutesByDriverAndStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoutes(t, recorder.Body, routes)
+			},
+		},
+		{
+			name:  "OKWithStatus",
+			query: Query{status: string(util.RoutePending), pageID: 2, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListRoutesByDriverAndStatusParams{
+					DriverID: user.ID,
+					Status:   string(util.RoutePending),
+					Limit:    int32(n),
+					Offset:   int32(n),
+				}
+				store.EXPECT().GetRoutesByDriverID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListRoutesByDriverAndStatus(gomock.Any(), gomock.Eq(arg)).Times(1).Return(routes, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoutes(t, recorder.Body, routes)
+			},
+		},
+		{
+			name:  "InvalidStatus",
+			query: Query{status: "lost", pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRoutesByDriverID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListRoutesByDriverAndStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: Query{pageID: 1, pageSize: 100},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRoutesByDriverID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListRoutesByDriverAndStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: Query{pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRoutesByDriverID(gomock.Any(), gomock.Any()).Times(1).Return([]db.Route{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/routes", nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			if tc.query.status != "" {
+				q.Add("status", tc.query.status)
+			}
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateRouteStatus(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	updated := route
+	updated.Status = string(util.RouteInProgress)
+
+	inProgress := route
+	inProgress.Status = string(util.RouteInProgress)
+	completed := route
+	completed.Status = string(util.RouteCompleted)
+	cancelled := route
+	cancelled.Status = string(util.RouteCancelled)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"status": string(util.RouteInProgress)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.StartRouteTxParams{
+					RouteID:    route.ID,
+					FromStatus: string(util.RoutePending),
+				}
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), EqTransitionParams(arg)).Times(1).
+					Return(db.StartRouteTxResult{Route: updated, Vehicle: vehicle}, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, updated)
+			},
+		},
+		{
+			name: "Complete",
+			body: gin.H{"status": string(util.RouteCompleted)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CompleteRouteTxParams{
+					RouteID:    route.ID,
+					FromStatus: string(util.RouteInProgress),
+				}
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(inProgress, nil)
+				store.EXPECT().CompleteRouteTx(gomock.Any(), EqTransitionParams(arg)).Times(1).
+					Return(db.CompleteRouteTxResult{Route: completed, Vehicle: vehicle}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, completed)
+			},
+		},
+		{
+			name: "Cancel",
+			body: gin.H{"status": string(util.RouteCancelled)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransitionRouteStatusParams{
+					ID:         route.ID,
+					FromStatus: string(util.RouteInProgress),
+					ToStatus:   string(util.RouteCancelled),
+				}
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(inProgress, nil)
+				store.EXPECT().TransitionRouteStatus(gomock.Any(), EqTransitionParams(arg)).Times(1).Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, cancelled)
+			},
+		},
+		{
+			name: "VehicleInUse",
+			body: gin.H{"status": string(util.RouteInProgress)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(1).Return(db.StartRouteTxResult{}, db.ErrVehicleInUse)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), db.ErrVehicleInUse.Error())
+			},
+		},
+		{
+			name: "IllegalTransition",
+			body: gin.H{"status": string(util.RouteCompleted)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "TerminalStatus",
+			body: gin.H{"status": string(util.RouteInProgress)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentChange",
+			body: gin.H{"status": string(util.RouteInProgress)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(1).Return(db.StartRouteTxResult{}, db.ErrRouteStatusChanged)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidStatus",
+			body: gin.H{"status": "lost"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: gin.H{"status": string(util.RouteInProgress)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CompleteRouteTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"status": string(util.RouteInProgress)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(1).Return(db.StartRouteTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/status", route.ID)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateRouteActualDuration(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	updated := route
+	updated.ActualDurationMin = sql.NullFloat64{Float64: 42, Valid: true}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"actual_duration_min": 42},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateRouteActualDurationParams{
+					ID:                route.ID,
+					ActualDurationMin: sql.NullFloat64{Float64: 42, Valid: true},
+				}
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().UpdateRouteActualDuration(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchRoute(t, recorder.Body, updated)
+			},
+		},
+		{
+			name: "NegativeDuration",
+			body: gin.H{"actual_duration_min": -5},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().UpdateRouteActualDuration(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"actual_duration_min": 42},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().UpdateRouteActualDuration(gomock.Any(), gomock.Any()).Times(1).Return(db.Route{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/routes/%s/actual_duration", route.ID)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteRoute(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().DeleteRoute(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.Route{}, sql.ErrNoRows)
+				store.EXPECT().DeleteRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().DeleteRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().DeleteRoute(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/routes/%s", route.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchRoute(t *testing.T, body *bytes.Buffer, route db.Route) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotRoute RouteResponse
+	err = json.Unmarshal(data, &gotRoute)
+	require.NoError(t, err)
+	requireRouteResponseMatch(t, route, gotRoute)
+}
+
+func requireBodyMatchRoutes(t *testing.T, body *bytes.Buffer, routes []db.Route) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotRoutes []RouteResponse
+	err = json.Unmarshal(data, &gotRoutes)
+	require.NoError(t, err)
+	require.Len(t, gotRoutes, len(routes))
+	for i := range routes {
+		requireRouteResponseMatch(t, routes[i], gotRoutes[i])
+	}
+}
+
+func requireRouteResponseMatch(t *testing.T, route db.Route, gotRoute RouteResponse) {
+	require.Equal(t, route.ID, gotRoute.ID)
+	require.Equal(t, route.DriverID, gotRoute.DriverID)
+	require.Equal(t, route.VehicleID, gotRoute.VehicleID)
+	require.Equal(t, route.OriginAddress.String, gotRoute.OriginAddress)
+	require.Equal(t, route.OriginLat, gotRoute.OriginLat)
+	require.Equal(t, route.OriginLng, gotRoute.OriginLng)
+	require.Equal(t, route.DestinationAddress.String, gotRoute.DestinationAddress)
+	require.Equal(t, route.DestinationLat, gotRoute.DestinationLat)
+	require.Equal(t, route.DestinationLng, gotRoute.DestinationLng)
+	require.Equal(t, route.EstimatedDistanceKm.Float64, gotRoute.EstimatedDistanceKm)
+	require.Equal(t, route.EstimatedDurationMin.Float64, gotRoute.EstimatedDurationMin)
+	require.Equal(t, route.ActualDurationMin.Float64, gotRoute.ActualDurationMin)
+	require.Equal(t, route.Status, gotRoute.Status)
+}
@@ -2,12 +2,19 @@ package api
 
 import (
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/deviation"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/geofence"
+	"github.com/joekings2k/logistics-eta/routing"
 	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/tracking"
 	"github.com/joekings2k/logistics-eta/util"
 )
 
@@ -16,21 +23,65 @@ type Server struct {
 	config util.Config
 	store db.Store
 	tokenMaker token.Maker
+	estimator *eta.Estimator
+	roads *routing.Graph
+	correction *eta.CorrectionModel
+	profile atomic.Pointer[learnedProfile]
+	profileLocation *time.Location
+	intervals atomic.Pointer[learnedIntervals]
+	geofences *geofence.Detector
+	deviations *deviation.Detector
+	hub *tracking.Hub
 	router *gin.Engine
 }
 
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err )
 	}
+	estimator, err := eta.NewEstimator(config.ETACircuityFactor, map[eta.VehicleClass]float64{
+		eta.ClassCar:   config.ETACarSpeedKmh,
+		eta.ClassVan:   config.ETAVanSpeedKmh,
+		eta.ClassTruck: config.ETATruckSpeedKmh,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create eta estimator: %w", err)
+	}
+	profileLocation, err := time.LoadLocation(config.ETAProfileTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load speed profile time zone: %w", err)
+	}
+	var roads *routing.Graph
+	if config.RoutingGraphFile != "" {
+		roads, err = routing.Import(config.RoutingGraphFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot import road network: %w", err)
+		}
+	}
+	var correction *eta.CorrectionModel
+	if config.ETAModelFile != "" {
+		correction, err = eta.LoadCorrectionModel(config.ETAModelFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load eta correction model: %w", err)
+		}
+	}
 	server := &Server{
 		config:config,
 		store: store,
 		tokenMaker: tokenMaker,
+		estimator: estimator,
+		roads: roads,
+		correction: correction,
+		profileLocation: profileLocation,
+		geofences: geofence.NewDetector(config.GeofenceExitMarginM, config.GeofenceDwell),
+		deviations: deviation.NewDetector(config.DeviationThresholdM, config.DeviationDwell),
+		hub: tracking.NewHub(config.StreamBufferSize),
 	}
 	if v, ok := binding.Validator.Engine().(*validator.Validate);ok{
 		v.RegisterValidation("roles", ValidRoles)
+		v.RegisterValidation("route_status", ValidRouteStatus)
+		v.RegisterValidation("stop_status", ValidStopStatus)
 	}
 
 	server.setupRouter()
@@ -39,21 +90,96 @@ func NewServer(config util.Config, store db.Store) (*Server, error) {
 }
 
 func (server *Server)setupRouter() {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(accessLogFormatter), gin.Recovery())
 	router.GET("/",server.checkHealth)
 
 	// user routes 
 	userRoute := router.Group("/users")
 	userRoute.POST("/login", server.LoginUser)
 	userRoute.POST("/register", server.CreateUser)
+	userRoute.POST("/logout", server.LogoutUser)
+
+	// token routes
+	tokenRoute := router.Group("/tokens")
+	tokenRoute.POST("/renew_access", server.RenewAccessToken)
+	tokenRoute.GET("/keys", server.ListTokenKeys)
 
 	protectedRoutes := router.Group("/")
 	protectedRoutes.Use(authMiddleware(server.tokenMaker))
 
 
+	// session routes
+	sessionRoute := restrictedGroup(protectedRoutes, "/sessions")
+	sessionRoute.GET("", server.ListSessions)
+	sessionRoute.DELETE("/:id", server.RevokeSession)
+
+	// profile routes
+	meRoute := restrictedGroup(protectedRoutes, "/users/me")
+	meRoute.GET("", server.GetCurrentUser)
+	meRoute.PATCH("", server.UpdateCurrentUser)
+	meRoute.PUT("/password", server.ChangePassword)
+
 	// vehicle routes
-	vehicleRoute := protectedRoutes.Group("/vehicles")
+	vehicleRoute := restrictedGroup(protectedRoutes, "/vehicles")
 	vehicleRoute.POST("/create", server.CreateVehicle)
+	vehicleRoute.GET("", server.ListVehicles)
+	vehicleRoute.GET("/plate/:license_plate", server.GetVehicleByPlate)
+	vehicleRoute.GET("/:id", server.GetVehicle)
+	vehicleRoute.PATCH("/:id", server.UpdateVehicle)
+	vehicleRoute.DELETE("/:id", server.DeleteVehicle)
+
+	// route routes
+	routeRoute := restrictedGroup(protectedRoutes, "/routes")
+	routeRoute.POST("/create", server.CreateRoute)
+	routeRoute.GET("", server.ListRoutes)
+	routeRoute.GET("/:id", server.GetRoute)
+	routeRoute.PATCH("/:id/status", server.UpdateRouteStatus)
+	routeRoute.PATCH("/:id/actual_duration", server.UpdateRouteActualDuration)
+	routeRoute.DELETE("/:id", server.DeleteRoute)
+	routeRoute.POST("/:id/locations", server.RecordLocation)
+	routeRoute.POST("/:id/locations/batch", server.RecordLocationBatch)
+	routeRoute.GET("/:id/eta", server.GetRouteEta)
+	routeRoute.GET("/:id/eta/history", server.ListRouteEtaHistory)
+	routeRoute.GET("/:id/geofence_events", server.ListGeofenceEvents)
+	routeRoute.GET("/:id/deviations", server.ListDeviationEvents)
+	routeRoute.PUT("/:id/stops", server.ReplaceRouteStops)
+	routeRoute.GET("/:id/stops", server.ListRouteStops)
+	routeRoute.POST("/:id/optimize", server.OptimizeRouteStops)
+	routeRoute.PATCH("/:id/stops/:stop_id/status", server.UpdateRouteStopStatus)
+
+	shipmentRoute := restrictedGroup(protectedRoutes, "/shipments")
+	shipmentRoute.POST("/create", server.CreateShipment)
+	shipmentRoute.GET("", server.ListShipments)
+	shipmentRoute.GET("/:id", server.GetShipment)
+	shipmentRoute.GET("/:id/eta", server.GetShipmentEta)
+
+	// tracking streams, which browsers can only authenticate through the query string
+	trackingRoute := restrictedGroup(&router.RouterGroup, "/tracking", tokenFromQuery(), authMiddleware(server.tokenMaker))
+	trackingRoute.GET("/routes/:id/events", server.StreamRouteEvents)
+	trackingRoute.GET("/routes/:id/ws", server.StreamRouteWebSocket)
+	trackingRoute.GET("/drivers/:id/events", server.StreamDriverEvents)
+	trackingRoute.GET("/drivers/:id/ws", server.StreamDriverWebSocket)
+
+	// admin routes
+	adminRoute := restrictedGroup(protectedRoutes, "/admin")
+	adminRoute.GET("/users", server.ListUsers)
+	adminRoute.GET("/users/search", server.SearchUsers)
+	adminRoute.PATCH("/users/:id/role", server.UpdateUserRole)
+	adminRoute.DELETE("/users/:id", server.DeleteUser)
+	adminRoute.PATCH("/shipments/:id/route", server.AssignShipmentRoute)
+	adminRoute.POST("/depots", server.CreateDepot)
+	adminRoute.GET("/depots", server.ListDepots)
+	adminRoute.POST("/dispatch", server.PlanDispatch)
+	adminRoute.GET("/speed_profile", server.GetSpeedProfile)
+	adminRoute.POST("/speed_profile/learn", server.RelearnSpeedProfile)
+	adminRoute.GET("/prediction_intervals", server.GetPredictionIntervals)
+	adminRoute.POST("/prediction_intervals/learn", server.RelearnPredictionIntervals)
+	adminRoute.GET("/eta_accuracy", server.GetETAAccuracy)
+	adminRoute.GET("/eta_accuracy/drivers", server.ListETAAccuracyByDriver)
+	adminRoute.GET("/eta_accuracy/vehicles", server.ListETAAccuracyByVehicle)
+	adminRoute.GET("/eta_accuracy/hours", server.ListETAAccuracyByHour)
+	adminRoute.GET("/eta_accuracy/distance_bands", server.ListETAAccuracyByDistanceBand)
 	
 	
 	server.router = router
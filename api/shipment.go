@@ -0,0 +1,364 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+var (
+	errShipmentNotOwned    = errors.New("shipment doesn't belong to the authenticated user")
+	errShipmentNotAssigned = errors.New("shipment is not assigned to a route yet")
+	errShipmentWindow      = errors.New("window_end must be after window_start")
+	errRouteClosed         = errors.New("route is already completed or cancelled")
+	errShipmentUndelivered = errors.New("shipment was not delivered and has no ETA")
+)
+
+type CreateShipmentRequest struct {
+	PickupAddress  string     `json:"pickup_address"`
+	PickupLat      float64    `json:"pickup_lat" binding:"min=-90,max=90"`
+	PickupLng      float64    `json:"pickup_lng" binding:"min=-180,max=180"`
+	DropoffAddress string     `json:"dropoff_address"`
+	DropoffLat     float64    `json:"dropoff_lat" binding:"min=-90,max=90"`
+	DropoffLng     float64    `json:"dropoff_lng" binding:"min=-180,max=180"`
+	WeightKg       float64    `json:"weight_kg" binding:"required,gt=0"`
+	VolumeM3       float64    `json:"volume_m3" binding:"required,gt=0"`
+	WindowStart    *time.Time `json:"window_start"`
+	WindowEnd      *time.Time `json:"window_end"`
+}
+
+type ShipmentResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	CustomerID     uuid.UUID  `json:"customer_id"`
+	RouteID        *uuid.UUID `json:"route_id,omitempty"`
+	PickupAddress  string     `json:"pickup_address"`
+	PickupLat      float64    `json:"pickup_lat"`
+	PickupLng      float64    `json:"pickup_lng"`
+	DropoffAddress string     `json:"dropoff_address"`
+	DropoffLat     float64    `json:"dropoff_lat"`
+	DropoffLng     float64    `json:"dropoff_lng"`
+	WeightKg       float64    `json:"weight_kg"`
+	VolumeM3       float64    `json:"volume_m3"`
+	WindowStart    *time.Time `json:"window_start,omitempty"`
+	WindowEnd      *time.Time `json:"window_end,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func newShipmentResponse(shipment db.Shipment) ShipmentResponse {
+	response := ShipmentResponse{
+		ID:             shipment.ID,
+		CustomerID:     shipment.CustomerID,
+		PickupAddress:  shipment.PickupAddress.String,
+		PickupLat:      shipment.PickupLat,
+		PickupLng:      shipment.PickupLng,
+		DropoffAddress: shipment.DropoffAddress.String,
+		DropoffLat:     shipment.DropoffLat,
+		DropoffLng:     shipment.DropoffLng,
+		WeightKg:       shipment.WeightKg,
+		VolumeM3:       shipment.VolumeM3,
+		WindowStart:    nullTimePtr(shipment.WindowStart),
+		WindowEnd:      nullTimePtr(shipment.WindowEnd),
+		CreatedAt:      shipment.CreatedAt,
+		UpdatedAt:      shipment.UpdatedAt,
+	}
+	if shipment.RouteID.Valid {
+		response.RouteID = &shipment.RouteID.UUID
+	}
+	return response
+}
+
+func nullTimeFromPtr(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// CreateShipment books a shipment for the authenticated customer. It starts
+// unassigned until it is planned onto a route.
+func (server *Server) CreateShipment(ctx *gin.Context) {
+	var req CreateShipmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.WindowStart != nil && req.WindowEnd != nil && !req.WindowEnd.After(*req.WindowStart) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errShipmentWindow))
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateShipmentParams{
+		ID:             uuid.New(),
+		CustomerID:     authPayload.UserID,
+		PickupLat:      req.PickupLat,
+		PickupLng:      req.PickupLng,
+		PickupAddress:  sql.NullString{String: req.PickupAddress, Valid: req.PickupAddress != ""},
+		DropoffLat:     req.DropoffLat,
+		DropoffLng:     req.DropoffLng,
+		DropoffAddress: sql.NullString{String: req.DropoffAddress, Valid: req.DropoffAddress != ""},
+		WeightKg:       req.WeightKg,
+		VolumeM3:       req.VolumeM3,
+		WindowStart:    nullTimeFromPtr(req.WindowStart),
+		WindowEnd:      nullTimeFromPtr(req.WindowEnd),
+	}
+
+	shipment, err := server.store.CreateShipment(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newShipmentResponse(shipment))
+}
+
+type ShipmentIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// canAccessShipment reports whether the authenticated user may read the
+// shipment. Customers only reach their own shipments; admins reach every one.
+func canAccessShipment(payload *token.Payload, shipment db.Shipment) bool {
+	return payload.Role == util.RoleAdmin || shipment.CustomerID == payload.UserID
+}
+
+// getAccessibleShipment loads the shipment named by the :id path parameter and
+// makes sure the authenticated user may access it. It writes the error
+// response itself and reports whether the handler may continue.
+func (server *Server) getAccessibleShipment(ctx *gin.Context) (db.Shipment, bool) {
+	var req ShipmentIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Shipment{}, false
+	}
+
+	shipment, err := server.store.GetShipment(ctx, uuid.MustParse(req.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.Shipment{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.Shipment{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canAccessShipment(authPayload, shipment) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errShipmentNotOwned))
+		return db.Shipment{}, false
+	}
+	return shipment, true
+}
+
+func (server *Server) GetShipment(ctx *gin.Context) {
+	shipment, ok := server.getAccessibleShipment(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newShipmentResponse(shipment))
+}
+
+type ListShipmentsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListShipments lists the authenticated customer's own shipments, newest first.
+func (server *Server) ListShipments(ctx *gin.Context) {
+	var req ListShipmentsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	shipments, err := server.store.ListShipmentsByCustomer(ctx, db.ListShipmentsByCustomerParams{
+		CustomerID: authPayload.UserID,
+		Limit:      req.PageSize,
+		Offset:     (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]ShipmentResponse, 0, len(shipments))
+	for _, shipment := range shipments {
+		response = append(response, newShipmentResponse(shipment))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type ShipmentEtaResponse struct {
+	ShipmentID uuid.UUID `json:"shipment_id"`
+	StopID     *int64    `json:"stop_id,omitempty"`
+	RouteEtaResponse
+}
+
+// GetShipmentEta reports when the shipment is delivered: at the route stop
+// dropping it off when it has one, and with the route otherwise. The customer
+// sees the estimate without needing access to the route itself.
+func (server *Server) GetShipmentEta(ctx *gin.Context) {
+	shipment, ok := server.getAccessibleShipment(ctx)
+	if !ok {
+		return
+	}
+	if !shipment.RouteID.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(errShipmentNotAssigned))
+		return
+	}
+
+	route, err := server.store.GetRouteByID(ctx, shipment.RouteID.UUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	stops, err := server.store.ListRouteStops(ctx, route.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := ShipmentEtaResponse{ShipmentID: shipment.ID}
+	now := time.Now()
+	index := -1
+	for i, stop := range stops {
+		if stop.ShipmentID.Valid && stop.ShipmentID.UUID == shipment.ID {
+			index = i
+			response.StopID = &stops[i].ID
+		}
+	}
+	if index < 0 {
+		response.RouteEtaResponse, err = server.currentRouteEta(ctx, route, now)
+	} else {
+		response.RouteEtaResponse, err = server.stopEta(ctx, route, stops, index, now)
+	}
+	if err != nil {
+		if err == errRouteCancelled || err == errShipmentUndelivered {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// stopEta works out the arrival at stops[index] as of now, the way
+// currentRouteEta does for the route's destination: from the schedule of the
+// stops ahead while the stop is one of them, the driver's arrival while they
+// serve it and its completion afterwards. Stops that failed or were left
+// behind by a finished route yield errShipmentUndelivered.
+func (server *Server) stopEta(ctx context.Context, route db.Route, stops []db.RouteStop, index int, now time.Time) (RouteEtaResponse, error) {
+	stop := stops[index]
+	response := RouteEtaResponse{
+		RouteID:    route.ID,
+		Status:     route.Status,
+		ComputedAt: now,
+	}
+
+	switch util.StopStatus(stop.Status) {
+	case util.StopCompleted:
+		// completing a stop records when the driver left it
+		response.Source = etaSourceCompleted
+		response.PredictedArrivalAt = stop.DepartedAt.Time
+		response.Confidence = 1
+		return response, nil
+	case util.StopArrived:
+		// the driver is handing the shipment over
+		response.Source = etaSourceLive
+		response.PredictedArrivalAt = stop.ArrivedAt.Time
+		response.Confidence = 1
+		return response, nil
+	case util.StopFailed:
+		return response, errShipmentUndelivered
+	}
+	switch util.RouteStatus(route.Status) {
+	case util.RouteCancelled:
+		return response, errRouteCancelled
+	case util.RouteCompleted:
+		return response, errShipmentUndelivered
+	}
+
+	schedule, err := server.scheduleStops(ctx, route, stops, now)
+	if err != nil {
+		return response, err
+	}
+	for j, i := range schedule.ahead {
+		if i != index {
+			continue
+		}
+		visit := schedule.visits[j]
+		arrival := visit.ArrivalAt
+		if arrival.Before(now) {
+			arrival = now
+		}
+		response.Source = schedule.source
+		response.RemainingDistanceKm = visit.DistanceKm
+		response.RemainingDurationMin = math.Round(arrival.Sub(now).Minutes()*10) / 10
+		response.PredictedArrivalAt = arrival
+		response.Confidence = schedule.confidence
+	}
+	return response, nil
+}
+
+type AssignShipmentRouteRequest struct {
+	RouteID string `json:"route_id" binding:"required,uuid"`
+}
+
+// AssignShipmentRoute puts a shipment on a route that hasn't finished yet,
+// replacing any earlier assignment.
+func (server *Server) AssignShipmentRoute(ctx *gin.Context) {
+	var uri ShipmentIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req AssignShipmentRouteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	route, err := server.store.GetRouteByID(ctx, uuid.MustParse(req.RouteID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	switch util.RouteStatus(route.Status) {
+	case util.RouteCompleted, util.RouteCancelled:
+		ctx.JSON(http.StatusConflict, errorResponse(errRouteClosed))
+		return
+	}
+
+	shipment, err := server.store.AssignShipmentRoute(ctx, db.AssignShipmentRouteParams{
+		ID:      uuid.MustParse(uri.ID),
+		RouteID: uuid.NullUUID{UUID: route.ID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newShipmentResponse(shipment))
+}
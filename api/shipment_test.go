@@ -0,0 +1,749 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomShipment(t *testing.T, customerID uuid.UUID) db.Shipment {
+	windowStart := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	return db.Shipment{
+		ID:             uuid.New(),
+		CustomerID:     customerID,
+		PickupLat:      6.5244,
+		PickupLng:      3.3792,
+		PickupAddress:  sql.NullString{String: util.RandomString(12), Valid: true},
+		DropoffLat:     6.4654,
+		DropoffLng:     3.4064,
+		DropoffAddress: sql.NullString{String: util.RandomString(12), Valid: true},
+		WeightKg:       float64(util.RandomInt(1, 50)),
+		VolumeM3:       0.5,
+		WindowStart:    sql.NullTime{Time: windowStart, Valid: true},
+		WindowEnd:      sql.NullTime{Time: windowStart.Add(2 * time.Hour), Valid: true},
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+		UpdatedAt:      time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+type eqCreateShipmentParamsMatcher struct {
+	arg db.CreateShipmentParams
+}
+
+func (e eqCreateShipmentParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateShipmentParams)
+	if !ok {
+		return false
+	}
+	e.arg.ID = arg.ID
+	return reflect.DeepEqual(e.arg, arg)
+}
+
+func (e eqCreateShipmentParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v", e.arg)
+}
+
+func EqCreateShipmentParams(arg db.CreateShipmentParams) gomock.Matcher {
+	return eqCreateShipmentParamsMatcher{arg}
+}
+
+func TestCreateShipment(t *testing.T) {
+	customerID := uuid.New()
+	shipment := randomShipment(t, customerID)
+
+	body := gin.H{
+		"pickup_address":  shipment.PickupAddress.String,
+		"pickup_lat":      shipment.PickupLat,
+		"pickup_lng":      shipment.PickupLng,
+		"dropoff_address": shipment.DropoffAddress.String,
+		"dropoff_lat":     shipment.DropoffLat,
+		"dropoff_lng":     shipment.DropoffLng,
+		"weight_kg":       shipment.WeightKg,
+		"volume_m3":       shipment.VolumeM3,
+		"window_start":    shipment.WindowStart.Time,
+		"window_end":      shipment.WindowEnd.Time,
+	}
+	withBody := func(changes gin.H) gin.H {
+		changed := gin.H{}
+		for key, value := range body {
+			changed[key] = value
+		}
+		for key, value := range changes {
+			changed[key] = value
+		}
+		return changed
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateShipmentParams{
+					CustomerID:     customerID,
+					PickupLat:      shipment.PickupLat,
+					PickupLng:      shipment.PickupLng,
+					PickupAddress:  shipment.PickupAddress,
+					DropoffLat:     shipment.DropoffLat,
+					DropoffLng:     shipment.DropoffLng,
+					DropoffAddress: shipment.DropoffAddress,
+					WeightKg:       shipment.WeightKg,
+					VolumeM3:       shipment.VolumeM3,
+					WindowStart:    shipment.WindowStart,
+					WindowEnd:      shipment.WindowEnd,
+				}
+				store.EXPECT().CreateShipment(gomock.Any(), EqCreateShipmentParams(arg)).Times(1).Return(shipment, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchShipment(t, recorder.Body, shipment)
+			},
+		},
+		{
+			name: "OpenWindow",
+			body: withBody(gin.H{"window_start": nil, "window_end": nil}),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateShipment(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateShipmentParams) (db.Shipment, error) {
+						require.False(t, arg.WindowStart.Valid)
+						require.False(t, arg.WindowEnd.Valid)
+						return shipment, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WindowEndBeforeStart",
+			body: withBody(gin.H{"window_end": shipment.WindowStart.Time.Add(-time.Minute)}),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateShipment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidWeight",
+			body: withBody(gin.H{"weight_kg": 0}),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateShipment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidLatitude",
+			body: withBody(gin.H{"dropoff_lat": 91}),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateShipment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DriverForbidden",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateShipment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateShipment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateShipment(gomock.Any(), gomock.Any()).Times(1).Return(db.Shipment{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/shipments/create", bytes.NewReader(data))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetShipment(t *testing.T) {
+	customerID := uuid.New()
+	shipment := randomShipment(t, customerID)
+
+	testCases := []struct {
+		name          string
+		shipmentID    string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			shipmentID: shipment.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetShipment(gomock.Any(), gomock.Eq(shipment.ID)).Times(1).Return(shipment, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchShipment(t, recorder.Body, shipment)
+			},
+		},
+		{
+			name:       "AdminOtherCustomer",
+			shipmentID: shipment.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetShipment(gomock.Any(), gomock.Eq(shipment.ID)).Times(1).Return(shipment, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "OtherCustomer",
+			shipmentID: shipment.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetShipment(gomock.Any(), gomock.Eq(shipment.ID)).Times(1).Return(shipment, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			shipmentID: shipment.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetShipment(gomock.Any(), gomock.Eq(shipment.ID)).Times(1).Return(db.Shipment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			shipmentID: "not-a-uuid",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetShipment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/shipments/%s", tc.shipmentID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListShipments(t *testing.T) {
+	customerID := uuid.New()
+	shipments := []db.Shipment{randomShipment(t, customerID), randomShipment(t, customerID)}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListShipmentsByCustomer(gomock.Any(), gomock.Eq(db.ListShipmentsByCustomerParams{
+					CustomerID: customerID,
+					Limit:      5,
+					Offset:     5,
+				})).Times(1).Return(shipments, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got []ShipmentResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, len(shipments))
+				for i := range shipments {
+					require.Equal(t, shipments[i].ID, got[i].ID)
+				}
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=100",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListShipmentsByCustomer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListShipmentsByCustomer(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/shipments?"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetShipmentEta(t *testing.T) {
+	customerID := uuid.New()
+	vehicle := RandomVehicle(t)
+	route := randomRoute(t, vehicle)
+
+	cancelled := route
+	cancelled.Status = string(util.RouteCancelled)
+
+	unassigned := randomShipment(t, customerID)
+	assigned := unassigned
+	assigned.RouteID = uuid.NullUUID{UUID: route.ID, Valid: true}
+
+	// dispatched routes return to the depot they left from, and this shipment
+	// is dropped off at the second of three stops
+	dispatched := randomRoute(t, vehicle)
+	dispatched.DestinationLat, dispatched.DestinationLng = dispatched.OriginLat, dispatched.OriginLng
+	dispatchedStops := randomRouteStops(dispatched, 3)
+	dispatchedStops[1].ShipmentID = uuid.NullUUID{UUID: assigned.ID, Valid: true}
+	onDispatched := assigned
+	onDispatched.RouteID = uuid.NullUUID{UUID: dispatched.ID, Valid: true}
+
+	estimator, err := eta.NewEstimator(0, nil)
+	require.NoError(t, err)
+	waypoints := make([]eta.Stop, 0, len(dispatchedStops)+1)
+	for _, stop := range dispatchedStops {
+		waypoints = append(waypoints, eta.Stop{Point: eta.Point{Lat: stop.Lat, Lng: stop.Lng}, ServiceMin: stop.ServiceTimeMin})
+	}
+	waypoints = append(waypoints, eta.Stop{Point: eta.Point{Lat: dispatched.DestinationLat, Lng: dispatched.DestinationLng}})
+	schedule := estimator.Schedule(eta.Point{Lat: dispatched.OriginLat, Lng: dispatched.OriginLng}, time.Now(), waypoints, eta.ClassForCapacity(vehicle.Capacity.Int32))
+
+	delivered := make([]db.RouteStop, len(dispatchedStops))
+	copy(delivered, dispatchedStops)
+	delivered[1].Status = string(util.StopCompleted)
+	delivered[1].ArrivedAt = sql.NullTime{Time: time.Now().Add(-20 * time.Minute).UTC().Truncate(time.Second), Valid: true}
+	delivered[1].DepartedAt = sql.NullTime{Time: time.Now().Add(-15 * time.Minute).UTC().Truncate(time.Second), Valid: true}
+
+	failed := make([]db.RouteStop, len(dispatchedStops))
+	copy(failed, dispatchedStops)
+	failed[1].Status = string(util.StopFailed)
+
+	testCases := []struct {
+		name          string
+		shipment      db.Shipment
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			shipment: assigned,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				// without a stop of its own the shipment arrives with the route
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got ShipmentEtaResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, assigned.ID, got.ShipmentID)
+				require.Equal(t, route.ID, got.RouteID)
+				require.Equal(t, etaSourcePlanned, got.Source)
+				require.Equal(t, route.EstimatedDistanceKm.Float64, got.RemainingDistanceKm)
+			},
+		},
+		{
+			name:     "DispatchedStop",
+			shipment: onDispatched,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(dispatched.ID)).Times(1).Return(dispatched, nil)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(dispatched.ID)).Times(1).Return(dispatchedStops, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got ShipmentEtaResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, onDispatched.ID, got.ShipmentID)
+				require.NotNil(t, got.StopID)
+				require.Equal(t, dispatchedStops[1].ID, *got.StopID)
+				require.Equal(t, etaSourcePlanned, got.Source)
+				require.InDelta(t, schedule[1].DistanceKm, got.RemainingDistanceKm, 0.01)
+				require.WithinDuration(t, schedule[1].ArrivalAt, got.PredictedArrivalAt, 2*time.Second)
+				// well before the truck is back at the depot
+				require.True(t, got.PredictedArrivalAt.Before(schedule[len(schedule)-1].ArrivalAt.Add(-time.Minute)))
+			},
+		},
+		{
+			name:     "StopCompleted",
+			shipment: onDispatched,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(dispatched.ID)).Times(1).Return(dispatched, nil)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(dispatched.ID)).Times(1).Return(delivered, nil)
+				store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got ShipmentEtaResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, etaSourceCompleted, got.Source)
+				require.WithinDuration(t, delivered[1].DepartedAt.Time, got.PredictedArrivalAt, time.Second)
+			},
+		},
+		{
+			name:     "StopFailed",
+			shipment: onDispatched,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(dispatched.ID)).Times(1).Return(dispatched, nil)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(dispatched.ID)).Times(1).Return(failed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "NotAssigned",
+			shipment: unassigned,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "RouteCancelled",
+			shipment: assigned,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(cancelled, nil)
+				store.EXPECT().ListRouteStops(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return([]db.RouteStop{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "RouteNotFound",
+			shipment: assigned,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.Route{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			shipment: assigned,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.Route{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetShipment(gomock.Any(), gomock.Eq(tc.shipment.ID)).Times(1).Return(tc.shipment, nil)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/shipments/%s/eta", tc.shipment.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, customerID, util.RoleCustomer, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAssignShipmentRoute(t *testing.T) {
+	adminID := uuid.New()
+	vehicle := RandomVehicle(t)
+	route := randomRoute(t, vehicle)
+	shipment := randomShipment(t, uuid.New())
+
+	assigned := shipment
+	assigned.RouteID = uuid.NullUUID{UUID: route.ID, Valid: true}
+
+	completed := route
+	completed.Status = string(util.RouteCompleted)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		role          util.Role
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"route_id": route.ID},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().AssignShipmentRoute(gomock.Any(), gomock.Eq(db.AssignShipmentRouteParams{
+					ID:      shipment.ID,
+					RouteID: uuid.NullUUID{UUID: route.ID, Valid: true},
+				})).Times(1).Return(assigned, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchShipment(t, recorder.Body, assigned)
+			},
+		},
+		{
+			name: "RouteClosed",
+			body: gin.H{"route_id": route.ID},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(completed, nil)
+				store.EXPECT().AssignShipmentRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "RouteNotFound",
+			body: gin.H{"route_id": route.ID},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.Route{}, sql.ErrNoRows)
+				store.EXPECT().AssignShipmentRoute(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ShipmentNotFound",
+			body: gin.H{"route_id": route.ID},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+				store.EXPECT().AssignShipmentRoute(gomock.Any(), gomock.Any()).Times(1).Return(db.Shipment{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRouteID",
+			body: gin.H{"route_id": "not-a-uuid"},
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "CustomerForbidden",
+			body: gin.H{"route_id": route.ID},
+			role: util.RoleCustomer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/admin/shipments/%s/route", shipment.ID)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, adminID, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchShipment(t *testing.T, body *bytes.Buffer, shipment db.Shipment) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var got ShipmentResponse
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, shipment.ID, got.ID)
+	require.Equal(t, shipment.CustomerID, got.CustomerID)
+	require.Equal(t, shipment.PickupAddress.String, got.PickupAddress)
+	require.Equal(t, shipment.DropoffAddress.String, got.DropoffAddress)
+	require.Equal(t, shipment.WeightKg, got.WeightKg)
+	require.Equal(t, shipment.VolumeM3, got.VolumeM3)
+	require.WithinDuration(t, shipment.WindowStart.Time, *got.WindowStart, time.Second)
+	require.WithinDuration(t, shipment.WindowEnd.Time, *got.WindowEnd, time.Second)
+	if shipment.RouteID.Valid {
+		require.NotNil(t, got.RouteID)
+		require.Equal(t, shipment.RouteID.UUID, *got.RouteID)
+	} else {
+		require.Nil(t, got.RouteID)
+	}
+}
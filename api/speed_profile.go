@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+const (
+	// defaultProfileWindow is how far back completed routes are learned from
+	// when ETA_PROFILE_WINDOW isn't set.
+	defaultProfileWindow = 8 * 7 * 24 * time.Hour
+	// maxProfileSamples bounds how many routes, the latest first, a profile
+	// is learned from.
+	maxProfileSamples = 50000
+)
+
+// learnedProfile is the speed profile estimates currently follow.
+type learnedProfile struct {
+	profile   *eta.SpeedProfile
+	samples   int
+	learnedAt time.Time
+}
+
+// speedProfile returns the current speed profile, or nil before one is
+// learned.
+func (server *Server) speedProfile() *eta.SpeedProfile {
+	if learned := server.profile.Load(); learned != nil {
+		return learned.profile
+	}
+	return nil
+}
+
+// trafficEstimator returns the road estimator adjusted for traffic at the
+// departure time of every estimate by the current speed profile.
+func (server *Server) trafficEstimator() *eta.Estimator {
+	return server.roadEstimator().WithProfile(server.speedProfile())
+}
+
+// LearnSpeedProfile learns the speed profile from the routes completed over
+// the profile window, and plans every route from then on with it.
+func (server *Server) LearnSpeedProfile(ctx context.Context) error {
+	window := server.config.ETAProfileWindow
+	if window == 0 {
+		window = defaultProfileWindow
+	}
+	now := time.Now()
+	rows, err := server.store.ListSpeedProfileSamples(ctx, db.ListSpeedProfileSamplesParams{
+		Since:      now.Add(-window),
+		MaxSamples: maxProfileSamples,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list completed routes: %w", err)
+	}
+
+	samples := make([]eta.Sample, 0, len(rows))
+	for _, row := range rows {
+		samples = append(samples, eta.Sample{
+			Origin:      eta.Point{Lat: row.OriginLat, Lng: row.OriginLng},
+			StartedAt:   row.StartedAt,
+			BaselineMin: row.BaselineDurationMin,
+			ActualMin:   row.ActualDurationMin,
+		})
+	}
+	server.profile.Store(&learnedProfile{
+		profile:   eta.LearnSpeedProfile(samples, server.profileLocation),
+		samples:   len(samples),
+		learnedAt: now,
+	})
+	return nil
+}
+
+type SpeedProfileBucketResponse struct {
+	Weekday string `json:"weekday"`
+	// StartsAt is the time of day the bucket starts, as hh:mm
+	StartsAt string  `json:"starts_at"`
+	Factor   float64 `json:"factor"`
+	Samples  int     `json:"samples"`
+}
+
+type SpeedProfileResponse struct {
+	Timezone  string     `json:"timezone"`
+	Samples   int        `json:"samples"`
+	Cells     int        `json:"cells"`
+	LearnedAt *time.Time `json:"learned_at,omitempty"`
+	// Buckets is the profile over every cell, from Sunday midnight
+	Buckets []SpeedProfileBucketResponse `json:"buckets"`
+}
+
+func newSpeedProfileResponse(learned *learnedProfile, location *time.Location) SpeedProfileResponse {
+	if learned == nil {
+		learned = &learnedProfile{profile: eta.LearnSpeedProfile(nil, location)}
+	}
+	response := SpeedProfileResponse{
+		Timezone: learned.profile.Location().String(),
+		Samples:  learned.samples,
+		Cells:    learned.profile.Cells(),
+		Buckets:  make([]SpeedProfileBucketResponse, 0, eta.BucketsPerWeek),
+	}
+	if !learned.learnedAt.IsZero() {
+		response.LearnedAt = &learned.learnedAt
+	}
+	for _, bucket := range learned.profile.Buckets() {
+		response.Buckets = append(response.Buckets, SpeedProfileBucketResponse{
+			Weekday:  bucket.Weekday.String(),
+			StartsAt: fmt.Sprintf("%02d:%02d", int(bucket.Start.Hours()), int(bucket.Start.Minutes())%60),
+			Factor:   bucket.Factor,
+			Samples:  bucket.Samples,
+		})
+	}
+	return response
+}
+
+// GetSpeedProfile returns the speed profile estimates currently follow. Until
+// one is learned, every factor is 1.
+func (server *Server) GetSpeedProfile(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, newSpeedProfileResponse(server.profile.Load(), server.profileLocation))
+}
+
+// RelearnSpeedProfile learns the speed profile again from the latest
+// completed routes and returns it.
+func (server *Server) RelearnSpeedProfile(ctx *gin.Context) {
+	if err := server.LearnSpeedProfile(ctx); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newSpeedProfileResponse(server.profile.Load(), server.profileLocation))
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// rushHour is a Monday at 8 in UTC, the time zone profiles default to.
+var rushHour = time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+
+// rushHourSamples are completed trips that took twice their free-flow
+// estimate leaving at rush hour.
+func rushHourSamples(n int) []db.ListSpeedProfileSamplesRow {
+	samples := make([]db.ListSpeedProfileSamplesRow, n)
+	for i := range samples {
+		samples[i] = db.ListSpeedProfileSamplesRow{
+			OriginLat:           6.5244,
+			OriginLng:           3.3792,
+			StartedAt:           rushHour.AddDate(0, 0, -7*(i%4)),
+			BaselineDurationMin: 30,
+			ActualDurationMin:   60,
+		}
+	}
+	return samples
+}
+
+func requireBodySpeedProfile(t *testing.T, recorder *httptest.ResponseRecorder) SpeedProfileResponse {
+	var got SpeedProfileResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Len(t, got.Buckets, eta.BucketsPerWeek)
+	return got
+}
+
+func TestGetSpeedProfile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := NewTestServer(t, store)
+
+	request, err := http.NewRequest(http.MethodGet, "/admin/speed_profile", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), util.RoleAdmin, time.Minute)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// nothing learned yet, so estimates are free-flow
+	got := requireBodySpeedProfile(t, recorder)
+	require.Equal(t, "UTC", got.Timezone)
+	require.Nil(t, got.LearnedAt)
+	require.Equal(t, "Sunday", got.Buckets[0].Weekday)
+	require.Equal(t, "00:00", got.Buckets[0].StartsAt)
+	require.Equal(t, "23:45", got.Buckets[eta.BucketsPerWeek-1].StartsAt)
+	for _, bucket := range got.Buckets {
+		require.Equal(t, 1.0, bucket.Factor)
+	}
+}
+
+func TestRelearnSpeedProfile(t *testing.T) {
+	testCases := []struct {
+		name          string
+		role          util.Role
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSpeedProfileSamples(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListSpeedProfileSamplesParams) ([]db.ListSpeedProfileSamplesRow, error) {
+						require.WithinDuration(t, time.Now().Add(-defaultProfileWindow), arg.Since, time.Minute)
+						require.Equal(t, int32(maxProfileSamples), arg.MaxSamples)
+						return rushHourSamples(40), nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				got := requireBodySpeedProfile(t, recorder)
+				require.Equal(t, 40, got.Samples)
+				require.Equal(t, 1, got.Cells)
+				require.NotNil(t, got.LearnedAt)
+
+				rush := got.Buckets[int(time.Monday)*24*4+8*4]
+				require.Equal(t, "Monday", rush.Weekday)
+				require.Equal(t, "08:00", rush.StartsAt)
+				require.Equal(t, 40, rush.Samples)
+				require.Greater(t, rush.Factor, 1.5)
+				require.Equal(t, 1.0, got.Buckets[0].Factor)
+			},
+		},
+		{
+			name: "Forbidden",
+			role: util.RoleDriver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListSpeedProfileSamples(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			role: util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSpeedProfileSamples(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/speed_profile/learn", nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, uuid.New(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateRouteSpeedProfile(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+	origin := eta.Point{Lat: route.OriginLat, Lng: route.OriginLng}
+
+	estimator, err := eta.NewEstimator(0, nil)
+	require.NoError(t, err)
+	free := estimator.Estimate(
+		origin,
+		eta.Point{Lat: route.DestinationLat, Lng: route.DestinationLng},
+		eta.ClassForCapacity(vehicle.Capacity.Int32),
+	)
+
+	testCases := []struct {
+		name      string
+		departure time.Time
+		slower    bool
+	}{
+		{name: "RushHour", departure: rushHour.AddDate(0, 0, 7), slower: true},
+		{name: "Night", departure: rushHour.AddDate(0, 0, 7).Add(-6 * time.Hour)},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ListSpeedProfileSamples(gomock.Any(), gomock.Any()).Times(1).Return(rushHourSamples(40), nil)
+			store.EXPECT().GetVehicleByID(gomock.Any(), gomock.Eq(vehicle.ID)).Times(1).Return(vehicle, nil)
+			store.EXPECT().
+				CreateRoute(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.CreateRouteParams) (db.Route, error) {
+					// the profile learns from the free-flow baseline, which stays put
+					require.Equal(t, free.DurationMin, arg.BaselineDurationMin.Float64)
+					require.Equal(t, free.DistanceKm, arg.EstimatedDistanceKm.Float64)
+					if tc.slower {
+						require.Greater(t, arg.EstimatedDurationMin.Float64, free.DurationMin*1.5)
+					} else {
+						require.Equal(t, free.DurationMin, arg.EstimatedDurationMin.Float64)
+					}
+					return route, nil
+				})
+
+			server := NewTestServer(t, store)
+			require.NoError(t, server.LearnSpeedProfile(context.Background()))
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"vehicle_id":      vehicle.ID,
+				"origin_lat":      route.OriginLat,
+				"origin_lng":      route.OriginLng,
+				"destination_lat": route.DestinationLat,
+				"destination_lng": route.DestinationLng,
+				"departure_at":    tc.departure,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/routes/create", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+var (
+	errSessionRevoked        = errors.New("session has been revoked")
+	errSessionUserMismatch   = errors.New("session belongs to another user")
+	errSessionTokenMismatch  = errors.New("refresh token does not match the session")
+	errSessionClientMismatch = errors.New("refresh token was issued to another client")
+	errSessionExpired        = errors.New("session has expired")
+	errNoPublicKeys          = errors.New("tokens are not signed with public keys")
+	errNotRefreshToken       = errors.New("token is not a refresh token")
+)
+
+type RenewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type RenewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (server *Server) RenewAccessToken(ctx *gin.Context) {
+	var req RenewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if refreshPayload.Type != token.RefreshToken {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errNotRefreshToken))
+		return
+	}
+
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.RevokedAt.Valid {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errSessionRevoked))
+		return
+	}
+	if session.UserID != refreshPayload.UserID {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errSessionUserMismatch))
+		return
+	}
+	if session.RefreshToken != req.RefreshToken {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errSessionTokenMismatch))
+		return
+	}
+	// a refresh token replayed from another device is treated as stolen
+	if session.UserAgent != ctx.Request.UserAgent() || session.ClientIp != ctx.ClientIP() {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errSessionClientMismatch))
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errSessionExpired))
+		return
+	}
+
+	// use the current role so role changes apply from the next renewal
+	user, err := server.store.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.ID, util.Role(user.Role), token.AccessToken, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, RenewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	})
+}
+
+// tokenKeysMaxAge bounds how long verifiers cache the key set, and so how
+// long after a rotation they may take to see a new signing key.
+const tokenKeysMaxAge = 5 * time.Minute
+
+// TokenKeyResponse describes an Ed25519 verification key in JWK form.
+type TokenKeyResponse struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	X         string `json:"x"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type ListTokenKeysResponse struct {
+	Keys []TokenKeyResponse `json:"keys"`
+}
+
+func newTokenKeyResponse(key token.PublicKey) TokenKeyResponse {
+	return TokenKeyResponse{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		KeyID:     key.KID,
+		X:         base64.RawURLEncoding.EncodeToString(key.Key),
+		Use:       "sig",
+		Algorithm: "EdDSA",
+	}
+}
+
+// ListTokenKeys publishes the keys tokens can be verified with, so other
+// services verify them offline.
+func (server *Server) ListTokenKeys(ctx *gin.Context) {
+	provider, ok := server.tokenMaker.(token.PublicKeyProvider)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(errNoPublicKeys))
+		return
+	}
+
+	keys := provider.PublicKeys()
+	response := ListTokenKeysResponse{Keys: make([]TokenKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		response.Keys = append(response.Keys, newTokenKeyResponse(key))
+	}
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(tokenKeysMaxAge.Seconds())))
+	ctx.JSON(http.StatusOK, response)
+}
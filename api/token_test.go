@@ -0,0 +1,359 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserAgent = "logistics-eta-test/1.0"
+	testClientIP  = "192.0.2.10"
+)
+
+// newTestSession issues a token of the given type, a refresh token unless
+// tokenType is set, and the session the login handler would have stored for it.
+func newTestSession(t *testing.T, tokenMaker token.Maker, user db.User, tokenType token.TokenType) (string, db.Session) {
+	if tokenType == "" {
+		tokenType = token.RefreshToken
+	}
+	refreshToken, payload, err := tokenMaker.CreateToken(user.ID, util.Role(user.Role), tokenType, time.Hour)
+	require.NoError(t, err)
+
+	session := db.Session{
+		ID:           payload.ID,
+		UserID:       user.ID,
+		RefreshToken: refreshToken,
+		UserAgent:    testUserAgent,
+		ClientIp:     testClientIP,
+		ExpiresAt:    payload.ExpiredAt,
+		CreatedAt:    time.Now(),
+	}
+	return refreshToken, session
+}
+
+func TestRenewAccessToken(t *testing.T) {
+	user, _ := randomUser(t)
+	user.Role = string(util.RoleDriver)
+
+	testCases := []struct {
+		name          string
+		userAgent     string
+		clientIP      string
+		tokenType     token.TokenType
+		buildSession  func(session db.Session) db.Session
+		buildBody     func(refreshToken string) gin.H
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response RenewAccessTokenResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				payload, err := tokenMaker.VerifyToken(response.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.ID, payload.UserID)
+				require.Equal(t, util.RoleDriver, payload.Role)
+				require.WithinDuration(t, payload.ExpiredAt, response.AccessTokenExpiresAt, time.Second)
+			},
+		},
+		{
+			name: "InvalidRefreshToken",
+			buildBody: func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": "invalid"}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingRefreshToken",
+			buildBody: func(refreshToken string) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "AccessToken",
+			tokenType: token.AccessToken,
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errNotRefreshToken.Error())
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "GetSessionError",
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "RevokedSession",
+			buildSession: func(session db.Session) db.Session {
+				session.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errSessionRevoked.Error())
+			},
+		},
+		{
+			name: "SessionOfOtherUser",
+			buildSession: func(session db.Session) db.Session {
+				other, _ := randomUser(t)
+				session.UserID = other.ID
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errSessionUserMismatch.Error())
+			},
+		},
+		{
+			name: "TokenMismatch",
+			buildSession: func(session db.Session) db.Session {
+				session.RefreshToken = "another-token"
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errSessionTokenMismatch.Error())
+			},
+		},
+		{
+			name:      "UserAgentMismatch",
+			userAgent: "curl/8.0",
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errSessionClientMismatch.Error())
+			},
+		},
+		{
+			name:     "ClientIPMismatch",
+			clientIP: "198.51.100.7",
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errSessionClientMismatch.Error())
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildSession: func(session db.Session) db.Session {
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errSessionExpired.Error())
+			},
+		},
+		{
+			name: "UserDeleted",
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUserByID(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := NewTestServer(t, store)
+
+			refreshToken, session := newTestSession(t, server.tokenMaker, user, tc.tokenType)
+			if tc.buildSession != nil {
+				session = tc.buildSession(session)
+			}
+			tc.buildStubs(store, session)
+
+			body := gin.H{"refresh_token": refreshToken}
+			if tc.buildBody != nil {
+				body = tc.buildBody(refreshToken)
+			}
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set("User-Agent", testUserAgent)
+			if tc.userAgent != "" {
+				request.Header.Set("User-Agent", tc.userAgent)
+			}
+			request.RemoteAddr = testClientIP + ":40000"
+			if tc.clientIP != "" {
+				request.RemoteAddr = tc.clientIP + ":40000"
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestListTokenKeys(t *testing.T) {
+	t.Run("SymmetricTokens", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+		request, err := http.NewRequest(http.MethodGet, "/tokens/keys", nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("PublicTokens", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+		require.NoError(t, err)
+		signingFile := filepath.Join(dir, "signing.pem")
+		require.NoError(t, os.WriteFile(signingFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+		retiredPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		der, err = x509.MarshalPKIXPublicKey(retiredPublicKey)
+		require.NoError(t, err)
+		retiredFile := filepath.Join(dir, "retired.pem")
+		require.NoError(t, os.WriteFile(retiredFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+		config := util.Config{
+			TokenType:                 token.TypePasetoPublic,
+			TokenPrivateKeyFile:       signingFile,
+			TokenVerificationKeyFiles: []string{retiredFile},
+			AccessTokenDuration:       time.Minute,
+			RefreshTokenDuration:      time.Hour,
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		server, err := NewServer(config, mockdb.NewMockStore(ctrl))
+		require.NoError(t, err)
+		request, err := http.NewRequest(http.MethodGet, "/tokens/keys", nil)
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Contains(t, recorder.Header().Get("Cache-Control"), "max-age=")
+
+		var response ListTokenKeysResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Keys, 2)
+
+		signingPublicKey := signingKey.Public().(ed25519.PublicKey)
+		require.Equal(t, token.KeyID(signingPublicKey), response.Keys[0].KeyID)
+		require.Equal(t, token.KeyID(retiredPublicKey), response.Keys[1].KeyID)
+		for _, key := range response.Keys {
+			require.Equal(t, "OKP", key.KeyType)
+			require.Equal(t, "Ed25519", key.Curve)
+			require.Equal(t, "EdDSA", key.Algorithm)
+		}
+
+		// the published keys are all a downstream service needs to verify
+		publicKeys := make([]ed25519.PublicKey, 0, len(response.Keys))
+		for _, key := range response.Keys {
+			x, err := base64.RawURLEncoding.DecodeString(key.X)
+			require.NoError(t, err)
+			publicKeys = append(publicKeys, ed25519.PublicKey(x))
+		}
+		ring, err := token.NewVerificationKeyRing(publicKeys...)
+		require.NoError(t, err)
+		verifier, err := token.NewPasetoPublicMaker(ring)
+		require.NoError(t, err)
+
+		userID := uuid.New()
+		accessToken, _, err := server.tokenMaker.CreateToken(userID, util.RoleDriver, token.AccessToken, time.Minute)
+		require.NoError(t, err)
+		payload, err := verifier.VerifyToken(accessToken)
+		require.NoError(t, err)
+		require.Equal(t, userID, payload.UserID)
+	})
+}
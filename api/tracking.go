@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+const (
+	defaultStreamHeartbeat = 15 * time.Second
+	streamWriteWait        = 10 * time.Second
+)
+
+var (
+	errDriverNotSelf   = errors.New("drivers can only follow their own routes")
+	errRouteNotShipped = errors.New("none of the authenticated user's shipments is on this route")
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+type DriverIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// driverTopic resolves the driver named by the :id path parameter and makes
+// sure it is the authenticated user, unless that is an admin. It writes the
+// error response itself and reports whether the handler may continue.
+func (server *Server) driverTopic(ctx *gin.Context) (tracking.Topic, bool) {
+	var req DriverIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return "", false
+	}
+	driverID := uuid.MustParse(req.ID)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if driverID != authPayload.UserID && authPayload.Role != util.RoleAdmin {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errDriverNotSelf))
+		return "", false
+	}
+	return tracking.DriverTopic(driverID), true
+}
+
+// routeTopic resolves the route named by the :id path parameter, which its
+// driver, admins and the customers with a shipment on it may follow. Customers
+// only get the events of their own stops, which the returned filter picks
+// out. It writes the error response itself and reports whether the handler
+// may continue.
+func (server *Server) routeTopic(ctx *gin.Context) (tracking.Topic, tracking.Filter, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Role != util.RoleCustomer {
+		route, ok := server.getReadableRoute(ctx)
+		if !ok {
+			return "", nil, false
+		}
+		return tracking.RouteTopic(route.ID), nil, true
+	}
+
+	var req RouteIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return "", nil, false
+	}
+	routeID := uuid.MustParse(req.ID)
+	shipments, err := server.store.ListRouteShipmentIDsByCustomer(ctx, db.ListRouteShipmentIDsByCustomerParams{
+		RouteID:    uuid.NullUUID{UUID: routeID, Valid: true},
+		CustomerID: authPayload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return "", nil, false
+	}
+	if len(shipments) == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errRouteNotShipped))
+		return "", nil, false
+	}
+	return tracking.RouteTopic(routeID), customerEvents(shipments), true
+}
+
+// StopEtasEvent is the part of a live ETA update a customer may see: the
+// predicted visits of the stops delivering their shipments.
+type StopEtasEvent struct {
+	RouteID    uuid.UUID           `json:"route_id"`
+	Stops      []RouteStopResponse `json:"stops"`
+	ComputedAt time.Time           `json:"computed_at"`
+}
+
+// customerEvents narrows a route's events to the status changes and ETA
+// updates of the stops delivering shipments, the customer's shipments on the
+// route when they subscribed. The driver's positions, the rest of the route
+// and the other customers' stops are left out.
+func customerEvents(shipments []uuid.UUID) tracking.Filter {
+	owned := make(map[uuid.UUID]bool, len(shipments))
+	for _, id := range shipments {
+		owned[id] = true
+	}
+	delivers := func(stop RouteStopResponse) bool {
+		return stop.ShipmentID != nil && owned[*stop.ShipmentID]
+	}
+
+	return func(event tracking.Event) (tracking.Event, bool) {
+		switch data := event.Data.(type) {
+		case RouteStopResponse:
+			return event, delivers(data)
+		case RouteEtaEvent:
+			update := StopEtasEvent{RouteID: data.RouteID, ComputedAt: data.ComputedAt}
+			for _, stop := range data.Stops {
+				if delivers(stop) {
+					update.Stops = append(update.Stops, stop)
+				}
+			}
+			event.Data = update
+			return event, len(update.Stops) > 0
+		}
+		return event, false
+	}
+}
+
+func (server *Server) StreamRouteEvents(ctx *gin.Context) {
+	if topic, filter, ok := server.routeTopic(ctx); ok {
+		server.streamEvents(ctx, topic, filter)
+	}
+}
+
+func (server *Server) StreamRouteWebSocket(ctx *gin.Context) {
+	if topic, filter, ok := server.routeTopic(ctx); ok {
+		server.streamWebSocket(ctx, topic, filter)
+	}
+}
+
+func (server *Server) StreamDriverEvents(ctx *gin.Context) {
+	if topic, ok := server.driverTopic(ctx); ok {
+		server.streamEvents(ctx, topic, nil)
+	}
+}
+
+func (server *Server) StreamDriverWebSocket(ctx *gin.Context) {
+	if topic, ok := server.driverTopic(ctx); ok {
+		server.streamWebSocket(ctx, topic, nil)
+	}
+}
+
+// publish tells everyone following route or its driver about a change.
+func (server *Server) publish(eventType tracking.EventType, route db.Route, data any) {
+	server.hub.Publish(tracking.Event{
+		Type:     eventType,
+		RouteID:  route.ID,
+		DriverID: route.DriverID,
+		Data:     data,
+		At:       time.Now(),
+	})
+}
+
+func (server *Server) heartbeatInterval() time.Duration {
+	if server.config.StreamHeartbeatInterval > 0 {
+		return server.config.StreamHeartbeatInterval
+	}
+	return defaultStreamHeartbeat
+}
+
+// streamEvents pushes the events of topic that filter lets through to the
+// client as Server-Sent Events until the client disconnects or falls too far
+// behind.
+func (server *Server) streamEvents(ctx *gin.Context, topic tracking.Topic, filter tracking.Filter) {
+	sub, err := server.hub.SubscribeFiltered(topic, filter)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(err))
+		return
+	}
+	defer sub.Close()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(server.heartbeatInterval())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					ctx.SSEvent("error", errorResponse(err))
+					ctx.Writer.Flush()
+				}
+				return
+			}
+			ctx.SSEvent(string(event.Type), event)
+		case <-heartbeat.C:
+			if _, err := ctx.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// streamWebSocket pushes the events of topic that filter lets through to the
+// client as JSON WebSocket messages. Messages sent by the client are ignored.
+func (server *Server) streamWebSocket(ctx *gin.Context, topic tracking.Topic, filter tracking.Filter) {
+	sub, err := server.hub.SubscribeFiltered(topic, filter)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(err))
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// the upgrader has already written the error response
+		return
+	}
+	defer conn.Close()
+
+	// the read loop handles control frames and notices when the client goes away
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(server.heartbeatInterval())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-clientGone:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				code, reason := websocket.CloseNormalClosure, ""
+				if err := sub.Err(); errors.Is(err, tracking.ErrSlowConsumer) {
+					code, reason = websocket.CloseTryAgainLater, err.Error()
+				} else if err != nil {
+					code, reason = websocket.CloseGoingAway, err.Error()
+				}
+				message := websocket.FormatCloseMessage(code, reason)
+				conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteWait))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
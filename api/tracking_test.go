@@ -0,0 +1,497 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	mockdb "github.com/joekings2k/logistics-eta/db/mock"
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/tracking"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// readSSEvent reads the next named event from an SSE stream, skipping
+// heartbeat comments.
+func readSSEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	var name, data string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimPrefix(line, "data:")
+		case line == "" && name != "":
+			return name, data
+		}
+	}
+}
+
+func newAccessToken(t *testing.T, tokenMaker token.Maker, userID uuid.UUID) string {
+	accessToken, _, err := tokenMaker.CreateToken(userID, util.RoleDriver, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+	return accessToken
+}
+
+func TestStreamRouteEvents(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	otherUser, _ := randomUser(t)
+	// the first stop delivers the customer's shipment, the second someone else's
+	shipmentID := uuid.New()
+	stops := randomRouteStops(route, 2)
+	stops[0].ShipmentID = uuid.NullUUID{UUID: shipmentID, Valid: true}
+	stops[1].ShipmentID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, response *http.Response)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+				require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+				server.publish(tracking.EventStatus, route, newRouteResponse(route))
+				name, data := readSSEvent(t, bufio.NewReader(response.Body))
+				require.Equal(t, string(tracking.EventStatus), name)
+
+				var event tracking.Event
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				require.Equal(t, route.ID, event.RouteID)
+				require.Equal(t, user.ID, event.DriverID)
+			},
+		},
+		{
+			name: "QueryToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				query := request.URL.Query()
+				query.Set(accessTokenQueryKey, newAccessToken(t, tokenMaker, user.ID))
+				request.URL.RawQuery = query.Encode()
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+			},
+		},
+		{
+			name: "HubClosed",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+
+				server.hub.Close()
+				name, data := readSSEvent(t, bufio.NewReader(response.Body))
+				require.Equal(t, "error", name)
+				require.Contains(t, data, tracking.ErrHubClosed.Error())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			name: "InvalidQueryToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.URL.RawQuery = accessTokenQueryKey + "=invalid"
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+				require.Zero(t, server.hub.SubscriberCount(tracking.RouteTopic(route.ID)))
+			},
+		},
+		{
+			name: "Admin",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.ID, util.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+			},
+		},
+		{
+			name: "CustomerWithShipment",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.ID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					ListRouteShipmentIDsByCustomer(gomock.Any(), gomock.Eq(db.ListRouteShipmentIDsByCustomerParams{
+						RouteID:    uuid.NullUUID{UUID: route.ID, Valid: true},
+						CustomerID: otherUser.ID,
+					})).
+					Times(1).
+					Return([]uuid.UUID{shipmentID}, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+				reader := bufio.NewReader(response.Body)
+
+				// neither the route's status nor another customer's stop is delivered
+				server.publish(tracking.EventStatus, route, newRouteResponse(route))
+				server.publish(tracking.EventStop, route, newRouteStopResponse(stops[1]))
+				server.publish(tracking.EventStop, route, newRouteStopResponse(stops[0]))
+				name, data := readSSEvent(t, reader)
+				require.Equal(t, string(tracking.EventStop), name)
+				var stopEvent struct {
+					Data RouteStopResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(data), &stopEvent))
+				require.Equal(t, stops[0].ID, stopEvent.Data.ID)
+
+				// ETA updates only carry the customer's own stops
+				server.publish(tracking.EventEta, route, RouteEtaEvent{
+					RouteEtaResponse: RouteEtaResponse{RouteID: route.ID, RemainingDistanceKm: 12},
+					Stops:            []RouteStopResponse{newRouteStopResponse(stops[0]), newRouteStopResponse(stops[1])},
+				})
+				name, data = readSSEvent(t, reader)
+				require.Equal(t, string(tracking.EventEta), name)
+				require.NotContains(t, data, "remaining_distance_km")
+				var etaEvent struct {
+					Data StopEtasEvent `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(data), &etaEvent))
+				require.Len(t, etaEvent.Data.Stops, 1)
+				require.Equal(t, stops[0].ID, etaEvent.Data.Stops[0].ID)
+			},
+		},
+		{
+			name: "CustomerWithoutShipment",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.ID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteShipmentIDsByCustomer(gomock.Any(), gomock.Any()).Times(1).Return([]uuid.UUID{}, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+				require.Zero(t, server.hub.SubscriberCount(tracking.RouteTopic(route.ID)))
+			},
+		},
+		{
+			name: "CustomerInternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser.ID, util.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRouteShipmentIDsByCustomer(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusInternalServerError, response.StatusCode)
+			},
+		},
+		{
+			name: "NotFound",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(db.Route{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusNotFound, response.StatusCode)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := NewTestServer(t, store)
+			httpServer := httptest.NewServer(server.router)
+			defer httpServer.Close()
+
+			requestCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			url := fmt.Sprintf("%s/tracking/routes/%s/events", httpServer.URL, route.ID)
+			request, err := http.NewRequestWithContext(requestCtx, http.MethodGet, url, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			tc.checkResponse(t, server, response)
+		})
+	}
+}
+
+func TestStreamDriverEvents(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	first := randomRoute(t, vehicle)
+	second := randomRoute(t, vehicle)
+
+	testCases := []struct {
+		name          string
+		driverID      string
+		userID        uuid.UUID
+		role          util.Role
+		checkResponse func(t *testing.T, server *Server, response *http.Response)
+	}{
+		{
+			name:     "OK",
+			driverID: user.ID.String(),
+			userID:   user.ID,
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+
+				server.publish(tracking.EventPosition, first, gin.H{"lat": 1.0})
+				server.publish(tracking.EventEta, second, gin.H{"confidence": 0.7})
+
+				reader := bufio.NewReader(response.Body)
+				for _, expected := range []struct {
+					name    string
+					routeID uuid.UUID
+				}{
+					{string(tracking.EventPosition), first.ID},
+					{string(tracking.EventEta), second.ID},
+				} {
+					name, data := readSSEvent(t, reader)
+					require.Equal(t, expected.name, name)
+
+					var event tracking.Event
+					require.NoError(t, json.Unmarshal([]byte(data), &event))
+					require.Equal(t, expected.routeID, event.RouteID)
+				}
+			},
+		},
+		{
+			name:     "OtherDriver",
+			driverID: user.ID.String(),
+			userID:   otherUser.ID,
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			name:     "Admin",
+			driverID: user.ID.String(),
+			userID:   otherUser.ID,
+			role:     util.RoleAdmin,
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+			},
+		},
+		{
+			name:     "Customer",
+			driverID: user.ID.String(),
+			userID:   otherUser.ID,
+			role:     util.RoleCustomer,
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			name:     "InvalidID",
+			driverID: "invalid",
+			userID:   user.ID,
+			checkResponse: func(t *testing.T, server *Server, response *http.Response) {
+				require.Equal(t, http.StatusBadRequest, response.StatusCode)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := NewTestServer(t, mockdb.NewMockStore(ctrl))
+			httpServer := httptest.NewServer(server.router)
+			defer httpServer.Close()
+
+			requestCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			url := fmt.Sprintf("%s/tracking/drivers/%s/events", httpServer.URL, tc.driverID)
+			request, err := http.NewRequestWithContext(requestCtx, http.MethodGet, url, nil)
+			require.NoError(t, err)
+			role := util.RoleDriver
+			if tc.role != "" {
+				role = tc.role
+			}
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.userID, role, time.Minute)
+
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			tc.checkResponse(t, server, response)
+		})
+	}
+}
+
+func TestStreamRouteWebSocket(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).AnyTimes().Return(route, nil)
+
+	server := NewTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	url := fmt.Sprintf("ws%s/tracking/routes/%s/ws?%s=%s",
+		strings.TrimPrefix(httpServer.URL, "http"), route.ID,
+		accessTokenQueryKey, newAccessToken(t, server.tokenMaker, user.ID))
+
+	t.Run("OK", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		server.publish(tracking.EventStatus, route, newRouteResponse(route))
+
+		var event tracking.Event
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&event))
+		require.Equal(t, tracking.EventStatus, event.Type)
+		require.Equal(t, route.ID, event.RouteID)
+
+		// closing the socket releases the subscription
+		conn.Close()
+		require.Eventually(t, func() bool {
+			return server.hub.SubscriberCount(tracking.RouteTopic(route.ID)) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("NoAuthorization", func(t *testing.T) {
+		_, response, err := websocket.DefaultDialer.Dial(strings.Split(url, "?")[0], nil)
+		require.ErrorIs(t, err, websocket.ErrBadHandshake)
+		require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("HubClosed", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.Eventually(t, func() bool {
+			return server.hub.SubscriberCount(tracking.RouteTopic(route.ID)) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		server.hub.Close()
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, _, err = conn.ReadMessage()
+		require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+	})
+}
+
+func TestUpdateRouteStatusPublishesEvent(t *testing.T) {
+	user, _ := randomUser(t)
+	vehicle := RandomVehicle(t)
+	vehicle.DriverID = user.ID
+	route := randomRoute(t, vehicle)
+
+	started := route
+	started.Status = string(util.RouteInProgress)
+	started.StartedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetRouteByID(gomock.Any(), gomock.Eq(route.ID)).Times(1).Return(route, nil)
+	store.EXPECT().StartRouteTx(gomock.Any(), gomock.Any()).Times(1).Return(db.StartRouteTxResult{Route: started, Vehicle: vehicle}, nil)
+
+	server := NewTestServer(t, store)
+	sub, err := server.hub.Subscribe(tracking.DriverTopic(user.ID))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	recorder := httptest.NewRecorder()
+	url := fmt.Sprintf("/routes/%s/status", route.ID)
+	request, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(`{"status":"in_progress"}`))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.ID, util.RoleDriver, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	select {
+	case event := <-sub.Events():
+		require.Equal(t, tracking.EventStatus, event.Type)
+		require.Equal(t, route.ID, event.RouteID)
+		require.Equal(t, string(util.RouteInProgress), event.Data.(RouteResponse).Status)
+	default:
+		t.Fatal("expected a status event")
+	}
+}
@@ -2,12 +2,15 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/token"
 	"github.com/joekings2k/logistics-eta/util"
 	"github.com/lib/pq"
 
@@ -85,9 +88,12 @@ type LoginUserRequest struct {
 }
 
 type LoginUserResponse struct {
-	AccessToken string `json:"access_token"`
-	User UserResponse `json:"user"`
-
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  UserResponse `json:"user"`
 }
 
 func (server *Server) LoginUser(ctx *gin.Context) {
@@ -100,6 +106,7 @@ func (server *Server) LoginUser(ctx *gin.Context) {
 	if err != nil {
 		if err  == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -114,14 +121,306 @@ func (server *Server) LoginUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error":msg})
 		return
 	}
-	accessToken, err := server.tokenMaker.CreateToken(user.ID, server.config.AccessTokenDuration)
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.ID, util.Role(user.Role), token.AccessToken, server.config.AccessTokenDuration)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.ID, util.Role(user.Role), token.RefreshToken, server.config.RefreshTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the session shares its id with the refresh token so it can be found again on renewal
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		UserID:       user.ID,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
 	response := LoginUserResponse{
-		AccessToken: accessToken,
-		User: newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+type ListUsersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (server *Server) ListUsers(ctx *gin.Context) {
+	var req ListUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	users, err := server.store.ListUsers(ctx, db.ListUsersParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newUserResponse(user))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+var (
+	errWrongPassword   = errors.New("old password is incorrect")
+	errSamePassword    = errors.New("new password must differ from the old one")
+	errEmptyUserUpdate = errors.New("no fields to update")
+	errManageSelf      = errors.New("admins cannot change their own role or delete themselves")
+)
+
+// getCurrentUser loads the authenticated user. A token that outlived its user
+// is treated as unauthenticated.
+func (server *Server) getCurrentUser(ctx *gin.Context) (db.User, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUserByID(ctx, authPayload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return db.User{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+	return user, true
+}
+
+func (server *Server) GetCurrentUser(ctx *gin.Context) {
+	user, ok := server.getCurrentUser(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// UpdateCurrentUserRequest holds the profile fields to change. Roles are
+// only changed by admins and passwords through their own endpoint.
+type UpdateCurrentUserRequest struct {
+	Name  *string `json:"name" binding:"omitempty,min=1"`
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+func (server *Server) UpdateCurrentUser(ctx *gin.Context) {
+	var req UpdateCurrentUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Name == nil && req.Email == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errEmptyUserUpdate))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.UpdateUserPartialParams{ID: authPayload.UserID}
+	if req.Name != nil {
+		arg.Name = sql.NullString{String: *req.Name, Valid: true}
+	}
+	if req.Email != nil {
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+	}
+
+	user, err := server.store.UpdateUserPartial(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePassword replaces the password after checking the old one, then
+// revokes every session so stolen refresh tokens stop working.
+func (server *Server) ChangePassword(ctx *gin.Context) {
+	var req ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.OldPassword == req.NewPassword {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errSamePassword))
+		return
+	}
+
+	user, ok := server.getCurrentUser(ctx)
+	if !ok {
+		return
+	}
+	if err := util.CheckPassword(req.OldPassword, user.PasswordHash); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errWrongPassword))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	_, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		UserID:       user.ID,
+		PasswordHash: hashedPassword,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+type SearchUsersRequest struct {
+	Query    string `form:"q"`
+	Role     string `form:"role" binding:"omitempty,roles"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// SearchUsers finds users whose name or email contains q, optionally
+// narrowed to one role.
+func (server *Server) SearchUsers(ctx *gin.Context) {
+	var req SearchUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	users, err := server.store.SearchUsers(ctx, db.SearchUsersParams{
+		Search: sql.NullString{String: req.Query, Valid: req.Query != ""},
+		Role:   sql.NullString{String: req.Role, Valid: req.Role != ""},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newUserResponse(user))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type UserIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// getOtherUserID parses the id of the user an admin is managing. Admins may
+// not change their own role or delete themselves, which could leave no admin
+// behind. It writes the error response itself and reports whether the
+// handler may continue.
+func (server *Server) getOtherUserID(ctx *gin.Context, uri UserIDRequest) (uuid.UUID, bool) {
+	userID := uuid.MustParse(uri.ID)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if userID == authPayload.UserID {
+		ctx.JSON(http.StatusForbidden, errorResponse(errManageSelf))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,roles"`
+}
+
+func (server *Server) UpdateUserRole(ctx *gin.Context) {
+	var uri UserIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req UpdateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	userID, ok := server.getOtherUserID(ctx, uri)
+	if !ok {
+		return
+	}
+
+	// the user's sessions are revoked with a role change, so only access
+	// tokens already issued keep the old role, until they expire
+	result, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleTxParams{
+		UserID: userID,
+		Role:   req.Role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+func (server *Server) DeleteUser(ctx *gin.Context) {
+	var uri UserIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	userID, ok := server.getOtherUserID(ctx, uri)
+	if !ok {
+		return
+	}
+
+	// the user's vehicles and sessions are removed with it. Routes, and the
+	// trip history learned from them, are kept by refusing the delete.
+	err := server.store.DeleteUserTx(ctx, db.DeleteUserTxParams{UserID: userID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrUserHasRoutes) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+}
@@ -9,4 +9,16 @@ var ValidRoles  validator.Func = func(fl validator.FieldLevel) bool {
 		return util.Role(role).IsValid()
 	}
 	return false
-}
\ No newline at end of file
+}
+var ValidRouteStatus validator.Func = func(fl validator.FieldLevel) bool {
+	if status, ok := fl.Field().Interface().(string); ok {
+		return util.RouteStatus(status).IsValid()
+	}
+	return false
+}
+var ValidStopStatus validator.Func = func(fl validator.FieldLevel) bool {
+	if status, ok := fl.Field().Interface().(string); ok {
+		return util.StopStatus(status).IsValid()
+	}
+	return false
+}
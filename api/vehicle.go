@@ -2,6 +2,7 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	db "github.com/joekings2k/logistics-eta/db/sqlc"
 	"github.com/joekings2k/logistics-eta/token"
+	"github.com/joekings2k/logistics-eta/util"
 	"github.com/lib/pq"
 )
 
@@ -17,6 +19,7 @@ type CreateVehicleRequest struct {
 	LicensePlate string `json:"license_plate" binding:"required"`
 	Model string `json:"model" binding:"required"`
 	ImageUrl string `json:"image_url"`
+	// Capacity is the payload the vehicle carries, in kg.
 	Capacity int32 `json:"capacity"`
 }
 
@@ -26,6 +29,7 @@ type CreateVehicleResponse struct {
 	LicensePlate string `json:"license_plate"`
 	Model string `json:"model"`
 	ImageUrl string `json:"image_url"`
+	// Capacity is the payload the vehicle carries, in kg.
 	Capacity int32 `json:"capacity"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -82,3 +86,176 @@ func newVehicleResponse (vehicle db.Vehicle) CreateVehicleResponse {
 	}
 }
 
+
+var errVehicleHasRoutes = errors.New("vehicle still has routes and cannot be deleted")
+
+type VehicleIDRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// canAccessVehicle reports whether the authenticated user may read or change
+// the vehicle. Drivers only reach their own vehicles; admins reach every one.
+func canAccessVehicle(payload *token.Payload, vehicle db.Vehicle) bool {
+	return payload.Role == util.RoleAdmin || vehicle.DriverID == payload.UserID
+}
+
+// getAccessibleVehicle loads the vehicle named by the :id path parameter and
+// makes sure the authenticated user may access it. It writes the error
+// response itself and reports whether the handler may continue.
+func (server *Server) getAccessibleVehicle(ctx *gin.Context) (db.Vehicle, bool) {
+	var req VehicleIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Vehicle{}, false
+	}
+
+	vehicle, err := server.store.GetVehicleByID(ctx, uuid.MustParse(req.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.Vehicle{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.Vehicle{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canAccessVehicle(authPayload, vehicle) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errVehicleNotOwned))
+		return db.Vehicle{}, false
+	}
+	return vehicle, true
+}
+
+func (server *Server) GetVehicle(ctx *gin.Context) {
+	vehicle, ok := server.getAccessibleVehicle(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newVehicleResponse(vehicle))
+}
+
+type ListVehiclesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListVehicles lists the authenticated driver's own vehicles.
+func (server *Server) ListVehicles(ctx *gin.Context) {
+	var req ListVehiclesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.GetVehiclesByDriverIDParams{
+		DriverID: authPayload.UserID,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+	vehicles, err := server.store.GetVehiclesByDriverID(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]CreateVehicleResponse, 0, len(vehicles))
+	for _, vehicle := range vehicles {
+		response = append(response, newVehicleResponse(vehicle))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type GetVehicleByPlateRequest struct {
+	LicensePlate string `uri:"license_plate" binding:"required"`
+}
+
+func (server *Server) GetVehicleByPlate(ctx *gin.Context) {
+	var req GetVehicleByPlateRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	vehicle, err := server.store.GetVehicleByLicensePlate(ctx, req.LicensePlate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canAccessVehicle(authPayload, vehicle) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errVehicleNotOwned))
+		return
+	}
+	ctx.JSON(http.StatusOK, newVehicleResponse(vehicle))
+}
+
+// UpdateVehicleRequest holds the fields to change; fields left out of the
+// body keep their current value.
+type UpdateVehicleRequest struct {
+	Model    *string `json:"model" binding:"omitempty,min=1"`
+	ImageUrl *string `json:"image_url"`
+	// Capacity is the payload the vehicle carries, in kg.
+	Capacity *int32 `json:"capacity" binding:"omitempty,min=1"`
+}
+
+func (server *Server) UpdateVehicle(ctx *gin.Context) {
+	vehicle, ok := server.getAccessibleVehicle(ctx)
+	if !ok {
+		return
+	}
+	var req UpdateVehicleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateVehicleParams{ID: vehicle.ID}
+	if req.Model != nil {
+		arg.Model = sql.NullString{String: *req.Model, Valid: true}
+	}
+	if req.ImageUrl != nil {
+		arg.ImageUrl = sql.NullString{String: *req.ImageUrl, Valid: true}
+	}
+	if req.Capacity != nil {
+		arg.Capacity = sql.NullInt32{Int32: *req.Capacity, Valid: true}
+	}
+
+	vehicle, err := server.store.UpdateVehicle(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, newVehicleResponse(vehicle))
+}
+
+func (server *Server) DeleteVehicle(ctx *gin.Context) {
+	vehicle, ok := server.getAccessibleVehicle(ctx)
+	if !ok {
+		return
+	}
+	if err := server.store.DeleteVehicle(ctx, vehicle.ID); err != nil {
+		// routes keep their vehicle_id, so a vehicle that has driven any
+		// route can't be removed
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation", "not_null_violation":
+				ctx.JSON(http.StatusConflict, errorResponse(errVehicleHasRoutes))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "vehicle deleted"})
+}
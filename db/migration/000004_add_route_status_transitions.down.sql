@@ -0,0 +1,6 @@
+ALTER TABLE routes DROP CONSTRAINT IF EXISTS routes_status_check;
+
+ALTER TABLE routes
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS cancelled_at;
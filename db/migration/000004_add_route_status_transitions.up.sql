@@ -0,0 +1,9 @@
+ALTER TABLE routes
+    ADD COLUMN started_at TIMESTAMPTZ,
+    ADD COLUMN completed_at TIMESTAMPTZ,
+    ADD COLUMN cancelled_at TIMESTAMPTZ;
+
+-- Only the statuses known to the route state machine are allowed
+ALTER TABLE routes
+    ADD CONSTRAINT routes_status_check
+    CHECK (status IN ('pending', 'in_progress', 'completed', 'cancelled'));
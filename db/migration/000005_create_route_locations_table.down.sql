@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_route_locations_route_recorded;
+DROP INDEX IF EXISTS idx_route_locations_driver_id;
+
+DROP TABLE IF EXISTS route_locations CASCADE;
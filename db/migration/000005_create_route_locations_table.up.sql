@@ -0,0 +1,20 @@
+CREATE TABLE route_locations (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    driver_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+
+    -- Optional readings reported by the device
+    speed_kmh DOUBLE PRECISION,
+    heading_deg DOUBLE PRECISION,
+    accuracy_m DOUBLE PRECISION,
+
+    -- When the device took the reading, as opposed to when we received it
+    recorded_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_route_locations_route_recorded ON route_locations(route_id, recorded_at DESC);
+CREATE INDEX idx_route_locations_driver_id ON route_locations(driver_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_route_eta_history_route_created;
+
+DROP TABLE IF EXISTS route_eta_history CASCADE;
+
+ALTER TABLE routes
+    DROP COLUMN IF EXISTS remaining_distance_km,
+    DROP COLUMN IF EXISTS remaining_duration_min,
+    DROP COLUMN IF EXISTS eta_at,
+    DROP COLUMN IF EXISTS eta_confidence,
+    DROP COLUMN IF EXISTS eta_updated_at;
@@ -0,0 +1,23 @@
+-- Latest live ETA, recomputed from reported positions while a route is in progress
+ALTER TABLE routes
+    ADD COLUMN remaining_distance_km DOUBLE PRECISION,
+    ADD COLUMN remaining_duration_min DOUBLE PRECISION,
+    ADD COLUMN eta_at TIMESTAMPTZ,
+    ADD COLUMN eta_confidence DOUBLE PRECISION,
+    ADD COLUMN eta_updated_at TIMESTAMPTZ;
+
+CREATE TABLE route_eta_history (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    -- Position the estimate was computed from
+    location_id BIGINT REFERENCES route_locations(id) ON DELETE SET NULL,
+
+    remaining_distance_km DOUBLE PRECISION NOT NULL,
+    remaining_duration_min DOUBLE PRECISION NOT NULL,
+    eta_at TIMESTAMPTZ NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_route_eta_history_route_created ON route_eta_history(route_id, created_at DESC);
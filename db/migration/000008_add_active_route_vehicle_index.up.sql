@@ -0,0 +1,2 @@
+-- A vehicle can only be driven on one route at a time
+CREATE UNIQUE INDEX idx_routes_active_vehicle ON routes(vehicle_id) WHERE status = 'in_progress';
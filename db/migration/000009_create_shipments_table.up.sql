@@ -0,0 +1,29 @@
+-- A customer's package, carried by at most one route
+CREATE TABLE shipments (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Unset until the shipment is planned onto a route
+    route_id UUID REFERENCES routes(id) ON DELETE SET NULL,
+
+    pickup_lat DOUBLE PRECISION NOT NULL,
+    pickup_lng DOUBLE PRECISION NOT NULL,
+    pickup_address TEXT,
+    dropoff_lat DOUBLE PRECISION NOT NULL,
+    dropoff_lng DOUBLE PRECISION NOT NULL,
+    dropoff_address TEXT,
+
+    weight_kg DOUBLE PRECISION NOT NULL CHECK (weight_kg > 0),
+    volume_m3 DOUBLE PRECISION NOT NULL CHECK (volume_m3 > 0),
+
+    -- Requested delivery window, either end may be left open
+    window_start TIMESTAMPTZ,
+    window_end TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT shipments_window_check CHECK (window_start IS NULL OR window_end IS NULL OR window_end > window_start)
+);
+
+CREATE INDEX idx_shipments_customer_created ON shipments(customer_id, created_at DESC);
+CREATE INDEX idx_shipments_route_id ON shipments(route_id);
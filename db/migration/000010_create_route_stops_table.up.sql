@@ -0,0 +1,28 @@
+-- Ordered waypoints a driver visits between a route's origin and destination
+CREATE TABLE route_stops (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    -- Shipment delivered at this stop, if any
+    shipment_id UUID REFERENCES shipments(id) ON DELETE SET NULL,
+    sequence INT NOT NULL CHECK (sequence > 0),
+
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+    address TEXT,
+    -- Time spent at the stop before driving on
+    service_time_min DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (service_time_min >= 0),
+
+    -- Status: "pending", "arrived", "completed" or "failed"
+    status TEXT NOT NULL DEFAULT 'pending',
+    arrived_at TIMESTAMPTZ,
+    departed_at TIMESTAMPTZ,
+    failure_reason TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT route_stops_status_check CHECK (status IN ('pending', 'arrived', 'completed', 'failed')),
+    CONSTRAINT route_stops_route_sequence_key UNIQUE (route_id, sequence)
+);
+
+CREATE INDEX idx_route_stops_shipment_id ON route_stops(shipment_id);
@@ -0,0 +1,4 @@
+ALTER TABLE route_stops
+    DROP CONSTRAINT IF EXISTS route_stops_window_check,
+    DROP COLUMN IF EXISTS window_start,
+    DROP COLUMN IF EXISTS window_end;
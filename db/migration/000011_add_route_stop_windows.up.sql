@@ -0,0 +1,5 @@
+-- Time window the stop has to be visited in, either end may be left open
+ALTER TABLE route_stops
+    ADD COLUMN window_start TIMESTAMPTZ,
+    ADD COLUMN window_end TIMESTAMPTZ,
+    ADD CONSTRAINT route_stops_window_check CHECK (window_start IS NULL OR window_end IS NULL OR window_end > window_start);
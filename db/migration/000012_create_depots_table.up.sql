@@ -0,0 +1,11 @@
+-- A site vehicles load at and return to at the end of their trip
+CREATE TABLE depots (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    address TEXT,
+    lat DOUBLE PRECISION NOT NULL,
+    lng DOUBLE PRECISION NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_routes_completed_at;
+ALTER TABLE routes DROP COLUMN IF EXISTS baseline_duration_min;
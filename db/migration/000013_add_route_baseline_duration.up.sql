@@ -0,0 +1,6 @@
+-- Free-flow estimate of the trip, before any time-of-day traffic adjustment.
+-- Speed profiles learn from how actual durations compare to it.
+ALTER TABLE routes ADD COLUMN baseline_duration_min DOUBLE PRECISION;
+
+-- Speed profiles are learned from the routes completed lately
+CREATE INDEX idx_routes_completed_at ON routes(completed_at) WHERE status = 'completed';
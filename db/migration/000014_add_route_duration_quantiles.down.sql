@@ -0,0 +1,4 @@
+ALTER TABLE routes
+    DROP COLUMN IF EXISTS duration_p10_min,
+    DROP COLUMN IF EXISTS duration_p50_min,
+    DROP COLUMN IF EXISTS duration_p90_min;
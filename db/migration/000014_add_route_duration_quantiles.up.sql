@@ -0,0 +1,7 @@
+-- P10, P50 and P90 of the trip's duration when it was planned, from how far
+-- off the estimates of similar routes were. Kept to measure how well the
+-- intervals are calibrated.
+ALTER TABLE routes
+    ADD COLUMN duration_p10_min DOUBLE PRECISION,
+    ADD COLUMN duration_p50_min DOUBLE PRECISION,
+    ADD COLUMN duration_p90_min DOUBLE PRECISION;
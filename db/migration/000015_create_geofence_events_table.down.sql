@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS geofence_events;
+ALTER TABLE route_stops DROP COLUMN IF EXISTS geofence_radius_m;
+ALTER TABLE routes
+    DROP COLUMN IF EXISTS origin_geofence_radius_m,
+    DROP COLUMN IF EXISTS destination_geofence_radius_m;
@@ -0,0 +1,29 @@
+-- Radii of the geofences around the route's origin and destination and
+-- around every stop, the configured default when null
+ALTER TABLE routes
+    ADD COLUMN origin_geofence_radius_m DOUBLE PRECISION CHECK (origin_geofence_radius_m > 0),
+    ADD COLUMN destination_geofence_radius_m DOUBLE PRECISION CHECK (destination_geofence_radius_m > 0);
+ALTER TABLE route_stops ADD COLUMN geofence_radius_m DOUBLE PRECISION CHECK (geofence_radius_m > 0);
+
+-- Arrivals at and departures from a route's places, detected from the
+-- positions its driver reports
+CREATE TABLE geofence_events (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    -- Place: "origin", "stop" or "destination"
+    place TEXT NOT NULL,
+    stop_id BIGINT REFERENCES route_stops(id) ON DELETE CASCADE,
+    -- Transition: "arrival" or "departure"
+    transition TEXT NOT NULL,
+    -- First position on the new side of the fence, recorded at occurred_at
+    location_id BIGINT REFERENCES route_locations(id) ON DELETE SET NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT geofence_events_place_check CHECK (place IN ('origin', 'stop', 'destination')),
+    CONSTRAINT geofence_events_stop_check CHECK ((place = 'stop') = (stop_id IS NOT NULL)),
+    CONSTRAINT geofence_events_transition_check CHECK (transition IN ('arrival', 'departure'))
+);
+
+CREATE INDEX idx_geofence_events_route_occurred ON geofence_events(route_id, occurred_at DESC);
+CREATE INDEX idx_geofence_events_stop_id ON geofence_events(stop_id);
@@ -0,0 +1,18 @@
+-- The driver leaving the planned path of a route and getting back on it,
+-- detected from the positions they report
+CREATE TABLE deviation_events (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    -- Transition: "off_route" or "on_route"
+    transition TEXT NOT NULL,
+    -- How far from the planned path the driver was at occurred_at
+    distance_m DOUBLE PRECISION NOT NULL CHECK (distance_m >= 0),
+    -- First position on the new side of the corridor, recorded at occurred_at
+    location_id BIGINT REFERENCES route_locations(id) ON DELETE SET NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT deviation_events_transition_check CHECK (transition IN ('off_route', 'on_route'))
+);
+
+CREATE INDEX idx_deviation_events_route_occurred ON deviation_events(route_id, occurred_at DESC);
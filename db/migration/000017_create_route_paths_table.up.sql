@@ -0,0 +1,9 @@
+-- The roads a route was planned along, from its origin through its stops to
+-- its destination, which the positions its driver reports are checked
+-- against. Routes planned without a road network covering them have none.
+CREATE TABLE route_paths (
+    route_id UUID PRIMARY KEY REFERENCES routes(id) ON DELETE CASCADE,
+    -- Points of the path in driving order, as [{"lat": ..., "lng": ...}]
+    points JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+COMMENT ON COLUMN vehicles.capacity IS NULL;
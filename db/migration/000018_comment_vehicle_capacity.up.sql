@@ -0,0 +1,3 @@
+-- Vehicle capacity is the payload weight the vehicle carries, which dispatch
+-- fills with shipment weights and ETAs derive the vehicle class from.
+COMMENT ON COLUMN vehicles.capacity IS 'Payload capacity in kg';
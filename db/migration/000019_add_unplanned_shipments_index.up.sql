@@ -0,0 +1,3 @@
+-- Dispatch looks up the shipments still waiting for a route. Databases that
+-- ran 000012 before it was split out already have it.
+CREATE INDEX IF NOT EXISTS idx_shipments_unplanned ON shipments(created_at) WHERE route_id IS NULL;
@@ -0,0 +1,94 @@
+// Package migration embeds the SQL migrations so the server binary can apply
+// them without the migrate CLI.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migrator applies the embedded migrations to a database. Up and Down hold a
+// Postgres advisory lock while they run, so replicas starting together apply
+// each migration once and the rest wait for them to finish. Versions are kept
+// in schema_migrations like the migrate CLI does, so databases migrated with
+// the Makefile carry on from where they are.
+type Migrator struct {
+	migrate *migrate.Migrate
+}
+
+// NewMigrator takes a dedicated connection from db for the migrations, which
+// the advisory lock is tied to. Close returns it to the pool without closing
+// db.
+func NewMigrator(ctx context.Context, db *sql.DB) (*Migrator, error) {
+	source, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot create migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("cannot create migrator: %w", err)
+	}
+	return &Migrator{migrate: m}, nil
+}
+
+// Up applies every pending migration. An up-to-date database is not an error.
+func (migrator *Migrator) Up() error {
+	err := migrator.migrate.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down rolls back the last steps migrations.
+func (migrator *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	err := migrator.migrate.Steps(-steps)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Version returns the current migration version, and whether the last
+// migration failed half way and left the schema dirty. It returns 0 before
+// any migration was applied.
+func (migrator *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = migrator.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.migrate.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return dbErr
+}
@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	source, err := iofs.New(files, ".")
+	require.NoError(t, err)
+	defer source.Close()
+
+	version, err := source.First()
+	require.NoError(t, err)
+	require.Equal(t, uint(1), version)
+
+	count := 0
+	for {
+		count++
+
+		// every migration must be reversible
+		for _, read := range []func(uint) (io.ReadCloser, string, error){source.ReadUp, source.ReadDown} {
+			r, identifier, err := read(version)
+			require.NoError(t, err, "version %d", version)
+			require.NotEmpty(t, identifier)
+			body, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			require.NotEmpty(t, body, "version %d %s", version, identifier)
+		}
+
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, version+1, next, "migration versions must have no gaps")
+		version = next
+	}
+
+	entries, err := fs.Glob(files, "*.up.sql")
+	require.NoError(t, err)
+	require.Equal(t, len(entries), count)
+}
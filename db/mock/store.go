@@ -36,6 +36,141 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AssignShipmentRoute mocks base method.
+func (m *MockStore) AssignShipmentRoute(arg0 context.Context, arg1 db.AssignShipmentRouteParams) (db.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignShipmentRoute", arg0, arg1)
+	ret0, _ := ret[0].(db.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignShipmentRoute indicates an expected call of AssignShipmentRoute.
+func (mr *MockStoreMockRecorder) AssignShipmentRoute(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignShipmentRoute", reflect.TypeOf((*MockStore)(nil).AssignShipmentRoute), arg0, arg1)
+}
+
+// ChangePasswordTx mocks base method.
+func (m *MockStore) ChangePasswordTx(arg0 context.Context, arg1 db.ChangePasswordTxParams) (db.ChangePasswordTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePasswordTx", arg0, arg1)
+	ret0, _ := ret[0].(db.ChangePasswordTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePasswordTx indicates an expected call of ChangePasswordTx.
+func (mr *MockStoreMockRecorder) ChangePasswordTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePasswordTx", reflect.TypeOf((*MockStore)(nil).ChangePasswordTx), arg0, arg1)
+}
+
+// ClaimShipment mocks base method.
+func (m *MockStore) ClaimShipment(arg0 context.Context, arg1 db.ClaimShipmentParams) (db.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimShipment", arg0, arg1)
+	ret0, _ := ret[0].(db.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimShipment indicates an expected call of ClaimShipment.
+func (mr *MockStoreMockRecorder) ClaimShipment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimShipment", reflect.TypeOf((*MockStore)(nil).ClaimShipment), arg0, arg1)
+}
+
+// CompleteRouteTx mocks base method.
+func (m *MockStore) CompleteRouteTx(arg0 context.Context, arg1 db.CompleteRouteTxParams) (db.CompleteRouteTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteRouteTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CompleteRouteTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteRouteTx indicates an expected call of CompleteRouteTx.
+func (mr *MockStoreMockRecorder) CompleteRouteTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteRouteTx", reflect.TypeOf((*MockStore)(nil).CompleteRouteTx), arg0, arg1)
+}
+
+// CountRoutesByDriver mocks base method.
+func (m *MockStore) CountRoutesByDriver(arg0 context.Context, arg1 uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRoutesByDriver", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRoutesByDriver indicates an expected call of CountRoutesByDriver.
+func (mr *MockStoreMockRecorder) CountRoutesByDriver(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRoutesByDriver", reflect.TypeOf((*MockStore)(nil).CountRoutesByDriver), arg0, arg1)
+}
+
+// CreateDepot mocks base method.
+func (m *MockStore) CreateDepot(arg0 context.Context, arg1 db.CreateDepotParams) (db.Depot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDepot", arg0, arg1)
+	ret0, _ := ret[0].(db.Depot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDepot indicates an expected call of CreateDepot.
+func (mr *MockStoreMockRecorder) CreateDepot(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDepot", reflect.TypeOf((*MockStore)(nil).CreateDepot), arg0, arg1)
+}
+
+// CreateDeviationEvent mocks base method.
+func (m *MockStore) CreateDeviationEvent(arg0 context.Context, arg1 db.CreateDeviationEventParams) (db.DeviationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeviationEvent", arg0, arg1)
+	ret0, _ := ret[0].(db.DeviationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDeviationEvent indicates an expected call of CreateDeviationEvent.
+func (mr *MockStoreMockRecorder) CreateDeviationEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeviationEvent", reflect.TypeOf((*MockStore)(nil).CreateDeviationEvent), arg0, arg1)
+}
+
+// CreateDispatchPlanTx mocks base method.
+func (m *MockStore) CreateDispatchPlanTx(arg0 context.Context, arg1 db.CreateDispatchPlanTxParams) (db.CreateDispatchPlanTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDispatchPlanTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CreateDispatchPlanTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDispatchPlanTx indicates an expected call of CreateDispatchPlanTx.
+func (mr *MockStoreMockRecorder) CreateDispatchPlanTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDispatchPlanTx", reflect.TypeOf((*MockStore)(nil).CreateDispatchPlanTx), arg0, arg1)
+}
+
+// CreateGeofenceEvent mocks base method.
+func (m *MockStore) CreateGeofenceEvent(arg0 context.Context, arg1 db.CreateGeofenceEventParams) (db.GeofenceEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGeofenceEvent", arg0, arg1)
+	ret0, _ := ret[0].(db.GeofenceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGeofenceEvent indicates an expected call of CreateGeofenceEvent.
+func (mr *MockStoreMockRecorder) CreateGeofenceEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGeofenceEvent", reflect.TypeOf((*MockStore)(nil).CreateGeofenceEvent), arg0, arg1)
+}
+
 // CreateRoute mocks base method.
 func (m *MockStore) CreateRoute(arg0 context.Context, arg1 db.CreateRouteParams) (db.Route, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +186,96 @@ func (mr *MockStoreMockRecorder) CreateRoute(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRoute", reflect.TypeOf((*MockStore)(nil).CreateRoute), arg0, arg1)
 }
 
+// CreateRouteEtaHistory mocks base method.
+func (m *MockStore) CreateRouteEtaHistory(arg0 context.Context, arg1 db.CreateRouteEtaHistoryParams) (db.RouteEtaHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRouteEtaHistory", arg0, arg1)
+	ret0, _ := ret[0].(db.RouteEtaHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRouteEtaHistory indicates an expected call of CreateRouteEtaHistory.
+func (mr *MockStoreMockRecorder) CreateRouteEtaHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRouteEtaHistory", reflect.TypeOf((*MockStore)(nil).CreateRouteEtaHistory), arg0, arg1)
+}
+
+// CreateRouteLocation mocks base method.
+func (m *MockStore) CreateRouteLocation(arg0 context.Context, arg1 db.CreateRouteLocationParams) (db.RouteLocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRouteLocation", arg0, arg1)
+	ret0, _ := ret[0].(db.RouteLocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRouteLocation indicates an expected call of CreateRouteLocation.
+func (mr *MockStoreMockRecorder) CreateRouteLocation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRouteLocation", reflect.TypeOf((*MockStore)(nil).CreateRouteLocation), arg0, arg1)
+}
+
+// CreateRouteLocationsTx mocks base method.
+func (m *MockStore) CreateRouteLocationsTx(arg0 context.Context, arg1 db.CreateRouteLocationsTxParams) (db.CreateRouteLocationsTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRouteLocationsTx", arg0, arg1)
+	ret0, _ := ret[0].(db.CreateRouteLocationsTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRouteLocationsTx indicates an expected call of CreateRouteLocationsTx.
+func (mr *MockStoreMockRecorder) CreateRouteLocationsTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRouteLocationsTx", reflect.TypeOf((*MockStore)(nil).CreateRouteLocationsTx), arg0, arg1)
+}
+
+// CreateRouteStop mocks base method.
+func (m *MockStore) CreateRouteStop(arg0 context.Context, arg1 db.CreateRouteStopParams) (db.RouteStop, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRouteStop", arg0, arg1)
+	ret0, _ := ret[0].(db.RouteStop)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRouteStop indicates an expected call of CreateRouteStop.
+func (mr *MockStoreMockRecorder) CreateRouteStop(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRouteStop", reflect.TypeOf((*MockStore)(nil).CreateRouteStop), arg0, arg1)
+}
+
+// CreateSession mocks base method.
+func (m *MockStore) CreateSession(arg0 context.Context, arg1 db.CreateSessionParams) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockStoreMockRecorder) CreateSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), arg0, arg1)
+}
+
+// CreateShipment mocks base method.
+func (m *MockStore) CreateShipment(arg0 context.Context, arg1 db.CreateShipmentParams) (db.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateShipment", arg0, arg1)
+	ret0, _ := ret[0].(db.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateShipment indicates an expected call of CreateShipment.
+func (mr *MockStoreMockRecorder) CreateShipment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateShipment", reflect.TypeOf((*MockStore)(nil).CreateShipment), arg0, arg1)
+}
+
 // CreateUser mocks base method.
 func (m *MockStore) CreateUser(arg0 context.Context, arg1 db.CreateUserParams) (db.User, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +320,34 @@ func (mr *MockStoreMockRecorder) DeleteRoute(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoute", reflect.TypeOf((*MockStore)(nil).DeleteRoute), arg0, arg1)
 }
 
+// DeleteRoutePath mocks base method.
+func (m *MockStore) DeleteRoutePath(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRoutePath", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRoutePath indicates an expected call of DeleteRoutePath.
+func (mr *MockStoreMockRecorder) DeleteRoutePath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoutePath", reflect.TypeOf((*MockStore)(nil).DeleteRoutePath), arg0, arg1)
+}
+
+// DeleteRouteStops mocks base method.
+func (m *MockStore) DeleteRouteStops(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRouteStops", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRouteStops indicates an expected call of DeleteRouteStops.
+func (mr *MockStoreMockRecorder) DeleteRouteStops(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRouteStops", reflect.TypeOf((*MockStore)(nil).DeleteRouteStops), arg0, arg1)
+}
+
 // DeleteUser mocks base method.
 func (m *MockStore) DeleteUser(arg0 context.Context, arg1 uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -109,6 +362,20 @@ func (mr *MockStoreMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockStore)(nil).DeleteUser), arg0, arg1)
 }
 
+// DeleteUserTx mocks base method.
+func (m *MockStore) DeleteUserTx(arg0 context.Context, arg1 db.DeleteUserTxParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserTx indicates an expected call of DeleteUserTx.
+func (mr *MockStoreMockRecorder) DeleteUserTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTx", reflect.TypeOf((*MockStore)(nil).DeleteUserTx), arg0, arg1)
+}
+
 // DeleteVehicle mocks base method.
 func (m *MockStore) DeleteVehicle(arg0 context.Context, arg1 uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -123,6 +390,96 @@ func (mr *MockStoreMockRecorder) DeleteVehicle(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVehicle", reflect.TypeOf((*MockStore)(nil).DeleteVehicle), arg0, arg1)
 }
 
+// GetActiveRouteByVehicle mocks base method.
+func (m *MockStore) GetActiveRouteByVehicle(arg0 context.Context, arg1 uuid.UUID) (db.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveRouteByVehicle", arg0, arg1)
+	ret0, _ := ret[0].(db.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveRouteByVehicle indicates an expected call of GetActiveRouteByVehicle.
+func (mr *MockStoreMockRecorder) GetActiveRouteByVehicle(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveRouteByVehicle", reflect.TypeOf((*MockStore)(nil).GetActiveRouteByVehicle), arg0, arg1)
+}
+
+// GetDepot mocks base method.
+func (m *MockStore) GetDepot(arg0 context.Context, arg1 uuid.UUID) (db.Depot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDepot", arg0, arg1)
+	ret0, _ := ret[0].(db.Depot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDepot indicates an expected call of GetDepot.
+func (mr *MockStoreMockRecorder) GetDepot(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDepot", reflect.TypeOf((*MockStore)(nil).GetDepot), arg0, arg1)
+}
+
+// GetETAAccuracy mocks base method.
+func (m *MockStore) GetETAAccuracy(arg0 context.Context, arg1 db.GetETAAccuracyParams) (db.GetETAAccuracyRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetETAAccuracy", arg0, arg1)
+	ret0, _ := ret[0].(db.GetETAAccuracyRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetETAAccuracy indicates an expected call of GetETAAccuracy.
+func (mr *MockStoreMockRecorder) GetETAAccuracy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetETAAccuracy", reflect.TypeOf((*MockStore)(nil).GetETAAccuracy), arg0, arg1)
+}
+
+// GetLatestDeviationEvent mocks base method.
+func (m *MockStore) GetLatestDeviationEvent(arg0 context.Context, arg1 uuid.UUID) (db.DeviationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestDeviationEvent", arg0, arg1)
+	ret0, _ := ret[0].(db.DeviationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestDeviationEvent indicates an expected call of GetLatestDeviationEvent.
+func (mr *MockStoreMockRecorder) GetLatestDeviationEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestDeviationEvent", reflect.TypeOf((*MockStore)(nil).GetLatestDeviationEvent), arg0, arg1)
+}
+
+// GetLatestGeofenceEvent mocks base method.
+func (m *MockStore) GetLatestGeofenceEvent(arg0 context.Context, arg1 db.GetLatestGeofenceEventParams) (db.GeofenceEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestGeofenceEvent", arg0, arg1)
+	ret0, _ := ret[0].(db.GeofenceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestGeofenceEvent indicates an expected call of GetLatestGeofenceEvent.
+func (mr *MockStoreMockRecorder) GetLatestGeofenceEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestGeofenceEvent", reflect.TypeOf((*MockStore)(nil).GetLatestGeofenceEvent), arg0, arg1)
+}
+
+// GetLatestRouteLocation mocks base method.
+func (m *MockStore) GetLatestRouteLocation(arg0 context.Context, arg1 uuid.UUID) (db.RouteLocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestRouteLocation", arg0, arg1)
+	ret0, _ := ret[0].(db.RouteLocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestRouteLocation indicates an expected call of GetLatestRouteLocation.
+func (mr *MockStoreMockRecorder) GetLatestRouteLocation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestRouteLocation", reflect.TypeOf((*MockStore)(nil).GetLatestRouteLocation), arg0, arg1)
+}
+
 // GetRouteByID mocks base method.
 func (m *MockStore) GetRouteByID(arg0 context.Context, arg1 uuid.UUID) (db.Route, error) {
 	m.ctrl.T.Helper()
@@ -138,6 +495,51 @@ func (mr *MockStoreMockRecorder) GetRouteByID(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRouteByID", reflect.TypeOf((*MockStore)(nil).GetRouteByID), arg0, arg1)
 }
 
+// GetRouteForUpdate mocks base method.
+func (m *MockStore) GetRouteForUpdate(arg0 context.Context, arg1 uuid.UUID) (db.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRouteForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRouteForUpdate indicates an expected call of GetRouteForUpdate.
+func (mr *MockStoreMockRecorder) GetRouteForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRouteForUpdate", reflect.TypeOf((*MockStore)(nil).GetRouteForUpdate), arg0, arg1)
+}
+
+// GetRoutePath mocks base method.
+func (m *MockStore) GetRoutePath(arg0 context.Context, arg1 uuid.UUID) (db.RoutePath, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoutePath", arg0, arg1)
+	ret0, _ := ret[0].(db.RoutePath)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoutePath indicates an expected call of GetRoutePath.
+func (mr *MockStoreMockRecorder) GetRoutePath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutePath", reflect.TypeOf((*MockStore)(nil).GetRoutePath), arg0, arg1)
+}
+
+// GetRouteStop mocks base method.
+func (m *MockStore) GetRouteStop(arg0 context.Context, arg1 db.GetRouteStopParams) (db.RouteStop, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRouteStop", arg0, arg1)
+	ret0, _ := ret[0].(db.RouteStop)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRouteStop indicates an expected call of GetRouteStop.
+func (mr *MockStoreMockRecorder) GetRouteStop(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRouteStop", reflect.TypeOf((*MockStore)(nil).GetRouteStop), arg0, arg1)
+}
+
 // GetRoutesByDriverID mocks base method.
 func (m *MockStore) GetRoutesByDriverID(arg0 context.Context, arg1 db.GetRoutesByDriverIDParams) ([]db.Route, error) {
 	m.ctrl.T.Helper()
@@ -153,6 +555,36 @@ func (mr *MockStoreMockRecorder) GetRoutesByDriverID(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutesByDriverID", reflect.TypeOf((*MockStore)(nil).GetRoutesByDriverID), arg0, arg1)
 }
 
+// GetSession mocks base method.
+func (m *MockStore) GetSession(arg0 context.Context, arg1 uuid.UUID) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockStoreMockRecorder) GetSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockStore)(nil).GetSession), arg0, arg1)
+}
+
+// GetShipment mocks base method.
+func (m *MockStore) GetShipment(arg0 context.Context, arg1 uuid.UUID) (db.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetShipment", arg0, arg1)
+	ret0, _ := ret[0].(db.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetShipment indicates an expected call of GetShipment.
+func (mr *MockStoreMockRecorder) GetShipment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShipment", reflect.TypeOf((*MockStore)(nil).GetShipment), arg0, arg1)
+}
+
 // GetUserByEmail mocks base method.
 func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (db.User, error) {
 	m.ctrl.T.Helper()
@@ -183,6 +615,21 @@ func (mr *MockStoreMockRecorder) GetUserByID(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockStore)(nil).GetUserByID), arg0, arg1)
 }
 
+// GetUserForUpdate mocks base method.
+func (m *MockStore) GetUserForUpdate(arg0 context.Context, arg1 uuid.UUID) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserForUpdate indicates an expected call of GetUserForUpdate.
+func (mr *MockStoreMockRecorder) GetUserForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserForUpdate", reflect.TypeOf((*MockStore)(nil).GetUserForUpdate), arg0, arg1)
+}
+
 // GetVehicleByID mocks base method.
 func (m *MockStore) GetVehicleByID(arg0 context.Context, arg1 uuid.UUID) (db.Vehicle, error) {
 	m.ctrl.T.Helper()
@@ -213,6 +660,21 @@ func (mr *MockStoreMockRecorder) GetVehicleByLicensePlate(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicleByLicensePlate", reflect.TypeOf((*MockStore)(nil).GetVehicleByLicensePlate), arg0, arg1)
 }
 
+// GetVehicleForUpdate mocks base method.
+func (m *MockStore) GetVehicleForUpdate(arg0 context.Context, arg1 uuid.UUID) (db.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVehicleForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVehicleForUpdate indicates an expected call of GetVehicleForUpdate.
+func (mr *MockStoreMockRecorder) GetVehicleForUpdate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehicleForUpdate", reflect.TypeOf((*MockStore)(nil).GetVehicleForUpdate), arg0, arg1)
+}
+
 // GetVehiclesByDriverID mocks base method.
 func (m *MockStore) GetVehiclesByDriverID(arg0 context.Context, arg1 db.GetVehiclesByDriverIDParams) ([]db.Vehicle, error) {
 	m.ctrl.T.Helper()
@@ -228,6 +690,231 @@ func (mr *MockStoreMockRecorder) GetVehiclesByDriverID(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVehiclesByDriverID", reflect.TypeOf((*MockStore)(nil).GetVehiclesByDriverID), arg0, arg1)
 }
 
+// ListActiveSessions mocks base method.
+func (m *MockStore) ListActiveSessions(arg0 context.Context, arg1 uuid.UUID) ([]db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveSessions", arg0, arg1)
+	ret0, _ := ret[0].([]db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveSessions indicates an expected call of ListActiveSessions.
+func (mr *MockStoreMockRecorder) ListActiveSessions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveSessions", reflect.TypeOf((*MockStore)(nil).ListActiveSessions), arg0, arg1)
+}
+
+// ListCorrectionSamples mocks base method.
+func (m *MockStore) ListCorrectionSamples(arg0 context.Context, arg1 db.ListCorrectionSamplesParams) ([]db.ListCorrectionSamplesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCorrectionSamples", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListCorrectionSamplesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCorrectionSamples indicates an expected call of ListCorrectionSamples.
+func (mr *MockStoreMockRecorder) ListCorrectionSamples(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCorrectionSamples", reflect.TypeOf((*MockStore)(nil).ListCorrectionSamples), arg0, arg1)
+}
+
+// ListDepots mocks base method.
+func (m *MockStore) ListDepots(arg0 context.Context, arg1 db.ListDepotsParams) ([]db.Depot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDepots", arg0, arg1)
+	ret0, _ := ret[0].([]db.Depot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDepots indicates an expected call of ListDepots.
+func (mr *MockStoreMockRecorder) ListDepots(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDepots", reflect.TypeOf((*MockStore)(nil).ListDepots), arg0, arg1)
+}
+
+// ListDeviationEvents mocks base method.
+func (m *MockStore) ListDeviationEvents(arg0 context.Context, arg1 db.ListDeviationEventsParams) ([]db.DeviationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeviationEvents", arg0, arg1)
+	ret0, _ := ret[0].([]db.DeviationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeviationEvents indicates an expected call of ListDeviationEvents.
+func (mr *MockStoreMockRecorder) ListDeviationEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeviationEvents", reflect.TypeOf((*MockStore)(nil).ListDeviationEvents), arg0, arg1)
+}
+
+// ListETAAccuracyByDistanceBand mocks base method.
+func (m *MockStore) ListETAAccuracyByDistanceBand(arg0 context.Context, arg1 db.ListETAAccuracyByDistanceBandParams) ([]db.ListETAAccuracyByDistanceBandRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListETAAccuracyByDistanceBand", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListETAAccuracyByDistanceBandRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListETAAccuracyByDistanceBand indicates an expected call of ListETAAccuracyByDistanceBand.
+func (mr *MockStoreMockRecorder) ListETAAccuracyByDistanceBand(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListETAAccuracyByDistanceBand", reflect.TypeOf((*MockStore)(nil).ListETAAccuracyByDistanceBand), arg0, arg1)
+}
+
+// ListETAAccuracyByDriver mocks base method.
+func (m *MockStore) ListETAAccuracyByDriver(arg0 context.Context, arg1 db.ListETAAccuracyByDriverParams) ([]db.ListETAAccuracyByDriverRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListETAAccuracyByDriver", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListETAAccuracyByDriverRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListETAAccuracyByDriver indicates an expected call of ListETAAccuracyByDriver.
+func (mr *MockStoreMockRecorder) ListETAAccuracyByDriver(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListETAAccuracyByDriver", reflect.TypeOf((*MockStore)(nil).ListETAAccuracyByDriver), arg0, arg1)
+}
+
+// ListETAAccuracyByHour mocks base method.
+func (m *MockStore) ListETAAccuracyByHour(arg0 context.Context, arg1 db.ListETAAccuracyByHourParams) ([]db.ListETAAccuracyByHourRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListETAAccuracyByHour", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListETAAccuracyByHourRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListETAAccuracyByHour indicates an expected call of ListETAAccuracyByHour.
+func (mr *MockStoreMockRecorder) ListETAAccuracyByHour(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListETAAccuracyByHour", reflect.TypeOf((*MockStore)(nil).ListETAAccuracyByHour), arg0, arg1)
+}
+
+// ListETAAccuracyByVehicle mocks base method.
+func (m *MockStore) ListETAAccuracyByVehicle(arg0 context.Context, arg1 db.ListETAAccuracyByVehicleParams) ([]db.ListETAAccuracyByVehicleRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListETAAccuracyByVehicle", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListETAAccuracyByVehicleRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListETAAccuracyByVehicle indicates an expected call of ListETAAccuracyByVehicle.
+func (mr *MockStoreMockRecorder) ListETAAccuracyByVehicle(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListETAAccuracyByVehicle", reflect.TypeOf((*MockStore)(nil).ListETAAccuracyByVehicle), arg0, arg1)
+}
+
+// ListFleetVehicles mocks base method.
+func (m *MockStore) ListFleetVehicles(arg0 context.Context) ([]db.Vehicle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFleetVehicles", arg0)
+	ret0, _ := ret[0].([]db.Vehicle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFleetVehicles indicates an expected call of ListFleetVehicles.
+func (mr *MockStoreMockRecorder) ListFleetVehicles(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFleetVehicles", reflect.TypeOf((*MockStore)(nil).ListFleetVehicles), arg0)
+}
+
+// ListGeofenceEvents mocks base method.
+func (m *MockStore) ListGeofenceEvents(arg0 context.Context, arg1 db.ListGeofenceEventsParams) ([]db.GeofenceEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGeofenceEvents", arg0, arg1)
+	ret0, _ := ret[0].([]db.GeofenceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGeofenceEvents indicates an expected call of ListGeofenceEvents.
+func (mr *MockStoreMockRecorder) ListGeofenceEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGeofenceEvents", reflect.TypeOf((*MockStore)(nil).ListGeofenceEvents), arg0, arg1)
+}
+
+// ListRouteEtaHistory mocks base method.
+func (m *MockStore) ListRouteEtaHistory(arg0 context.Context, arg1 db.ListRouteEtaHistoryParams) ([]db.RouteEtaHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRouteEtaHistory", arg0, arg1)
+	ret0, _ := ret[0].([]db.RouteEtaHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRouteEtaHistory indicates an expected call of ListRouteEtaHistory.
+func (mr *MockStoreMockRecorder) ListRouteEtaHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRouteEtaHistory", reflect.TypeOf((*MockStore)(nil).ListRouteEtaHistory), arg0, arg1)
+}
+
+// ListRouteLocations mocks base method.
+func (m *MockStore) ListRouteLocations(arg0 context.Context, arg1 db.ListRouteLocationsParams) ([]db.RouteLocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRouteLocations", arg0, arg1)
+	ret0, _ := ret[0].([]db.RouteLocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRouteLocations indicates an expected call of ListRouteLocations.
+func (mr *MockStoreMockRecorder) ListRouteLocations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRouteLocations", reflect.TypeOf((*MockStore)(nil).ListRouteLocations), arg0, arg1)
+}
+
+// ListRouteLocationsSince mocks base method.
+func (m *MockStore) ListRouteLocationsSince(arg0 context.Context, arg1 db.ListRouteLocationsSinceParams) ([]db.RouteLocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRouteLocationsSince", arg0, arg1)
+	ret0, _ := ret[0].([]db.RouteLocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRouteLocationsSince indicates an expected call of ListRouteLocationsSince.
+func (mr *MockStoreMockRecorder) ListRouteLocationsSince(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRouteLocationsSince", reflect.TypeOf((*MockStore)(nil).ListRouteLocationsSince), arg0, arg1)
+}
+
+// ListRouteShipmentIDsByCustomer mocks base method.
+func (m *MockStore) ListRouteShipmentIDsByCustomer(arg0 context.Context, arg1 db.ListRouteShipmentIDsByCustomerParams) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRouteShipmentIDsByCustomer", arg0, arg1)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRouteShipmentIDsByCustomer indicates an expected call of ListRouteShipmentIDsByCustomer.
+func (mr *MockStoreMockRecorder) ListRouteShipmentIDsByCustomer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRouteShipmentIDsByCustomer", reflect.TypeOf((*MockStore)(nil).ListRouteShipmentIDsByCustomer), arg0, arg1)
+}
+
+// ListRouteStops mocks base method.
+func (m *MockStore) ListRouteStops(arg0 context.Context, arg1 uuid.UUID) ([]db.RouteStop, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRouteStops", arg0, arg1)
+	ret0, _ := ret[0].([]db.RouteStop)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRouteStops indicates an expected call of ListRouteStops.
+func (mr *MockStoreMockRecorder) ListRouteStops(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRouteStops", reflect.TypeOf((*MockStore)(nil).ListRouteStops), arg0, arg1)
+}
+
 // ListRoutesByDriverAndStatus mocks base method.
 func (m *MockStore) ListRoutesByDriverAndStatus(arg0 context.Context, arg1 db.ListRoutesByDriverAndStatusParams) ([]db.Route, error) {
 	m.ctrl.T.Helper()
@@ -243,6 +930,51 @@ func (mr *MockStoreMockRecorder) ListRoutesByDriverAndStatus(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRoutesByDriverAndStatus", reflect.TypeOf((*MockStore)(nil).ListRoutesByDriverAndStatus), arg0, arg1)
 }
 
+// ListShipmentsByCustomer mocks base method.
+func (m *MockStore) ListShipmentsByCustomer(arg0 context.Context, arg1 db.ListShipmentsByCustomerParams) ([]db.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListShipmentsByCustomer", arg0, arg1)
+	ret0, _ := ret[0].([]db.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListShipmentsByCustomer indicates an expected call of ListShipmentsByCustomer.
+func (mr *MockStoreMockRecorder) ListShipmentsByCustomer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListShipmentsByCustomer", reflect.TypeOf((*MockStore)(nil).ListShipmentsByCustomer), arg0, arg1)
+}
+
+// ListSpeedProfileSamples mocks base method.
+func (m *MockStore) ListSpeedProfileSamples(arg0 context.Context, arg1 db.ListSpeedProfileSamplesParams) ([]db.ListSpeedProfileSamplesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSpeedProfileSamples", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListSpeedProfileSamplesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSpeedProfileSamples indicates an expected call of ListSpeedProfileSamples.
+func (mr *MockStoreMockRecorder) ListSpeedProfileSamples(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSpeedProfileSamples", reflect.TypeOf((*MockStore)(nil).ListSpeedProfileSamples), arg0, arg1)
+}
+
+// ListUnplannedShipments mocks base method.
+func (m *MockStore) ListUnplannedShipments(arg0 context.Context, arg1 db.ListUnplannedShipmentsParams) ([]db.Shipment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnplannedShipments", arg0, arg1)
+	ret0, _ := ret[0].([]db.Shipment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnplannedShipments indicates an expected call of ListUnplannedShipments.
+func (mr *MockStoreMockRecorder) ListUnplannedShipments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnplannedShipments", reflect.TypeOf((*MockStore)(nil).ListUnplannedShipments), arg0, arg1)
+}
+
 // ListUsers mocks base method.
 func (m *MockStore) ListUsers(arg0 context.Context, arg1 db.ListUsersParams) ([]db.User, error) {
 	m.ctrl.T.Helper()
@@ -258,6 +990,110 @@ func (mr *MockStoreMockRecorder) ListUsers(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockStore)(nil).ListUsers), arg0, arg1)
 }
 
+// ReplaceRouteStopsTx mocks base method.
+func (m *MockStore) ReplaceRouteStopsTx(arg0 context.Context, arg1 db.ReplaceRouteStopsTxParams) (db.ReplaceRouteStopsTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRouteStopsTx", arg0, arg1)
+	ret0, _ := ret[0].(db.ReplaceRouteStopsTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplaceRouteStopsTx indicates an expected call of ReplaceRouteStopsTx.
+func (mr *MockStoreMockRecorder) ReplaceRouteStopsTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRouteStopsTx", reflect.TypeOf((*MockStore)(nil).ReplaceRouteStopsTx), arg0, arg1)
+}
+
+// RevokeSession mocks base method.
+func (m *MockStore) RevokeSession(arg0 context.Context, arg1 db.RevokeSessionParams) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockStoreMockRecorder) RevokeSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockStore)(nil).RevokeSession), arg0, arg1)
+}
+
+// RevokeUserSessions mocks base method.
+func (m *MockStore) RevokeUserSessions(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSessions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserSessions indicates an expected call of RevokeUserSessions.
+func (mr *MockStoreMockRecorder) RevokeUserSessions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSessions", reflect.TypeOf((*MockStore)(nil).RevokeUserSessions), arg0, arg1)
+}
+
+// SearchUsers mocks base method.
+func (m *MockStore) SearchUsers(arg0 context.Context, arg1 db.SearchUsersParams) ([]db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", arg0, arg1)
+	ret0, _ := ret[0].([]db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockStoreMockRecorder) SearchUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockStore)(nil).SearchUsers), arg0, arg1)
+}
+
+// StartRouteTx mocks base method.
+func (m *MockStore) StartRouteTx(arg0 context.Context, arg1 db.StartRouteTxParams) (db.StartRouteTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartRouteTx", arg0, arg1)
+	ret0, _ := ret[0].(db.StartRouteTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartRouteTx indicates an expected call of StartRouteTx.
+func (mr *MockStoreMockRecorder) StartRouteTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartRouteTx", reflect.TypeOf((*MockStore)(nil).StartRouteTx), arg0, arg1)
+}
+
+// TransitionRouteStatus mocks base method.
+func (m *MockStore) TransitionRouteStatus(arg0 context.Context, arg1 db.TransitionRouteStatusParams) (db.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionRouteStatus", arg0, arg1)
+	ret0, _ := ret[0].(db.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitionRouteStatus indicates an expected call of TransitionRouteStatus.
+func (mr *MockStoreMockRecorder) TransitionRouteStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionRouteStatus", reflect.TypeOf((*MockStore)(nil).TransitionRouteStatus), arg0, arg1)
+}
+
+// TransitionRouteStopStatus mocks base method.
+func (m *MockStore) TransitionRouteStopStatus(arg0 context.Context, arg1 db.TransitionRouteStopStatusParams) (db.RouteStop, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionRouteStopStatus", arg0, arg1)
+	ret0, _ := ret[0].(db.RouteStop)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitionRouteStopStatus indicates an expected call of TransitionRouteStopStatus.
+func (mr *MockStoreMockRecorder) TransitionRouteStopStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionRouteStopStatus", reflect.TypeOf((*MockStore)(nil).TransitionRouteStopStatus), arg0, arg1)
+}
+
 // UpdateRouteActualDuration mocks base method.
 func (m *MockStore) UpdateRouteActualDuration(arg0 context.Context, arg1 db.UpdateRouteActualDurationParams) (db.Route, error) {
 	m.ctrl.T.Helper()
@@ -273,6 +1109,36 @@ func (mr *MockStoreMockRecorder) UpdateRouteActualDuration(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRouteActualDuration", reflect.TypeOf((*MockStore)(nil).UpdateRouteActualDuration), arg0, arg1)
 }
 
+// UpdateRouteEta mocks base method.
+func (m *MockStore) UpdateRouteEta(arg0 context.Context, arg1 db.UpdateRouteEtaParams) (db.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRouteEta", arg0, arg1)
+	ret0, _ := ret[0].(db.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRouteEta indicates an expected call of UpdateRouteEta.
+func (mr *MockStoreMockRecorder) UpdateRouteEta(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRouteEta", reflect.TypeOf((*MockStore)(nil).UpdateRouteEta), arg0, arg1)
+}
+
+// UpdateRoutePlan mocks base method.
+func (m *MockStore) UpdateRoutePlan(arg0 context.Context, arg1 db.UpdateRoutePlanParams) (db.Route, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRoutePlan", arg0, arg1)
+	ret0, _ := ret[0].(db.Route)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRoutePlan indicates an expected call of UpdateRoutePlan.
+func (mr *MockStoreMockRecorder) UpdateRoutePlan(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoutePlan", reflect.TypeOf((*MockStore)(nil).UpdateRoutePlan), arg0, arg1)
+}
+
 // UpdateRouteStatus mocks base method.
 func (m *MockStore) UpdateRouteStatus(arg0 context.Context, arg1 db.UpdateRouteStatusParams) (db.Route, error) {
 	m.ctrl.T.Helper()
@@ -318,6 +1184,21 @@ func (mr *MockStoreMockRecorder) UpdateUserPartial(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPartial", reflect.TypeOf((*MockStore)(nil).UpdateUserPartial), arg0, arg1)
 }
 
+// UpdateUserRoleTx mocks base method.
+func (m *MockStore) UpdateUserRoleTx(arg0 context.Context, arg1 db.UpdateUserRoleTxParams) (db.UpdateUserRoleTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRoleTx", arg0, arg1)
+	ret0, _ := ret[0].(db.UpdateUserRoleTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserRoleTx indicates an expected call of UpdateUserRoleTx.
+func (mr *MockStoreMockRecorder) UpdateUserRoleTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRoleTx", reflect.TypeOf((*MockStore)(nil).UpdateUserRoleTx), arg0, arg1)
+}
+
 // UpdateVehicle mocks base method.
 func (m *MockStore) UpdateVehicle(arg0 context.Context, arg1 db.UpdateVehicleParams) (db.Vehicle, error) {
 	m.ctrl.T.Helper()
@@ -332,3 +1213,17 @@ func (mr *MockStoreMockRecorder) UpdateVehicle(arg0, arg1 interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVehicle", reflect.TypeOf((*MockStore)(nil).UpdateVehicle), arg0, arg1)
 }
+
+// UpsertRoutePath mocks base method.
+func (m *MockStore) UpsertRoutePath(arg0 context.Context, arg1 db.UpsertRoutePathParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertRoutePath", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertRoutePath indicates an expected call of UpsertRoutePath.
+func (mr *MockStoreMockRecorder) UpsertRoutePath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertRoutePath", reflect.TypeOf((*MockStore)(nil).UpsertRoutePath), arg0, arg1)
+}
@@ -0,0 +1,12 @@
+-- name: CreateDepot :one
+INSERT INTO depots (id, name, address, lat, lng)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetDepot :one
+SELECT * FROM depots WHERE id = $1;
+
+-- name: ListDepots :many
+SELECT * FROM depots
+ORDER BY name, id
+LIMIT $1 OFFSET $2;
@@ -0,0 +1,22 @@
+-- name: CreateDeviationEvent :one
+INSERT INTO deviation_events (
+    route_id,
+    transition,
+    distance_m,
+    location_id,
+    occurred_at
+)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetLatestDeviationEvent :one
+SELECT * FROM deviation_events
+WHERE route_id = $1
+ORDER BY occurred_at DESC, id DESC
+LIMIT 1;
+
+-- name: ListDeviationEvents :many
+SELECT * FROM deviation_events
+WHERE route_id = $1
+ORDER BY occurred_at DESC, id DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,24 @@
+-- name: CreateGeofenceEvent :one
+INSERT INTO geofence_events (
+    route_id,
+    place,
+    stop_id,
+    transition,
+    location_id,
+    occurred_at
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetLatestGeofenceEvent :one
+SELECT * FROM geofence_events
+WHERE route_id = $1
+AND place = $2
+ORDER BY occurred_at DESC, id DESC
+LIMIT 1;
+
+-- name: ListGeofenceEvents :many
+SELECT * FROM geofence_events
+WHERE route_id = $1
+ORDER BY occurred_at DESC, id DESC
+LIMIT $2 OFFSET $3;
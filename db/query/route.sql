@@ -11,13 +11,21 @@ INSERT INTO routes (
     destination_lng,
     estimated_distance_km,
     estimated_duration_min,
+    baseline_duration_min,
+    duration_p10_min,
+    duration_p50_min,
+    duration_p90_min,
+    origin_geofence_radius_m,
+    destination_geofence_radius_m,
     status
 )
 VALUES (
     $1, $2, $3,
     $4, $5, $6,
     $7, $8, $9,
-    $10, $11, $12
+    $10, $11, $12,
+    $13, $14, $15,
+    $16, $17, $18
 )
 RETURNING *;
 
@@ -25,6 +33,15 @@ RETURNING *;
 -- name: GetRouteByID :one
 SELECT * FROM routes WHERE id = $1;
 
+-- name: GetRouteForUpdate :one
+SELECT * FROM routes WHERE id = $1 FOR NO KEY UPDATE;
+
+-- name: GetActiveRouteByVehicle :one
+SELECT * FROM routes
+WHERE vehicle_id = $1
+AND status = 'in_progress'
+LIMIT 1;
+
 -- name: GetRoutesByDriverID :many
 SELECT * FROM routes
 WHERE driver_id = $1
@@ -49,6 +66,9 @@ RETURNING *; -- when the route is completed
 -- name: DeleteRoute :exec
 DELETE FROM routes WHERE id = $1;
 
+-- name: CountRoutesByDriver :one
+SELECT COUNT(*) FROM routes WHERE driver_id = $1;
+
 -- name: ListRoutesByDriverAndStatus :many
 SELECT * FROM routes
 WHERE driver_id= $1
@@ -57,3 +77,244 @@ ORDER BY created_at DESC
 LIMIT $3 OFFSET $4;
 
 
+
+-- name: TransitionRouteStatus :one
+UPDATE routes
+SET status = @to_status::text,
+    started_at = CASE WHEN @to_status::text = 'in_progress' THEN sqlc.arg(at)::timestamptz ELSE started_at END,
+    completed_at = CASE WHEN @to_status::text = 'completed' THEN sqlc.arg(at)::timestamptz ELSE completed_at END,
+    cancelled_at = CASE WHEN @to_status::text = 'cancelled' THEN sqlc.arg(at)::timestamptz ELSE cancelled_at END,
+    actual_duration_min = CASE
+        WHEN @to_status::text = 'completed' AND started_at IS NOT NULL
+        THEN EXTRACT(EPOCH FROM (sqlc.arg(at)::timestamptz - started_at)) / 60
+        ELSE actual_duration_min
+    END,
+    updated_at = NOW()
+WHERE id = @id
+AND status = @from_status::text
+RETURNING *;
+
+-- name: UpdateRouteEta :one
+UPDATE routes
+SET remaining_distance_km = $2,
+    remaining_duration_min = $3,
+    eta_at = $4,
+    eta_confidence = $5,
+    eta_updated_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateRoutePlan :one
+UPDATE routes
+SET estimated_distance_km = $2,
+    estimated_duration_min = $3,
+    baseline_duration_min = $4,
+    duration_p10_min = $5,
+    duration_p50_min = $6,
+    duration_p90_min = $7,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListSpeedProfileSamples :many
+-- Routes completed since the given time, with the free-flow estimate their
+-- actual duration compares to. Routes planned before baselines were stored
+-- had no traffic adjustment, so their estimate is their baseline.
+SELECT
+    origin_lat,
+    origin_lng,
+    started_at::timestamptz AS started_at,
+    COALESCE(baseline_duration_min, estimated_duration_min)::float8 AS baseline_duration_min,
+    actual_duration_min::float8 AS actual_duration_min
+FROM routes
+WHERE status = 'completed'
+AND completed_at >= @since::timestamptz
+AND started_at IS NOT NULL
+AND COALESCE(baseline_duration_min, estimated_duration_min) > 0
+AND actual_duration_min > 0
+ORDER BY completed_at DESC
+LIMIT @max_samples::int;
+
+-- name: ListCorrectionSamples :many
+-- Routes completed since the given time with what the correction model
+-- learns from: the trip, its driver and vehicle, the estimate it was
+-- planned with, its free-flow baseline and how long it actually took.
+SELECT
+    r.driver_id,
+    COALESCE(v.model, '')::text AS vehicle_model,
+    r.origin_lat,
+    r.origin_lng,
+    r.destination_lat,
+    r.destination_lng,
+    r.started_at::timestamptz AS started_at,
+    r.estimated_distance_km::float8 AS estimated_distance_km,
+    r.estimated_duration_min::float8 AS estimated_duration_min,
+    COALESCE(r.baseline_duration_min, r.estimated_duration_min)::float8 AS baseline_duration_min,
+    r.actual_duration_min::float8 AS actual_duration_min
+FROM routes r
+JOIN vehicles v ON v.id = r.vehicle_id
+WHERE r.status = 'completed'
+AND r.completed_at >= @since::timestamptz
+AND r.started_at IS NOT NULL
+AND r.estimated_distance_km IS NOT NULL
+AND r.estimated_duration_min > 0
+AND r.actual_duration_min > 0
+ORDER BY r.completed_at DESC
+LIMIT @max_samples::int;
+
+-- name: GetETAAccuracy :one
+-- ETA error statistics of the routes completed in [completed_from,
+-- completed_to). Errors are actual minus estimated duration, so a positive
+-- bias means routes take longer than estimated; percentages are of the
+-- actual duration.
+WITH errors AS (
+    SELECT
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= @completed_from::timestamptz
+    AND r.completed_at < @completed_to::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+)
+SELECT
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e;
+
+-- name: ListETAAccuracyByDriver :many
+WITH errors AS (
+    SELECT
+        r.driver_id,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= @completed_from::timestamptz
+    AND r.completed_at < @completed_to::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+)
+SELECT
+    e.driver_id,
+    u.name AS driver_name,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+JOIN users u ON u.id = e.driver_id
+GROUP BY e.driver_id, u.name
+ORDER BY trips DESC, e.driver_id;
+
+-- name: ListETAAccuracyByVehicle :many
+WITH errors AS (
+    SELECT
+        r.vehicle_id,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= @completed_from::timestamptz
+    AND r.completed_at < @completed_to::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+)
+SELECT
+    e.vehicle_id,
+    v.license_plate,
+    COALESCE(v.model, '')::text AS vehicle_model,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+JOIN vehicles v ON v.id = e.vehicle_id
+GROUP BY e.vehicle_id, v.license_plate, v.model
+ORDER BY trips DESC, e.vehicle_id;
+
+-- name: ListETAAccuracyByHour :many
+-- Routes by the hour of the day they started in the given time zone.
+WITH errors AS (
+    SELECT
+        EXTRACT(HOUR FROM r.started_at AT TIME ZONE @timezone::text)::int AS hour,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= @completed_from::timestamptz
+    AND r.completed_at < @completed_to::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+    AND r.started_at IS NOT NULL
+)
+SELECT
+    e.hour,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+GROUP BY e.hour
+ORDER BY e.hour;
+
+-- name: ListETAAccuracyByDistanceBand :many
+-- Routes by band of estimated distance, each band named by the distance it
+-- starts at. The bands are eta.DistanceBandsKm.
+WITH errors AS (
+    SELECT
+        CASE
+            WHEN r.estimated_distance_km < 5 THEN 0
+            WHEN r.estimated_distance_km < 20 THEN 5
+            WHEN r.estimated_distance_km < 50 THEN 20
+            WHEN r.estimated_distance_km < 100 THEN 50
+            ELSE 100
+        END::float8 AS min_distance_km,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= @completed_from::timestamptz
+    AND r.completed_at < @completed_to::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+    AND r.estimated_distance_km IS NOT NULL
+)
+SELECT
+    e.min_distance_km,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+GROUP BY e.min_distance_km
+ORDER BY e.min_distance_km;
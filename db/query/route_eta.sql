@@ -0,0 +1,17 @@
+-- name: CreateRouteEtaHistory :one
+INSERT INTO route_eta_history (
+    route_id,
+    location_id,
+    remaining_distance_km,
+    remaining_duration_min,
+    eta_at,
+    confidence
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListRouteEtaHistory :many
+SELECT * FROM route_eta_history
+WHERE route_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,34 @@
+-- name: CreateRouteLocation :one
+INSERT INTO route_locations (
+    route_id,
+    driver_id,
+    lat,
+    lng,
+    speed_kmh,
+    heading_deg,
+    accuracy_m,
+    recorded_at
+)
+VALUES (
+    $1, $2, $3, $4,
+    $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetLatestRouteLocation :one
+SELECT * FROM route_locations
+WHERE route_id = $1
+ORDER BY recorded_at DESC
+LIMIT 1;
+
+-- name: ListRouteLocations :many
+SELECT * FROM route_locations
+WHERE route_id = $1
+ORDER BY recorded_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListRouteLocationsSince :many
+SELECT * FROM route_locations
+WHERE route_id = $1
+AND recorded_at >= $2
+ORDER BY recorded_at ASC;
@@ -0,0 +1,14 @@
+-- name: UpsertRoutePath :exec
+INSERT INTO route_paths (route_id, points)
+VALUES ($1, $2)
+ON CONFLICT (route_id) DO UPDATE
+SET points = EXCLUDED.points,
+    created_at = NOW();
+
+-- name: GetRoutePath :one
+SELECT * FROM route_paths
+WHERE route_id = $1;
+
+-- name: DeleteRoutePath :exec
+DELETE FROM route_paths
+WHERE route_id = $1;
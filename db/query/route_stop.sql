@@ -0,0 +1,40 @@
+-- name: CreateRouteStop :one
+INSERT INTO route_stops (
+    route_id,
+    shipment_id,
+    sequence,
+    lat,
+    lng,
+    address,
+    service_time_min,
+    window_start,
+    window_end,
+    geofence_radius_m
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetRouteStop :one
+SELECT * FROM route_stops
+WHERE id = $1
+AND route_id = $2;
+
+-- name: ListRouteStops :many
+SELECT * FROM route_stops
+WHERE route_id = $1
+ORDER BY sequence;
+
+-- name: DeleteRouteStops :exec
+DELETE FROM route_stops WHERE route_id = $1;
+
+-- name: TransitionRouteStopStatus :one
+UPDATE route_stops
+SET status = @to_status::text,
+    arrived_at = CASE WHEN @to_status::text IN ('arrived', 'completed') THEN COALESCE(arrived_at, sqlc.arg(at)::timestamptz) ELSE arrived_at END,
+    departed_at = CASE WHEN @to_status::text IN ('completed', 'failed') THEN sqlc.arg(at)::timestamptz ELSE departed_at END,
+    failure_reason = sqlc.narg('failure_reason'),
+    updated_at = NOW()
+WHERE id = @id
+AND route_id = @route_id
+AND status = @from_status::text
+RETURNING *;
@@ -0,0 +1,58 @@
+-- name: CreateShipment :one
+INSERT INTO shipments (
+    id,
+    customer_id,
+    pickup_lat,
+    pickup_lng,
+    pickup_address,
+    dropoff_lat,
+    dropoff_lng,
+    dropoff_address,
+    weight_kg,
+    volume_m3,
+    window_start,
+    window_end
+)
+VALUES (
+    $1, $2,
+    $3, $4, $5,
+    $6, $7, $8,
+    $9, $10,
+    $11, $12
+)
+RETURNING *;
+
+-- name: GetShipment :one
+SELECT * FROM shipments WHERE id = $1;
+
+-- name: ListShipmentsByCustomer :many
+SELECT * FROM shipments
+WHERE customer_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListRouteShipmentIDsByCustomer :many
+SELECT id FROM shipments
+WHERE route_id = $1 AND customer_id = $2
+ORDER BY id;
+
+-- name: AssignShipmentRoute :one
+UPDATE shipments
+SET route_id = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListUnplannedShipments :many
+-- Shipments without a route whose window, if any, overlaps [since, until)
+SELECT * FROM shipments
+WHERE route_id IS NULL
+AND (window_start IS NULL OR window_start < @until::timestamptz)
+AND (window_end IS NULL OR window_end > @since::timestamptz)
+ORDER BY created_at, id
+LIMIT @max_shipments::int;
+
+-- name: ClaimShipment :one
+UPDATE shipments
+SET route_id = $2, updated_at = NOW()
+WHERE id = $1 AND route_id IS NULL
+RETURNING *;
@@ -9,6 +9,9 @@ SELECT * FROM users WHERE id = $1;
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: GetUserForUpdate :one
+SELECT * FROM users WHERE id = $1 FOR UPDATE;
+
 -- name: UpdateUser :one
 UPDATE users
 SET name = $2, email = $3, password_hash = $4, role = $5, updated_at = NOW()
@@ -27,6 +30,16 @@ SET
   name = COALESCE(sqlc.narg('name'), name),
   email = COALESCE(sqlc.narg('email'), email),
   password_hash = COALESCE(sqlc.narg('password_hash'), password_hash),
-  role = COALESCE(sqlc.narg('role'), role)
+  role = COALESCE(sqlc.narg('role'), role),
+  updated_at = NOW()
 WHERE id = sqlc.arg('id')
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg('search')::text IS NULL
+    OR name ILIKE '%' || sqlc.narg('search') || '%'
+    OR email ILIKE '%' || sqlc.narg('search') || '%')
+AND (sqlc.narg('role')::text IS NULL OR role = sqlc.narg('role'))
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
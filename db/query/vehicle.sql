@@ -24,4 +24,24 @@ RETURNING *;
 
 
 -- name: DeleteVehicle :exec
-DELETE FROM vehicles WHERE id = $1;
\ No newline at end of file
+DELETE FROM vehicles WHERE id = $1;
+
+-- name: GetVehicleForUpdate :one
+SELECT * FROM vehicles WHERE id = $1 FOR NO KEY UPDATE;
+
+-- name: ListFleetVehicles :many
+-- Vehicles free to take a dispatched route: they have a payload capacity, and
+-- neither they nor their driver are on a route that hasn't finished. Drivers
+-- with several of them are offered their oldest one only.
+SELECT * FROM vehicles
+WHERE id IN (
+    SELECT DISTINCT ON (v.driver_id) v.id FROM vehicles v
+    WHERE v.capacity IS NOT NULL AND v.capacity > 0
+      AND NOT EXISTS (
+        SELECT 1 FROM routes r
+        WHERE (r.vehicle_id = v.id OR r.driver_id = v.driver_id)
+          AND r.status IN ('pending', 'in_progress')
+      )
+    ORDER BY v.driver_id, v.created_at, v.id
+)
+ORDER BY created_at, id;
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomDepot(t *testing.T) Depot {
+	arg := CreateDepotParams{
+		ID:      uuid.New(),
+		Name:    util.RandomString(8),
+		Address: sql.NullString{String: "1 Harbour rd", Valid: true},
+		Lat:     37.7949,
+		Lng:     -122.3994,
+	}
+
+	depot, err := testQueries.CreateDepot(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, depot.ID)
+	require.Equal(t, arg.Name, depot.Name)
+	require.Equal(t, arg.Address, depot.Address)
+	require.Equal(t, arg.Lat, depot.Lat)
+	require.Equal(t, arg.Lng, depot.Lng)
+	require.NotZero(t, depot.CreatedAt)
+	return depot
+}
+
+func TestCreateDepot(t *testing.T) {
+	createRandomDepot(t)
+}
+
+func TestGetDepot(t *testing.T) {
+	depot1 := createRandomDepot(t)
+
+	depot2, err := testQueries.GetDepot(context.Background(), depot1.ID)
+	require.NoError(t, err)
+	require.Equal(t, depot1.ID, depot2.ID)
+	require.Equal(t, depot1.Name, depot2.Name)
+
+	_, err = testQueries.GetDepot(context.Background(), uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListDepots(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		createRandomDepot(t)
+	}
+
+	depots, err := testQueries.ListDepots(context.Background(), ListDepotsParams{Limit: 3, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, depots, 3)
+	for i := 1; i < len(depots); i++ {
+		require.LessOrEqual(t, depots[i-1].Name, depots[i].Name)
+	}
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomDeviationEvent(t *testing.T, route Route, transition string, occurredAt time.Time) DeviationEvent {
+	location := createRandomRouteLocation(t, route, occurredAt)
+	arg := CreateDeviationEventParams{
+		RouteID:    route.ID,
+		Transition: transition,
+		DistanceM:  320,
+		LocationID: sql.NullInt64{Int64: location.ID, Valid: true},
+		OccurredAt: occurredAt,
+	}
+
+	event, err := testQueries.CreateDeviationEvent(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, event.ID)
+
+	require.Equal(t, arg.RouteID, event.RouteID)
+	require.Equal(t, arg.Transition, event.Transition)
+	require.Equal(t, arg.DistanceM, event.DistanceM)
+	require.Equal(t, arg.LocationID, event.LocationID)
+	require.WithinDuration(t, arg.OccurredAt, event.OccurredAt, time.Second)
+	require.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+func TestCreateDeviationEvent(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	createRandomDeviationEvent(t, route, "off_route", time.Now())
+
+	_, err := testQueries.CreateDeviationEvent(context.Background(), CreateDeviationEventParams{
+		RouteID:    route.ID,
+		Transition: "lost",
+		OccurredAt: time.Now(),
+	})
+	require.Error(t, err)
+}
+
+func TestGetLatestDeviationEvent(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	_, err := testQueries.GetLatestDeviationEvent(context.Background(), route.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	now := time.Now()
+	createRandomDeviationEvent(t, route, "off_route", now.Add(-10*time.Minute))
+	back := createRandomDeviationEvent(t, route, "on_route", now.Add(-5*time.Minute))
+
+	event, err := testQueries.GetLatestDeviationEvent(context.Background(), route.ID)
+	require.NoError(t, err)
+	require.Equal(t, back.ID, event.ID)
+}
+
+func TestListDeviationEvents(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		createRandomDeviationEvent(t, route, "off_route", now.Add(-time.Duration(i)*time.Minute))
+	}
+
+	events, err := testQueries.ListDeviationEvents(context.Background(), ListDeviationEventsParams{
+		RouteID: route.ID,
+		Limit:   3,
+		Offset:  0,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for i := 1; i < len(events); i++ {
+		require.True(t, events[i-1].OccurredAt.After(events[i].OccurredAt))
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrShipmentPlanned is returned by CreateDispatchPlanTx when one of the
+// shipments was planned onto another route in the meantime.
+var ErrShipmentPlanned = errors.New("shipment is already planned onto a route")
+
+// DispatchRoute is one draft route of a dispatch plan and its stops, in
+// visiting order.
+type DispatchRoute struct {
+	Route CreateRouteParams `json:"route"`
+	Stops []NewRouteStop    `json:"stops"`
+	// Path is the roads the route follows, nil when it couldn't be planned
+	// along roads
+	Path json.RawMessage `json:"path"`
+}
+
+type CreateDispatchPlanTxParams struct {
+	Routes []DispatchRoute `json:"routes"`
+}
+
+type DispatchRouteResult struct {
+	Route Route       `json:"route"`
+	Stops []RouteStop `json:"stops"`
+}
+
+type CreateDispatchPlanTxResult struct {
+	Routes []DispatchRouteResult `json:"routes"`
+}
+
+// CreateDispatchPlanTx creates every route of the plan with its path and its
+// stops, numbered in order from 1, and puts the shipment of each stop on its
+// route.
+// Either the whole plan is stored or none of it, so a shipment that is no
+// longer unplanned fails the plan with ErrShipmentPlanned.
+func (store *SQLStore) CreateDispatchPlanTx(ctx context.Context, arg CreateDispatchPlanTxParams) (CreateDispatchPlanTxResult, error) {
+	var result CreateDispatchPlanTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		result.Routes = make([]DispatchRouteResult, 0, len(arg.Routes))
+		for _, planned := range arg.Routes {
+			route, err := q.CreateRoute(ctx, planned.Route)
+			if err != nil {
+				return err
+			}
+			if len(planned.Path) > 0 {
+				err = q.UpsertRoutePath(ctx, UpsertRoutePathParams{RouteID: route.ID, Points: planned.Path})
+				if err != nil {
+					return err
+				}
+			}
+
+			stops := make([]RouteStop, 0, len(planned.Stops))
+			for i, stop := range planned.Stops {
+				created, err := q.CreateRouteStop(ctx, CreateRouteStopParams{
+					RouteID:        route.ID,
+					ShipmentID:     stop.ShipmentID,
+					Sequence:       int32(i + 1),
+					Lat:            stop.Lat,
+					Lng:            stop.Lng,
+					Address:        stop.Address,
+					ServiceTimeMin: stop.ServiceTimeMin,
+					WindowStart:    stop.WindowStart,
+					WindowEnd:      stop.WindowEnd,
+				})
+				if err != nil {
+					return err
+				}
+				stops = append(stops, created)
+
+				if !stop.ShipmentID.Valid {
+					continue
+				}
+				_, err = q.ClaimShipment(ctx, ClaimShipmentParams{
+					ID:      stop.ShipmentID.UUID,
+					RouteID: uuid.NullUUID{UUID: route.ID, Valid: true},
+				})
+				if err != nil {
+					if errors.Is(err, sql.ErrNoRows) {
+						return ErrShipmentPlanned
+					}
+					return err
+				}
+			}
+			result.Routes = append(result.Routes, DispatchRouteResult{Route: route, Stops: stops})
+		}
+		return nil
+	})
+	return result, err
+}
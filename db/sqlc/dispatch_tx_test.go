@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func dispatchRoute(driver User, vehicle Vehicle, depot Depot, shipments ...Shipment) DispatchRoute {
+	route := DispatchRoute{
+		Route: CreateRouteParams{
+			ID:                 uuid.New(),
+			DriverID:           driver.ID,
+			VehicleID:          vehicle.ID,
+			OriginAddress:      depot.Address,
+			OriginLat:          depot.Lat,
+			OriginLng:          depot.Lng,
+			DestinationAddress: depot.Address,
+			DestinationLat:     depot.Lat,
+			DestinationLng:     depot.Lng,
+			Status:             "pending",
+		},
+	}
+	for _, shipment := range shipments {
+		route.Stops = append(route.Stops, NewRouteStop{
+			ShipmentID:  uuid.NullUUID{UUID: shipment.ID, Valid: true},
+			Lat:         shipment.DropoffLat,
+			Lng:         shipment.DropoffLng,
+			Address:     shipment.DropoffAddress,
+			WindowStart: shipment.WindowStart,
+			WindowEnd:   shipment.WindowEnd,
+		})
+	}
+	return route
+}
+
+func TestCreateDispatchPlanTx(t *testing.T) {
+	depot := createRandomDepot(t)
+	customer := createRandomUser(t)
+	driver1, driver2 := createRandomUser(t), createRandomUser(t)
+	vehicle1, vehicle2 := createRandomVehicle(t, driver1), createRandomVehicle(t, driver2)
+	shipments := make([]Shipment, 3)
+	for i := range shipments {
+		shipments[i] = createRandomShipment(t, customer)
+	}
+
+	// only the first route could be planned along roads
+	first := dispatchRoute(driver1, vehicle1, depot, shipments[1], shipments[0])
+	first.Path = json.RawMessage(`[{"lat": 6.5, "lng": 3.3}, {"lat": 6.6, "lng": 3.3}]`)
+
+	result, err := testStore.CreateDispatchPlanTx(context.Background(), CreateDispatchPlanTxParams{
+		Routes: []DispatchRoute{
+			first,
+			dispatchRoute(driver2, vehicle2, depot, shipments[2]),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Routes, 2)
+	require.Equal(t, vehicle1.ID, result.Routes[0].Route.VehicleID)
+	require.Equal(t, "pending", result.Routes[0].Route.Status)
+	require.Len(t, result.Routes[0].Stops, 2)
+	require.Equal(t, shipments[1].ID, result.Routes[0].Stops[0].ShipmentID.UUID)
+	require.Equal(t, int32(2), result.Routes[0].Stops[1].Sequence)
+
+	for i, routeIndex := range []int{0, 0, 1} {
+		shipment, err := testQueries.GetShipment(context.Background(), shipments[i].ID)
+		require.NoError(t, err)
+		require.Equal(t, result.Routes[routeIndex].Route.ID, shipment.RouteID.UUID)
+	}
+
+	path, err := testQueries.GetRoutePath(context.Background(), result.Routes[0].Route.ID)
+	require.NoError(t, err)
+	require.JSONEq(t, string(first.Path), string(path.Points))
+	_, err = testQueries.GetRoutePath(context.Background(), result.Routes[1].Route.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateDispatchPlanTxShipmentPlanned(t *testing.T) {
+	depot := createRandomDepot(t)
+	customer := createRandomUser(t)
+	driver := createRandomUser(t)
+	vehicle := createRandomVehicle(t, driver)
+	free := createRandomShipment(t, customer)
+	planned := createRandomShipment(t, customer)
+
+	_, err := testStore.CreateDispatchPlanTx(context.Background(), CreateDispatchPlanTxParams{
+		Routes: []DispatchRoute{dispatchRoute(driver, vehicle, depot, planned)},
+	})
+	require.NoError(t, err)
+
+	route := dispatchRoute(driver, vehicle, depot, free, planned)
+	_, err = testStore.CreateDispatchPlanTx(context.Background(), CreateDispatchPlanTxParams{
+		Routes: []DispatchRoute{route},
+	})
+	require.ErrorIs(t, err, ErrShipmentPlanned)
+
+	// nothing of the failed plan is kept
+	_, err = testQueries.GetRouteByID(context.Background(), route.Route.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	shipment, err := testQueries.GetShipment(context.Background(), free.ID)
+	require.NoError(t, err)
+	require.False(t, shipment.RouteID.Valid)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomGeofenceEvent(t *testing.T, route Route, place string, stopID int64, transition string, occurredAt time.Time) GeofenceEvent {
+	location := createRandomRouteLocation(t, route, occurredAt)
+	arg := CreateGeofenceEventParams{
+		RouteID:    route.ID,
+		Place:      place,
+		Transition: transition,
+		LocationID: sql.NullInt64{Int64: location.ID, Valid: true},
+		OccurredAt: occurredAt,
+	}
+	if stopID != 0 {
+		arg.StopID = sql.NullInt64{Int64: stopID, Valid: true}
+	}
+
+	event, err := testQueries.CreateGeofenceEvent(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, event.ID)
+
+	require.Equal(t, arg.RouteID, event.RouteID)
+	require.Equal(t, arg.Place, event.Place)
+	require.Equal(t, arg.StopID, event.StopID)
+	require.Equal(t, arg.Transition, event.Transition)
+	require.Equal(t, arg.LocationID, event.LocationID)
+	require.WithinDuration(t, arg.OccurredAt, event.OccurredAt, time.Second)
+	require.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+func TestCreateGeofenceEvent(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	stops := createRandomRouteStops(t, route, 1)
+
+	createRandomGeofenceEvent(t, route, "origin", 0, "departure", time.Now())
+	createRandomGeofenceEvent(t, route, "stop", stops[0].ID, "arrival", time.Now())
+
+	// only stop events name a stop
+	_, err := testQueries.CreateGeofenceEvent(context.Background(), CreateGeofenceEventParams{
+		RouteID:    route.ID,
+		Place:      "stop",
+		Transition: "arrival",
+		OccurredAt: time.Now(),
+	})
+	require.Error(t, err)
+}
+
+func TestGetLatestGeofenceEvent(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	_, err := testQueries.GetLatestGeofenceEvent(context.Background(), GetLatestGeofenceEventParams{
+		RouteID: route.ID,
+		Place:   "origin",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	now := time.Now()
+	createRandomGeofenceEvent(t, route, "origin", 0, "arrival", now.Add(-10*time.Minute))
+	departure := createRandomGeofenceEvent(t, route, "origin", 0, "departure", now.Add(-5*time.Minute))
+	createRandomGeofenceEvent(t, route, "destination", 0, "arrival", now)
+
+	event, err := testQueries.GetLatestGeofenceEvent(context.Background(), GetLatestGeofenceEventParams{
+		RouteID: route.ID,
+		Place:   "origin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, departure.ID, event.ID)
+}
+
+func TestListGeofenceEvents(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		createRandomGeofenceEvent(t, route, "origin", 0, "arrival", now.Add(-time.Duration(i)*time.Minute))
+	}
+
+	events, err := testQueries.ListGeofenceEvents(context.Background(), ListGeofenceEventsParams{
+		RouteID: route.ID,
+		Limit:   3,
+		Offset:  0,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for i := 1; i < len(events); i++ {
+		require.True(t, events[i-1].OccurredAt.After(events[i].OccurredAt))
+	}
+}
@@ -11,6 +11,7 @@ import (
 )
 
 var testQueries *Queries
+var testStore Store
 
 
 
@@ -25,5 +26,6 @@ func TestMain(m *testing.M){
 		log.Fatal("cannot connect to db:", err)
 	}
 	testQueries = New(conn)
+	testStore = NewStore(conn)
 	os.Exit(m.Run())
 }
\ No newline at end of file
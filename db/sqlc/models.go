@@ -6,27 +6,152 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Depot struct {
+	ID        uuid.UUID      `json:"id"`
+	Name      string         `json:"name"`
+	Address   sql.NullString `json:"address"`
+	Lat       float64        `json:"lat"`
+	Lng       float64        `json:"lng"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type DeviationEvent struct {
+	ID         int64         `json:"id"`
+	RouteID    uuid.UUID     `json:"route_id"`
+	Transition string        `json:"transition"`
+	DistanceM  float64       `json:"distance_m"`
+	LocationID sql.NullInt64 `json:"location_id"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type GeofenceEvent struct {
+	ID         int64         `json:"id"`
+	RouteID    uuid.UUID     `json:"route_id"`
+	Place      string        `json:"place"`
+	StopID     sql.NullInt64 `json:"stop_id"`
+	Transition string        `json:"transition"`
+	LocationID sql.NullInt64 `json:"location_id"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
 type Route struct {
-	ID                   uuid.UUID       `json:"id"`
-	DriverID             uuid.UUID       `json:"driver_id"`
-	VehicleID            uuid.UUID       `json:"vehicle_id"`
-	OriginLat            float64         `json:"origin_lat"`
-	OriginLng            float64         `json:"origin_lng"`
-	DestinationLat       float64         `json:"destination_lat"`
-	DestinationLng       float64         `json:"destination_lng"`
-	OriginAddress        sql.NullString  `json:"origin_address"`
-	DestinationAddress   sql.NullString  `json:"destination_address"`
-	EstimatedDistanceKm  sql.NullFloat64 `json:"estimated_distance_km"`
-	EstimatedDurationMin sql.NullFloat64 `json:"estimated_duration_min"`
-	ActualDurationMin    sql.NullFloat64 `json:"actual_duration_min"`
-	Status               string          `json:"status"`
-	CreatedAt            sql.NullTime    `json:"created_at"`
-	UpdatedAt            sql.NullTime    `json:"updated_at"`
+	ID                         uuid.UUID       `json:"id"`
+	DriverID                   uuid.UUID       `json:"driver_id"`
+	VehicleID                  uuid.UUID       `json:"vehicle_id"`
+	OriginLat                  float64         `json:"origin_lat"`
+	OriginLng                  float64         `json:"origin_lng"`
+	DestinationLat             float64         `json:"destination_lat"`
+	DestinationLng             float64         `json:"destination_lng"`
+	OriginAddress              sql.NullString  `json:"origin_address"`
+	DestinationAddress         sql.NullString  `json:"destination_address"`
+	EstimatedDistanceKm        sql.NullFloat64 `json:"estimated_distance_km"`
+	EstimatedDurationMin       sql.NullFloat64 `json:"estimated_duration_min"`
+	ActualDurationMin          sql.NullFloat64 `json:"actual_duration_min"`
+	Status                     string          `json:"status"`
+	CreatedAt                  sql.NullTime    `json:"created_at"`
+	UpdatedAt                  sql.NullTime    `json:"updated_at"`
+	StartedAt                  sql.NullTime    `json:"started_at"`
+	CompletedAt                sql.NullTime    `json:"completed_at"`
+	CancelledAt                sql.NullTime    `json:"cancelled_at"`
+	RemainingDistanceKm        sql.NullFloat64 `json:"remaining_distance_km"`
+	RemainingDurationMin       sql.NullFloat64 `json:"remaining_duration_min"`
+	EtaAt                      sql.NullTime    `json:"eta_at"`
+	EtaConfidence              sql.NullFloat64 `json:"eta_confidence"`
+	EtaUpdatedAt               sql.NullTime    `json:"eta_updated_at"`
+	BaselineDurationMin        sql.NullFloat64 `json:"baseline_duration_min"`
+	DurationP10Min             sql.NullFloat64 `json:"duration_p10_min"`
+	DurationP50Min             sql.NullFloat64 `json:"duration_p50_min"`
+	DurationP90Min             sql.NullFloat64 `json:"duration_p90_min"`
+	OriginGeofenceRadiusM      sql.NullFloat64 `json:"origin_geofence_radius_m"`
+	DestinationGeofenceRadiusM sql.NullFloat64 `json:"destination_geofence_radius_m"`
+}
+
+type RouteEtaHistory struct {
+	ID                   int64         `json:"id"`
+	RouteID              uuid.UUID     `json:"route_id"`
+	LocationID           sql.NullInt64 `json:"location_id"`
+	RemainingDistanceKm  float64       `json:"remaining_distance_km"`
+	RemainingDurationMin float64       `json:"remaining_duration_min"`
+	EtaAt                time.Time     `json:"eta_at"`
+	Confidence           float64       `json:"confidence"`
+	CreatedAt            time.Time     `json:"created_at"`
+}
+
+type RouteLocation struct {
+	ID         int64           `json:"id"`
+	RouteID    uuid.UUID       `json:"route_id"`
+	DriverID   uuid.UUID       `json:"driver_id"`
+	Lat        float64         `json:"lat"`
+	Lng        float64         `json:"lng"`
+	SpeedKmh   sql.NullFloat64 `json:"speed_kmh"`
+	HeadingDeg sql.NullFloat64 `json:"heading_deg"`
+	AccuracyM  sql.NullFloat64 `json:"accuracy_m"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type RoutePath struct {
+	RouteID   uuid.UUID       `json:"route_id"`
+	Points    json.RawMessage `json:"points"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type RouteStop struct {
+	ID              int64           `json:"id"`
+	RouteID         uuid.UUID       `json:"route_id"`
+	ShipmentID      uuid.NullUUID   `json:"shipment_id"`
+	Sequence        int32           `json:"sequence"`
+	Lat             float64         `json:"lat"`
+	Lng             float64         `json:"lng"`
+	Address         sql.NullString  `json:"address"`
+	ServiceTimeMin  float64         `json:"service_time_min"`
+	Status          string          `json:"status"`
+	ArrivedAt       sql.NullTime    `json:"arrived_at"`
+	DepartedAt      sql.NullTime    `json:"departed_at"`
+	FailureReason   sql.NullString  `json:"failure_reason"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	WindowStart     sql.NullTime    `json:"window_start"`
+	WindowEnd       sql.NullTime    `json:"window_end"`
+	GeofenceRadiusM sql.NullFloat64 `json:"geofence_radius_m"`
+}
+
+type Session struct {
+	ID           uuid.UUID    `json:"id"`
+	UserID       uuid.UUID    `json:"user_id"`
+	RefreshToken string       `json:"refresh_token"`
+	UserAgent    string       `json:"user_agent"`
+	ClientIp     string       `json:"client_ip"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	RevokedAt    sql.NullTime `json:"revoked_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type Shipment struct {
+	ID             uuid.UUID      `json:"id"`
+	CustomerID     uuid.UUID      `json:"customer_id"`
+	RouteID        uuid.NullUUID  `json:"route_id"`
+	PickupLat      float64        `json:"pickup_lat"`
+	PickupLng      float64        `json:"pickup_lng"`
+	PickupAddress  sql.NullString `json:"pickup_address"`
+	DropoffLat     float64        `json:"dropoff_lat"`
+	DropoffLng     float64        `json:"dropoff_lng"`
+	DropoffAddress sql.NullString `json:"dropoff_address"`
+	WeightKg       float64        `json:"weight_kg"`
+	VolumeM3       float64        `json:"volume_m3"`
+	WindowStart    sql.NullTime   `json:"window_start"`
+	WindowEnd      sql.NullTime   `json:"window_end"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
 type User struct {
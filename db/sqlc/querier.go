@@ -11,30 +11,99 @@ import (
 )
 
 type Querier interface {
+	AssignShipmentRoute(ctx context.Context, arg AssignShipmentRouteParams) (Shipment, error)
+	ClaimShipment(ctx context.Context, arg ClaimShipmentParams) (Shipment, error)
+	CountRoutesByDriver(ctx context.Context, driverID uuid.UUID) (int64, error)
+	CreateDepot(ctx context.Context, arg CreateDepotParams) (Depot, error)
+	CreateDeviationEvent(ctx context.Context, arg CreateDeviationEventParams) (DeviationEvent, error)
+	CreateGeofenceEvent(ctx context.Context, arg CreateGeofenceEventParams) (GeofenceEvent, error)
 	CreateRoute(ctx context.Context, arg CreateRouteParams) (Route, error)
+	CreateRouteEtaHistory(ctx context.Context, arg CreateRouteEtaHistoryParams) (RouteEtaHistory, error)
+	CreateRouteLocation(ctx context.Context, arg CreateRouteLocationParams) (RouteLocation, error)
+	CreateRouteStop(ctx context.Context, arg CreateRouteStopParams) (RouteStop, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateShipment(ctx context.Context, arg CreateShipmentParams) (Shipment, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVehicle(ctx context.Context, arg CreateVehicleParams) (Vehicle, error)
 	// when the route is completed
 	DeleteRoute(ctx context.Context, id uuid.UUID) error
+	DeleteRoutePath(ctx context.Context, routeID uuid.UUID) error
+	DeleteRouteStops(ctx context.Context, routeID uuid.UUID) error
 	// returns the updated user
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 	DeleteVehicle(ctx context.Context, id uuid.UUID) error
+	GetActiveRouteByVehicle(ctx context.Context, vehicleID uuid.UUID) (Route, error)
+	GetDepot(ctx context.Context, id uuid.UUID) (Depot, error)
+	// ETA error statistics of the routes completed in [completed_from,
+	// completed_to). Errors are actual minus estimated duration, so a positive
+	// bias means routes take longer than estimated; percentages are of the
+	// actual duration.
+	GetETAAccuracy(ctx context.Context, arg GetETAAccuracyParams) (GetETAAccuracyRow, error)
+	GetLatestDeviationEvent(ctx context.Context, routeID uuid.UUID) (DeviationEvent, error)
+	GetLatestGeofenceEvent(ctx context.Context, arg GetLatestGeofenceEventParams) (GeofenceEvent, error)
+	GetLatestRouteLocation(ctx context.Context, routeID uuid.UUID) (RouteLocation, error)
 	GetRouteByID(ctx context.Context, id uuid.UUID) (Route, error)
+	GetRouteForUpdate(ctx context.Context, id uuid.UUID) (Route, error)
+	GetRoutePath(ctx context.Context, routeID uuid.UUID) (RoutePath, error)
+	GetRouteStop(ctx context.Context, arg GetRouteStopParams) (RouteStop, error)
 	GetRoutesByDriverID(ctx context.Context, arg GetRoutesByDriverIDParams) ([]Route, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetShipment(ctx context.Context, id uuid.UUID) (Shipment, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	// returns the created user
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUserForUpdate(ctx context.Context, id uuid.UUID) (User, error)
 	// returns the created vehicle
 	GetVehicleByID(ctx context.Context, id uuid.UUID) (Vehicle, error)
 	GetVehicleByLicensePlate(ctx context.Context, licensePlate string) (Vehicle, error)
+	GetVehicleForUpdate(ctx context.Context, id uuid.UUID) (Vehicle, error)
 	GetVehiclesByDriverID(ctx context.Context, arg GetVehiclesByDriverIDParams) ([]Vehicle, error)
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	// Routes completed since the given time with what the correction model
+	// learns from: the trip, its driver and vehicle, the estimate it was
+	// planned with, its free-flow baseline and how long it actually took.
+	ListCorrectionSamples(ctx context.Context, arg ListCorrectionSamplesParams) ([]ListCorrectionSamplesRow, error)
+	ListDepots(ctx context.Context, arg ListDepotsParams) ([]Depot, error)
+	ListDeviationEvents(ctx context.Context, arg ListDeviationEventsParams) ([]DeviationEvent, error)
+	// Routes by band of estimated distance, each band named by the distance it
+	// starts at. The bands are eta.DistanceBandsKm.
+	ListETAAccuracyByDistanceBand(ctx context.Context, arg ListETAAccuracyByDistanceBandParams) ([]ListETAAccuracyByDistanceBandRow, error)
+	ListETAAccuracyByDriver(ctx context.Context, arg ListETAAccuracyByDriverParams) ([]ListETAAccuracyByDriverRow, error)
+	// Routes by the hour of the day they started in the given time zone.
+	ListETAAccuracyByHour(ctx context.Context, arg ListETAAccuracyByHourParams) ([]ListETAAccuracyByHourRow, error)
+	ListETAAccuracyByVehicle(ctx context.Context, arg ListETAAccuracyByVehicleParams) ([]ListETAAccuracyByVehicleRow, error)
+	// Vehicles free to take a dispatched route: they have a payload capacity, and
+	// neither they nor their driver are on a route that hasn't finished. Drivers
+	// with several of them are offered their oldest one only.
+	ListFleetVehicles(ctx context.Context) ([]Vehicle, error)
+	ListGeofenceEvents(ctx context.Context, arg ListGeofenceEventsParams) ([]GeofenceEvent, error)
+	ListRouteEtaHistory(ctx context.Context, arg ListRouteEtaHistoryParams) ([]RouteEtaHistory, error)
+	ListRouteLocations(ctx context.Context, arg ListRouteLocationsParams) ([]RouteLocation, error)
+	ListRouteLocationsSince(ctx context.Context, arg ListRouteLocationsSinceParams) ([]RouteLocation, error)
+	ListRouteShipmentIDsByCustomer(ctx context.Context, arg ListRouteShipmentIDsByCustomerParams) ([]uuid.UUID, error)
+	ListRouteStops(ctx context.Context, routeID uuid.UUID) ([]RouteStop, error)
 	ListRoutesByDriverAndStatus(ctx context.Context, arg ListRoutesByDriverAndStatusParams) ([]Route, error)
+	ListShipmentsByCustomer(ctx context.Context, arg ListShipmentsByCustomerParams) ([]Shipment, error)
+	// Routes completed since the given time, with the free-flow estimate their
+	// actual duration compares to. Routes planned before baselines were stored
+	// had no traffic adjustment, so their estimate is their baseline.
+	ListSpeedProfileSamples(ctx context.Context, arg ListSpeedProfileSamplesParams) ([]ListSpeedProfileSamplesRow, error)
+	// Shipments without a route whose window, if any, overlaps [since, until)
+	ListUnplannedShipments(ctx context.Context, arg ListUnplannedShipmentsParams) ([]Shipment, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (Session, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error)
+	TransitionRouteStatus(ctx context.Context, arg TransitionRouteStatusParams) (Route, error)
+	TransitionRouteStopStatus(ctx context.Context, arg TransitionRouteStopStatusParams) (RouteStop, error)
 	UpdateRouteActualDuration(ctx context.Context, arg UpdateRouteActualDurationParams) (Route, error)
+	UpdateRouteEta(ctx context.Context, arg UpdateRouteEtaParams) (Route, error)
+	UpdateRoutePlan(ctx context.Context, arg UpdateRoutePlanParams) (Route, error)
 	UpdateRouteStatus(ctx context.Context, arg UpdateRouteStatusParams) (Route, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserPartial(ctx context.Context, arg UpdateUserPartialParams) (User, error)
 	UpdateVehicle(ctx context.Context, arg UpdateVehicleParams) (Vehicle, error)
+	UpsertRoutePath(ctx context.Context, arg UpsertRoutePathParams) error
 }
 
 var _ Querier = (*Queries)(nil)
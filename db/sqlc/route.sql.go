@@ -8,10 +8,22 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const countRoutesByDriver = `-- name: CountRoutesByDriver :one
+SELECT COUNT(*) FROM routes WHERE driver_id = $1
+`
+
+func (q *Queries) CountRoutesByDriver(ctx context.Context, driverID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countRoutesByDriver, driverID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createRoute = `-- name: CreateRoute :one
 INSERT INTO routes (
     id,
@@ -25,30 +37,44 @@ INSERT INTO routes (
     destination_lng,
     estimated_distance_km,
     estimated_duration_min,
+    baseline_duration_min,
+    duration_p10_min,
+    duration_p50_min,
+    duration_p90_min,
+    origin_geofence_radius_m,
+    destination_geofence_radius_m,
     status
 )
 VALUES (
     $1, $2, $3,
     $4, $5, $6,
     $7, $8, $9,
-    $10, $11, $12
+    $10, $11, $12,
+    $13, $14, $15,
+    $16, $17, $18
 )
-RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at
+RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m
 `
 
 type CreateRouteParams struct {
-	ID                   uuid.UUID       `json:"id"`
-	DriverID             uuid.UUID       `json:"driver_id"`
-	VehicleID            uuid.UUID       `json:"vehicle_id"`
-	OriginAddress        sql.NullString  `json:"origin_address"`
-	OriginLat            float64         `json:"origin_lat"`
-	OriginLng            float64         `json:"origin_lng"`
-	DestinationAddress   sql.NullString  `json:"destination_address"`
-	DestinationLat       float64         `json:"destination_lat"`
-	DestinationLng       float64         `json:"destination_lng"`
-	EstimatedDistanceKm  sql.NullFloat64 `json:"estimated_distance_km"`
-	EstimatedDurationMin sql.NullFloat64 `json:"estimated_duration_min"`
-	Status               string          `json:"status"`
+	ID                         uuid.UUID       `json:"id"`
+	DriverID                   uuid.UUID       `json:"driver_id"`
+	VehicleID                  uuid.UUID       `json:"vehicle_id"`
+	OriginAddress              sql.NullString  `json:"origin_address"`
+	OriginLat                  float64         `json:"origin_lat"`
+	OriginLng                  float64         `json:"origin_lng"`
+	DestinationAddress         sql.NullString  `json:"destination_address"`
+	DestinationLat             float64         `json:"destination_lat"`
+	DestinationLng             float64         `json:"destination_lng"`
+	EstimatedDistanceKm        sql.NullFloat64 `json:"estimated_distance_km"`
+	EstimatedDurationMin       sql.NullFloat64 `json:"estimated_duration_min"`
+	BaselineDurationMin        sql.NullFloat64 `json:"baseline_duration_min"`
+	DurationP10Min             sql.NullFloat64 `json:"duration_p10_min"`
+	DurationP50Min             sql.NullFloat64 `json:"duration_p50_min"`
+	DurationP90Min             sql.NullFloat64 `json:"duration_p90_min"`
+	OriginGeofenceRadiusM      sql.NullFloat64 `json:"origin_geofence_radius_m"`
+	DestinationGeofenceRadiusM sql.NullFloat64 `json:"destination_geofence_radius_m"`
+	Status                     string          `json:"status"`
 }
 
 func (q *Queries) CreateRoute(ctx context.Context, arg CreateRouteParams) (Route, error) {
@@ -64,6 +90,12 @@ func (q *Queries) CreateRoute(ctx context.Context, arg CreateRouteParams) (Route
 		arg.DestinationLng,
 		arg.EstimatedDistanceKm,
 		arg.EstimatedDurationMin,
+		arg.BaselineDurationMin,
+		arg.DurationP10Min,
+		arg.DurationP50Min,
+		arg.DurationP90Min,
+		arg.OriginGeofenceRadiusM,
+		arg.DestinationGeofenceRadiusM,
 		arg.Status,
 	)
 	var i Route
@@ -83,6 +115,20 @@ func (q *Queries) CreateRoute(ctx context.Context, arg CreateRouteParams) (Route
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
 	)
 	return i, err
 }
@@ -99,8 +145,113 @@ func (q *Queries) DeleteRoute(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const getActiveRouteByVehicle = `-- name: GetActiveRouteByVehicle :one
+SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m FROM routes
+WHERE vehicle_id = $1
+AND status = 'in_progress'
+LIMIT 1
+`
+
+func (q *Queries) GetActiveRouteByVehicle(ctx context.Context, vehicleID uuid.UUID) (Route, error) {
+	row := q.db.QueryRowContext(ctx, getActiveRouteByVehicle, vehicleID)
+	var i Route
+	err := row.Scan(
+		&i.ID,
+		&i.DriverID,
+		&i.VehicleID,
+		&i.OriginLat,
+		&i.OriginLng,
+		&i.DestinationLat,
+		&i.DestinationLng,
+		&i.OriginAddress,
+		&i.DestinationAddress,
+		&i.EstimatedDistanceKm,
+		&i.EstimatedDurationMin,
+		&i.ActualDurationMin,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
+	)
+	return i, err
+}
+
+const getETAAccuracy = `-- name: GetETAAccuracy :one
+WITH errors AS (
+    SELECT
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= $1::timestamptz
+    AND r.completed_at < $2::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+)
+SELECT
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+`
+
+type GetETAAccuracyParams struct {
+	CompletedFrom time.Time `json:"completed_from"`
+	CompletedTo   time.Time `json:"completed_to"`
+}
+
+type GetETAAccuracyRow struct {
+	Trips             int64   `json:"trips"`
+	MeanAbsErrorMin   float64 `json:"mean_abs_error_min"`
+	MedianAbsErrorMin float64 `json:"median_abs_error_min"`
+	P90AbsErrorMin    float64 `json:"p90_abs_error_min"`
+	MeanAbsErrorPct   float64 `json:"mean_abs_error_pct"`
+	MedianAbsErrorPct float64 `json:"median_abs_error_pct"`
+	P90AbsErrorPct    float64 `json:"p90_abs_error_pct"`
+	BiasMin           float64 `json:"bias_min"`
+}
+
+// ETA error statistics of the routes completed in [completed_from,
+// completed_to). Errors are actual minus estimated duration, so a positive
+// bias means routes take longer than estimated; percentages are of the
+// actual duration.
+func (q *Queries) GetETAAccuracy(ctx context.Context, arg GetETAAccuracyParams) (GetETAAccuracyRow, error) {
+	row := q.db.QueryRowContext(ctx, getETAAccuracy, arg.CompletedFrom, arg.CompletedTo)
+	var i GetETAAccuracyRow
+	err := row.Scan(
+		&i.Trips,
+		&i.MeanAbsErrorMin,
+		&i.MedianAbsErrorMin,
+		&i.P90AbsErrorMin,
+		&i.MeanAbsErrorPct,
+		&i.MedianAbsErrorPct,
+		&i.P90AbsErrorPct,
+		&i.BiasMin,
+	)
+	return i, err
+}
+
 const getRouteByID = `-- name: GetRouteByID :one
-SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at FROM routes WHERE id = $1
+SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m FROM routes WHERE id = $1
 `
 
 func (q *Queries) GetRouteByID(ctx context.Context, id uuid.UUID) (Route, error) {
@@ -122,12 +273,67 @@ func (q *Queries) GetRouteByID(ctx context.Context, id uuid.UUID) (Route, error)
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
+	)
+	return i, err
+}
+
+const getRouteForUpdate = `-- name: GetRouteForUpdate :one
+SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m FROM routes WHERE id = $1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetRouteForUpdate(ctx context.Context, id uuid.UUID) (Route, error) {
+	row := q.db.QueryRowContext(ctx, getRouteForUpdate, id)
+	var i Route
+	err := row.Scan(
+		&i.ID,
+		&i.DriverID,
+		&i.VehicleID,
+		&i.OriginLat,
+		&i.OriginLng,
+		&i.DestinationLat,
+		&i.DestinationLng,
+		&i.OriginAddress,
+		&i.DestinationAddress,
+		&i.EstimatedDistanceKm,
+		&i.EstimatedDurationMin,
+		&i.ActualDurationMin,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
 	)
 	return i, err
 }
 
 const getRoutesByDriverID = `-- name: GetRoutesByDriverID :many
-SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at FROM routes
+SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m FROM routes
 WHERE driver_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -164,6 +370,441 @@ func (q *Queries) GetRoutesByDriverID(ctx context.Context, arg GetRoutesByDriver
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.StartedAt,
+			&i.CompletedAt,
+			&i.CancelledAt,
+			&i.RemainingDistanceKm,
+			&i.RemainingDurationMin,
+			&i.EtaAt,
+			&i.EtaConfidence,
+			&i.EtaUpdatedAt,
+			&i.BaselineDurationMin,
+			&i.DurationP10Min,
+			&i.DurationP50Min,
+			&i.DurationP90Min,
+			&i.OriginGeofenceRadiusM,
+			&i.DestinationGeofenceRadiusM,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCorrectionSamples = `-- name: ListCorrectionSamples :many
+SELECT
+    r.driver_id,
+    COALESCE(v.model, '')::text AS vehicle_model,
+    r.origin_lat,
+    r.origin_lng,
+    r.destination_lat,
+    r.destination_lng,
+    r.started_at::timestamptz AS started_at,
+    r.estimated_distance_km::float8 AS estimated_distance_km,
+    r.estimated_duration_min::float8 AS estimated_duration_min,
+    COALESCE(r.baseline_duration_min, r.estimated_duration_min)::float8 AS baseline_duration_min,
+    r.actual_duration_min::float8 AS actual_duration_min
+FROM routes r
+JOIN vehicles v ON v.id = r.vehicle_id
+WHERE r.status = 'completed'
+AND r.completed_at >= $1::timestamptz
+AND r.started_at IS NOT NULL
+AND r.estimated_distance_km IS NOT NULL
+AND r.estimated_duration_min > 0
+AND r.actual_duration_min > 0
+ORDER BY r.completed_at DESC
+LIMIT $2::int
+`
+
+type ListCorrectionSamplesParams struct {
+	Since      time.Time `json:"since"`
+	MaxSamples int32     `json:"max_samples"`
+}
+
+type ListCorrectionSamplesRow struct {
+	DriverID             uuid.UUID `json:"driver_id"`
+	VehicleModel         string    `json:"vehicle_model"`
+	OriginLat            float64   `json:"origin_lat"`
+	OriginLng            float64   `json:"origin_lng"`
+	DestinationLat       float64   `json:"destination_lat"`
+	DestinationLng       float64   `json:"destination_lng"`
+	StartedAt            time.Time `json:"started_at"`
+	EstimatedDistanceKm  float64   `json:"estimated_distance_km"`
+	EstimatedDurationMin float64   `json:"estimated_duration_min"`
+	BaselineDurationMin  float64   `json:"baseline_duration_min"`
+	ActualDurationMin    float64   `json:"actual_duration_min"`
+}
+
+// Routes completed since the given time with what the correction model
+// learns from: the trip, its driver and vehicle, the estimate it was
+// planned with, its free-flow baseline and how long it actually took.
+func (q *Queries) ListCorrectionSamples(ctx context.Context, arg ListCorrectionSamplesParams) ([]ListCorrectionSamplesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCorrectionSamples, arg.Since, arg.MaxSamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCorrectionSamplesRow{}
+	for rows.Next() {
+		var i ListCorrectionSamplesRow
+		if err := rows.Scan(
+			&i.DriverID,
+			&i.VehicleModel,
+			&i.OriginLat,
+			&i.OriginLng,
+			&i.DestinationLat,
+			&i.DestinationLng,
+			&i.StartedAt,
+			&i.EstimatedDistanceKm,
+			&i.EstimatedDurationMin,
+			&i.BaselineDurationMin,
+			&i.ActualDurationMin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listETAAccuracyByDistanceBand = `-- name: ListETAAccuracyByDistanceBand :many
+WITH errors AS (
+    SELECT
+        CASE
+            WHEN r.estimated_distance_km < 5 THEN 0
+            WHEN r.estimated_distance_km < 20 THEN 5
+            WHEN r.estimated_distance_km < 50 THEN 20
+            WHEN r.estimated_distance_km < 100 THEN 50
+            ELSE 100
+        END::float8 AS min_distance_km,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= $1::timestamptz
+    AND r.completed_at < $2::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+    AND r.estimated_distance_km IS NOT NULL
+)
+SELECT
+    e.min_distance_km,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+GROUP BY e.min_distance_km
+ORDER BY e.min_distance_km
+`
+
+type ListETAAccuracyByDistanceBandParams struct {
+	CompletedFrom time.Time `json:"completed_from"`
+	CompletedTo   time.Time `json:"completed_to"`
+}
+
+type ListETAAccuracyByDistanceBandRow struct {
+	MinDistanceKm     float64 `json:"min_distance_km"`
+	Trips             int64   `json:"trips"`
+	MeanAbsErrorMin   float64 `json:"mean_abs_error_min"`
+	MedianAbsErrorMin float64 `json:"median_abs_error_min"`
+	P90AbsErrorMin    float64 `json:"p90_abs_error_min"`
+	MeanAbsErrorPct   float64 `json:"mean_abs_error_pct"`
+	MedianAbsErrorPct float64 `json:"median_abs_error_pct"`
+	P90AbsErrorPct    float64 `json:"p90_abs_error_pct"`
+	BiasMin           float64 `json:"bias_min"`
+}
+
+// Routes by band of estimated distance, each band named by the distance it
+// starts at. The bands are eta.DistanceBandsKm.
+func (q *Queries) ListETAAccuracyByDistanceBand(ctx context.Context, arg ListETAAccuracyByDistanceBandParams) ([]ListETAAccuracyByDistanceBandRow, error) {
+	rows, err := q.db.QueryContext(ctx, listETAAccuracyByDistanceBand, arg.CompletedFrom, arg.CompletedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListETAAccuracyByDistanceBandRow{}
+	for rows.Next() {
+		var i ListETAAccuracyByDistanceBandRow
+		if err := rows.Scan(
+			&i.MinDistanceKm,
+			&i.Trips,
+			&i.MeanAbsErrorMin,
+			&i.MedianAbsErrorMin,
+			&i.P90AbsErrorMin,
+			&i.MeanAbsErrorPct,
+			&i.MedianAbsErrorPct,
+			&i.P90AbsErrorPct,
+			&i.BiasMin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listETAAccuracyByDriver = `-- name: ListETAAccuracyByDriver :many
+WITH errors AS (
+    SELECT
+        r.driver_id,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= $1::timestamptz
+    AND r.completed_at < $2::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+)
+SELECT
+    e.driver_id,
+    u.name AS driver_name,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+JOIN users u ON u.id = e.driver_id
+GROUP BY e.driver_id, u.name
+ORDER BY trips DESC, e.driver_id
+`
+
+type ListETAAccuracyByDriverParams struct {
+	CompletedFrom time.Time `json:"completed_from"`
+	CompletedTo   time.Time `json:"completed_to"`
+}
+
+type ListETAAccuracyByDriverRow struct {
+	DriverID          uuid.UUID `json:"driver_id"`
+	DriverName        string    `json:"driver_name"`
+	Trips             int64     `json:"trips"`
+	MeanAbsErrorMin   float64   `json:"mean_abs_error_min"`
+	MedianAbsErrorMin float64   `json:"median_abs_error_min"`
+	P90AbsErrorMin    float64   `json:"p90_abs_error_min"`
+	MeanAbsErrorPct   float64   `json:"mean_abs_error_pct"`
+	MedianAbsErrorPct float64   `json:"median_abs_error_pct"`
+	P90AbsErrorPct    float64   `json:"p90_abs_error_pct"`
+	BiasMin           float64   `json:"bias_min"`
+}
+
+func (q *Queries) ListETAAccuracyByDriver(ctx context.Context, arg ListETAAccuracyByDriverParams) ([]ListETAAccuracyByDriverRow, error) {
+	rows, err := q.db.QueryContext(ctx, listETAAccuracyByDriver, arg.CompletedFrom, arg.CompletedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListETAAccuracyByDriverRow{}
+	for rows.Next() {
+		var i ListETAAccuracyByDriverRow
+		if err := rows.Scan(
+			&i.DriverID,
+			&i.DriverName,
+			&i.Trips,
+			&i.MeanAbsErrorMin,
+			&i.MedianAbsErrorMin,
+			&i.P90AbsErrorMin,
+			&i.MeanAbsErrorPct,
+			&i.MedianAbsErrorPct,
+			&i.P90AbsErrorPct,
+			&i.BiasMin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listETAAccuracyByHour = `-- name: ListETAAccuracyByHour :many
+WITH errors AS (
+    SELECT
+        EXTRACT(HOUR FROM r.started_at AT TIME ZONE $1::text)::int AS hour,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= $2::timestamptz
+    AND r.completed_at < $3::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+    AND r.started_at IS NOT NULL
+)
+SELECT
+    e.hour,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+GROUP BY e.hour
+ORDER BY e.hour
+`
+
+type ListETAAccuracyByHourParams struct {
+	Timezone      string    `json:"timezone"`
+	CompletedFrom time.Time `json:"completed_from"`
+	CompletedTo   time.Time `json:"completed_to"`
+}
+
+type ListETAAccuracyByHourRow struct {
+	Hour              int32   `json:"hour"`
+	Trips             int64   `json:"trips"`
+	MeanAbsErrorMin   float64 `json:"mean_abs_error_min"`
+	MedianAbsErrorMin float64 `json:"median_abs_error_min"`
+	P90AbsErrorMin    float64 `json:"p90_abs_error_min"`
+	MeanAbsErrorPct   float64 `json:"mean_abs_error_pct"`
+	MedianAbsErrorPct float64 `json:"median_abs_error_pct"`
+	P90AbsErrorPct    float64 `json:"p90_abs_error_pct"`
+	BiasMin           float64 `json:"bias_min"`
+}
+
+// Routes by the hour of the day they started in the given time zone.
+func (q *Queries) ListETAAccuracyByHour(ctx context.Context, arg ListETAAccuracyByHourParams) ([]ListETAAccuracyByHourRow, error) {
+	rows, err := q.db.QueryContext(ctx, listETAAccuracyByHour, arg.Timezone, arg.CompletedFrom, arg.CompletedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListETAAccuracyByHourRow{}
+	for rows.Next() {
+		var i ListETAAccuracyByHourRow
+		if err := rows.Scan(
+			&i.Hour,
+			&i.Trips,
+			&i.MeanAbsErrorMin,
+			&i.MedianAbsErrorMin,
+			&i.P90AbsErrorMin,
+			&i.MeanAbsErrorPct,
+			&i.MedianAbsErrorPct,
+			&i.P90AbsErrorPct,
+			&i.BiasMin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listETAAccuracyByVehicle = `-- name: ListETAAccuracyByVehicle :many
+WITH errors AS (
+    SELECT
+        r.vehicle_id,
+        r.actual_duration_min - r.estimated_duration_min AS error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) AS abs_error_min,
+        ABS(r.actual_duration_min - r.estimated_duration_min) / r.actual_duration_min * 100 AS abs_error_pct
+    FROM routes r
+    WHERE r.status = 'completed'
+    AND r.completed_at >= $1::timestamptz
+    AND r.completed_at < $2::timestamptz
+    AND r.estimated_duration_min IS NOT NULL
+    AND r.actual_duration_min > 0
+)
+SELECT
+    e.vehicle_id,
+    v.license_plate,
+    COALESCE(v.model, '')::text AS vehicle_model,
+    COUNT(*) AS trips,
+    COALESCE(AVG(e.abs_error_min), 0)::float8 AS mean_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS median_abs_error_min,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_min), 0)::float8 AS p90_abs_error_min,
+    COALESCE(AVG(e.abs_error_pct), 0)::float8 AS mean_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS median_abs_error_pct,
+    COALESCE(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY e.abs_error_pct), 0)::float8 AS p90_abs_error_pct,
+    COALESCE(AVG(e.error_min), 0)::float8 AS bias_min
+FROM errors e
+JOIN vehicles v ON v.id = e.vehicle_id
+GROUP BY e.vehicle_id, v.license_plate, v.model
+ORDER BY trips DESC, e.vehicle_id
+`
+
+type ListETAAccuracyByVehicleParams struct {
+	CompletedFrom time.Time `json:"completed_from"`
+	CompletedTo   time.Time `json:"completed_to"`
+}
+
+type ListETAAccuracyByVehicleRow struct {
+	VehicleID         uuid.UUID `json:"vehicle_id"`
+	LicensePlate      string    `json:"license_plate"`
+	VehicleModel      string    `json:"vehicle_model"`
+	Trips             int64     `json:"trips"`
+	MeanAbsErrorMin   float64   `json:"mean_abs_error_min"`
+	MedianAbsErrorMin float64   `json:"median_abs_error_min"`
+	P90AbsErrorMin    float64   `json:"p90_abs_error_min"`
+	MeanAbsErrorPct   float64   `json:"mean_abs_error_pct"`
+	MedianAbsErrorPct float64   `json:"median_abs_error_pct"`
+	P90AbsErrorPct    float64   `json:"p90_abs_error_pct"`
+	BiasMin           float64   `json:"bias_min"`
+}
+
+func (q *Queries) ListETAAccuracyByVehicle(ctx context.Context, arg ListETAAccuracyByVehicleParams) ([]ListETAAccuracyByVehicleRow, error) {
+	rows, err := q.db.QueryContext(ctx, listETAAccuracyByVehicle, arg.CompletedFrom, arg.CompletedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListETAAccuracyByVehicleRow{}
+	for rows.Next() {
+		var i ListETAAccuracyByVehicleRow
+		if err := rows.Scan(
+			&i.VehicleID,
+			&i.LicensePlate,
+			&i.VehicleModel,
+			&i.Trips,
+			&i.MeanAbsErrorMin,
+			&i.MedianAbsErrorMin,
+			&i.P90AbsErrorMin,
+			&i.MeanAbsErrorPct,
+			&i.MedianAbsErrorPct,
+			&i.P90AbsErrorPct,
+			&i.BiasMin,
 		); err != nil {
 			return nil, err
 		}
@@ -179,7 +820,7 @@ func (q *Queries) GetRoutesByDriverID(ctx context.Context, arg GetRoutesByDriver
 }
 
 const listRoutesByDriverAndStatus = `-- name: ListRoutesByDriverAndStatus :many
-SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at FROM routes
+SELECT id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m FROM routes
 WHERE driver_id= $1
 AND status = $2
 ORDER BY created_at DESC
@@ -223,6 +864,82 @@ func (q *Queries) ListRoutesByDriverAndStatus(ctx context.Context, arg ListRoute
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.StartedAt,
+			&i.CompletedAt,
+			&i.CancelledAt,
+			&i.RemainingDistanceKm,
+			&i.RemainingDurationMin,
+			&i.EtaAt,
+			&i.EtaConfidence,
+			&i.EtaUpdatedAt,
+			&i.BaselineDurationMin,
+			&i.DurationP10Min,
+			&i.DurationP50Min,
+			&i.DurationP90Min,
+			&i.OriginGeofenceRadiusM,
+			&i.DestinationGeofenceRadiusM,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSpeedProfileSamples = `-- name: ListSpeedProfileSamples :many
+SELECT
+    origin_lat,
+    origin_lng,
+    started_at::timestamptz AS started_at,
+    COALESCE(baseline_duration_min, estimated_duration_min)::float8 AS baseline_duration_min,
+    actual_duration_min::float8 AS actual_duration_min
+FROM routes
+WHERE status = 'completed'
+AND completed_at >= $1::timestamptz
+AND started_at IS NOT NULL
+AND COALESCE(baseline_duration_min, estimated_duration_min) > 0
+AND actual_duration_min > 0
+ORDER BY completed_at DESC
+LIMIT $2::int
+`
+
+type ListSpeedProfileSamplesParams struct {
+	Since      time.Time `json:"since"`
+	MaxSamples int32     `json:"max_samples"`
+}
+
+type ListSpeedProfileSamplesRow struct {
+	OriginLat           float64   `json:"origin_lat"`
+	OriginLng           float64   `json:"origin_lng"`
+	StartedAt           time.Time `json:"started_at"`
+	BaselineDurationMin float64   `json:"baseline_duration_min"`
+	ActualDurationMin   float64   `json:"actual_duration_min"`
+}
+
+// Routes completed since the given time, with the free-flow estimate their
+// actual duration compares to. Routes planned before baselines were stored
+// had no traffic adjustment, so their estimate is their baseline.
+func (q *Queries) ListSpeedProfileSamples(ctx context.Context, arg ListSpeedProfileSamplesParams) ([]ListSpeedProfileSamplesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSpeedProfileSamples, arg.Since, arg.MaxSamples)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSpeedProfileSamplesRow{}
+	for rows.Next() {
+		var i ListSpeedProfileSamplesRow
+		if err := rows.Scan(
+			&i.OriginLat,
+			&i.OriginLng,
+			&i.StartedAt,
+			&i.BaselineDurationMin,
+			&i.ActualDurationMin,
 		); err != nil {
 			return nil, err
 		}
@@ -237,12 +954,78 @@ func (q *Queries) ListRoutesByDriverAndStatus(ctx context.Context, arg ListRoute
 	return items, nil
 }
 
+const transitionRouteStatus = `-- name: TransitionRouteStatus :one
+UPDATE routes
+SET status = $1::text,
+    started_at = CASE WHEN $1::text = 'in_progress' THEN $2::timestamptz ELSE started_at END,
+    completed_at = CASE WHEN $1::text = 'completed' THEN $2::timestamptz ELSE completed_at END,
+    cancelled_at = CASE WHEN $1::text = 'cancelled' THEN $2::timestamptz ELSE cancelled_at END,
+    actual_duration_min = CASE
+        WHEN $1::text = 'completed' AND started_at IS NOT NULL
+        THEN EXTRACT(EPOCH FROM ($2::timestamptz - started_at)) / 60
+        ELSE actual_duration_min
+    END,
+    updated_at = NOW()
+WHERE id = $3
+AND status = $4::text
+RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m
+`
+
+type TransitionRouteStatusParams struct {
+	ToStatus   string    `json:"to_status"`
+	At         time.Time `json:"at"`
+	ID         uuid.UUID `json:"id"`
+	FromStatus string    `json:"from_status"`
+}
+
+func (q *Queries) TransitionRouteStatus(ctx context.Context, arg TransitionRouteStatusParams) (Route, error) {
+	row := q.db.QueryRowContext(ctx, transitionRouteStatus,
+		arg.ToStatus,
+		arg.At,
+		arg.ID,
+		arg.FromStatus,
+	)
+	var i Route
+	err := row.Scan(
+		&i.ID,
+		&i.DriverID,
+		&i.VehicleID,
+		&i.OriginLat,
+		&i.OriginLng,
+		&i.DestinationLat,
+		&i.DestinationLng,
+		&i.OriginAddress,
+		&i.DestinationAddress,
+		&i.EstimatedDistanceKm,
+		&i.EstimatedDurationMin,
+		&i.ActualDurationMin,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
+	)
+	return i, err
+}
+
 const updateRouteActualDuration = `-- name: UpdateRouteActualDuration :one
 UPDATE routes
 SET actual_duration_min = $2,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at
+RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m
 `
 
 type UpdateRouteActualDurationParams struct {
@@ -269,6 +1052,151 @@ func (q *Queries) UpdateRouteActualDuration(ctx context.Context, arg UpdateRoute
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
+	)
+	return i, err
+}
+
+const updateRouteEta = `-- name: UpdateRouteEta :one
+UPDATE routes
+SET remaining_distance_km = $2,
+    remaining_duration_min = $3,
+    eta_at = $4,
+    eta_confidence = $5,
+    eta_updated_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m
+`
+
+type UpdateRouteEtaParams struct {
+	ID                   uuid.UUID       `json:"id"`
+	RemainingDistanceKm  sql.NullFloat64 `json:"remaining_distance_km"`
+	RemainingDurationMin sql.NullFloat64 `json:"remaining_duration_min"`
+	EtaAt                sql.NullTime    `json:"eta_at"`
+	EtaConfidence        sql.NullFloat64 `json:"eta_confidence"`
+}
+
+func (q *Queries) UpdateRouteEta(ctx context.Context, arg UpdateRouteEtaParams) (Route, error) {
+	row := q.db.QueryRowContext(ctx, updateRouteEta,
+		arg.ID,
+		arg.RemainingDistanceKm,
+		arg.RemainingDurationMin,
+		arg.EtaAt,
+		arg.EtaConfidence,
+	)
+	var i Route
+	err := row.Scan(
+		&i.ID,
+		&i.DriverID,
+		&i.VehicleID,
+		&i.OriginLat,
+		&i.OriginLng,
+		&i.DestinationLat,
+		&i.DestinationLng,
+		&i.OriginAddress,
+		&i.DestinationAddress,
+		&i.EstimatedDistanceKm,
+		&i.EstimatedDurationMin,
+		&i.ActualDurationMin,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
+	)
+	return i, err
+}
+
+const updateRoutePlan = `-- name: UpdateRoutePlan :one
+UPDATE routes
+SET estimated_distance_km = $2,
+    estimated_duration_min = $3,
+    baseline_duration_min = $4,
+    duration_p10_min = $5,
+    duration_p50_min = $6,
+    duration_p90_min = $7,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m
+`
+
+type UpdateRoutePlanParams struct {
+	ID                   uuid.UUID       `json:"id"`
+	EstimatedDistanceKm  sql.NullFloat64 `json:"estimated_distance_km"`
+	EstimatedDurationMin sql.NullFloat64 `json:"estimated_duration_min"`
+	BaselineDurationMin  sql.NullFloat64 `json:"baseline_duration_min"`
+	DurationP10Min       sql.NullFloat64 `json:"duration_p10_min"`
+	DurationP50Min       sql.NullFloat64 `json:"duration_p50_min"`
+	DurationP90Min       sql.NullFloat64 `json:"duration_p90_min"`
+}
+
+func (q *Queries) UpdateRoutePlan(ctx context.Context, arg UpdateRoutePlanParams) (Route, error) {
+	row := q.db.QueryRowContext(ctx, updateRoutePlan,
+		arg.ID,
+		arg.EstimatedDistanceKm,
+		arg.EstimatedDurationMin,
+		arg.BaselineDurationMin,
+		arg.DurationP10Min,
+		arg.DurationP50Min,
+		arg.DurationP90Min,
+	)
+	var i Route
+	err := row.Scan(
+		&i.ID,
+		&i.DriverID,
+		&i.VehicleID,
+		&i.OriginLat,
+		&i.OriginLng,
+		&i.DestinationLat,
+		&i.DestinationLng,
+		&i.OriginAddress,
+		&i.DestinationAddress,
+		&i.EstimatedDistanceKm,
+		&i.EstimatedDurationMin,
+		&i.ActualDurationMin,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
 	)
 	return i, err
 }
@@ -278,7 +1206,7 @@ UPDATE routes
 SET status = COALESCE($2, status),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at
+RETURNING id, driver_id, vehicle_id, origin_lat, origin_lng, destination_lat, destination_lng, origin_address, destination_address, estimated_distance_km, estimated_duration_min, actual_duration_min, status, created_at, updated_at, started_at, completed_at, cancelled_at, remaining_distance_km, remaining_duration_min, eta_at, eta_confidence, eta_updated_at, baseline_duration_min, duration_p10_min, duration_p50_min, duration_p90_min, origin_geofence_radius_m, destination_geofence_radius_m
 `
 
 type UpdateRouteStatusParams struct {
@@ -305,6 +1233,20 @@ func (q *Queries) UpdateRouteStatus(ctx context.Context, arg UpdateRouteStatusPa
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.CancelledAt,
+		&i.RemainingDistanceKm,
+		&i.RemainingDurationMin,
+		&i.EtaAt,
+		&i.EtaConfidence,
+		&i.EtaUpdatedAt,
+		&i.BaselineDurationMin,
+		&i.DurationP10Min,
+		&i.DurationP50Min,
+		&i.DurationP90Min,
+		&i.OriginGeofenceRadiusM,
+		&i.DestinationGeofenceRadiusM,
 	)
 	return i, err
 }
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRouteEtaHistory(t *testing.T, route Route, location RouteLocation) RouteEtaHistory {
+	arg := CreateRouteEtaHistoryParams{
+		RouteID:              route.ID,
+		LocationID:           sql.NullInt64{Int64: location.ID, Valid: true},
+		RemainingDistanceKm:  12.5,
+		RemainingDurationMin: 21.4,
+		EtaAt:                time.Now().Add(21 * time.Minute),
+		Confidence:           0.75,
+	}
+
+	history, err := testQueries.CreateRouteEtaHistory(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, history.ID)
+
+	require.Equal(t, arg.RouteID, history.RouteID)
+	require.Equal(t, arg.LocationID, history.LocationID)
+	require.Equal(t, arg.RemainingDistanceKm, history.RemainingDistanceKm)
+	require.Equal(t, arg.RemainingDurationMin, history.RemainingDurationMin)
+	require.WithinDuration(t, arg.EtaAt, history.EtaAt, time.Second)
+	require.Equal(t, arg.Confidence, history.Confidence)
+	require.NotZero(t, history.CreatedAt)
+
+	return history
+}
+
+func TestCreateRouteEtaHistory(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	location := createRandomRouteLocation(t, route, time.Now())
+	createRandomRouteEtaHistory(t, route, location)
+}
+
+func TestListRouteEtaHistory(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	location := createRandomRouteLocation(t, route, time.Now())
+
+	var last RouteEtaHistory
+	for i := 0; i < 6; i++ {
+		last = createRandomRouteEtaHistory(t, route, location)
+	}
+
+	history, err := testQueries.ListRouteEtaHistory(context.Background(), ListRouteEtaHistoryParams{
+		RouteID: route.ID,
+		Limit:   5,
+		Offset:  0,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+	// newest first
+	require.Equal(t, last.ID, history[0].ID)
+	for _, entry := range history {
+		require.Equal(t, route.ID, entry.RouteID)
+	}
+}
+
+func TestUpdateRouteEta(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	arg := UpdateRouteEtaParams{
+		ID:                   route.ID,
+		RemainingDistanceKm:  sql.NullFloat64{Float64: 8.4, Valid: true},
+		RemainingDurationMin: sql.NullFloat64{Float64: 14.2, Valid: true},
+		EtaAt:                sql.NullTime{Time: time.Now().Add(14 * time.Minute), Valid: true},
+		EtaConfidence:        sql.NullFloat64{Float64: 0.8, Valid: true},
+	}
+	updated, err := testQueries.UpdateRouteEta(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, route.ID, updated.ID)
+	require.Equal(t, arg.RemainingDistanceKm, updated.RemainingDistanceKm)
+	require.Equal(t, arg.RemainingDurationMin, updated.RemainingDurationMin)
+	require.WithinDuration(t, arg.EtaAt.Time, updated.EtaAt.Time, time.Second)
+	require.Equal(t, arg.EtaConfidence, updated.EtaConfidence)
+	require.True(t, updated.EtaUpdatedAt.Valid)
+}
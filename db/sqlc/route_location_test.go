@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRouteLocation(t *testing.T, route Route, recordedAt time.Time) RouteLocation {
+	arg := CreateRouteLocationParams{
+		RouteID:    route.ID,
+		DriverID:   route.DriverID,
+		Lat:        route.OriginLat,
+		Lng:        route.OriginLng,
+		SpeedKmh:   sql.NullFloat64{Float64: 32.5, Valid: true},
+		HeadingDeg: sql.NullFloat64{Float64: 90, Valid: true},
+		RecordedAt: recordedAt,
+	}
+
+	location, err := testQueries.CreateRouteLocation(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, location.ID)
+
+	require.Equal(t, arg.RouteID, location.RouteID)
+	require.Equal(t, arg.DriverID, location.DriverID)
+	require.Equal(t, arg.Lat, location.Lat)
+	require.Equal(t, arg.Lng, location.Lng)
+	require.Equal(t, arg.SpeedKmh, location.SpeedKmh)
+	require.Equal(t, arg.HeadingDeg, location.HeadingDeg)
+	require.False(t, location.AccuracyM.Valid)
+	require.WithinDuration(t, arg.RecordedAt, location.RecordedAt, time.Second)
+	require.NotZero(t, location.CreatedAt)
+
+	return location
+}
+
+func TestCreateRouteLocation(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	createRandomRouteLocation(t, route, time.Now())
+}
+
+func TestGetLatestRouteLocation(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	latest := createRandomRouteLocation(t, route, now)
+	createRandomRouteLocation(t, route, now.Add(-time.Minute))
+
+	location, err := testQueries.GetLatestRouteLocation(context.Background(), route.ID)
+	require.NoError(t, err)
+	require.Equal(t, latest.ID, location.ID)
+}
+
+func TestListRouteLocations(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		createRandomRouteLocation(t, route, now.Add(-time.Duration(i)*time.Minute))
+	}
+
+	locations, err := testQueries.ListRouteLocations(context.Background(), ListRouteLocationsParams{
+		RouteID: route.ID,
+		Limit:   3,
+		Offset:  0,
+	})
+	require.NoError(t, err)
+	require.Len(t, locations, 3)
+	for i := 1; i < len(locations); i++ {
+		require.True(t, locations[i-1].RecordedAt.After(locations[i].RecordedAt))
+	}
+}
+
+func TestListRouteLocationsSince(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	createRandomRouteLocation(t, route, now.Add(-20*time.Minute))
+	older := createRandomRouteLocation(t, route, now.Add(-5*time.Minute))
+	newer := createRandomRouteLocation(t, route, now)
+
+	locations, err := testQueries.ListRouteLocationsSince(context.Background(), ListRouteLocationsSinceParams{
+		RouteID:    route.ID,
+		RecordedAt: now.Add(-10 * time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, locations, 2)
+	require.Equal(t, older.ID, locations[0].ID)
+	require.Equal(t, newer.ID, locations[1].ID)
+}
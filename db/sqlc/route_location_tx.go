@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type CreateRouteLocationsTxParams struct {
+	Locations []CreateRouteLocationParams `json:"locations"`
+}
+
+type CreateRouteLocationsTxResult struct {
+	Locations []RouteLocation `json:"locations"`
+}
+
+// CreateRouteLocationsTx stores a batch of pings in the order given. Either
+// all of them are stored or none are, so a client can safely retry a batch
+// that failed.
+func (store *SQLStore) CreateRouteLocationsTx(ctx context.Context, arg CreateRouteLocationsTxParams) (CreateRouteLocationsTxResult, error) {
+	var result CreateRouteLocationsTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		result.Locations = make([]RouteLocation, 0, len(arg.Locations))
+		for _, location := range arg.Locations {
+			created, err := q.CreateRouteLocation(ctx, location)
+			if err != nil {
+				return err
+			}
+			result.Locations = append(result.Locations, created)
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRouteLocationsTx(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	arg := CreateRouteLocationsTxParams{}
+	for i := 0; i < 3; i++ {
+		arg.Locations = append(arg.Locations, CreateRouteLocationParams{
+			RouteID:    route.ID,
+			DriverID:   user.ID,
+			Lat:        6.5 + float64(i)/1000,
+			Lng:        3.3,
+			RecordedAt: now.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	result, err := testStore.CreateRouteLocationsTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, result.Locations, 3)
+	for i, location := range result.Locations {
+		require.NotZero(t, location.ID)
+		require.Equal(t, arg.Locations[i].Lat, location.Lat)
+	}
+}
+
+func TestCreateRouteLocationsTxRollback(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	now := time.Now()
+	arg := CreateRouteLocationsTxParams{
+		Locations: []CreateRouteLocationParams{
+			{RouteID: route.ID, DriverID: user.ID, Lat: 6.5, Lng: 3.3, RecordedAt: now},
+			// no such driver, so the insert fails after the first ping
+			{RouteID: route.ID, DriverID: uuid.New(), Lat: 6.5, Lng: 3.3, RecordedAt: now.Add(time.Second)},
+		},
+	}
+
+	_, err := testStore.CreateRouteLocationsTx(context.Background(), arg)
+	require.Error(t, err)
+
+	_, err = testQueries.GetLatestRouteLocation(context.Background(), route.ID)
+	require.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertRoutePath(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	_, err := testQueries.GetRoutePath(context.Background(), route.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	for _, points := range []string{
+		`[{"lat": 6.5, "lng": 3.3}, {"lat": 6.6, "lng": 3.3}]`,
+		`[{"lat": 6.5, "lng": 3.3}, {"lat": 6.5, "lng": 3.4}, {"lat": 6.6, "lng": 3.4}]`,
+	} {
+		err = testQueries.UpsertRoutePath(context.Background(), UpsertRoutePathParams{
+			RouteID: route.ID,
+			Points:  json.RawMessage(points),
+		})
+		require.NoError(t, err)
+
+		path, err := testQueries.GetRoutePath(context.Background(), route.ID)
+		require.NoError(t, err)
+		require.Equal(t, route.ID, path.RouteID)
+		require.JSONEq(t, points, string(path.Points))
+		require.WithinDuration(t, time.Now(), path.CreatedAt, time.Minute)
+	}
+}
+
+func TestDeleteRoutePath(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	err := testQueries.UpsertRoutePath(context.Background(), UpsertRoutePathParams{
+		RouteID: route.ID,
+		Points:  json.RawMessage(`[{"lat": 6.5, "lng": 3.3}, {"lat": 6.6, "lng": 3.3}]`),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, testQueries.DeleteRoutePath(context.Background(), route.ID))
+	_, err = testQueries.GetRoutePath(context.Background(), route.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRouteStops(t *testing.T, route Route, n int) []RouteStop {
+	stops := make([]NewRouteStop, 0, n)
+	for i := 0; i < n; i++ {
+		stops = append(stops, NewRouteStop{
+			Lat:            route.OriginLat + 0.01*float64(i+1),
+			Lng:            route.OriginLng + 0.01*float64(i+1),
+			Address:        sql.NullString{String: "789 Oak st", Valid: true},
+			ServiceTimeMin: 5,
+		})
+	}
+	// the first stop is booked for a delivery window
+	windowStart := time.Now().Add(time.Hour)
+	stops[0].WindowStart = sql.NullTime{Time: windowStart, Valid: true}
+	stops[0].WindowEnd = sql.NullTime{Time: windowStart.Add(time.Hour), Valid: true}
+	stops[0].GeofenceRadiusM = sql.NullFloat64{Float64: 80, Valid: true}
+
+	result, err := testStore.ReplaceRouteStopsTx(context.Background(), ReplaceRouteStopsTxParams{
+		RouteID:              route.ID,
+		FromStatus:           route.Status,
+		Stops:                stops,
+		EstimatedDistanceKm:  42,
+		EstimatedDurationMin: 90,
+		BaselineDurationMin:  75,
+		DurationP10Min:       sql.NullFloat64{Float64: 85, Valid: true},
+		DurationP50Min:       sql.NullFloat64{Float64: 100, Valid: true},
+		DurationP90Min:       sql.NullFloat64{Float64: 130, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Stops, n)
+	require.Equal(t, 42.0, result.Route.EstimatedDistanceKm.Float64)
+	require.Equal(t, 90.0, result.Route.EstimatedDurationMin.Float64)
+	require.Equal(t, 75.0, result.Route.BaselineDurationMin.Float64)
+	require.Equal(t, 100.0, result.Route.DurationP50Min.Float64)
+	require.Equal(t, 130.0, result.Route.DurationP90Min.Float64)
+
+	for i, stop := range result.Stops {
+		require.Equal(t, route.ID, stop.RouteID)
+		require.Equal(t, int32(i+1), stop.Sequence)
+		require.Equal(t, stops[i].Lat, stop.Lat)
+		require.Equal(t, "pending", stop.Status)
+		require.False(t, stop.ArrivedAt.Valid)
+		require.Equal(t, stops[i].WindowStart.Valid, stop.WindowStart.Valid)
+		require.Equal(t, stops[i].GeofenceRadiusM, stop.GeofenceRadiusM)
+	}
+	require.WithinDuration(t, windowStart, result.Stops[0].WindowStart.Time, time.Second)
+	return result.Stops
+}
+
+func TestReplaceRouteStopsTx(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	createRandomRouteStops(t, route, 3)
+	replaced := createRandomRouteStops(t, route, 2)
+
+	stops, err := testQueries.ListRouteStops(context.Background(), route.ID)
+	require.NoError(t, err)
+	require.Len(t, stops, 2)
+	for i := range stops {
+		require.Equal(t, replaced[i].ID, stops[i].ID)
+	}
+}
+
+func TestReplaceRouteStopsTxPath(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	points := `[{"lat": 6.5, "lng": 3.3}, {"lat": 6.6, "lng": 3.3}]`
+
+	_, err := testStore.ReplaceRouteStopsTx(context.Background(), ReplaceRouteStopsTxParams{
+		RouteID:    route.ID,
+		FromStatus: route.Status,
+		Stops:      []NewRouteStop{{Lat: 6.55, Lng: 3.3}},
+		Path:       json.RawMessage(points),
+	})
+	require.NoError(t, err)
+	path, err := testQueries.GetRoutePath(context.Background(), route.ID)
+	require.NoError(t, err)
+	require.JSONEq(t, points, string(path.Points))
+
+	// stops planned without roads leave no path to check positions against
+	_, err = testStore.ReplaceRouteStopsTx(context.Background(), ReplaceRouteStopsTxParams{
+		RouteID:    route.ID,
+		FromStatus: route.Status,
+		Stops:      []NewRouteStop{{Lat: 7, Lng: 4}},
+	})
+	require.NoError(t, err)
+	_, err = testQueries.GetRoutePath(context.Background(), route.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestReplaceRouteStopsTxStatusChanged(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	_, err := testStore.ReplaceRouteStopsTx(context.Background(), ReplaceRouteStopsTxParams{
+		RouteID:    route.ID,
+		FromStatus: "in_progress",
+		Stops:      []NewRouteStop{{Lat: 1, Lng: 1}},
+	})
+	require.ErrorIs(t, err, ErrRouteStatusChanged)
+
+	stops, err := testQueries.ListRouteStops(context.Background(), route.ID)
+	require.NoError(t, err)
+	require.Empty(t, stops)
+}
+
+func TestTransitionRouteStopStatus(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	stops := createRandomRouteStops(t, route, 2)
+
+	// the arrival is stamped with the time it happened, not the time it was written
+	arrivedAt := time.Now().Add(-10 * time.Minute)
+	arrived, err := testQueries.TransitionRouteStopStatus(context.Background(), TransitionRouteStopStatusParams{
+		ToStatus:   "arrived",
+		ID:         stops[0].ID,
+		RouteID:    route.ID,
+		FromStatus: "pending",
+		At:         arrivedAt,
+	})
+	require.NoError(t, err)
+	require.True(t, arrived.ArrivedAt.Valid)
+	require.WithinDuration(t, arrivedAt, arrived.ArrivedAt.Time, time.Second)
+	require.False(t, arrived.DepartedAt.Valid)
+
+	completed, err := testQueries.TransitionRouteStopStatus(context.Background(), TransitionRouteStopStatusParams{
+		ToStatus:   "completed",
+		ID:         stops[0].ID,
+		RouteID:    route.ID,
+		FromStatus: "arrived",
+		At:         time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, arrived.ArrivedAt.Time, completed.ArrivedAt.Time)
+	require.True(t, completed.DepartedAt.Valid)
+
+	// a stop given up on before reaching it has no arrival
+	failed, err := testQueries.TransitionRouteStopStatus(context.Background(), TransitionRouteStopStatusParams{
+		ToStatus:      "failed",
+		FailureReason: sql.NullString{String: "road closed", Valid: true},
+		ID:            stops[1].ID,
+		RouteID:       route.ID,
+		FromStatus:    "pending",
+		At:            time.Now(),
+	})
+	require.NoError(t, err)
+	require.False(t, failed.ArrivedAt.Valid)
+	require.True(t, failed.DepartedAt.Valid)
+	require.Equal(t, "road closed", failed.FailureReason.String)
+
+	// the status no longer matches, so nothing is updated
+	_, err = testQueries.TransitionRouteStopStatus(context.Background(), TransitionRouteStopStatusParams{
+		ToStatus:   "arrived",
+		ID:         stops[1].ID,
+		RouteID:    route.ID,
+		FromStatus: "pending",
+		At:         time.Now(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetRouteStopOtherRoute(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route1 := createRandomRoute(t, &user, &vehicle)
+	route2 := createRandomRoute(t, &user, &vehicle)
+	stops := createRandomRouteStops(t, route1, 1)
+
+	_, err := testQueries.GetRouteStop(context.Background(), GetRouteStopParams{ID: stops[0].ID, RouteID: route2.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// NewRouteStop is one stop of the ordered list given to ReplaceRouteStopsTx.
+type NewRouteStop struct {
+	ShipmentID     uuid.NullUUID  `json:"shipment_id"`
+	Lat            float64        `json:"lat"`
+	Lng            float64        `json:"lng"`
+	Address        sql.NullString `json:"address"`
+	ServiceTimeMin float64        `json:"service_time_min"`
+	WindowStart    sql.NullTime   `json:"window_start"`
+	WindowEnd      sql.NullTime   `json:"window_end"`
+	// GeofenceRadiusM is null for the configured default
+	GeofenceRadiusM sql.NullFloat64 `json:"geofence_radius_m"`
+}
+
+type ReplaceRouteStopsTxParams struct {
+	RouteID    uuid.UUID      `json:"route_id"`
+	FromStatus string         `json:"from_status"`
+	Stops      []NewRouteStop `json:"stops"`
+	// Plan of the whole trip through the new stops
+	EstimatedDistanceKm  float64 `json:"estimated_distance_km"`
+	EstimatedDurationMin float64 `json:"estimated_duration_min"`
+	BaselineDurationMin  float64 `json:"baseline_duration_min"`
+	// Quantiles of the trip's duration, null when there's no history to
+	// derive them from
+	DurationP10Min sql.NullFloat64 `json:"duration_p10_min"`
+	DurationP50Min sql.NullFloat64 `json:"duration_p50_min"`
+	DurationP90Min sql.NullFloat64 `json:"duration_p90_min"`
+	// Path is the roads the trip follows, nil when it couldn't be planned
+	// along roads
+	Path json.RawMessage `json:"path"`
+}
+
+type ReplaceRouteStopsTxResult struct {
+	Route Route       `json:"route"`
+	Stops []RouteStop `json:"stops"`
+}
+
+// ReplaceRouteStopsTx swaps the route's stops for the given ones, numbered in
+// order from 1, and stores the trip plan through them along with its path,
+// dropping the old path when there is no new one. The route is locked and
+// must still have FromStatus.
+func (store *SQLStore) ReplaceRouteStopsTx(ctx context.Context, arg ReplaceRouteStopsTxParams) (ReplaceRouteStopsTxResult, error) {
+	var result ReplaceRouteStopsTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		route, err := lockRoute(ctx, q, arg.RouteID, arg.FromStatus)
+		if err != nil {
+			return err
+		}
+
+		if err := q.DeleteRouteStops(ctx, route.ID); err != nil {
+			return err
+		}
+
+		result.Stops = make([]RouteStop, 0, len(arg.Stops))
+		for i, stop := range arg.Stops {
+			created, err := q.CreateRouteStop(ctx, CreateRouteStopParams{
+				RouteID:         route.ID,
+				ShipmentID:      stop.ShipmentID,
+				Sequence:        int32(i + 1),
+				Lat:             stop.Lat,
+				Lng:             stop.Lng,
+				Address:         stop.Address,
+				ServiceTimeMin:  stop.ServiceTimeMin,
+				WindowStart:     stop.WindowStart,
+				WindowEnd:       stop.WindowEnd,
+				GeofenceRadiusM: stop.GeofenceRadiusM,
+			})
+			if err != nil {
+				return err
+			}
+			result.Stops = append(result.Stops, created)
+		}
+
+		result.Route, err = q.UpdateRoutePlan(ctx, UpdateRoutePlanParams{
+			ID:                   route.ID,
+			EstimatedDistanceKm:  sql.NullFloat64{Float64: arg.EstimatedDistanceKm, Valid: true},
+			EstimatedDurationMin: sql.NullFloat64{Float64: arg.EstimatedDurationMin, Valid: true},
+			BaselineDurationMin:  sql.NullFloat64{Float64: arg.BaselineDurationMin, Valid: true},
+			DurationP10Min:       arg.DurationP10Min,
+			DurationP50Min:       arg.DurationP50Min,
+			DurationP90Min:       arg.DurationP90Min,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(arg.Path) == 0 {
+			return q.DeleteRoutePath(ctx, route.ID)
+		}
+		return q.UpsertRoutePath(ctx, UpsertRoutePathParams{RouteID: route.ID, Points: arg.Path})
+	})
+	return result, err
+}
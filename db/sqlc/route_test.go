@@ -27,6 +27,11 @@ func createRandomRoute(t  *testing.T, user *User, vehicle *Vehicle) Route {
 		DestinationLng: -122.4094,
 		EstimatedDistanceKm: sql.NullFloat64{Float64: 5.0, Valid: true},
 		EstimatedDurationMin: sql.NullFloat64{Float64: 15.0, Valid: true},
+		BaselineDurationMin: sql.NullFloat64{Float64: 12.0, Valid: true},
+		DurationP10Min: sql.NullFloat64{Float64: 14.0, Valid: true},
+		DurationP50Min: sql.NullFloat64{Float64: 17.0, Valid: true},
+		DurationP90Min: sql.NullFloat64{Float64: 25.0, Valid: true},
+		DestinationGeofenceRadiusM: sql.NullFloat64{Float64: 300.0, Valid: true},
 		Status: "pending",
 	}
 
@@ -45,6 +50,12 @@ func createRandomRoute(t  *testing.T, user *User, vehicle *Vehicle) Route {
 	require.Equal(t, arg.DestinationLng, route.DestinationLng)
 	require.Equal(t, arg.EstimatedDistanceKm, route.EstimatedDistanceKm)
 	require.Equal(t, arg.EstimatedDurationMin, route.EstimatedDurationMin)
+	require.Equal(t, arg.BaselineDurationMin, route.BaselineDurationMin)
+	require.Equal(t, arg.DurationP10Min, route.DurationP10Min)
+	require.Equal(t, arg.DurationP50Min, route.DurationP50Min)
+	require.Equal(t, arg.DurationP90Min, route.DurationP90Min)
+	require.False(t, route.OriginGeofenceRadiusM.Valid)
+	require.Equal(t, arg.DestinationGeofenceRadiusM, route.DestinationGeofenceRadiusM)
 	require.Equal(t, arg.Status, route.Status)
 
 	require.NotZero(t, route.CreatedAt)
@@ -196,3 +207,181 @@ func TestUpdateRouteActualDuration(t *testing.T) {
 	require.Equal(t, route.VehicleID, route2.VehicleID)
 	require.Equal(t, newActualDuration, route2.ActualDurationMin)
 }
+
+func TestTransitionRouteStatus(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	started, err := testQueries.TransitionRouteStatus(context.Background(), TransitionRouteStatusParams{
+		ID:         route.ID,
+		FromStatus: string(util.RoutePending),
+		ToStatus:   string(util.RouteInProgress),
+		At:         time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, string(util.RouteInProgress), started.Status)
+	require.True(t, started.StartedAt.Valid)
+	require.False(t, started.CompletedAt.Valid)
+
+	// a stale from-status must not match the row
+	_, err = testQueries.TransitionRouteStatus(context.Background(), TransitionRouteStatusParams{
+		ID:         route.ID,
+		FromStatus: string(util.RoutePending),
+		ToStatus:   string(util.RouteCancelled),
+		At:         time.Now(),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	completed, err := testQueries.TransitionRouteStatus(context.Background(), TransitionRouteStatusParams{
+		ID:         route.ID,
+		FromStatus: string(util.RouteInProgress),
+		ToStatus:   string(util.RouteCompleted),
+		At:         time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, string(util.RouteCompleted), completed.Status)
+	require.True(t, completed.CompletedAt.Valid)
+	require.WithinDuration(t, started.StartedAt.Time, completed.StartedAt.Time, 0)
+	require.True(t, completed.ActualDurationMin.Valid)
+	require.GreaterOrEqual(t, completed.ActualDurationMin.Float64, 0.0)
+}
+
+func TestListSpeedProfileSamples(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	since := time.Now().Add(-time.Minute)
+
+	for _, status := range []util.RouteStatus{util.RouteInProgress, util.RouteCompleted} {
+		var err error
+		route, err = testQueries.TransitionRouteStatus(context.Background(), TransitionRouteStatusParams{
+			ID:         route.ID,
+			FromStatus: route.Status,
+			ToStatus:   string(status),
+			At:         time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	_, err := testQueries.UpdateRouteActualDuration(context.Background(), UpdateRouteActualDurationParams{
+		ID:                route.ID,
+		ActualDurationMin: sql.NullFloat64{Float64: 20, Valid: true},
+	})
+	require.NoError(t, err)
+
+	samples, err := testQueries.ListSpeedProfileSamples(context.Background(), ListSpeedProfileSamplesParams{
+		Since:      since,
+		MaxSamples: 10,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, samples)
+	// the latest completed route comes first
+	require.Equal(t, route.OriginLat, samples[0].OriginLat)
+	require.WithinDuration(t, route.StartedAt.Time, samples[0].StartedAt, time.Millisecond)
+	require.Equal(t, route.BaselineDurationMin.Float64, samples[0].BaselineDurationMin)
+	require.Equal(t, 20.0, samples[0].ActualDurationMin)
+
+	samples, err = testQueries.ListSpeedProfileSamples(context.Background(), ListSpeedProfileSamplesParams{
+		Since:      time.Now().Add(time.Minute),
+		MaxSamples: 10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, samples)
+}
+
+func TestListCorrectionSamples(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+	since := time.Now().Add(-time.Minute)
+
+	for _, status := range []util.RouteStatus{util.RouteInProgress, util.RouteCompleted} {
+		var err error
+		route, err = testQueries.TransitionRouteStatus(context.Background(), TransitionRouteStatusParams{
+			ID:         route.ID,
+			FromStatus: route.Status,
+			ToStatus:   string(status),
+			At:         time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	_, err := testQueries.UpdateRouteActualDuration(context.Background(), UpdateRouteActualDurationParams{
+		ID:                route.ID,
+		ActualDurationMin: sql.NullFloat64{Float64: 20, Valid: true},
+	})
+	require.NoError(t, err)
+
+	samples, err := testQueries.ListCorrectionSamples(context.Background(), ListCorrectionSamplesParams{
+		Since:      since,
+		MaxSamples: 10,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, samples)
+	// the latest completed route comes first
+	sample := samples[0]
+	require.Equal(t, user.ID, sample.DriverID)
+	require.Equal(t, vehicle.Model.String, sample.VehicleModel)
+	require.Equal(t, route.DestinationLat, sample.DestinationLat)
+	require.WithinDuration(t, route.StartedAt.Time, sample.StartedAt, time.Millisecond)
+	require.Equal(t, route.EstimatedDistanceKm.Float64, sample.EstimatedDistanceKm)
+	require.Equal(t, route.EstimatedDurationMin.Float64, sample.EstimatedDurationMin)
+	require.Equal(t, route.BaselineDurationMin.Float64, sample.BaselineDurationMin)
+	require.Equal(t, 20.0, sample.ActualDurationMin)
+}
+
+func TestListETAAccuracyByDriver(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	from := time.Now().Add(-time.Minute)
+
+	// estimated at 15 minutes, the routes take 10, 20 and 30
+	for _, actual := range []float64{10, 20, 30} {
+		route := createRandomRoute(t, &user, &vehicle)
+		for _, status := range []util.RouteStatus{util.RouteInProgress, util.RouteCompleted} {
+			var err error
+			route, err = testQueries.TransitionRouteStatus(context.Background(), TransitionRouteStatusParams{
+				ID:         route.ID,
+				FromStatus: route.Status,
+				ToStatus:   string(status),
+				At:         time.Now(),
+			})
+			require.NoError(t, err)
+		}
+		_, err := testQueries.UpdateRouteActualDuration(context.Background(), UpdateRouteActualDurationParams{
+			ID:                route.ID,
+			ActualDurationMin: sql.NullFloat64{Float64: actual, Valid: true},
+		})
+		require.NoError(t, err)
+	}
+
+	rows, err := testQueries.ListETAAccuracyByDriver(context.Background(), ListETAAccuracyByDriverParams{
+		CompletedFrom: from,
+		CompletedTo:   time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	var found bool
+	for _, row := range rows {
+		if row.DriverID != user.ID {
+			continue
+		}
+		found = true
+		require.Equal(t, user.Name, row.DriverName)
+		require.Equal(t, int64(3), row.Trips)
+		require.InDelta(t, 25.0/3, row.MeanAbsErrorMin, 1e-9)
+		require.InDelta(t, 5, row.MedianAbsErrorMin, 1e-9)
+		require.InDelta(t, 13, row.P90AbsErrorMin, 1e-9)
+		require.InDelta(t, (50+25+50)/3.0, row.MeanAbsErrorPct, 1e-9)
+		require.InDelta(t, 5, row.BiasMin, 1e-9)
+	}
+	require.True(t, found)
+
+	// nothing completed in the future
+	overall, err := testQueries.GetETAAccuracy(context.Background(), GetETAAccuracyParams{
+		CompletedFrom: time.Now().Add(time.Hour),
+		CompletedTo:   time.Now().Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Zero(t, overall.Trips)
+	require.Zero(t, overall.MeanAbsErrorMin)
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrRouteStatusChanged is returned when the route no longer has the
+	// status the caller expected to move it from.
+	ErrRouteStatusChanged = errors.New("route status was changed by another request")
+	// ErrVehicleInUse is returned when the route's vehicle is already out on
+	// another route.
+	ErrVehicleInUse = errors.New("vehicle is already assigned to an active route")
+)
+
+const (
+	routeStatusInProgress = "in_progress"
+	routeStatusCompleted  = "completed"
+)
+
+type StartRouteTxParams struct {
+	RouteID    uuid.UUID `json:"route_id"`
+	FromStatus string    `json:"from_status"`
+	At         time.Time `json:"at"`
+}
+
+type StartRouteTxResult struct {
+	Route   Route   `json:"route"`
+	Vehicle Vehicle `json:"vehicle"`
+}
+
+// StartRouteTx moves a route to in_progress, as started at arg.At. The route
+// and its vehicle are locked for the length of the transaction, so two routes
+// sharing a vehicle can't both be started.
+func (store *SQLStore) StartRouteTx(ctx context.Context, arg StartRouteTxParams) (StartRouteTxResult, error) {
+	var result StartRouteTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		route, err := lockRoute(ctx, q, arg.RouteID, arg.FromStatus)
+		if err != nil {
+			return err
+		}
+
+		result.Vehicle, err = q.GetVehicleForUpdate(ctx, route.VehicleID)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.GetActiveRouteByVehicle(ctx, route.VehicleID)
+		if err == nil {
+			return ErrVehicleInUse
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		result.Route, err = q.TransitionRouteStatus(ctx, TransitionRouteStatusParams{
+			ID:         route.ID,
+			FromStatus: arg.FromStatus,
+			ToStatus:   routeStatusInProgress,
+			At:         arg.At,
+		})
+		return err
+	})
+	if isUniqueViolation(err) {
+		// idx_routes_active_vehicle caught a start the locks didn't serialize
+		return result, ErrVehicleInUse
+	}
+	return result, err
+}
+
+type CompleteRouteTxParams struct {
+	RouteID    uuid.UUID `json:"route_id"`
+	FromStatus string    `json:"from_status"`
+	At         time.Time `json:"at"`
+}
+
+type CompleteRouteTxResult struct {
+	Route   Route   `json:"route"`
+	Vehicle Vehicle `json:"vehicle"`
+}
+
+// CompleteRouteTx moves a route to completed, as completed at arg.At, and
+// releases its vehicle. The vehicle is locked like in StartRouteTx, so a start
+// waiting on it sees the vehicle as free once this commits.
+func (store *SQLStore) CompleteRouteTx(ctx context.Context, arg CompleteRouteTxParams) (CompleteRouteTxResult, error) {
+	var result CompleteRouteTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		route, err := lockRoute(ctx, q, arg.RouteID, arg.FromStatus)
+		if err != nil {
+			return err
+		}
+
+		result.Vehicle, err = q.GetVehicleForUpdate(ctx, route.VehicleID)
+		if err != nil {
+			return err
+		}
+
+		result.Route, err = q.TransitionRouteStatus(ctx, TransitionRouteStatusParams{
+			ID:         route.ID,
+			FromStatus: arg.FromStatus,
+			ToStatus:   routeStatusCompleted,
+			At:         arg.At,
+		})
+		return err
+	})
+	return result, err
+}
+
+// lockRoute locks the route row and checks it still has fromStatus.
+func lockRoute(ctx context.Context, q *Queries, id uuid.UUID, fromStatus string) (Route, error) {
+	route, err := q.GetRouteForUpdate(ctx, id)
+	if err != nil {
+		return route, err
+	}
+	if route.Status != fromStatus {
+		return route, ErrRouteStatusChanged
+	}
+	return route, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation"
+}
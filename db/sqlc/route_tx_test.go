@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartRouteTx(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	result, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{
+		RouteID:    route.ID,
+		FromStatus: "pending",
+		At:         time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, route.ID, result.Route.ID)
+	require.Equal(t, "in_progress", result.Route.Status)
+	require.True(t, result.Route.StartedAt.Valid)
+	require.Equal(t, vehicle.ID, result.Vehicle.ID)
+
+	// the route is no longer pending
+	_, err = testStore.StartRouteTx(context.Background(), StartRouteTxParams{
+		RouteID:    route.ID,
+		FromStatus: "pending",
+		At:         time.Now(),
+	})
+	require.ErrorIs(t, err, ErrRouteStatusChanged)
+}
+
+func TestStartRouteTxVehicleInUse(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route1 := createRandomRoute(t, &user, &vehicle)
+	route2 := createRandomRoute(t, &user, &vehicle)
+
+	_, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{RouteID: route1.ID, FromStatus: "pending", At: time.Now()})
+	require.NoError(t, err)
+
+	_, err = testStore.StartRouteTx(context.Background(), StartRouteTxParams{RouteID: route2.ID, FromStatus: "pending", At: time.Now()})
+	require.ErrorIs(t, err, ErrVehicleInUse)
+
+	route2, err = testQueries.GetRouteByID(context.Background(), route2.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", route2.Status)
+}
+
+func TestStartRouteTxConcurrentVehicle(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+
+	n := 10
+	routes := make([]Route, n)
+	for i := range routes {
+		routes[i] = createRandomRoute(t, &user, &vehicle)
+	}
+
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(route Route) {
+			_, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{
+				RouteID:    route.ID,
+				FromStatus: "pending",
+				At:         time.Now(),
+			})
+			errs <- err
+		}(routes[i])
+	}
+
+	// every route competes for the same vehicle, so exactly one may start
+	started := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			started++
+			continue
+		}
+		require.ErrorIs(t, err, ErrVehicleInUse)
+	}
+	require.Equal(t, 1, started)
+
+	active, err := testQueries.GetActiveRouteByVehicle(context.Background(), vehicle.ID)
+	require.NoError(t, err)
+	require.Equal(t, "in_progress", active.Status)
+}
+
+func TestStartRouteTxConcurrentRoute(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	n := 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{
+				RouteID:    route.ID,
+				FromStatus: "pending",
+				At:         time.Now(),
+			})
+			errs <- err
+		}()
+	}
+
+	started := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			started++
+			continue
+		}
+		require.ErrorIs(t, err, ErrRouteStatusChanged)
+	}
+	require.Equal(t, 1, started)
+}
+
+func TestCompleteRouteTx(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route1 := createRandomRoute(t, &user, &vehicle)
+	route2 := createRandomRoute(t, &user, &vehicle)
+
+	_, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{RouteID: route1.ID, FromStatus: "pending", At: time.Now()})
+	require.NoError(t, err)
+
+	result, err := testStore.CompleteRouteTx(context.Background(), CompleteRouteTxParams{
+		RouteID:    route1.ID,
+		FromStatus: "in_progress",
+		At:         time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "completed", result.Route.Status)
+	require.True(t, result.Route.CompletedAt.Valid)
+	require.True(t, result.Route.ActualDurationMin.Valid)
+	require.Equal(t, vehicle.ID, result.Vehicle.ID)
+
+	// completing the route frees the vehicle for the next one
+	_, err = testStore.StartRouteTx(context.Background(), StartRouteTxParams{RouteID: route2.ID, FromStatus: "pending", At: time.Now()})
+	require.NoError(t, err)
+
+	_, err = testStore.CompleteRouteTx(context.Background(), CompleteRouteTxParams{
+		RouteID:    route1.ID,
+		FromStatus: "in_progress",
+		At:         time.Now(),
+	})
+	require.ErrorIs(t, err, ErrRouteStatusChanged)
+}
+
+func TestCompleteAndStartRouteTxConcurrent(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+
+	n := 5
+	for i := 0; i < n; i++ {
+		current := createRandomRoute(t, &user, &vehicle)
+		next := createRandomRoute(t, &user, &vehicle)
+
+		_, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{RouteID: current.ID, FromStatus: "pending", At: time.Now()})
+		require.NoError(t, err)
+
+		// the start either waits for the completion and succeeds, or runs
+		// first and finds the vehicle busy; it never sees both in progress
+		errs := make(chan error, 2)
+		go func() {
+			_, err := testStore.CompleteRouteTx(context.Background(), CompleteRouteTxParams{RouteID: current.ID, FromStatus: "in_progress", At: time.Now()})
+			errs <- err
+		}()
+		go func() {
+			_, err := testStore.StartRouteTx(context.Background(), StartRouteTxParams{RouteID: next.ID, FromStatus: "pending", At: time.Now()})
+			if err == ErrVehicleInUse {
+				err = nil
+			}
+			errs <- err
+		}()
+		require.NoError(t, <-errs)
+		require.NoError(t, <-errs)
+
+		active, err := testQueries.GetActiveRouteByVehicle(context.Background(), vehicle.ID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, next.ID, active.ID)
+
+		_, err = testStore.CompleteRouteTx(context.Background(), CompleteRouteTxParams{RouteID: next.ID, FromStatus: "in_progress", At: time.Now()})
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, user User, expiresAt time.Time) Session {
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    "logistics-eta-test/1.0",
+		ClientIp:     "192.0.2.10",
+		ExpiresAt:    expiresAt,
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.UserID, session.UserID)
+	require.Equal(t, arg.RefreshToken, session.RefreshToken)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.False(t, session.RevokedAt.Valid)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomSession(t, user, time.Now().Add(time.Hour))
+}
+
+func TestGetSession(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user, time.Now().Add(time.Hour))
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.RefreshToken, session2.RefreshToken)
+}
+
+func TestListActiveSessions(t *testing.T) {
+	user := createRandomUser(t)
+	active := createRandomSession(t, user, time.Now().Add(time.Hour))
+	createRandomSession(t, user, time.Now().Add(-time.Minute))
+	revoked := createRandomSession(t, user, time.Now().Add(time.Hour))
+
+	_, err := testQueries.RevokeSession(context.Background(), RevokeSessionParams{ID: revoked.ID, UserID: user.ID})
+	require.NoError(t, err)
+
+	sessions, err := testQueries.ListActiveSessions(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, active.ID, sessions[0].ID)
+}
+
+func TestRevokeSession(t *testing.T) {
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	session := createRandomSession(t, user, time.Now().Add(time.Hour))
+
+	// only the owner can revoke a session
+	_, err := testQueries.RevokeSession(context.Background(), RevokeSessionParams{ID: session.ID, UserID: other.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	revoked, err := testQueries.RevokeSession(context.Background(), RevokeSessionParams{ID: session.ID, UserID: user.ID})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+	require.WithinDuration(t, time.Now(), revoked.RevokedAt.Time, time.Second)
+
+	// revoking twice is reported as not found
+	_, err = testQueries.RevokeSession(context.Background(), RevokeSessionParams{ID: session.ID, UserID: user.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomSession(t, user, time.Now().Add(time.Hour))
+	}
+	otherSession := createRandomSession(t, other, time.Now().Add(time.Hour))
+
+	err := testQueries.RevokeUserSessions(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	sessions, err := testQueries.ListActiveSessions(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	// other users keep their sessions
+	session, err := testQueries.GetSession(context.Background(), otherSession.ID)
+	require.NoError(t, err)
+	require.False(t, session.RevokedAt.Valid)
+}
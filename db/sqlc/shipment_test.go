@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomShipment(t *testing.T, customer User) Shipment {
+	windowStart := time.Now().Add(time.Hour)
+	arg := CreateShipmentParams{
+		ID:             uuid.New(),
+		CustomerID:     customer.ID,
+		PickupLat:      37.7749,
+		PickupLng:      -122.4194,
+		PickupAddress:  sql.NullString{String: "123 Main st", Valid: true},
+		DropoffLat:     37.8044,
+		DropoffLng:     -122.2712,
+		DropoffAddress: sql.NullString{String: "456 Elm st", Valid: true},
+		WeightKg:       12.5,
+		VolumeM3:       0.4,
+		WindowStart:    sql.NullTime{Time: windowStart, Valid: true},
+		WindowEnd:      sql.NullTime{Time: windowStart.Add(2 * time.Hour), Valid: true},
+	}
+
+	shipment, err := testQueries.CreateShipment(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.ID, shipment.ID)
+	require.Equal(t, arg.CustomerID, shipment.CustomerID)
+	require.False(t, shipment.RouteID.Valid)
+	require.Equal(t, arg.PickupAddress, shipment.PickupAddress)
+	require.Equal(t, arg.DropoffAddress, shipment.DropoffAddress)
+	require.Equal(t, arg.WeightKg, shipment.WeightKg)
+	require.Equal(t, arg.VolumeM3, shipment.VolumeM3)
+	require.WithinDuration(t, arg.WindowStart.Time, shipment.WindowStart.Time, time.Second)
+	require.WithinDuration(t, arg.WindowEnd.Time, shipment.WindowEnd.Time, time.Second)
+	require.NotZero(t, shipment.CreatedAt)
+
+	return shipment
+}
+
+func TestCreateShipment(t *testing.T) {
+	customer := createRandomUser(t)
+	createRandomShipment(t, customer)
+}
+
+func TestCreateShipmentInvalidWindow(t *testing.T) {
+	customer := createRandomUser(t)
+	now := time.Now()
+
+	_, err := testQueries.CreateShipment(context.Background(), CreateShipmentParams{
+		ID:          uuid.New(),
+		CustomerID:  customer.ID,
+		WeightKg:    1,
+		VolumeM3:    1,
+		WindowStart: sql.NullTime{Time: now, Valid: true},
+		WindowEnd:   sql.NullTime{Time: now.Add(-time.Minute), Valid: true},
+	})
+	require.Error(t, err)
+}
+
+func TestGetShipment(t *testing.T) {
+	customer := createRandomUser(t)
+	shipment1 := createRandomShipment(t, customer)
+
+	shipment2, err := testQueries.GetShipment(context.Background(), shipment1.ID)
+	require.NoError(t, err)
+	require.Equal(t, shipment1.ID, shipment2.ID)
+	require.Equal(t, shipment1.CustomerID, shipment2.CustomerID)
+}
+
+func TestListShipmentsByCustomer(t *testing.T) {
+	customer := createRandomUser(t)
+	for i := 0; i < 3; i++ {
+		createRandomShipment(t, customer)
+	}
+	createRandomShipment(t, createRandomUser(t))
+
+	shipments, err := testQueries.ListShipmentsByCustomer(context.Background(), ListShipmentsByCustomerParams{
+		CustomerID: customer.ID,
+		Limit:      5,
+		Offset:     0,
+	})
+	require.NoError(t, err)
+	require.Len(t, shipments, 3)
+	for _, shipment := range shipments {
+		require.Equal(t, customer.ID, shipment.CustomerID)
+	}
+}
+
+func TestAssignShipmentRoute(t *testing.T) {
+	customer := createRandomUser(t)
+	driver := createRandomUser(t)
+	vehicle := createRandomVehicle(t, driver)
+	route := createRandomRoute(t, &driver, &vehicle)
+	shipment := createRandomShipment(t, customer)
+
+	assigned, err := testQueries.AssignShipmentRoute(context.Background(), AssignShipmentRouteParams{
+		ID:      shipment.ID,
+		RouteID: uuid.NullUUID{UUID: route.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, assigned.RouteID.Valid)
+	require.Equal(t, route.ID, assigned.RouteID.UUID)
+
+	// deleting the route leaves the shipment unassigned rather than deleting it
+	require.NoError(t, testQueries.DeleteRoute(context.Background(), route.ID))
+	unassigned, err := testQueries.GetShipment(context.Background(), shipment.ID)
+	require.NoError(t, err)
+	require.False(t, unassigned.RouteID.Valid)
+}
+
+func TestListRouteShipmentIDsByCustomer(t *testing.T) {
+	customer, other := createRandomUser(t), createRandomUser(t)
+	driver := createRandomUser(t)
+	vehicle := createRandomVehicle(t, driver)
+	route := createRandomRoute(t, &driver, &vehicle)
+	arg := ListRouteShipmentIDsByCustomerParams{
+		RouteID:    uuid.NullUUID{UUID: route.ID, Valid: true},
+		CustomerID: customer.ID,
+	}
+
+	ids, err := testQueries.ListRouteShipmentIDsByCustomer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	var want []uuid.UUID
+	for _, owner := range []User{customer, customer, other} {
+		shipment := createRandomShipment(t, owner)
+		_, err := testQueries.AssignShipmentRoute(context.Background(), AssignShipmentRouteParams{ID: shipment.ID, RouteID: arg.RouteID})
+		require.NoError(t, err)
+		if owner.ID == customer.ID {
+			want = append(want, shipment.ID)
+		}
+	}
+
+	ids, err = testQueries.ListRouteShipmentIDsByCustomer(context.Background(), arg)
+	require.NoError(t, err)
+	require.ElementsMatch(t, want, ids)
+}
+
+func TestListUnplannedShipments(t *testing.T) {
+	customer := createRandomUser(t)
+	driver := createRandomUser(t)
+	vehicle := createRandomVehicle(t, driver)
+	route := createRandomRoute(t, &driver, &vehicle)
+
+	// createRandomShipment books a window from an hour from now
+	unplanned := createRandomShipment(t, customer)
+	planned := createRandomShipment(t, customer)
+	_, err := testQueries.AssignShipmentRoute(context.Background(), AssignShipmentRouteParams{
+		ID:      planned.ID,
+		RouteID: uuid.NullUUID{UUID: route.ID, Valid: true},
+	})
+	require.NoError(t, err)
+
+	shipments, err := testQueries.ListUnplannedShipments(context.Background(), ListUnplannedShipmentsParams{
+		Since:        time.Now(),
+		Until:        time.Now().Add(24 * time.Hour),
+		MaxShipments: 1000,
+	})
+	require.NoError(t, err)
+	ids := make(map[uuid.UUID]bool)
+	for _, shipment := range shipments {
+		require.False(t, shipment.RouteID.Valid)
+		ids[shipment.ID] = true
+	}
+	require.True(t, ids[unplanned.ID])
+	require.False(t, ids[planned.ID])
+
+	// the window is over by then
+	shipments, err = testQueries.ListUnplannedShipments(context.Background(), ListUnplannedShipmentsParams{
+		Since:        time.Now().Add(4 * time.Hour),
+		Until:        time.Now().Add(28 * time.Hour),
+		MaxShipments: 1000,
+	})
+	require.NoError(t, err)
+	for _, shipment := range shipments {
+		require.NotEqual(t, unplanned.ID, shipment.ID)
+	}
+}
+
+func TestClaimShipment(t *testing.T) {
+	customer := createRandomUser(t)
+	driver := createRandomUser(t)
+	vehicle := createRandomVehicle(t, driver)
+	route1 := createRandomRoute(t, &driver, &vehicle)
+	route2 := createRandomRoute(t, &driver, &vehicle)
+	shipment := createRandomShipment(t, customer)
+
+	claimed, err := testQueries.ClaimShipment(context.Background(), ClaimShipmentParams{
+		ID:      shipment.ID,
+		RouteID: uuid.NullUUID{UUID: route1.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, route1.ID, claimed.RouteID.UUID)
+
+	// a shipment already on a route can't be claimed again
+	_, err = testQueries.ClaimShipment(context.Background(), ClaimShipmentParams{
+		ID:      shipment.ID,
+		RouteID: uuid.NullUUID{UUID: route2.ID, Valid: true},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -1,10 +1,29 @@
 package db
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
+)
+
+// maxTxAttempts bounds how often a transaction is run when Postgres aborts
+// it with a serialization failure or a deadlock.
+const maxTxAttempts = 5
 
 type Store interface {
 	Querier
+	StartRouteTx(ctx context.Context, arg StartRouteTxParams) (StartRouteTxResult, error)
+	CompleteRouteTx(ctx context.Context, arg CompleteRouteTxParams) (CompleteRouteTxResult, error)
+	ReplaceRouteStopsTx(ctx context.Context, arg ReplaceRouteStopsTxParams) (ReplaceRouteStopsTxResult, error)
+	CreateDispatchPlanTx(ctx context.Context, arg CreateDispatchPlanTxParams) (CreateDispatchPlanTxResult, error)
+	CreateRouteLocationsTx(ctx context.Context, arg CreateRouteLocationsTxParams) (CreateRouteLocationsTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (UpdateUserRoleTxResult, error)
+	DeleteUserTx(ctx context.Context, arg DeleteUserTxParams) error
 }
 
 type SQLStore struct {
@@ -12,9 +31,62 @@ type SQLStore struct {
 	db *sql.DB
 }
 
-func NewStore(db *sql.DB) Store{
-	return  &SQLStore{
-		db:db,
+func NewStore(db *sql.DB) Store {
+	return &SQLStore{
+		db:      db,
 		Queries: New(db),
 	}
-}
\ No newline at end of file
+}
+
+// execTx runs fn inside a transaction with the given isolation level. When
+// Postgres aborts the transaction because it could not be serialized, or to
+// break a deadlock, the whole of fn is run again in a new transaction, so fn
+// must not have side effects outside of the queries it is given.
+func (store *SQLStore) execTx(ctx context.Context, isolation sql.IsolationLevel, fn func(*Queries) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = store.runTx(ctx, isolation, fn)
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+
+		// back off a little so the conflicting transaction can finish
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxTxAttempts, err)
+}
+
+func (store *SQLStore) runTx(ctx context.Context, isolation sql.IsolationLevel, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return err
+	}
+
+	q := New(tx)
+	err = fn(q)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// isRetryableTxError reports whether err is a serialization_failure (40001)
+// or deadlock_detected (40P01), after which the transaction can succeed when
+// run again.
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "serialization_failure", "deadlock_detected":
+			return true
+		}
+	}
+	return false
+}
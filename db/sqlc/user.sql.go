@@ -98,6 +98,25 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 	return i, err
 }
 
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT id, name, email, password_hash, role, created_at, updated_at FROM users WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const listUsers = `-- name: ListUsers :many
 SELECT id, name, email, password_hash, role, created_at, updated_at FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2
 `
@@ -138,6 +157,59 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	return items, nil
 }
 
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, name, email, password_hash, role, created_at, updated_at FROM users
+WHERE ($1::text IS NULL
+    OR name ILIKE '%' || $1 || '%'
+    OR email ILIKE '%' || $1 || '%')
+AND ($2::text IS NULL OR role = $2)
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4
+`
+
+type SearchUsersParams struct {
+	Search sql.NullString `json:"search"`
+	Role   sql.NullString `json:"role"`
+	Limit  int32          `json:"limit"`
+	Offset int32          `json:"offset"`
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers,
+		arg.Search,
+		arg.Role,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.PasswordHash,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET name = $2, email = $3, password_hash = $4, role = $5, updated_at = NOW()
@@ -180,7 +252,8 @@ SET
   name = COALESCE($1, name),
   email = COALESCE($2, email),
   password_hash = COALESCE($3, password_hash),
-  role = COALESCE($4, role)
+  role = COALESCE($4, role),
+  updated_at = NOW()
 WHERE id = $5
 RETURNING id, name, email, password_hash, role, created_at, updated_at
 `
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"github.com/google/uuid"
 	"github.com/joekings2k/logistics-eta/util"
@@ -200,4 +201,32 @@ func TestListUsers(t *testing.T) {
 // 			}
 // 		})
 // 	}
-// }
\ No newline at end of file
+// }
+func TestSearchUsers(t *testing.T) {
+	user := createRandomUser(t)
+
+	// a unique fragment of the email only matches this user
+	fragment := user.Email[:len(user.Email)-4]
+	arg := SearchUsersParams{
+		Search: sql.NullString{String: strings.ToUpper(fragment), Valid: true},
+		Limit:  5,
+		Offset: 0,
+	}
+	users, err := testQueries.SearchUsers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, user.ID, users[0].ID)
+
+	arg.Role = sql.NullString{String: user.Role + "-other", Valid: true}
+	users, err = testQueries.SearchUsers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	arg = SearchUsersParams{Role: sql.NullString{String: user.Role, Valid: true}, Limit: 5}
+	users, err = testQueries.SearchUsers(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, users)
+	for _, u := range users {
+		require.Equal(t, user.Role, u.Role)
+	}
+}
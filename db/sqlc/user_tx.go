@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrUserHasRoutes is returned when deleting a user would take their routes,
+// and the trip history learned from them, with it.
+var ErrUserHasRoutes = errors.New("user has routes and cannot be deleted")
+
+type ChangePasswordTxParams struct {
+	UserID       uuid.UUID `json:"user_id"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+type ChangePasswordTxResult struct {
+	User User `json:"user"`
+}
+
+// ChangePasswordTx stores the user's new password hash and revokes all of
+// their sessions, so either both happen or neither does.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		var err error
+		result.User, err = q.UpdateUserPartial(ctx, UpdateUserPartialParams{
+			ID:           arg.UserID,
+			PasswordHash: sql.NullString{String: arg.PasswordHash, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return q.RevokeUserSessions(ctx, arg.UserID)
+	})
+	return result, err
+}
+
+type UpdateUserRoleTxParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+type UpdateUserRoleTxResult struct {
+	User User `json:"user"`
+}
+
+// UpdateUserRoleTx changes the user's role. When it differs from the old one
+// their sessions are revoked, so no refresh token keeps issuing access tokens
+// for the old role.
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (UpdateUserRoleTxResult, error) {
+	var result UpdateUserRoleTxResult
+
+	err := store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+		if user.Role == arg.Role {
+			result.User = user
+			return nil
+		}
+
+		result.User, err = q.UpdateUserPartial(ctx, UpdateUserPartialParams{
+			ID:   user.ID,
+			Role: sql.NullString{String: arg.Role, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		return q.RevokeUserSessions(ctx, user.ID)
+	})
+	return result, err
+}
+
+type DeleteUserTxParams struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// DeleteUserTx deletes a user who has never driven a route, along with their
+// vehicles and sessions. The user is locked first, so no route can be created
+// for them between the check and the delete.
+func (store *SQLStore) DeleteUserTx(ctx context.Context, arg DeleteUserTxParams) error {
+	return store.execTx(ctx, sql.LevelSerializable, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		routes, err := q.CountRoutesByDriver(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if routes > 0 {
+			return ErrUserHasRoutes
+		}
+		return q.DeleteUser(ctx, user.ID)
+	})
+}
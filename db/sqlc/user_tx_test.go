@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordTx(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user, time.Now().Add(time.Hour))
+
+	result, err := testStore.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UserID:       user.ID,
+		PasswordHash: "new-hash",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", result.User.PasswordHash)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+}
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user, time.Now().Add(time.Hour))
+
+	// keeping the role leaves the sessions alone
+	result, err := testStore.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{UserID: user.ID, Role: user.Role})
+	require.NoError(t, err)
+	require.Equal(t, user.Role, result.User.Role)
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.False(t, session.RevokedAt.Valid)
+
+	role := "admin"
+	if user.Role == role {
+		role = "driver"
+	}
+	result, err = testStore.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{UserID: user.ID, Role: role})
+	require.NoError(t, err)
+	require.Equal(t, role, result.User.Role)
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+}
+
+func TestDeleteUserTx(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomVehicle(t, user)
+
+	err := testStore.DeleteUserTx(context.Background(), DeleteUserTxParams{UserID: user.ID})
+	require.NoError(t, err)
+	_, err = testQueries.GetUserByID(context.Background(), user.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = testStore.DeleteUserTx(context.Background(), DeleteUserTxParams{UserID: user.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDeleteUserTxWithRoutes(t *testing.T) {
+	user := createRandomUser(t)
+	vehicle := createRandomVehicle(t, user)
+	route := createRandomRoute(t, &user, &vehicle)
+
+	err := testStore.DeleteUserTx(context.Background(), DeleteUserTxParams{UserID: user.ID})
+	require.ErrorIs(t, err, ErrUserHasRoutes)
+
+	_, err = testQueries.GetRouteByID(context.Background(), route.ID)
+	require.NoError(t, err)
+}
@@ -101,6 +101,26 @@ func (q *Queries) GetVehicleByLicensePlate(ctx context.Context, licensePlate str
 	return i, err
 }
 
+const getVehicleForUpdate = `-- name: GetVehicleForUpdate :one
+SELECT id, driver_id, license_plate, model, image_url, capacity, created_at, updated_at FROM vehicles WHERE id = $1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetVehicleForUpdate(ctx context.Context, id uuid.UUID) (Vehicle, error) {
+	row := q.db.QueryRowContext(ctx, getVehicleForUpdate, id)
+	var i Vehicle
+	err := row.Scan(
+		&i.ID,
+		&i.DriverID,
+		&i.LicensePlate,
+		&i.Model,
+		&i.ImageUrl,
+		&i.Capacity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getVehiclesByDriverID = `-- name: GetVehiclesByDriverID :many
 SELECT id, driver_id, license_plate, model, image_url, capacity, created_at, updated_at FROM vehicles WHERE driver_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
 `
@@ -143,6 +163,56 @@ func (q *Queries) GetVehiclesByDriverID(ctx context.Context, arg GetVehiclesByDr
 	return items, nil
 }
 
+const listFleetVehicles = `-- name: ListFleetVehicles :many
+SELECT id, driver_id, license_plate, model, image_url, capacity, created_at, updated_at FROM vehicles
+WHERE id IN (
+    SELECT DISTINCT ON (v.driver_id) v.id FROM vehicles v
+    WHERE v.capacity IS NOT NULL AND v.capacity > 0
+      AND NOT EXISTS (
+        SELECT 1 FROM routes r
+        WHERE (r.vehicle_id = v.id OR r.driver_id = v.driver_id)
+          AND r.status IN ('pending', 'in_progress')
+      )
+    ORDER BY v.driver_id, v.created_at, v.id
+)
+ORDER BY created_at, id
+`
+
+// Vehicles free to take a dispatched route: they have a payload capacity, and
+// neither they nor their driver are on a route that hasn't finished. Drivers
+// with several of them are offered their oldest one only.
+func (q *Queries) ListFleetVehicles(ctx context.Context) ([]Vehicle, error) {
+	rows, err := q.db.QueryContext(ctx, listFleetVehicles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Vehicle{}
+	for rows.Next() {
+		var i Vehicle
+		if err := rows.Scan(
+			&i.ID,
+			&i.DriverID,
+			&i.LicensePlate,
+			&i.Model,
+			&i.ImageUrl,
+			&i.Capacity,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateVehicle = `-- name: UpdateVehicle :one
 UPDATE vehicles
 SET 
@@ -181,4 +181,28 @@ func TestDeletevehicle(t *testing.T) {
 	require.Error(t, err)
 	require.EqualError(t, err, sql.ErrNoRows.Error())
 	require.Empty(t, vehicle2)
-}
\ No newline at end of file
+}
+func TestListFleetVehicles(t *testing.T) {
+	driver := createRandomUser(t)
+	vehicle := createRandomVehicle(t, driver)
+	// a driver can only take one route, whichever vehicle it is on
+	spare := createRandomVehicle(t, driver)
+
+	busyDriver := createRandomUser(t)
+	busy := createRandomVehicle(t, busyDriver)
+	createRandomRoute(t, &busyDriver, &busy)
+
+	vehicles, err := testQueries.ListFleetVehicles(context.Background())
+	require.NoError(t, err)
+	found := false
+	for _, fleetVehicle := range vehicles {
+		require.True(t, fleetVehicle.Capacity.Valid)
+		require.Positive(t, fleetVehicle.Capacity.Int32)
+		require.NotEqual(t, spare.ID, fleetVehicle.ID)
+		require.NotEqual(t, busy.ID, fleetVehicle.ID)
+		if fleetVehicle.ID == vehicle.ID {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
@@ -0,0 +1,162 @@
+// Package deviation detects vehicles leaving the planned path of their route
+// and getting back on it from the positions they report.
+//
+// Like geofences, the corridor along the path is damped against GPS jitter
+// twice: a vehicle leaves it beyond the threshold distance but only gets back
+// on within half of it, and a change only counts once positions have agreed
+// on it for a dwell time.
+package deviation
+
+import (
+	"math"
+	"time"
+
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+const (
+	DefaultThresholdM = 250.0
+	DefaultDwell      = 2 * time.Minute
+
+	// rejoinRatio is the share of the threshold a vehicle off the route must
+	// come back within to be on it again.
+	rejoinRatio = 0.5
+	// minPings is how many positions in a row must agree on a change before
+	// it is confirmed, however long they span.
+	minPings = 2
+
+	metresPerDeg = 111195
+)
+
+type Transition string
+
+const (
+	OffRoute Transition = "off_route"
+	OnRoute  Transition = "on_route"
+)
+
+// Path is the planned way along a route as the points it passes through, in
+// driving order.
+type Path []eta.Point
+
+// DistanceM returns how far point is from the nearest part of the path, in
+// metres, or +Inf for an empty path.
+func (path Path) DistanceM(point eta.Point) float64 {
+	if len(path) == 1 {
+		return eta.HaversineKm(point, path[0]) * 1000
+	}
+	best := math.Inf(1)
+	for i := 1; i < len(path); i++ {
+		best = math.Min(best, segmentDistanceM(point, path[i-1], path[i]))
+	}
+	return best
+}
+
+// segmentDistanceM is how far point is from the segment from a to b, on a
+// flat projection around point that holds over the few kilometres between
+// road nodes.
+func segmentDistanceM(point, a, b eta.Point) float64 {
+	cosLat := math.Cos(point.Lat * math.Pi / 180)
+	ax, ay := (a.Lng-point.Lng)*cosLat*metresPerDeg, (a.Lat-point.Lat)*metresPerDeg
+	bx, by := (b.Lng-point.Lng)*cosLat*metresPerDeg, (b.Lat-point.Lat)*metresPerDeg
+
+	dx, dy := bx-ax, by-ay
+	var t float64
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// State is whether the vehicle was last confirmed off the route, and since
+// when. Positions recorded before Since are ignored.
+type State struct {
+	OffRoute bool
+	Since    time.Time
+}
+
+// Ping is a reported position. AccuracyM is 0 when the device didn't report
+// one.
+type Ping struct {
+	ID         int64
+	Point      eta.Point
+	AccuracyM  float64
+	RecordedAt time.Time
+}
+
+// Deviation is a confirmed change of the vehicle leaving the route or getting
+// back on it. Ping is the first position on the new side of the corridor and
+// DistanceM how far it is from the path.
+type Deviation struct {
+	Transition Transition
+	Ping       Ping
+	DistanceM  float64
+}
+
+type Detector struct {
+	thresholdM float64
+	dwell      time.Duration
+}
+
+// NewDetector returns a detector that takes vehicles further than thresholdM
+// from the path to be off the route once positions agreed on it for dwell.
+// Zero values select the defaults.
+func NewDetector(thresholdM float64, dwell time.Duration) *Detector {
+	if thresholdM <= 0 {
+		thresholdM = DefaultThresholdM
+	}
+	if dwell <= 0 {
+		dwell = DefaultDwell
+	}
+	return &Detector{thresholdM: thresholdM, dwell: dwell}
+}
+
+// Dwell is how long positions must agree on a change before it is confirmed.
+func (detector *Detector) Dwell() time.Duration {
+	return detector.dwell
+}
+
+// Detect returns the changes from state confirmed by pings, which must be
+// ordered oldest first. Positions less accurate than the threshold can't tell
+// whether the vehicle is on the route and are skipped.
+func (detector *Detector) Detect(path Path, state State, pings []Ping) []Deviation {
+	if len(path) == 0 {
+		return nil
+	}
+
+	var deviations []Deviation
+	var streak []Ping
+	var firstM float64
+	for _, ping := range pings {
+		if ping.RecordedAt.Before(state.Since) || ping.AccuracyM > detector.thresholdM {
+			continue
+		}
+
+		distanceM := path.DistanceM(ping.Point)
+		changed := distanceM > detector.thresholdM
+		if state.OffRoute {
+			changed = distanceM <= detector.thresholdM*rejoinRatio
+		}
+		if !changed {
+			streak = streak[:0]
+			continue
+		}
+
+		if len(streak) == 0 {
+			firstM = distanceM
+		}
+		streak = append(streak, ping)
+		if len(streak) < minPings || ping.RecordedAt.Sub(streak[0].RecordedAt) < detector.dwell {
+			continue
+		}
+		deviation := Deviation{Transition: OffRoute, Ping: streak[0], DistanceM: firstM}
+		if state.OffRoute {
+			deviation.Transition = OnRoute
+		}
+		deviations = append(deviations, deviation)
+		state.OffRoute = !state.OffRoute
+		state.Since = streak[0].RecordedAt
+		streak = streak[:0]
+	}
+	return deviations
+}
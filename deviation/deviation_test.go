@@ -0,0 +1,107 @@
+package deviation
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// a road heading east from the depot for about 2 km, then north
+	depot = eta.Point{Lat: 6.5244, Lng: 3.3792}
+	bend  = eta.Point{Lat: 6.5244, Lng: 3.3973}
+	road  = Path{depot, bend, {Lat: 6.5424, Lng: 3.3973}}
+	start = time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+)
+
+// besideRoad is a point 1 km along the road and metres south of it.
+func besideRoad(metres float64) eta.Point {
+	return eta.Point{Lat: depot.Lat - metres/metresPerDeg, Lng: 3.3882}
+}
+
+// track is a ping every 30 seconds at each of the distances from the road.
+func track(distancesM ...float64) []Ping {
+	pings := make([]Ping, len(distancesM))
+	for i, distanceM := range distancesM {
+		pings[i] = Ping{
+			ID:         int64(i + 1),
+			Point:      besideRoad(distanceM),
+			RecordedAt: start.Add(time.Duration(i) * 30 * time.Second),
+		}
+	}
+	return pings
+}
+
+func TestPathDistanceM(t *testing.T) {
+	require.InDelta(t, 0, road.DistanceM(bend), 0.1)
+	require.InDelta(t, 300, road.DistanceM(besideRoad(300)), 1)
+
+	// past the end of the road, the distance is to its end
+	east := eta.Point{Lat: bend.Lat, Lng: bend.Lng + 500/(metresPerDeg*math.Cos(bend.Lat*math.Pi/180))}
+	require.InDelta(t, 500, road.DistanceM(east), 1)
+
+	require.InDelta(t, eta.HaversineKm(depot, bend)*1000, Path{depot}.DistanceM(bend), 0.1)
+	require.True(t, math.IsInf(Path{}.DistanceM(depot), 1))
+}
+
+func TestDetectOffRouteAndBack(t *testing.T) {
+	detector := NewDetector(0, 0)
+
+	deviations := detector.Detect(road, State{}, track(10, 20, 400, 600, 800, 900, 900, 500, 100, 20, 10, 10, 10))
+	require.Len(t, deviations, 2)
+	require.Equal(t, OffRoute, deviations[0].Transition)
+	require.Equal(t, int64(3), deviations[0].Ping.ID)
+	require.InDelta(t, 400, deviations[0].DistanceM, 1)
+	require.Equal(t, OnRoute, deviations[1].Transition)
+	require.Equal(t, int64(9), deviations[1].Ping.ID)
+}
+
+func TestDetectDwell(t *testing.T) {
+	detector := NewDetector(0, 0)
+
+	// a short detour around an obstacle is over before the dwell time
+	require.Empty(t, detector.Detect(road, State{}, track(10, 400, 400, 400, 10, 10)))
+}
+
+func TestDetectHysteresis(t *testing.T) {
+	detector := NewDetector(0, 0)
+	off := State{OffRoute: true}
+
+	// driving alongside the road just within the threshold isn't being back
+	// on it
+	require.Empty(t, detector.Detect(road, off, track(200, 200, 180, 220, 200, 190, 210)))
+	require.Len(t, detector.Detect(road, off, track(200, 100, 100, 100, 100, 100)), 1)
+}
+
+func TestDetectSkips(t *testing.T) {
+	detector := NewDetector(0, 0)
+
+	// inaccurate positions can't place the vehicle off the route
+	pings := track(400, 400, 400, 400, 400, 400)
+	for i := range pings {
+		pings[i].AccuracyM = 500
+	}
+	require.Empty(t, detector.Detect(road, State{}, pings))
+
+	// nor can positions from before the vehicle was confirmed on it
+	deviations := detector.Detect(road, State{Since: start.Add(time.Minute)}, track(400, 400, 400, 400, 400, 400, 400))
+	require.Len(t, deviations, 1)
+	require.Equal(t, int64(3), deviations[0].Ping.ID)
+
+	// and there's no route to leave without a path
+	require.Empty(t, detector.Detect(nil, State{}, track(400, 400, 400, 400, 400, 400)))
+}
+
+func TestNewDetector(t *testing.T) {
+	detector := NewDetector(0, 0)
+	require.Equal(t, DefaultThresholdM, detector.thresholdM)
+	require.Equal(t, DefaultDwell, detector.Dwell())
+
+	detector = NewDetector(50, 30*time.Second)
+	deviations := detector.Detect(road, State{}, track(80, 80))
+	require.Len(t, deviations, 1)
+	require.Equal(t, OffRoute, deviations[0].Transition)
+}
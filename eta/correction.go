@@ -0,0 +1,402 @@
+package eta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// CorrectionModelVersion is the version of the model files this package
+	// reads and writes.
+	CorrectionModelVersion = 1
+
+	// MinTrainingSamples is the fewest usable trips a model trains on.
+	MinTrainingSamples = 20
+
+	// minCategoryCount is how many trips a driver, vehicle model, cell or
+	// time needs for the model to learn a weight of its own.
+	minCategoryCount = 3
+	// maxCategories bounds the values learned per feature, the most common
+	// first.
+	maxCategories = 1000
+	// correctionRidge is the L2 penalty of every weight but the intercept, in
+	// trips' worth of evidence.
+	correctionRidge = 1.0
+	// holdoutFraction is the share of the latest trips kept out of training
+	// to evaluate the model on.
+	holdoutFraction = 0.2
+
+	cgIterations = 500
+	cgTolerance  = 1e-10
+)
+
+var ErrTooFewSamples = errors.New("too few completed trips to train a correction model")
+
+// correctionFeatures are the categorical features of the model, in the
+// order CorrectionFeatures.values returns them.
+var correctionFeatures = []string{"hour", "weekday", "weekday_hour", "driver", "vehicle_model", "origin_cell", "destination_cell"}
+
+// CorrectionFeatures describe a trip to the correction model.
+type CorrectionFeatures struct {
+	DistanceKm   float64
+	Departure    time.Time
+	DriverID     string
+	VehicleModel string
+	Origin       Point
+	Destination  Point
+}
+
+func (features CorrectionFeatures) values(location *time.Location) []string {
+	at := features.Departure.In(location)
+	return []string{
+		strconv.Itoa(at.Hour()),
+		at.Weekday().String(),
+		fmt.Sprintf("%s %d", at.Weekday(), at.Hour()),
+		features.DriverID,
+		features.VehicleModel,
+		cellKey(features.Origin),
+		cellKey(features.Destination),
+	}
+}
+
+func (features CorrectionFeatures) logDistance() float64 {
+	return math.Log1p(math.Max(features.DistanceKm, 0))
+}
+
+func cellKey(point Point) string {
+	cell := profileCellOf(point)
+	return fmt.Sprintf("%d:%d", cell.row, cell.col)
+}
+
+// CorrectionSample is a completed trip to train the correction model on.
+type CorrectionSample struct {
+	CorrectionFeatures
+	// BaselineMin is the free-flow estimate of the trip, PlannedMin the
+	// estimate it was planned with and ActualMin how long it took.
+	BaselineMin float64
+	PlannedMin  float64
+	ActualMin   float64
+}
+
+// ErrorMetrics are the absolute errors of a set of estimates, in minutes.
+type ErrorMetrics struct {
+	Trips  int     `json:"trips"`
+	MAEMin float64 `json:"mae_min"`
+	P90Min float64 `json:"p90_min"`
+}
+
+func newErrorMetrics(errs []float64) ErrorMetrics {
+	metrics := ErrorMetrics{Trips: len(errs)}
+	if len(errs) == 0 {
+		return metrics
+	}
+	sorted := append([]float64(nil), errs...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, err := range sorted {
+		sum += err
+	}
+	metrics.MAEMin = round(sum/float64(len(sorted)), 2)
+	metrics.P90Min = round(sorted[int(math.Ceil(0.9*float64(len(sorted))))-1], 2)
+	return metrics
+}
+
+// Evaluation compares the errors of the free-flow estimates, the estimates
+// trips were planned with and the model's corrections over the latest trips,
+// held out of training.
+type Evaluation struct {
+	FreeFlow  ErrorMetrics `json:"free_flow"`
+	Planned   ErrorMetrics `json:"planned"`
+	Corrected ErrorMetrics `json:"corrected"`
+}
+
+// CorrectionModel is a ridge regression of how many times longer than their
+// free-flow estimate trips take, on a log scale, from their distance, the
+// hour of the day and of the week they leave, their driver, vehicle model
+// and the cells they leave from and go to. Values too rare to learn from
+// weigh nothing.
+type CorrectionModel struct {
+	Version   int       `json:"version"`
+	Timezone  string    `json:"timezone"`
+	TrainedAt time.Time `json:"trained_at"`
+	Samples   int       `json:"samples"`
+	Intercept float64   `json:"intercept"`
+	// DistanceWeight weighs the log of the distance, standardized by
+	// DistanceMean and DistanceStd.
+	DistanceMean   float64 `json:"distance_mean"`
+	DistanceStd    float64 `json:"distance_std"`
+	DistanceWeight float64 `json:"distance_weight"`
+	// Weights are the weights of the categorical features, by feature then
+	// value.
+	Weights    map[string]map[string]float64 `json:"weights"`
+	Evaluation Evaluation                    `json:"evaluation"`
+
+	location *time.Location
+}
+
+// TrainCorrectionModel fits a model to the samples, with hours and weekdays
+// taken in location. It's evaluated on the latest trips after fitting the
+// others, then fitted to every trip.
+func TrainCorrectionModel(samples []CorrectionSample, location *time.Location) (*CorrectionModel, error) {
+	usable := make([]CorrectionSample, 0, len(samples))
+	for _, sample := range samples {
+		if sample.BaselineMin > 0 && sample.ActualMin > 0 {
+			usable = append(usable, sample)
+		}
+	}
+	if len(usable) < MinTrainingSamples {
+		return nil, ErrTooFewSamples
+	}
+	sort.SliceStable(usable, func(i, j int) bool {
+		return usable[i].Departure.Before(usable[j].Departure)
+	})
+
+	split := len(usable) - int(float64(len(usable))*holdoutFraction)
+	evaluation := fitCorrection(usable[:split], location).evaluate(usable[split:])
+	model := fitCorrection(usable, location)
+	model.Evaluation = evaluation
+	return model, nil
+}
+
+// fitCorrection solves the ridge regression with conjugate gradients over
+// its normal equations. Every trip has a handful of non-zero features, so
+// the design matrix is kept as the columns each row sets.
+func fitCorrection(samples []CorrectionSample, location *time.Location) *CorrectionModel {
+	model := &CorrectionModel{
+		Version:   CorrectionModelVersion,
+		Timezone:  location.String(),
+		TrainedAt: time.Now().UTC(),
+		Samples:   len(samples),
+		Weights:   make(map[string]map[string]float64, len(correctionFeatures)),
+		location:  location,
+	}
+
+	var sum, sumSquares float64
+	for _, sample := range samples {
+		x := sample.logDistance()
+		sum += x
+		sumSquares += x * x
+	}
+	n := float64(len(samples))
+	model.DistanceMean = sum / n
+	model.DistanceStd = math.Sqrt(math.Max(sumSquares/n-model.DistanceMean*model.DistanceMean, 0))
+	if model.DistanceStd == 0 {
+		model.DistanceStd = 1
+	}
+
+	// columns 0 and 1 are the intercept and the distance
+	columns := learnVocabulary(samples, location, 2)
+	width := 2
+	for _, vocabulary := range columns {
+		width += len(vocabulary)
+	}
+	rows := make([][]int, len(samples))
+	distances := make([]float64, len(samples))
+	targets := make([]float64, len(samples))
+	for i, sample := range samples {
+		for f, value := range sample.values(location) {
+			if column, ok := columns[f][value]; ok {
+				rows[i] = append(rows[i], column)
+			}
+		}
+		distances[i] = model.standardDistance(sample.CorrectionFeatures)
+		targets[i] = math.Log(clampFactor(sample.ActualMin / sample.BaselineMin))
+	}
+
+	// multiply returns (X'X + ridge) w
+	multiply := func(w []float64) []float64 {
+		out := make([]float64, width)
+		for i, row := range rows {
+			s := w[0] + distances[i]*w[1]
+			for _, column := range row {
+				s += w[column]
+			}
+			out[0] += s
+			out[1] += distances[i] * s
+			for _, column := range row {
+				out[column] += s
+			}
+		}
+		for column := 1; column < width; column++ {
+			out[column] += correctionRidge * w[column]
+		}
+		return out
+	}
+
+	b := make([]float64, width)
+	w := make([]float64, width)
+	for i, row := range rows {
+		b[0] += targets[i]
+		b[1] += distances[i] * targets[i]
+		for _, column := range row {
+			b[column] += targets[i]
+		}
+		w[0] += targets[i] / n
+	}
+	conjugateGradient(multiply, b, w)
+
+	model.Intercept = w[0]
+	model.DistanceWeight = w[1]
+	for f, vocabulary := range columns {
+		weights := make(map[string]float64, len(vocabulary))
+		for value, column := range vocabulary {
+			weights[value] = w[column]
+		}
+		model.Weights[correctionFeatures[f]] = weights
+	}
+	return model
+}
+
+// learnVocabulary numbers, from first, the values of every feature common
+// enough to learn from.
+func learnVocabulary(samples []CorrectionSample, location *time.Location, first int) []map[string]int {
+	counts := make([]map[string]int, len(correctionFeatures))
+	for f := range counts {
+		counts[f] = make(map[string]int)
+	}
+	for _, sample := range samples {
+		for f, value := range sample.values(location) {
+			counts[f][value]++
+		}
+	}
+
+	columns := make([]map[string]int, len(correctionFeatures))
+	for f, count := range counts {
+		values := make([]string, 0, len(count))
+		for value, n := range count {
+			if n >= minCategoryCount {
+				values = append(values, value)
+			}
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if count[values[i]] != count[values[j]] {
+				return count[values[i]] > count[values[j]]
+			}
+			return values[i] < values[j]
+		})
+		if len(values) > maxCategories {
+			values = values[:maxCategories]
+		}
+		columns[f] = make(map[string]int, len(values))
+		for _, value := range values {
+			columns[f][value] = first
+			first++
+		}
+	}
+	return columns
+}
+
+// conjugateGradient solves multiply(w) = b for a symmetric positive definite
+// multiply, starting from and leaving the solution in w.
+func conjugateGradient(multiply func([]float64) []float64, b, w []float64) {
+	dot := func(x, y []float64) float64 {
+		var s float64
+		for i := range x {
+			s += x[i] * y[i]
+		}
+		return s
+	}
+
+	residual := multiply(w)
+	for i := range residual {
+		residual[i] = b[i] - residual[i]
+	}
+	direction := append([]float64(nil), residual...)
+	norm := dot(residual, residual)
+	target := cgTolerance * math.Max(dot(b, b), 1)
+	for iteration := 0; iteration < cgIterations && norm > target; iteration++ {
+		product := multiply(direction)
+		step := norm / dot(direction, product)
+		for i := range w {
+			w[i] += step * direction[i]
+			residual[i] -= step * product[i]
+		}
+		next := dot(residual, residual)
+		for i := range direction {
+			direction[i] = residual[i] + next/norm*direction[i]
+		}
+		norm = next
+	}
+}
+
+func clampFactor(factor float64) float64 {
+	return math.Min(math.Max(factor, minFactor), maxFactor)
+}
+
+func (model *CorrectionModel) standardDistance(features CorrectionFeatures) float64 {
+	return (features.logDistance() - model.DistanceMean) / model.DistanceStd
+}
+
+// Factor returns how many times longer than its free-flow estimate the model
+// expects a trip to take.
+func (model *CorrectionModel) Factor(features CorrectionFeatures) float64 {
+	s := model.Intercept + model.DistanceWeight*model.standardDistance(features)
+	for f, value := range features.values(model.location) {
+		s += model.Weights[correctionFeatures[f]][value]
+	}
+	return clampFactor(math.Exp(s))
+}
+
+// Correct scales the free-flow duration of baseline by the model's factor
+// for the trip.
+func (model *CorrectionModel) Correct(baseline Estimate, features CorrectionFeatures) Estimate {
+	baseline.DurationMin = round(baseline.DurationMin*model.Factor(features), 1)
+	return baseline
+}
+
+func (model *CorrectionModel) evaluate(samples []CorrectionSample) Evaluation {
+	freeFlow := make([]float64, 0, len(samples))
+	planned := make([]float64, 0, len(samples))
+	corrected := make([]float64, 0, len(samples))
+	for _, sample := range samples {
+		freeFlow = append(freeFlow, math.Abs(sample.BaselineMin-sample.ActualMin))
+		if sample.PlannedMin > 0 {
+			planned = append(planned, math.Abs(sample.PlannedMin-sample.ActualMin))
+		}
+		factor := model.Factor(sample.CorrectionFeatures)
+		corrected = append(corrected, math.Abs(sample.BaselineMin*factor-sample.ActualMin))
+	}
+	return Evaluation{
+		FreeFlow:  newErrorMetrics(freeFlow),
+		Planned:   newErrorMetrics(planned),
+		Corrected: newErrorMetrics(corrected),
+	}
+}
+
+// Save writes the model to path as JSON.
+func (model *CorrectionModel) Save(path string) error {
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadCorrectionModel reads a model saved to path.
+func LoadCorrectionModel(path string) (*CorrectionModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var model CorrectionModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("cannot decode correction model: %w", err)
+	}
+	if model.Version != CorrectionModelVersion {
+		return nil, fmt.Errorf("unsupported correction model version %d", model.Version)
+	}
+	if model.DistanceStd <= 0 {
+		return nil, errors.New("correction model has no distance scale")
+	}
+	model.location, err = time.LoadLocation(model.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load correction model time zone: %w", err)
+	}
+	return &model, nil
+}
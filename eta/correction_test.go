@@ -0,0 +1,118 @@
+package eta
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// correctionSamples are trips over eight weeks where a slow driver takes half
+// as long again as the others, and the Monday morning rush twice as long as
+// free-flow.
+func correctionSamples(n int) []CorrectionSample {
+	random := rand.New(rand.NewSource(7))
+	start := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	samples := make([]CorrectionSample, n)
+	for i := range samples {
+		departure := start.Add(time.Duration(random.Intn(8*7*24)) * time.Hour)
+		driver := []string{"slow", "steady", "quick"}[random.Intn(3)]
+		factor := 1.0
+		if driver == "slow" {
+			factor *= 1.5
+		}
+		if departure.Weekday() == time.Monday && departure.Hour() == 8 {
+			factor *= 2
+		}
+		baseline := 10 + 50*random.Float64()
+		samples[i] = CorrectionSample{
+			CorrectionFeatures: CorrectionFeatures{
+				DistanceKm:   baseline / 2,
+				Departure:    departure,
+				DriverID:     driver,
+				VehicleModel: "Sprinter",
+				Origin:       Point{Lat: 6.5244, Lng: 3.3792},
+				Destination:  Point{Lat: 6.6018, Lng: 3.3515},
+			},
+			BaselineMin: baseline,
+			PlannedMin:  baseline,
+			ActualMin:   baseline * factor * (0.95 + 0.1*random.Float64()),
+		}
+	}
+	return samples
+}
+
+func TestTrainCorrectionModel(t *testing.T) {
+	samples := correctionSamples(3000)
+	model, err := TrainCorrectionModel(samples, time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, len(samples), model.Samples)
+
+	features := samples[0].CorrectionFeatures
+	features.Departure = time.Date(2024, 5, 7, 14, 0, 0, 0, time.UTC)
+	features.DriverID = "steady"
+	require.InDelta(t, 1, model.Factor(features), 0.05)
+	features.DriverID = "slow"
+	require.InDelta(t, 1.5, model.Factor(features), 0.08)
+	// a Monday
+	features.Departure = time.Date(2024, 5, 6, 8, 30, 0, 0, time.UTC)
+	require.InDelta(t, 3, model.Factor(features), 0.3)
+	// a driver the model never saw weighs nothing
+	features.DriverID = "new"
+	features.Departure = time.Date(2024, 5, 7, 14, 0, 0, 0, time.UTC)
+	require.Less(t, model.Factor(features), 1.5)
+
+	corrected := model.Correct(Estimate{DistanceKm: 10, DurationMin: 20}, features)
+	require.Equal(t, 10.0, corrected.DistanceKm)
+	require.InDelta(t, 20*model.Factor(features), corrected.DurationMin, 0.05)
+
+	evaluation := model.Evaluation
+	require.Equal(t, len(samples)/5, evaluation.Corrected.Trips)
+	require.Equal(t, evaluation.FreeFlow, evaluation.Planned)
+	require.Less(t, evaluation.Corrected.MAEMin, evaluation.FreeFlow.MAEMin/3)
+	require.Less(t, evaluation.Corrected.P90Min, evaluation.FreeFlow.P90Min)
+	require.GreaterOrEqual(t, evaluation.Corrected.P90Min, evaluation.Corrected.MAEMin)
+}
+
+func TestTrainCorrectionModelTooFewSamples(t *testing.T) {
+	samples := correctionSamples(MinTrainingSamples)
+	// trips without a baseline can't be learned from
+	samples[0].BaselineMin = 0
+	_, err := TrainCorrectionModel(samples, time.UTC)
+	require.ErrorIs(t, err, ErrTooFewSamples)
+
+	_, err = TrainCorrectionModel(nil, time.UTC)
+	require.ErrorIs(t, err, ErrTooFewSamples)
+}
+
+func TestCorrectionModelSaveLoad(t *testing.T) {
+	location, err := time.LoadLocation("Africa/Lagos")
+	require.NoError(t, err)
+	samples := correctionSamples(200)
+	model, err := TrainCorrectionModel(samples, location)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "model.json")
+	require.NoError(t, model.Save(path))
+	loaded, err := LoadCorrectionModel(path)
+	require.NoError(t, err)
+	require.Equal(t, model.Evaluation, loaded.Evaluation)
+	require.Equal(t, "Africa/Lagos", loaded.Timezone)
+	for _, sample := range samples[:20] {
+		require.InDelta(t, model.Factor(sample.CorrectionFeatures), loaded.Factor(sample.CorrectionFeatures), 1e-12)
+	}
+
+	_, err = LoadCorrectionModel(filepath.Join(t.TempDir(), "missing.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 99}`), 0o644))
+	_, err = LoadCorrectionModel(path)
+	require.EqualError(t, err, "unsupported correction model version 99")
+
+	require.NoError(t, os.WriteFile(path, []byte(`not json`), 0o644))
+	_, err = LoadCorrectionModel(path)
+	require.Error(t, err)
+}
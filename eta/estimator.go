@@ -0,0 +1,144 @@
+package eta
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type VehicleClass string
+
+const (
+	ClassCar   VehicleClass = "car"
+	ClassVan   VehicleClass = "van"
+	ClassTruck VehicleClass = "truck"
+)
+
+const (
+	// DefaultCircuityFactor is the typical ratio between road distance and
+	// straight-line distance for urban and regional trips.
+	DefaultCircuityFactor = 1.3
+
+	// vehicles carrying from vanMinCapacityKg are vans, and trucks from
+	// truckMinCapacityKg
+	vanMinCapacityKg   = 500
+	truckMinCapacityKg = 2000
+)
+
+var DefaultSpeedsKmh = map[VehicleClass]float64{
+	ClassCar:   40,
+	ClassVan:   35,
+	ClassTruck: 30,
+}
+
+// ClassForCapacity maps a vehicle's payload capacity, in kg, to the class
+// used for speed lookups.
+func ClassForCapacity(capacityKg int32) VehicleClass {
+	switch {
+	case capacityKg >= truckMinCapacityKg:
+		return ClassTruck
+	case capacityKg >= vanMinCapacityKg:
+		return ClassVan
+	default:
+		return ClassCar
+	}
+}
+
+type Estimate struct {
+	DistanceKm  float64 `json:"distance_km"`
+	DurationMin float64 `json:"duration_min"`
+}
+
+type Estimator struct {
+	circuityFactor float64
+	speedsKmh      map[VehicleClass]float64
+	profile        *SpeedProfile
+	roads          RoadRouter
+}
+
+// RoadRouter measures the free-flow drive between two points along a road
+// network, and reports false when the network doesn't cover them.
+type RoadRouter func(origin, destination Point, class VehicleClass) (Estimate, bool)
+
+// NewEstimator creates a straight-line ETA estimator. A zero circuity factor
+// or a missing class speed falls back to the package defaults.
+func NewEstimator(circuityFactor float64, speedsKmh map[VehicleClass]float64) (*Estimator, error) {
+	if circuityFactor == 0 {
+		circuityFactor = DefaultCircuityFactor
+	}
+	if circuityFactor < 1 {
+		return nil, fmt.Errorf("circuity factor must be at least 1, got %v", circuityFactor)
+	}
+
+	speeds := make(map[VehicleClass]float64, len(DefaultSpeedsKmh))
+	for class, speed := range DefaultSpeedsKmh {
+		speeds[class] = speed
+	}
+	for class, speed := range speedsKmh {
+		if speed == 0 {
+			continue
+		}
+		if speed < 0 {
+			return nil, fmt.Errorf("average speed for %s must be positive, got %v", class, speed)
+		}
+		speeds[class] = speed
+	}
+
+	return &Estimator{
+		circuityFactor: circuityFactor,
+		speedsKmh:      speeds,
+	}, nil
+}
+
+// SpeedKmh returns the average speed used for the given vehicle class.
+func (estimator *Estimator) SpeedKmh(class VehicleClass) float64 {
+	if speed, ok := estimator.speedsKmh[class]; ok {
+		return speed
+	}
+	return estimator.speedsKmh[ClassCar]
+}
+
+// Estimate returns the expected road distance and driving time between two
+// points: along the estimator's roads when they cover both, and from the
+// straight line between them otherwise.
+func (estimator *Estimator) Estimate(origin, destination Point, class VehicleClass) Estimate {
+	if estimator.roads != nil {
+		if estimate, ok := estimator.roads(origin, destination, class); ok {
+			return estimate
+		}
+	}
+	distanceKm := HaversineKm(origin, destination) * estimator.circuityFactor
+	durationMin := distanceKm / estimator.SpeedKmh(class) * 60
+
+	return Estimate{
+		DistanceKm:  round(distanceKm, 2),
+		DurationMin: round(durationMin, 1),
+	}
+}
+
+// WithProfile returns a copy of the estimator whose estimates at a departure
+// time follow the speed profile.
+func (estimator *Estimator) WithProfile(profile *SpeedProfile) *Estimator {
+	adjusted := *estimator
+	adjusted.profile = profile
+	return &adjusted
+}
+
+// WithRoads returns a copy of the estimator whose estimates follow the roads
+// found by roads wherever it covers them.
+func (estimator *Estimator) WithRoads(roads RoadRouter) *Estimator {
+	adjusted := *estimator
+	adjusted.roads = roads
+	return &adjusted
+}
+
+// EstimateAt is Estimate for a trip leaving at departure, slowed down by the
+// estimator's speed profile if it has one.
+func (estimator *Estimator) EstimateAt(origin, destination Point, class VehicleClass, departure time.Time) Estimate {
+	return estimator.profile.Adjust(estimator.Estimate(origin, destination, class), origin, departure)
+}
+
+func round(value float64, places int) float64 {
+	pow := math.Pow(10, float64(places))
+	return math.Round(value*pow) / pow
+}
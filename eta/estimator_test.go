@@ -0,0 +1,80 @@
+package eta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHaversineKm(t *testing.T) {
+	lagos := Point{Lat: 6.5244, Lng: 3.3792}
+	abuja := Point{Lat: 9.0765, Lng: 7.3986}
+
+	require.InDelta(t, 525.0, HaversineKm(lagos, abuja), 5.0)
+	require.Zero(t, HaversineKm(lagos, lagos))
+	require.InDelta(t, HaversineKm(lagos, abuja), HaversineKm(abuja, lagos), 1e-9)
+}
+
+func TestClassForCapacity(t *testing.T) {
+	require.Equal(t, ClassCar, ClassForCapacity(0))
+	require.Equal(t, ClassCar, ClassForCapacity(350))
+	require.Equal(t, ClassVan, ClassForCapacity(vanMinCapacityKg))
+	require.Equal(t, ClassVan, ClassForCapacity(1200))
+	require.Equal(t, ClassTruck, ClassForCapacity(truckMinCapacityKg))
+	require.Equal(t, ClassTruck, ClassForCapacity(8000))
+}
+
+func TestNewEstimatorDefaults(t *testing.T) {
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+	require.Equal(t, DefaultCircuityFactor, estimator.circuityFactor)
+	for class, speed := range DefaultSpeedsKmh {
+		require.Equal(t, speed, estimator.SpeedKmh(class))
+	}
+}
+
+func TestNewEstimatorInvalid(t *testing.T) {
+	_, err := NewEstimator(0.5, nil)
+	require.Error(t, err)
+
+	_, err = NewEstimator(1.2, map[VehicleClass]float64{ClassVan: -10})
+	require.Error(t, err)
+}
+
+func TestEstimate(t *testing.T) {
+	estimator, err := NewEstimator(1.5, map[VehicleClass]float64{ClassTruck: 20})
+	require.NoError(t, err)
+
+	origin := Point{Lat: 6.5244, Lng: 3.3792}
+	destination := Point{Lat: 6.6018, Lng: 3.3515}
+	straightLine := HaversineKm(origin, destination)
+
+	truck := estimator.Estimate(origin, destination, ClassTruck)
+	require.InDelta(t, straightLine*1.5, truck.DistanceKm, 0.01)
+	require.InDelta(t, truck.DistanceKm/20*60, truck.DurationMin, 0.1)
+
+	car := estimator.Estimate(origin, destination, ClassCar)
+	require.Equal(t, truck.DistanceKm, car.DistanceKm)
+	require.Less(t, car.DurationMin, truck.DurationMin)
+}
+
+func TestEstimateWithRoads(t *testing.T) {
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+
+	origin := Point{Lat: 6.5244, Lng: 3.3792}
+	covered := Point{Lat: 6.6018, Lng: 3.3515}
+	uncovered := Point{Lat: 7.1, Lng: 3.9}
+	road := Estimate{DistanceKm: 12.5, DurationMin: 21}
+	withRoads := estimator.WithRoads(func(from, to Point, class VehicleClass) (Estimate, bool) {
+		return road, to == covered
+	})
+
+	require.Equal(t, road, withRoads.Estimate(origin, covered, ClassCar))
+	require.Equal(t, estimator.Estimate(origin, uncovered, ClassCar), withRoads.Estimate(origin, uncovered, ClassCar))
+
+	// schedules chain the road legs
+	schedule := withRoads.Schedule(origin, time.Now(), []Stop{{Point: covered}}, ClassCar)
+	require.Equal(t, road.DistanceKm, schedule[0].DistanceKm)
+}
@@ -0,0 +1,27 @@
+package eta
+
+import "math"
+
+const earthRadiusKm = 6371.0088
+
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// HaversineKm returns the great-circle distance between two points in kilometres.
+func HaversineKm(from, to Point) float64 {
+	lat1 := toRadians(from.Lat)
+	lat2 := toRadians(to.Lat)
+	dLat := toRadians(to.Lat - from.Lat)
+	dLng := toRadians(to.Lng - from.Lng)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadiusKm * c
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,169 @@
+package eta
+
+import (
+	"math"
+	"sort"
+)
+
+// minIntervalSamples is how many trips a distance band needs for intervals
+// of its own. Bands with fewer use the quantiles of every trip.
+const minIntervalSamples = 30
+
+// DistanceBandsKm are the distances the bands trips are compared in start
+// at, the last one having no end.
+var DistanceBandsKm = []float64{0, 5, 20, 50, 100}
+
+// DistanceBand returns the index in DistanceBandsKm of the band distanceKm
+// is in.
+func DistanceBand(distanceKm float64) int {
+	band := 0
+	for i, start := range DistanceBandsKm {
+		if distanceKm >= start {
+			band = i
+		}
+	}
+	return band
+}
+
+// IntervalSample is a completed trip's estimate and how long it took.
+type IntervalSample struct {
+	DistanceKm   float64
+	EstimatedMin float64
+	ActualMin    float64
+}
+
+// Interval are the 10th, 50th and 90th percentiles of how long a trip is
+// expected to take.
+type Interval struct {
+	P10Min float64 `json:"p10_min"`
+	P50Min float64 `json:"p50_min"`
+	P90Min float64 `json:"p90_min"`
+}
+
+// ratioQuantiles are the quantiles of how many times their estimate trips
+// took.
+type ratioQuantiles struct {
+	p10, p50, p90 float64
+}
+
+// ErrorDistribution is the empirical distribution of how far off estimates
+// were, for trips of every distance band.
+type ErrorDistribution struct {
+	overall *ratioQuantiles
+	bands   []*ratioQuantiles
+	samples []int
+}
+
+// LearnErrorDistribution learns the distribution of the ratio of actual to
+// estimated duration of the samples. Without minIntervalSamples trips in
+// total it yields no intervals.
+func LearnErrorDistribution(samples []IntervalSample) *ErrorDistribution {
+	var all []float64
+	bands := make([][]float64, len(DistanceBandsKm))
+	for _, sample := range samples {
+		if sample.EstimatedMin <= 0 || sample.ActualMin <= 0 {
+			continue
+		}
+		ratio := sample.ActualMin / sample.EstimatedMin
+		all = append(all, ratio)
+		band := DistanceBand(sample.DistanceKm)
+		bands[band] = append(bands[band], ratio)
+	}
+
+	distribution := &ErrorDistribution{
+		overall: newRatioQuantiles(all),
+		bands:   make([]*ratioQuantiles, len(DistanceBandsKm)),
+		samples: make([]int, len(DistanceBandsKm)),
+	}
+	for band, ratios := range bands {
+		distribution.bands[band] = newRatioQuantiles(ratios)
+		distribution.samples[band] = len(ratios)
+	}
+	return distribution
+}
+
+func newRatioQuantiles(ratios []float64) *ratioQuantiles {
+	if len(ratios) < minIntervalSamples {
+		return nil
+	}
+	sort.Float64s(ratios)
+	return &ratioQuantiles{
+		p10: quantile(ratios, 0.1),
+		p50: quantile(ratios, 0.5),
+		p90: quantile(ratios, 0.9),
+	}
+}
+
+// quantile interpolates the q quantile of sorted values linearly, like
+// Postgres' percentile_cont.
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (position-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+func (distribution *ErrorDistribution) quantiles(distanceKm float64) *ratioQuantiles {
+	if distribution == nil {
+		return nil
+	}
+	if quantiles := distribution.bands[DistanceBand(distanceKm)]; quantiles != nil {
+		return quantiles
+	}
+	return distribution.overall
+}
+
+// Interval scales a trip's estimated duration by the quantiles of the trips
+// of its distance band. It reports false when there are too few trips to
+// tell, and for a nil distribution.
+func (distribution *ErrorDistribution) Interval(estimate Estimate) (Interval, bool) {
+	quantiles := distribution.quantiles(estimate.DistanceKm)
+	if quantiles == nil {
+		return Interval{}, false
+	}
+	return Interval{
+		P10Min: round(estimate.DurationMin*quantiles.p10, 1),
+		P50Min: round(estimate.DurationMin*quantiles.p50, 1),
+		P90Min: round(estimate.DurationMin*quantiles.p90, 1),
+	}, true
+}
+
+// IntervalBand is what a distance band's intervals are derived from.
+type IntervalBand struct {
+	MinDistanceKm float64
+	// MaxDistanceKm is 0 for the last band, which has no end.
+	MaxDistanceKm float64
+	Samples       int
+	// Learned tells whether the band has intervals of its own, or uses those
+	// of every trip.
+	Learned bool
+	// P10Ratio, P50Ratio and P90Ratio are the quantiles of actual to
+	// estimated duration, 0 without enough trips.
+	P10Ratio float64
+	P50Ratio float64
+	P90Ratio float64
+}
+
+// Bands returns how every distance band's intervals are derived.
+func (distribution *ErrorDistribution) Bands() []IntervalBand {
+	bands := make([]IntervalBand, 0, len(DistanceBandsKm))
+	for band, start := range DistanceBandsKm {
+		info := IntervalBand{
+			MinDistanceKm: start,
+			Samples:       distribution.samples[band],
+			Learned:       distribution.bands[band] != nil,
+		}
+		if band+1 < len(DistanceBandsKm) {
+			info.MaxDistanceKm = DistanceBandsKm[band+1]
+		}
+		if quantiles := distribution.quantiles(start); quantiles != nil {
+			info.P10Ratio = quantiles.p10
+			info.P50Ratio = quantiles.p50
+			info.P90Ratio = quantiles.p90
+		}
+		bands = append(bands, info)
+	}
+	return bands
+}
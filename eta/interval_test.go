@@ -0,0 +1,83 @@
+package eta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistanceBand(t *testing.T) {
+	for distanceKm, band := range map[float64]int{0: 0, 4.99: 0, 5: 1, 19: 1, 20: 2, 75: 3, 100: 4, 900: 4} {
+		require.Equal(t, band, DistanceBand(distanceKm), distanceKm)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{5, 5, 15}
+	require.Equal(t, 5.0, quantile(sorted, 0.1))
+	require.Equal(t, 5.0, quantile(sorted, 0.5))
+	require.InDelta(t, 13, quantile(sorted, 0.9), 1e-9)
+	require.Equal(t, 15.0, quantile(sorted, 1))
+	require.Equal(t, 7.0, quantile([]float64{7}, 0.9))
+}
+
+// intervalSamples are n trips of distanceKm estimated at 20 minutes that took
+// 1 to 100% longer, evenly.
+func intervalSamples(n int, distanceKm float64) []IntervalSample {
+	samples := make([]IntervalSample, n)
+	for i := range samples {
+		samples[i] = IntervalSample{
+			DistanceKm:   distanceKm,
+			EstimatedMin: 20,
+			ActualMin:    20 * (1 + float64(i+1)/float64(n)),
+		}
+	}
+	return samples
+}
+
+func TestLearnErrorDistribution(t *testing.T) {
+	var none *ErrorDistribution
+	_, ok := none.Interval(Estimate{DistanceKm: 10, DurationMin: 30})
+	require.False(t, ok)
+
+	// too few trips to tell
+	distribution := LearnErrorDistribution(intervalSamples(minIntervalSamples-1, 10))
+	_, ok = distribution.Interval(Estimate{DistanceKm: 10, DurationMin: 30})
+	require.False(t, ok)
+	for _, band := range distribution.Bands() {
+		require.False(t, band.Learned)
+		require.Zero(t, band.P50Ratio)
+	}
+
+	// short trips run late by a lot, the few long ones by a little
+	samples := intervalSamples(101, 10)
+	samples = append(samples, IntervalSample{DistanceKm: 10, EstimatedMin: 0, ActualMin: 30})
+	for i := 0; i < 5; i++ {
+		samples = append(samples, IntervalSample{DistanceKm: 60, EstimatedMin: 60, ActualMin: 60})
+	}
+	distribution = LearnErrorDistribution(samples)
+
+	interval, ok := distribution.Interval(Estimate{DistanceKm: 10, DurationMin: 30})
+	require.True(t, ok)
+	require.InDelta(t, 30*1.11, interval.P10Min, 0.05)
+	require.InDelta(t, 30*1.505, interval.P50Min, 0.05)
+	require.InDelta(t, 30*1.901, interval.P90Min, 0.05)
+
+	// the long band has too few trips of its own and takes every trip's
+	long, ok := distribution.Interval(Estimate{DistanceKm: 60, DurationMin: 30})
+	require.True(t, ok)
+	require.Less(t, long.P10Min, interval.P10Min)
+	require.LessOrEqual(t, long.P10Min, long.P50Min)
+	require.LessOrEqual(t, long.P50Min, long.P90Min)
+
+	bands := distribution.Bands()
+	require.Len(t, bands, len(DistanceBandsKm))
+	require.Equal(t, 101, bands[1].Samples)
+	require.True(t, bands[1].Learned)
+	require.Equal(t, 5.0, bands[1].MinDistanceKm)
+	require.Equal(t, 20.0, bands[1].MaxDistanceKm)
+	require.Equal(t, 5, bands[3].Samples)
+	require.False(t, bands[3].Learned)
+	require.Greater(t, bands[3].P50Ratio, 0.0)
+	require.Zero(t, bands[4].MaxDistanceKm)
+}
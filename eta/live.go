@@ -0,0 +1,180 @@
+package eta
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultSpeedWindow is how far back reported positions are used to
+	// measure the vehicle's recent average speed.
+	DefaultSpeedWindow = 10 * time.Minute
+
+	// PlannedConfidence is reported for estimates that are not backed by any
+	// live position yet.
+	PlannedConfidence = 0.5
+
+	// staleAfter is the age after which a position starts to lose confidence.
+	staleAfter = 2 * time.Minute
+	// minObservedSpeedKmh is the speed below which the vehicle is treated as
+	// stopped and the observed speed is not trusted on its own.
+	minObservedSpeedKmh = 3.0
+	// fullTrustSamples is the number of samples at which the observed speed
+	// fully replaces the class average speed.
+	fullTrustSamples = 6
+)
+
+var (
+	ErrNoFixes = errors.New("at least one position is required")
+	ErrNoStops = errors.New("at least one stop is required")
+)
+
+// Fix is a single reported vehicle position.
+type Fix struct {
+	Point
+	SpeedKmh   float64
+	HasSpeed   bool
+	RecordedAt time.Time
+}
+
+type LiveEstimate struct {
+	RemainingDistanceKm  float64   `json:"remaining_distance_km"`
+	RemainingDurationMin float64   `json:"remaining_duration_min"`
+	ArrivalAt            time.Time `json:"arrival_at"`
+	Confidence           float64   `json:"confidence"`
+	SpeedKmh             float64   `json:"speed_kmh"`
+	Stops                []StopEta `json:"stops"`
+}
+
+// Recalculate estimates the rest of a trip from the latest position in
+// fixes, which must be ordered oldest first, through stops in order, the last
+// of them being the destination. Legs follow the estimator's roads where it has
+// them and are driven at the recent average speed, blended with the class
+// average speed according to how many samples back it. Stops are served as in
+// Schedule and the estimate holds the visit of each of them.
+func (estimator *Estimator) Recalculate(stops []Stop, class VehicleClass, fixes []Fix, now time.Time) (LiveEstimate, error) {
+	if len(fixes) == 0 {
+		return LiveEstimate{}, ErrNoFixes
+	}
+	if len(stops) == 0 {
+		return LiveEstimate{}, ErrNoStops
+	}
+	latest := fixes[len(fixes)-1]
+
+	classSpeed := estimator.SpeedKmh(class)
+	observedSpeed, samples := observedSpeedKmh(fixes)
+
+	weight := math.Min(float64(samples)/fullTrustSamples, 1)
+	if observedSpeed < minObservedSpeedKmh {
+		// a stopped vehicle will move again, so never let a standstill
+		// dominate the prediction
+		weight /= 2
+	}
+	speed := weight*observedSpeed + (1-weight)*classSpeed
+
+	// legs are estimated at the class speed, so they take longer or shorter
+	// in proportion to the speed actually driven
+	pace := classSpeed / speed
+	visits := schedule(latest.Point, latest.RecordedAt, stops, func(from, to Point, _ time.Time) Estimate {
+		leg := estimator.Estimate(from, to, class)
+		leg.DurationMin *= pace
+		return leg
+	})
+
+	end := visits[len(visits)-1]
+	durationMin := end.ArrivalAt.Sub(latest.RecordedAt).Minutes()
+	arrival := end.ArrivalAt
+	if arrival.Before(now) {
+		arrival = now
+	}
+
+	return LiveEstimate{
+		RemainingDistanceKm:  end.DistanceKm,
+		RemainingDurationMin: round(durationMin, 1),
+		ArrivalAt:            arrival,
+		Confidence:           round(confidence(fixes, samples, end.DistanceKm, now), 2),
+		SpeedKmh:             round(speed, 1),
+		Stops:                visits,
+	}, nil
+}
+
+// observedSpeedKmh prefers device-reported speeds and falls back to the
+// distance covered between consecutive positions.
+func observedSpeedKmh(fixes []Fix) (float64, int) {
+	var total float64
+	var reported int
+	for _, fix := range fixes {
+		if fix.HasSpeed {
+			total += fix.SpeedKmh
+			reported++
+		}
+	}
+	if reported > 0 {
+		return total / float64(reported), reported
+	}
+
+	if len(fixes) < 2 {
+		return 0, 0
+	}
+	var distanceKm float64
+	for i := 1; i < len(fixes); i++ {
+		distanceKm += HaversineKm(fixes[i-1].Point, fixes[i].Point)
+	}
+	hours := fixes[len(fixes)-1].RecordedAt.Sub(fixes[0].RecordedAt).Hours()
+	if hours <= 0 {
+		return 0, 0
+	}
+	return distanceKm / hours, len(fixes) - 1
+}
+
+// confidence is a 0..1 score that drops with stale positions, few samples,
+// erratic speeds and long remaining distances.
+func confidence(fixes []Fix, samples int, remainingKm float64, now time.Time) float64 {
+	score := 1.0
+
+	age := now.Sub(fixes[len(fixes)-1].RecordedAt)
+	if age > staleAfter {
+		score *= math.Exp(-(age - staleAfter).Minutes() / 10)
+	}
+
+	score *= 0.5 + 0.5*math.Min(float64(samples)/fullTrustSamples, 1)
+
+	if cv := speedVariation(fixes); cv > 0 {
+		score *= 1 / (1 + cv)
+	}
+
+	// every 50 km still to go leaves more room for the unexpected
+	score *= 1 / (1 + remainingKm/50)
+
+	return math.Max(0, math.Min(score, 1))
+}
+
+// speedVariation returns the coefficient of variation of reported speeds.
+func speedVariation(fixes []Fix) float64 {
+	var speeds []float64
+	for _, fix := range fixes {
+		if fix.HasSpeed {
+			speeds = append(speeds, fix.SpeedKmh)
+		}
+	}
+	if len(speeds) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, speed := range speeds {
+		mean += speed
+	}
+	mean /= float64(len(speeds))
+	if mean == 0 {
+		return 0
+	}
+
+	var variance float64
+	for _, speed := range speeds {
+		variance += (speed - mean) * (speed - mean)
+	}
+	variance /= float64(len(speeds))
+	return math.Sqrt(variance) / mean
+}
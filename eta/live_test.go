@@ -0,0 +1,126 @@
+package eta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecalculateNoFixes(t *testing.T) {
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+
+	_, err = estimator.Recalculate([]Stop{{}}, ClassCar, nil, time.Now())
+	require.ErrorIs(t, err, ErrNoFixes)
+
+	fixes := []Fix{{Point: Point{Lat: 6.5, Lng: 3.3}, RecordedAt: time.Now()}}
+	_, err = estimator.Recalculate(nil, ClassCar, fixes, time.Now())
+	require.ErrorIs(t, err, ErrNoStops)
+}
+
+func TestRecalculateUsesReportedSpeed(t *testing.T) {
+	estimator, err := NewEstimator(1, nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	destination := Point{Lat: 6.6018, Lng: 3.3515}
+	fixes := make([]Fix, 0, fullTrustSamples)
+	for i := fullTrustSamples; i > 0; i-- {
+		fixes = append(fixes, Fix{
+			Point:      Point{Lat: 6.5244, Lng: 3.3792},
+			SpeedKmh:   60,
+			HasSpeed:   true,
+			RecordedAt: now.Add(-time.Duration(i) * 10 * time.Second),
+		})
+	}
+
+	estimate, err := estimator.Recalculate([]Stop{{Point: destination}}, ClassCar, fixes, now)
+	require.NoError(t, err)
+
+	remaining := HaversineKm(fixes[0].Point, destination)
+	require.InDelta(t, remaining, estimate.RemainingDistanceKm, 0.01)
+	require.Equal(t, 60.0, estimate.SpeedKmh)
+	require.InDelta(t, remaining, estimate.RemainingDurationMin, 0.1)
+	require.True(t, estimate.ArrivalAt.After(now))
+	require.Greater(t, estimate.Confidence, 0.8)
+}
+
+func TestRecalculateThroughStops(t *testing.T) {
+	estimator, err := NewEstimator(1, nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	position := Point{Lat: 6.5244, Lng: 3.3792}
+	stops := []Stop{
+		{Point: Point{Lat: 6.4654, Lng: 3.4064}, ServiceMin: 10},
+		{Point: Point{Lat: 6.6018, Lng: 3.3515}},
+	}
+	fixes := make([]Fix, 0, fullTrustSamples)
+	for i := fullTrustSamples; i > 0; i-- {
+		fixes = append(fixes, Fix{
+			Point:      position,
+			SpeedKmh:   60,
+			HasSpeed:   true,
+			RecordedAt: now.Add(-time.Duration(i) * 10 * time.Second),
+		})
+	}
+
+	estimate, err := estimator.Recalculate(stops, ClassCar, fixes, now)
+	require.NoError(t, err)
+	require.Len(t, estimate.Stops, len(stops))
+
+	// at 60 km/h every kilometre takes a minute
+	first := HaversineKm(position, stops[0].Point)
+	second := HaversineKm(stops[0].Point, stops[1].Point)
+	require.InDelta(t, first, estimate.Stops[0].DistanceKm, 0.01)
+	require.InDelta(t, first+second, estimate.RemainingDistanceKm, 0.02)
+	require.Equal(t, 10*time.Minute, estimate.Stops[0].DepartureAt.Sub(estimate.Stops[0].ArrivalAt))
+	require.InDelta(t, first+second+10, estimate.RemainingDurationMin, 0.2)
+	require.Equal(t, estimate.Stops[1].ArrivalAt, estimate.ArrivalAt)
+}
+
+func TestRecalculateFallsBackToDisplacement(t *testing.T) {
+	estimator, err := NewEstimator(1, nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	from := Point{Lat: 6.5000, Lng: 3.3000}
+	to := Point{Lat: 6.5100, Lng: 3.3000}
+	fixes := []Fix{
+		{Point: from, RecordedAt: now.Add(-time.Minute)},
+		{Point: to, RecordedAt: now},
+	}
+
+	observed, samples := observedSpeedKmh(fixes)
+	require.Equal(t, 1, samples)
+	require.InDelta(t, HaversineKm(from, to)*60, observed, 0.01)
+
+	estimate, err := estimator.Recalculate([]Stop{{Point: Point{Lat: 6.6, Lng: 3.3}}}, ClassCar, fixes, now)
+	require.NoError(t, err)
+	require.Greater(t, estimate.SpeedKmh, 0.0)
+	require.Greater(t, estimate.RemainingDurationMin, 0.0)
+}
+
+func TestRecalculateStoppedVehicle(t *testing.T) {
+	estimator, err := NewEstimator(1, nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	fixes := []Fix{
+		{Point: Point{Lat: 6.5, Lng: 3.3}, SpeedKmh: 0, HasSpeed: true, RecordedAt: now},
+	}
+
+	estimate, err := estimator.Recalculate([]Stop{{Point: Point{Lat: 6.6, Lng: 3.3}}}, ClassCar, fixes, now)
+	require.NoError(t, err)
+	require.Greater(t, estimate.SpeedKmh, minObservedSpeedKmh)
+}
+
+func TestConfidenceDropsWithStalePosition(t *testing.T) {
+	now := time.Now()
+	fresh := []Fix{{Point: Point{Lat: 6.5, Lng: 3.3}, SpeedKmh: 40, HasSpeed: true, RecordedAt: now}}
+	stale := []Fix{{Point: Point{Lat: 6.5, Lng: 3.3}, SpeedKmh: 40, HasSpeed: true, RecordedAt: now.Add(-30 * time.Minute)}}
+
+	require.Greater(t, confidence(fresh, 1, 5, now), confidence(stale, 1, 5, now))
+	require.Greater(t, confidence(fresh, 1, 5, now), confidence(fresh, 1, 200, now))
+}
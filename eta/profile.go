@@ -0,0 +1,187 @@
+package eta
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// BucketMinutes is the length of the time-of-day buckets of a profile.
+	BucketMinutes = 15
+	// BucketsPerWeek is the number of buckets a profile has, weekdays
+	// included.
+	BucketsPerWeek = 7 * bucketsPerDay
+
+	// ProfileCellDeg is the size of the cells trips are grouped by, by the
+	// point they leave from. Each cell gets its own profile where it has
+	// trips.
+	ProfileCellDeg = 0.1
+
+	bucketsPerDay = 24 * 60 / BucketMinutes
+	// profilePrior is how many trips' worth of weight the broader profile
+	// keeps in a bucket, so a few trips don't swing a bucket on their own.
+	profilePrior = 5.0
+	// neighbourWeight is how much a trip counts towards the buckets just
+	// before and after its own, smoothing the profile over the day.
+	neighbourWeight = 0.5
+	// minFactor and maxFactor bound the slowdown a single trip can show,
+	// discarding the effect of routes completed long after arriving.
+	minFactor = 0.5
+	maxFactor = 4.0
+)
+
+// Sample is a completed trip to learn from.
+type Sample struct {
+	Origin    Point
+	StartedAt time.Time
+	// BaselineMin is the free-flow estimate of the trip, ActualMin how long
+	// it took.
+	BaselineMin float64
+	ActualMin   float64
+}
+
+type profileCell struct {
+	row, col int32
+}
+
+func profileCellOf(point Point) profileCell {
+	return profileCell{
+		row: int32(math.Floor(point.Lat / ProfileCellDeg)),
+		col: int32(math.Floor(point.Lng / ProfileCellDeg)),
+	}
+}
+
+// bucketStats sums the weight and weighted slowdown of the trips of every
+// bucket of a week.
+type bucketStats struct {
+	weight [BucketsPerWeek]float64
+	sum    [BucketsPerWeek]float64
+}
+
+func (stats *bucketStats) add(bucket int, factor float64) {
+	for _, b := range []struct {
+		bucket int
+		weight float64
+	}{
+		{bucket, 1},
+		{(bucket + BucketsPerWeek - 1) % BucketsPerWeek, neighbourWeight},
+		{(bucket + 1) % BucketsPerWeek, neighbourWeight},
+	} {
+		stats.weight[b.bucket] += b.weight
+		stats.sum[b.bucket] += b.weight * factor
+	}
+}
+
+// factors shrinks the mean slowdown of every bucket towards prior.
+func (stats *bucketStats) factors(prior func(bucket int) float64) *[BucketsPerWeek]float64 {
+	var factors [BucketsPerWeek]float64
+	for b := range factors {
+		factors[b] = (stats.sum[b] + profilePrior*prior(b)) / (stats.weight[b] + profilePrior)
+	}
+	return &factors
+}
+
+// SpeedProfile tells how much longer than their free-flow estimate trips
+// take depending on when they leave, by 15-minute bucket of the week in the
+// profile's time zone. It's learned over every trip, and for every cell of
+// ProfileCellDeg trips left from, shrunk towards the overall profile.
+type SpeedProfile struct {
+	location *time.Location
+	overall  *[BucketsPerWeek]float64
+	cells    map[profileCell]*[BucketsPerWeek]float64
+	samples  [BucketsPerWeek]int
+}
+
+// LearnSpeedProfile learns the profile of the samples, bucketed by their
+// start time in location. With no samples every factor is 1.
+func LearnSpeedProfile(samples []Sample, location *time.Location) *SpeedProfile {
+	profile := &SpeedProfile{
+		location: location,
+		cells:    make(map[profileCell]*[BucketsPerWeek]float64),
+	}
+
+	var overall bucketStats
+	cells := make(map[profileCell]*bucketStats)
+	for _, sample := range samples {
+		if sample.BaselineMin <= 0 || sample.ActualMin <= 0 {
+			continue
+		}
+		factor := math.Min(math.Max(sample.ActualMin/sample.BaselineMin, minFactor), maxFactor)
+		bucket := profile.bucket(sample.StartedAt)
+		profile.samples[bucket]++
+
+		overall.add(bucket, factor)
+		cell := profileCellOf(sample.Origin)
+		if cells[cell] == nil {
+			cells[cell] = &bucketStats{}
+		}
+		cells[cell].add(bucket, factor)
+	}
+
+	profile.overall = overall.factors(func(int) float64 { return 1 })
+	for cell, stats := range cells {
+		profile.cells[cell] = stats.factors(func(bucket int) float64 { return profile.overall[bucket] })
+	}
+	return profile
+}
+
+// bucket returns the bucket of the week at is in, counted from Sunday
+// midnight.
+func (profile *SpeedProfile) bucket(at time.Time) int {
+	at = at.In(profile.location)
+	return int(at.Weekday())*bucketsPerDay + (at.Hour()*60+at.Minute())/BucketMinutes
+}
+
+// Factor returns how many times longer than the free-flow estimate a trip
+// leaving origin at departure is expected to take. A nil profile has no
+// traffic to account for.
+func (profile *SpeedProfile) Factor(origin Point, departure time.Time) float64 {
+	if profile == nil {
+		return 1
+	}
+	bucket := profile.bucket(departure)
+	if factors, ok := profile.cells[profileCellOf(origin)]; ok {
+		return factors[bucket]
+	}
+	return profile.overall[bucket]
+}
+
+// Adjust scales the free-flow duration of estimate by the factor of a trip
+// leaving origin at departure.
+func (profile *SpeedProfile) Adjust(estimate Estimate, origin Point, departure time.Time) Estimate {
+	estimate.DurationMin = round(estimate.DurationMin*profile.Factor(origin, departure), 1)
+	return estimate
+}
+
+// ProfileBucket is one bucket of the overall profile.
+type ProfileBucket struct {
+	Weekday time.Weekday
+	// Start is how long after midnight the bucket starts.
+	Start   time.Duration
+	Factor  float64
+	Samples int
+}
+
+// Buckets returns the overall profile, starting on Sunday at midnight.
+func (profile *SpeedProfile) Buckets() []ProfileBucket {
+	buckets := make([]ProfileBucket, 0, BucketsPerWeek)
+	for b, factor := range profile.overall {
+		buckets = append(buckets, ProfileBucket{
+			Weekday: time.Weekday(b / bucketsPerDay),
+			Start:   time.Duration(b%bucketsPerDay*BucketMinutes) * time.Minute,
+			Factor:  factor,
+			Samples: profile.samples[b],
+		})
+	}
+	return buckets
+}
+
+// Cells returns how many cells have a profile of their own.
+func (profile *SpeedProfile) Cells() int {
+	return len(profile.cells)
+}
+
+// Location returns the time zone the profile's buckets are in.
+func (profile *SpeedProfile) Location() *time.Location {
+	return profile.location
+}
@@ -0,0 +1,107 @@
+package eta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var lagos = time.FixedZone("WAT", 60*60)
+
+// monday returns the time on the first Monday of March 2024 in Lagos.
+func monday(hour, min int) time.Time {
+	return time.Date(2024, 3, 4, hour, min, 0, 0, lagos)
+}
+
+func repeatSample(n int, sample Sample) []Sample {
+	samples := make([]Sample, n)
+	for i := range samples {
+		samples[i] = sample
+	}
+	return samples
+}
+
+func TestLearnSpeedProfileEmpty(t *testing.T) {
+	profile := LearnSpeedProfile(nil, lagos)
+	for _, bucket := range profile.Buckets() {
+		require.Equal(t, 1.0, bucket.Factor)
+		require.Zero(t, bucket.Samples)
+	}
+	require.Zero(t, profile.Cells())
+	require.Equal(t, lagos, profile.Location())
+
+	var none *SpeedProfile
+	require.Equal(t, 1.0, none.Factor(Point{}, monday(8, 0)))
+}
+
+func TestLearnSpeedProfile(t *testing.T) {
+	origin := Point{Lat: 6.5244, Lng: 3.3792}
+	// the morning rush takes twice as long as the night, which is free-flow
+	samples := repeatSample(50, Sample{Origin: origin, StartedAt: monday(8, 5), BaselineMin: 30, ActualMin: 60})
+	samples = append(samples, repeatSample(50, Sample{Origin: origin, StartedAt: monday(2, 0), BaselineMin: 30, ActualMin: 30})...)
+	// neither an unplanned trip nor a trip completed days late counts for much
+	samples = append(samples, Sample{Origin: origin, StartedAt: monday(2, 5), BaselineMin: 0, ActualMin: 30})
+	samples = append(samples, Sample{Origin: origin, StartedAt: monday(2, 10), BaselineMin: 30, ActualMin: 3000})
+	profile := LearnSpeedProfile(samples, lagos)
+
+	// the trips' cell is shrunk towards the overall profile of the same trips
+	overall := (50*2 + profilePrior) / (50 + profilePrior)
+	require.InDelta(t, (50*2+profilePrior*overall)/(50+profilePrior), profile.Factor(origin, monday(8, 14)), 1e-9)
+	// in UTC, an hour behind Lagos
+	require.Equal(t, profile.Factor(origin, monday(8, 0)), profile.Factor(origin, monday(8, 0).UTC()))
+	overall = (50*neighbourWeight*2 + profilePrior) / (50*neighbourWeight + profilePrior)
+	require.InDelta(t, (50*neighbourWeight*2+profilePrior*overall)/(50*neighbourWeight+profilePrior), profile.Factor(origin, monday(8, 20)), 1e-9)
+	require.Equal(t, 1.0, profile.Factor(origin, monday(8, 30)))
+	require.InDelta(t, 1, profile.Factor(origin, monday(2, 0)), 0.1)
+	require.Equal(t, 1.0, profile.Factor(origin, monday(8, 0).AddDate(0, 0, 1)))
+
+	buckets := profile.Buckets()
+	require.Len(t, buckets, BucketsPerWeek)
+	rush := buckets[bucketsPerDay+8*60/BucketMinutes]
+	require.Equal(t, time.Monday, rush.Weekday)
+	require.Equal(t, 8*time.Hour, rush.Start)
+	require.Equal(t, 50, rush.Samples)
+	require.InDelta(t, (50*2+profilePrior)/(50+profilePrior), rush.Factor, 1e-9)
+	require.Equal(t, 1, profile.Cells())
+}
+
+func TestLearnSpeedProfileCells(t *testing.T) {
+	city := Point{Lat: 6.5244, Lng: 3.3792}
+	suburb := Point{Lat: 6.9, Lng: 3.6}
+	samples := repeatSample(100, Sample{Origin: city, StartedAt: monday(8, 0), BaselineMin: 20, ActualMin: 30})
+	samples = append(samples, repeatSample(5, Sample{Origin: suburb, StartedAt: monday(8, 0), BaselineMin: 20, ActualMin: 20})...)
+	profile := LearnSpeedProfile(samples, lagos)
+	require.Equal(t, 2, profile.Cells())
+
+	overall := profile.Factor(Point{Lat: 9.07, Lng: 7.39}, monday(8, 0))
+	require.InDelta(t, (100*1.5+5*1+profilePrior)/(105+profilePrior), overall, 1e-9)
+	require.Greater(t, profile.Factor(city, monday(8, 0)), overall)
+	// too few trips to be sure the suburb is free-flow at rush hour
+	suburbFactor := profile.Factor(suburb, monday(8, 0))
+	require.Less(t, suburbFactor, overall)
+	require.InDelta(t, (5+profilePrior*overall)/(5+profilePrior), suburbFactor, 1e-9)
+}
+
+func TestEstimateAt(t *testing.T) {
+	origin := Point{Lat: 6.5244, Lng: 3.3792}
+	destination := Point{Lat: 6.6018, Lng: 3.3515}
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+	profile := LearnSpeedProfile(repeatSample(100, Sample{Origin: origin, StartedAt: monday(8, 0), BaselineMin: 20, ActualMin: 40}), lagos)
+	adjusted := estimator.WithProfile(profile)
+
+	free := estimator.Estimate(origin, destination, ClassCar)
+	require.Equal(t, free, estimator.EstimateAt(origin, destination, ClassCar, monday(8, 0)))
+	require.Equal(t, free, adjusted.EstimateAt(origin, destination, ClassCar, monday(3, 0)))
+	rush := adjusted.EstimateAt(origin, destination, ClassCar, monday(8, 0))
+	require.Equal(t, free.DistanceKm, rush.DistanceKm)
+	require.InDelta(t, free.DurationMin*profile.Factor(origin, monday(8, 0)), rush.DurationMin, 0.05)
+
+	// the second leg starts after the rush and isn't slowed down
+	stops := []Stop{{Point: destination}, {Point: origin}}
+	schedule := adjusted.Schedule(origin, monday(8, 0), stops, ClassCar)
+	require.WithinDuration(t, monday(8, 0).Add(minutes(rush.DurationMin)), schedule[0].ArrivalAt, time.Millisecond)
+	back := estimator.Estimate(destination, origin, ClassCar)
+	require.WithinDuration(t, schedule[0].DepartureAt.Add(minutes(back.DurationMin)), schedule[1].ArrivalAt, time.Millisecond)
+}
@@ -0,0 +1,59 @@
+package eta
+
+import "time"
+
+// Stop is a waypoint on a multi-stop route along with the time spent there.
+// A driver arriving before NotBefore waits for it before serving the stop.
+type Stop struct {
+	Point
+	ServiceMin float64
+	NotBefore  time.Time
+}
+
+// StopEta is the predicted visit of a single stop.
+type StopEta struct {
+	// DistanceKm is the road distance driven from the start up to the stop.
+	DistanceKm  float64   `json:"distance_km"`
+	ArrivalAt   time.Time `json:"arrival_at"`
+	DepartureAt time.Time `json:"departure_at"`
+}
+
+// Schedule chains leg estimates from start through stops in order, leaving
+// start at departure. Every stop is left once its service time has passed, so
+// each arrival includes the driving, waiting and service time of the stops
+// before it. Legs are estimated at the time they start.
+func (estimator *Estimator) Schedule(start Point, departure time.Time, stops []Stop, class VehicleClass) []StopEta {
+	return schedule(start, departure, stops, func(from, to Point, at time.Time) Estimate {
+		return estimator.EstimateAt(from, to, class, at)
+	})
+}
+
+// schedule chains the legs estimated by estimate from start through stops.
+func schedule(start Point, departure time.Time, stops []Stop, estimate func(from, to Point, at time.Time) Estimate) []StopEta {
+	schedule := make([]StopEta, 0, len(stops))
+	from := start
+	at := departure
+	var distanceKm float64
+	for _, stop := range stops {
+		leg := estimate(from, stop.Point, at)
+		distanceKm += leg.DistanceKm
+		arrival := at.Add(minutes(leg.DurationMin))
+		at = arrival
+		if at.Before(stop.NotBefore) {
+			at = stop.NotBefore
+		}
+		at = at.Add(minutes(stop.ServiceMin))
+
+		schedule = append(schedule, StopEta{
+			DistanceKm:  round(distanceKm, 2),
+			ArrivalAt:   arrival,
+			DepartureAt: at,
+		})
+		from = stop.Point
+	}
+	return schedule
+}
+
+func minutes(value float64) time.Duration {
+	return time.Duration(value * float64(time.Minute))
+}
@@ -0,0 +1,66 @@
+package eta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+
+	depot := Point{Lat: 6.5244, Lng: 3.3792}
+	stops := []Stop{
+		{Point: Point{Lat: 6.4654, Lng: 3.4064}, ServiceMin: 5},
+		{Point: Point{Lat: 6.6018, Lng: 3.3515}, ServiceMin: 10},
+		{Point: Point{Lat: 6.6018, Lng: 3.3515}, ServiceMin: 0},
+	}
+	departure := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	schedule := estimator.Schedule(depot, departure, stops, ClassVan)
+	require.Len(t, schedule, len(stops))
+
+	first := estimator.Estimate(depot, stops[0].Point, ClassVan)
+	require.Equal(t, first.DistanceKm, schedule[0].DistanceKm)
+	require.WithinDuration(t, departure.Add(minutes(first.DurationMin)), schedule[0].ArrivalAt, time.Millisecond)
+	require.Equal(t, 5*time.Minute, schedule[0].DepartureAt.Sub(schedule[0].ArrivalAt))
+
+	second := estimator.Estimate(stops[0].Point, stops[1].Point, ClassVan)
+	require.InDelta(t, first.DistanceKm+second.DistanceKm, schedule[1].DistanceKm, 0.01)
+	require.WithinDuration(t, schedule[0].DepartureAt.Add(minutes(second.DurationMin)), schedule[1].ArrivalAt, time.Millisecond)
+
+	// a second stop at the same place is reached as soon as the first is left
+	require.Equal(t, schedule[1].DistanceKm, schedule[2].DistanceKm)
+	require.Equal(t, schedule[1].DepartureAt, schedule[2].ArrivalAt)
+	require.Equal(t, schedule[2].ArrivalAt, schedule[2].DepartureAt)
+}
+
+func TestScheduleWaitsForWindow(t *testing.T) {
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+
+	depot := Point{Lat: 6.5244, Lng: 3.3792}
+	departure := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	opens := departure.Add(2 * time.Hour)
+	stops := []Stop{
+		{Point: Point{Lat: 6.4654, Lng: 3.4064}, ServiceMin: 5, NotBefore: opens},
+		{Point: Point{Lat: 6.6018, Lng: 3.3515}, ServiceMin: 5, NotBefore: departure},
+	}
+
+	schedule := estimator.Schedule(depot, departure, stops, ClassVan)
+	require.True(t, schedule[0].ArrivalAt.Before(opens))
+	require.Equal(t, opens.Add(5*time.Minute), schedule[0].DepartureAt)
+
+	// a window that is already open doesn't hold the driver up
+	require.Equal(t, schedule[1].ArrivalAt.Add(5*time.Minute), schedule[1].DepartureAt)
+}
+
+func TestScheduleWithoutStops(t *testing.T) {
+	estimator, err := NewEstimator(0, nil)
+	require.NoError(t, err)
+
+	schedule := estimator.Schedule(Point{}, time.Now(), nil, ClassCar)
+	require.Empty(t, schedule)
+}
@@ -0,0 +1,122 @@
+// Package geofence detects vehicles arriving at and leaving places from the
+// positions they report.
+//
+// GPS positions jitter by tens of metres, so a vehicle parked on the edge of
+// a fence would seem to cross it over and over. Detection damps that twice:
+// a vehicle enters a fence within its radius but only leaves it beyond the
+// radius plus an exit margin, and a crossing only counts once positions have
+// agreed on it for a dwell time.
+package geofence
+
+import (
+	"time"
+
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+const (
+	DefaultRadiusM     = 150.0
+	DefaultExitMarginM = 100.0
+	DefaultDwell       = time.Minute
+
+	// minPings is how many positions in a row must agree on a crossing
+	// before it is confirmed, however long they span.
+	minPings = 2
+)
+
+type Transition string
+
+const (
+	Arrival   Transition = "arrival"
+	Departure Transition = "departure"
+)
+
+// Fence is a circle around a place along with which side of it the vehicle
+// was last confirmed on.
+type Fence struct {
+	Center  eta.Point
+	RadiusM float64
+	Inside  bool
+	// Since is when the vehicle was confirmed on its side. Positions
+	// recorded before it are ignored.
+	Since time.Time
+}
+
+// Ping is a reported position. AccuracyM is 0 when the device didn't report
+// one.
+type Ping struct {
+	ID         int64
+	Point      eta.Point
+	AccuracyM  float64
+	RecordedAt time.Time
+}
+
+// Crossing is a confirmed arrival or departure. Ping is the first position
+// on the new side of the fence, so the crossing happened when it was
+// recorded.
+type Crossing struct {
+	Transition Transition
+	Ping       Ping
+}
+
+type Detector struct {
+	exitMarginM float64
+	dwell       time.Duration
+}
+
+// NewDetector returns a detector that takes vehicles to leave a fence
+// exitMarginM beyond its radius and confirms crossings positions agreed on
+// for dwell. Zero values select the defaults.
+func NewDetector(exitMarginM float64, dwell time.Duration) *Detector {
+	if exitMarginM <= 0 {
+		exitMarginM = DefaultExitMarginM
+	}
+	if dwell <= 0 {
+		dwell = DefaultDwell
+	}
+	return &Detector{exitMarginM: exitMarginM, dwell: dwell}
+}
+
+// Dwell is how long positions must agree on a crossing before it is
+// confirmed.
+func (detector *Detector) Dwell() time.Duration {
+	return detector.dwell
+}
+
+// Detect returns the crossings of fence confirmed by pings, which must be
+// ordered oldest first. A vehicle may arrive and leave again within pings.
+// Positions less accurate than the fence's radius can't tell which side the
+// vehicle is on and are skipped.
+func (detector *Detector) Detect(fence Fence, pings []Ping) []Crossing {
+	var crossings []Crossing
+	var streak []Ping
+	for _, ping := range pings {
+		if ping.RecordedAt.Before(fence.Since) || ping.AccuracyM > fence.RadiusM {
+			continue
+		}
+
+		distanceM := eta.HaversineKm(fence.Center, ping.Point) * 1000
+		crossed := distanceM <= fence.RadiusM
+		if fence.Inside {
+			crossed = distanceM > fence.RadiusM+detector.exitMarginM
+		}
+		if !crossed {
+			streak = streak[:0]
+			continue
+		}
+
+		streak = append(streak, ping)
+		if len(streak) < minPings || ping.RecordedAt.Sub(streak[0].RecordedAt) < detector.dwell {
+			continue
+		}
+		crossing := Crossing{Transition: Arrival, Ping: streak[0]}
+		if fence.Inside {
+			crossing.Transition = Departure
+		}
+		crossings = append(crossings, crossing)
+		fence.Inside = !fence.Inside
+		fence.Since = streak[0].RecordedAt
+		streak = streak[:0]
+	}
+	return crossings
+}
@@ -0,0 +1,99 @@
+package geofence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	depot = eta.Point{Lat: 6.5244, Lng: 3.3792}
+	start = time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+)
+
+// metresNorth is a point metres north of the depot.
+func metresNorth(metres float64) eta.Point {
+	return eta.Point{Lat: depot.Lat + metres/111195, Lng: depot.Lng}
+}
+
+// track is a ping every 20 seconds at each of the distances from the depot.
+func track(distancesM ...float64) []Ping {
+	pings := make([]Ping, len(distancesM))
+	for i, distanceM := range distancesM {
+		pings[i] = Ping{
+			ID:         int64(i + 1),
+			Point:      metresNorth(distanceM),
+			RecordedAt: start.Add(time.Duration(i) * 20 * time.Second),
+		}
+	}
+	return pings
+}
+
+func TestDetectArrivalAndDeparture(t *testing.T) {
+	detector := NewDetector(0, 0)
+	fence := Fence{Center: depot, RadiusM: DefaultRadiusM}
+
+	crossings := detector.Detect(fence, track(900, 500, 100, 50, 20, 20, 20, 400, 600, 800, 900))
+	require.Len(t, crossings, 2)
+	require.Equal(t, Arrival, crossings[0].Transition)
+	require.Equal(t, int64(3), crossings[0].Ping.ID)
+	require.Equal(t, Departure, crossings[1].Transition)
+	require.Equal(t, int64(8), crossings[1].Ping.ID)
+}
+
+func TestDetectDwell(t *testing.T) {
+	detector := NewDetector(0, 0)
+	fence := Fence{Center: depot, RadiusM: DefaultRadiusM}
+
+	// driving past, the vehicle is inside for less than the dwell time
+	require.Empty(t, detector.Detect(fence, track(900, 100, 50, 100, 900, 900)))
+
+	// a single position is never enough, however long ago it was
+	pings := track(100)
+	pings[0].RecordedAt = start.Add(-time.Hour)
+	require.Empty(t, detector.Detect(fence, pings))
+}
+
+func TestDetectHysteresis(t *testing.T) {
+	detector := NewDetector(0, 0)
+	inside := Fence{Center: depot, RadiusM: DefaultRadiusM, Inside: true}
+
+	// jitter just beyond the radius is still within the exit margin
+	require.Empty(t, detector.Detect(inside, track(100, 180, 220, 240, 200, 230, 190, 240)))
+
+	// and a jump beyond it breaks the streak of positions outside
+	require.Empty(t, detector.Detect(inside, track(300, 300, 100, 300, 300, 50, 300)))
+	require.Len(t, detector.Detect(inside, track(300, 300, 300, 300)), 1)
+}
+
+func TestDetectSkips(t *testing.T) {
+	detector := NewDetector(0, 0)
+	fence := Fence{Center: depot, RadiusM: DefaultRadiusM}
+
+	// inaccurate positions can't place the vehicle inside
+	pings := track(20, 20, 20, 20)
+	for i := range pings {
+		pings[i].AccuracyM = 500
+	}
+	require.Empty(t, detector.Detect(fence, pings))
+
+	// nor can positions from before the vehicle was confirmed outside
+	fence.Since = start.Add(time.Minute)
+	crossings := detector.Detect(fence, track(20, 20, 20, 20, 20, 20, 20))
+	require.Len(t, crossings, 1)
+	require.Equal(t, int64(4), crossings[0].Ping.ID)
+}
+
+func TestNewDetector(t *testing.T) {
+	detector := NewDetector(0, 0)
+	require.Equal(t, DefaultExitMarginM, detector.exitMarginM)
+	require.Equal(t, DefaultDwell, detector.dwell)
+
+	detector = NewDetector(20, 10*time.Second)
+	fence := Fence{Center: depot, RadiusM: 50, Inside: true}
+	crossings := detector.Detect(fence, track(40, 80, 80))
+	require.Len(t, crossings, 1)
+	require.Equal(t, Departure, crossings[0].Transition)
+}
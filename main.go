@@ -1,17 +1,32 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"github.com/joekings2k/logistics-eta/api"
+	"github.com/joekings2k/logistics-eta/db/migration"
 	db "github.com/joekings2k/logistics-eta/db/sqlc"
 	"github.com/joekings2k/logistics-eta/util"
 )
 
+const usage = `usage:
+  logistics-eta                   start the server
+  logistics-eta migrate up        apply every pending migration
+  logistics-eta migrate down [n]  roll back the last n migrations (default 1)
+  logistics-eta migrate version   print the current migration version
+  logistics-eta train [file]      train the eta correction model on completed routes
+                                  and save it to file (default ETA_MODEL_FILE)`
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "migrate" && os.Args[1] != "train" {
+		log.Fatal(usage)
+	}
+
 	config, err := util.LoadConfig(".")
 	if err != nil {
 		log.Fatal("cannot load config files")
@@ -21,13 +36,82 @@ func main() {
 		log.Fatal("cannot connect to db:", err)
 	}
 	fmt.Println("Connected to db")
+
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "migrate":
+		if err := runMigrateCommand(conn, os.Args[2:]); err != nil {
+			log.Fatal("migration failed: ", err)
+		}
+		return
+	case len(os.Args) > 1 && os.Args[1] == "train":
+		if err := runTrainCommand(db.NewStore(conn), config, os.Args[2:]); err != nil {
+			log.Fatal("training failed: ", err)
+		}
+		return
+	}
+
+	if config.MigrateOnStart {
+		if err := runMigrateCommand(conn, []string{"up"}); err != nil {
+			log.Fatal("cannot migrate db: ", err)
+		}
+	}
+
 	store := db.NewStore(conn)
-	server, err  := api.NewServer(config, store)
+	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
+	if config.RoutingGraphFile == "" {
+		log.Println("no road network loaded, routes have no path to detect deviations from")
+	}
+	if err := server.LearnSpeedProfile(context.Background()); err != nil {
+		log.Println("cannot learn speed profile, estimating without traffic:", err)
+	}
+	if err := server.LearnPredictionIntervals(context.Background()); err != nil {
+		log.Println("cannot learn prediction intervals, estimating without them:", err)
+	}
 	err = server.Start(config.ServerAddress)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
 	}
-}
\ No newline at end of file
+}
+
+func runMigrateCommand(conn *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", usage)
+	}
+
+	migrator, err := migration.NewMigrator(context.Background(), conn)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("db migration version %d (dirty: %t)\n", version, dirty)
+	return nil
+}
@@ -0,0 +1,527 @@
+package optimize
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+var (
+	ErrCapacity = errors.New("vehicle capacity must be positive")
+	ErrWeight   = errors.New("delivery weight must not be negative")
+)
+
+// Delivery is a stop a vehicle drops WeightKg off at.
+type Delivery struct {
+	Stop
+	WeightKg float64
+}
+
+// Vehicle is a vehicle of the fleet, able to carry CapacityKg in one trip.
+type Vehicle struct {
+	CapacityKg float64
+}
+
+// FleetProblem describes a day of deliveries from one depot. Every vehicle
+// leaves the depot at Departure, makes at most one trip and drives back to
+// the depot afterwards. The matrix covers the depot as node 0 and the
+// deliveries as nodes 1 to len(Deliveries).
+type FleetProblem struct {
+	Matrix     Matrix
+	Deliveries []Delivery
+	Vehicles   []Vehicle
+	Departure  time.Time
+	// Seed decides the order local search tries moves in. The same problem
+	// and seed always give the same plan.
+	Seed int64
+}
+
+// Trip is the tour of one vehicle, with Order indexing into
+// FleetProblem.Deliveries.
+type Trip struct {
+	Vehicle int
+	LoadKg  float64
+	Solution
+}
+
+// Plan splits the deliveries over the fleet. Trips are ordered by vehicle
+// and leave out vehicles that aren't needed. Deliveries no vehicle has room
+// for are left Unassigned.
+type Plan struct {
+	Trips      []Trip
+	Unassigned []int
+	DistanceKm float64
+	LateMin    float64
+	LateStops  int
+}
+
+// cost is what a trip, or a set of trips, is judged on: the same order of
+// preference as Solution.Better.
+type cost struct {
+	lateMin    float64
+	distanceKm float64
+}
+
+func (c cost) add(solution Solution) cost {
+	return cost{c.lateMin + solution.LateMin, c.distanceKm + solution.DistanceKm}
+}
+
+func (c cost) sub(solution Solution) cost {
+	return cost{c.lateMin - solution.LateMin, c.distanceKm - solution.DistanceKm}
+}
+
+func (c cost) less(other cost) bool {
+	if math.Abs(c.lateMin-other.lateMin) > epsilon {
+		return c.lateMin < other.lateMin
+	}
+	return c.distanceKm < other.distanceKm-epsilon
+}
+
+type fleet struct {
+	*solver
+	deliveries []Delivery
+	vehicles   []Vehicle
+	random     *rand.Rand
+
+	// tours[v] is the trip of vehicle v, empty when it stays at the depot
+	tours [][]int
+	loads []float64
+	trips []Solution
+}
+
+// SolveFleet assigns the deliveries to vehicles and orders each trip. It
+// merges single-delivery trips with the Clarke-Wright savings algorithm and
+// hands the merged trips to the vehicles that can carry them, falling back
+// to packing the heaviest deliveries first when that leaves fewer behind.
+// It then moves and swaps deliveries between trips, improving each trip
+// with 2-opt and Or-opt, until no move helps. Within capacity, it prefers
+// being less late over driving less.
+func SolveFleet(problem FleetProblem) (Plan, error) {
+	n := len(problem.Deliveries)
+	if problem.Matrix.size() != n+1 || !problem.Matrix.valid() {
+		return Plan{}, fmt.Errorf("%w: want %d nodes", ErrMatrixSize, n+1)
+	}
+	for i, vehicle := range problem.Vehicles {
+		if vehicle.CapacityKg <= 0 {
+			return Plan{}, fmt.Errorf("%w: vehicle %d", ErrCapacity, i)
+		}
+	}
+
+	stops := make([]Stop, n)
+	for i, delivery := range problem.Deliveries {
+		if delivery.WeightKg < 0 {
+			return Plan{}, fmt.Errorf("%w: delivery %d", ErrWeight, i)
+		}
+		stops[i] = delivery.Stop
+	}
+	// every trip ends back at the depot, which the solver needs as its own
+	// end node
+	s, err := newSolver(Problem{
+		Matrix:    withReturn(problem.Matrix),
+		Stops:     stops,
+		FixedEnd:  true,
+		Departure: problem.Departure,
+	})
+	if err != nil {
+		return Plan{}, err
+	}
+
+	f := &fleet{
+		solver:     s,
+		deliveries: problem.Deliveries,
+		vehicles:   problem.Vehicles,
+		random:     rand.New(rand.NewSource(problem.Seed)),
+	}
+	f.reset()
+	unassigned := f.insert(f.assign(f.savings()))
+	if len(unassigned) > 0 {
+		// savings joins trips by distance alone and can strand deliveries a
+		// tighter packing has room for
+		tours := f.tours
+		f.reset()
+		if packed := f.insert(f.pack()); len(packed) < len(unassigned) {
+			unassigned = packed
+		} else {
+			f.reset()
+			for v, tour := range tours {
+				f.setTour(v, tour)
+			}
+		}
+	}
+
+	for pass := 0; pass < maxPasses; pass++ {
+		improved := f.relocate()
+		if f.swap() {
+			improved = true
+		}
+		if f.improveTours() {
+			improved = true
+		}
+		if !improved {
+			break
+		}
+	}
+	return f.plan(unassigned), nil
+}
+
+// withReturn copies matrix with the depot, node 0, repeated as a last node.
+func withReturn(matrix Matrix) Matrix {
+	n := matrix.size()
+	extended := Matrix{
+		DistanceKm:  make([][]float64, n+1),
+		DurationMin: make([][]float64, n+1),
+	}
+	for i := 0; i <= n; i++ {
+		from := i
+		if i == n {
+			from = 0
+		}
+		extended.DistanceKm[i] = append(append([]float64(nil), matrix.DistanceKm[from]...), matrix.DistanceKm[from][0])
+		extended.DurationMin[i] = append(append([]float64(nil), matrix.DurationMin[from]...), matrix.DurationMin[from][0])
+	}
+	return extended
+}
+
+// savings starts with a trip per delivery and joins the end of one trip to
+// the start of another, largest saving first, while the joined trip fits the
+// biggest vehicle and is no later than the two apart.
+func (f *fleet) savings() [][]int {
+	n := len(f.deliveries)
+	maxCapacity := 0.0
+	for _, vehicle := range f.vehicles {
+		maxCapacity = math.Max(maxCapacity, vehicle.CapacityKg)
+	}
+
+	tours := make([][]int, n)
+	loads := make([]float64, n)
+	late := make([]float64, n)
+	tourOf := make([]int, n)
+	for i := range tours {
+		tours[i] = []int{i}
+		loads[i] = f.deliveries[i].WeightKg
+		late[i] = f.evaluate(tours[i]).LateMin
+		tourOf[i] = i
+	}
+
+	type saving struct {
+		from, to int
+		km       float64
+	}
+	distances := f.problem.Matrix.DistanceKm
+	var savings []saving
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			km := distances[i+1][0] + distances[0][j+1] - distances[i+1][j+1]
+			if km > epsilon {
+				savings = append(savings, saving{from: i, to: j, km: km})
+			}
+		}
+	}
+	sort.SliceStable(savings, func(a, b int) bool {
+		return savings[a].km > savings[b].km
+	})
+
+	for _, saving := range savings {
+		a, b := tourOf[saving.from], tourOf[saving.to]
+		if a == b || tours[a][len(tours[a])-1] != saving.from || tours[b][0] != saving.to {
+			continue
+		}
+		if loads[a]+loads[b] > maxCapacity+epsilon {
+			continue
+		}
+		joined := append(append([]int(nil), tours[a]...), tours[b]...)
+		joinedLate := f.evaluate(joined).LateMin
+		if joinedLate > late[a]+late[b]+epsilon {
+			continue
+		}
+		tours[a], loads[a], late[a] = joined, loads[a]+loads[b], joinedLate
+		for _, delivery := range tours[b] {
+			tourOf[delivery] = a
+		}
+		tours[b] = nil
+	}
+
+	merged := make([][]int, 0, n)
+	for _, tour := range tours {
+		if len(tour) > 0 {
+			merged = append(merged, tour)
+		}
+	}
+	return merged
+}
+
+// assign hands the heaviest trips to the biggest vehicles and returns the
+// deliveries of the trips left without a vehicle.
+func (f *fleet) assign(tours [][]int) []int {
+	loads := make([]float64, len(tours))
+	for t, tour := range tours {
+		for _, delivery := range tour {
+			loads[t] += f.deliveries[delivery].WeightKg
+		}
+	}
+	byLoad := make([]int, len(tours))
+	for t := range byLoad {
+		byLoad[t] = t
+	}
+	sort.SliceStable(byLoad, func(a, b int) bool {
+		return loads[byLoad[a]] > loads[byLoad[b]]
+	})
+	byCapacity := f.byCapacity()
+
+	var unassigned []int
+	used := make([]bool, len(f.vehicles))
+	for _, t := range byLoad {
+		assigned := false
+		for _, v := range byCapacity {
+			if !used[v] && loads[t] <= f.vehicles[v].CapacityKg+epsilon {
+				used[v] = true
+				f.setTour(v, tours[t])
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			unassigned = append(unassigned, tours[t]...)
+		}
+	}
+	sort.Ints(unassigned)
+	return unassigned
+}
+
+// pack loads the heaviest deliveries first, each onto the biggest vehicle
+// that still has room for it, and returns those that fit nowhere. Trips are
+// ordered by the local search afterwards.
+func (f *fleet) pack() []int {
+	byWeight := make([]int, len(f.deliveries))
+	for i := range byWeight {
+		byWeight[i] = i
+	}
+	sort.SliceStable(byWeight, func(a, b int) bool {
+		return f.deliveries[byWeight[a]].WeightKg > f.deliveries[byWeight[b]].WeightKg
+	})
+	byCapacity := f.byCapacity()
+
+	var left []int
+	for _, delivery := range byWeight {
+		weight := f.deliveries[delivery].WeightKg
+		packed := false
+		for _, v := range byCapacity {
+			if f.loads[v]+weight <= f.vehicles[v].CapacityKg+epsilon {
+				f.setTour(v, append(f.tours[v], delivery))
+				packed = true
+				break
+			}
+		}
+		if !packed {
+			left = append(left, delivery)
+		}
+	}
+	sort.Ints(left)
+	return left
+}
+
+// insert puts each delivery where it adds the least to the plan, on any
+// vehicle with room left, and returns those that didn't fit anywhere.
+func (f *fleet) insert(deliveries []int) []int {
+	var left []int
+	for _, delivery := range deliveries {
+		bestVehicle, bestAt := -1, 0
+		var bestTrip Solution
+		var bestDelta cost
+		for v := range f.vehicles {
+			if f.loads[v]+f.deliveries[delivery].WeightKg > f.vehicles[v].CapacityKg+epsilon {
+				continue
+			}
+			for at := 0; at <= len(f.tours[v]); at++ {
+				trip := f.evaluate(insertAt(f.tours[v], at, delivery))
+				delta := cost{}.add(trip).sub(f.trips[v])
+				if bestVehicle < 0 || delta.less(bestDelta) {
+					bestVehicle, bestAt, bestTrip, bestDelta = v, at, trip, delta
+				}
+			}
+		}
+		if bestVehicle < 0 {
+			left = append(left, delivery)
+			continue
+		}
+		f.tours[bestVehicle] = insertAt(f.tours[bestVehicle], bestAt, delivery)
+		f.loads[bestVehicle] += f.deliveries[delivery].WeightKg
+		f.trips[bestVehicle] = bestTrip
+	}
+	return left
+}
+
+// relocate moves single deliveries, tried in a seeded random order, to the
+// best place on another vehicle with room for them.
+func (f *fleet) relocate() bool {
+	improved := false
+	for _, delivery := range f.random.Perm(len(f.deliveries)) {
+		from, at := f.find(delivery)
+		if from < 0 {
+			continue
+		}
+		weight := f.deliveries[delivery].WeightKg
+		shorter := removeAt(f.tours[from], at)
+		shorterTrip := f.evaluate(shorter)
+
+		bestVehicle, bestAt := -1, 0
+		var bestDelta cost
+		var bestTrip Solution
+		for v := range f.vehicles {
+			if v == from || f.loads[v]+weight > f.vehicles[v].CapacityKg+epsilon {
+				continue
+			}
+			for i := 0; i <= len(f.tours[v]); i++ {
+				trip := f.evaluate(insertAt(f.tours[v], i, delivery))
+				delta := cost{}.add(shorterTrip).add(trip).sub(f.trips[from]).sub(f.trips[v])
+				if delta.less(cost{}) && (bestVehicle < 0 || delta.less(bestDelta)) {
+					bestVehicle, bestAt, bestDelta, bestTrip = v, i, delta, trip
+				}
+			}
+		}
+		if bestVehicle < 0 {
+			continue
+		}
+		f.tours[from], f.trips[from] = shorter, shorterTrip
+		f.loads[from] -= weight
+		f.tours[bestVehicle] = insertAt(f.tours[bestVehicle], bestAt, delivery)
+		f.trips[bestVehicle] = bestTrip
+		f.loads[bestVehicle] += weight
+		improved = true
+	}
+	return improved
+}
+
+// swap exchanges deliveries between two vehicles, each taking the other's
+// place in the trip, when both still fit and the plan gets better.
+func (f *fleet) swap() bool {
+	improved := false
+	for _, a := range f.random.Perm(len(f.deliveries)) {
+		va, ia := f.find(a)
+		if va < 0 {
+			continue
+		}
+		for b := range f.deliveries {
+			vb, ib := f.find(b)
+			if vb < 0 || vb == va {
+				continue
+			}
+			shift := f.deliveries[b].WeightKg - f.deliveries[a].WeightKg
+			if f.loads[va]+shift > f.vehicles[va].CapacityKg+epsilon || f.loads[vb]-shift > f.vehicles[vb].CapacityKg+epsilon {
+				continue
+			}
+			tourA := append([]int(nil), f.tours[va]...)
+			tourB := append([]int(nil), f.tours[vb]...)
+			tourA[ia], tourB[ib] = b, a
+			tripA, tripB := f.evaluate(tourA), f.evaluate(tourB)
+			delta := cost{}.add(tripA).add(tripB).sub(f.trips[va]).sub(f.trips[vb])
+			if !delta.less(cost{}) {
+				continue
+			}
+			f.tours[va], f.trips[va] = tourA, tripA
+			f.tours[vb], f.trips[vb] = tourB, tripB
+			f.loads[va] += shift
+			f.loads[vb] -= shift
+			improved = true
+			// a now rides with vb, so look for swaps of its new neighbours
+			va, ia = vb, ib
+		}
+	}
+	return improved
+}
+
+// improveTours reorders the stops of every trip with 2-opt and Or-opt.
+func (f *fleet) improveTours() bool {
+	improved := false
+	for v, tour := range f.tours {
+		if len(tour) < 2 {
+			continue
+		}
+		trip := f.improve(append([]int(nil), tour...))
+		if trip.Better(f.trips[v]) {
+			f.tours[v] = trip.Order
+			f.trips[v] = trip
+			improved = true
+		}
+	}
+	return improved
+}
+
+// reset sends every vehicle back to the depot empty.
+func (f *fleet) reset() {
+	f.tours = make([][]int, len(f.vehicles))
+	f.loads = make([]float64, len(f.vehicles))
+	f.trips = make([]Solution, len(f.vehicles))
+	for v := range f.trips {
+		f.trips[v] = f.evaluate(nil)
+	}
+}
+
+// byCapacity returns the vehicles, biggest first.
+func (f *fleet) byCapacity() []int {
+	vehicles := make([]int, len(f.vehicles))
+	for v := range vehicles {
+		vehicles[v] = v
+	}
+	sort.SliceStable(vehicles, func(a, b int) bool {
+		return f.vehicles[vehicles[a]].CapacityKg > f.vehicles[vehicles[b]].CapacityKg
+	})
+	return vehicles
+}
+
+func (f *fleet) setTour(v int, tour []int) {
+	f.tours[v] = tour
+	f.trips[v] = f.evaluate(tour)
+	f.loads[v] = 0
+	for _, delivery := range tour {
+		f.loads[v] += f.deliveries[delivery].WeightKg
+	}
+}
+
+// find returns the vehicle carrying delivery and its place in the trip, or
+// -1 when it is unassigned.
+func (f *fleet) find(delivery int) (int, int) {
+	for v, tour := range f.tours {
+		for i, stop := range tour {
+			if stop == delivery {
+				return v, i
+			}
+		}
+	}
+	return -1, 0
+}
+
+func (f *fleet) plan(unassigned []int) Plan {
+	plan := Plan{Unassigned: unassigned}
+	for v, tour := range f.tours {
+		if len(tour) == 0 {
+			continue
+		}
+		trip := f.trips[v]
+		trip.Order = tour
+		plan.Trips = append(plan.Trips, Trip{Vehicle: v, LoadKg: f.loads[v], Solution: trip})
+		plan.DistanceKm += trip.DistanceKm
+		plan.LateMin += trip.LateMin
+		plan.LateStops += trip.LateStops
+	}
+	return plan
+}
+
+func insertAt(tour []int, at, delivery int) []int {
+	inserted := make([]int, 0, len(tour)+1)
+	inserted = append(inserted, tour[:at]...)
+	inserted = append(inserted, delivery)
+	return append(inserted, tour[at:]...)
+}
+
+func removeAt(tour []int, at int) []int {
+	removed := make([]int, 0, len(tour)-1)
+	removed = append(removed, tour[:at]...)
+	return append(removed, tour[at+1:]...)
+}
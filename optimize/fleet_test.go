@@ -0,0 +1,228 @@
+package optimize
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/stretchr/testify/require"
+)
+
+// randomFleet scatters n deliveries of weightKg around a depot, to be
+// carried by vehicles of the given capacities at 40 km/h.
+func randomFleet(seed int64, n int, weightKg float64, capacities ...float64) FleetProblem {
+	random := rand.New(rand.NewSource(seed))
+	points := []eta.Point{{Lat: 6.5244, Lng: 3.3792}}
+	deliveries := make([]Delivery, 0, n)
+	for i := 0; i < n; i++ {
+		points = append(points, eta.Point{
+			Lat: 6.5244 + (random.Float64()-0.5)*0.3,
+			Lng: 3.3792 + (random.Float64()-0.5)*0.3,
+		})
+		deliveries = append(deliveries, Delivery{Stop: Stop{ServiceMin: 5}, WeightKg: weightKg})
+	}
+	vehicles := make([]Vehicle, 0, len(capacities))
+	for _, capacity := range capacities {
+		vehicles = append(vehicles, Vehicle{CapacityKg: capacity})
+	}
+	return FleetProblem{
+		Matrix:     BuildMatrix(points, Haversine(40)),
+		Deliveries: deliveries,
+		Vehicles:   vehicles,
+		Departure:  departure,
+		Seed:       1,
+	}
+}
+
+// requireValidPlan checks every delivery is carried once or left
+// unassigned, trips fit their vehicles and the totals add up.
+func requireValidPlan(t *testing.T, problem FleetProblem, plan Plan) {
+	seen := make(map[int]bool)
+	var distanceKm float64
+	for i, trip := range plan.Trips {
+		if i > 0 {
+			require.Greater(t, trip.Vehicle, plan.Trips[i-1].Vehicle)
+		}
+		require.NotEmpty(t, trip.Order)
+		var loadKg float64
+		for _, delivery := range trip.Order {
+			require.False(t, seen[delivery], "delivery %d planned twice", delivery)
+			seen[delivery] = true
+			loadKg += problem.Deliveries[delivery].WeightKg
+		}
+		require.InDelta(t, loadKg, trip.LoadKg, 1e-6)
+		require.LessOrEqual(t, trip.LoadKg, problem.Vehicles[trip.Vehicle].CapacityKg+1e-6)
+		distanceKm += trip.DistanceKm
+	}
+	for _, delivery := range plan.Unassigned {
+		require.False(t, seen[delivery], "delivery %d planned and unassigned", delivery)
+		seen[delivery] = true
+	}
+	require.Len(t, seen, len(problem.Deliveries))
+	require.InDelta(t, distanceKm, plan.DistanceKm, 1e-6)
+}
+
+func TestSolveFleetCapacity(t *testing.T) {
+	problem := randomFleet(3, 30, 10, 120, 120, 120)
+	plan, err := SolveFleet(problem)
+	require.NoError(t, err)
+	requireValidPlan(t, problem, plan)
+	require.Empty(t, plan.Unassigned)
+	require.Len(t, plan.Trips, 3)
+
+	// sharing trips has to beat driving out and back to every delivery
+	var separateKm float64
+	for i := range problem.Deliveries {
+		separateKm += problem.Matrix.DistanceKm[0][i+1] + problem.Matrix.DistanceKm[i+1][0]
+	}
+	require.Less(t, plan.DistanceKm, separateKm/2)
+}
+
+func TestSolveFleetUnassigned(t *testing.T) {
+	problem := randomFleet(5, 4, 15, 20, 20)
+	// too heavy for either vehicle
+	problem.Deliveries[2].WeightKg = 25
+	plan, err := SolveFleet(problem)
+	require.NoError(t, err)
+	requireValidPlan(t, problem, plan)
+	require.Len(t, plan.Trips, 2)
+	require.Len(t, plan.Unassigned, 2)
+	require.Contains(t, plan.Unassigned, 2)
+
+	problem.Vehicles = nil
+	plan, err = SolveFleet(problem)
+	require.NoError(t, err)
+	require.Empty(t, plan.Trips)
+	require.Equal(t, []int{0, 1, 2, 3}, plan.Unassigned)
+}
+
+func TestSolveFleetTightPacking(t *testing.T) {
+	// only 30+30 and 20+20 fill both vehicles, whatever trips are shortest
+	for seed := int64(0); seed < 20; seed++ {
+		problem := randomFleet(seed, 4, 0, 60, 40)
+		for i, weight := range []float64{30, 20, 30, 20} {
+			problem.Deliveries[i].WeightKg = weight
+		}
+		plan, err := SolveFleet(problem)
+		require.NoError(t, err)
+		requireValidPlan(t, problem, plan)
+		require.Empty(t, plan.Unassigned, "seed %d", seed)
+	}
+}
+
+func TestSolveFleetTimeWindows(t *testing.T) {
+	// on a line east of the depot, with the furthest delivery closing before
+	// it can be reached after serving the others
+	points := []eta.Point{{}}
+	for _, km := range []float64{1, 2, 10} {
+		points = append(points, eta.Point{Lng: km / 111.195})
+	}
+	problem := FleetProblem{
+		Matrix: BuildMatrix(points, Haversine(60)),
+		Deliveries: []Delivery{
+			{Stop: Stop{ServiceMin: 5}, WeightKg: 1},
+			{Stop: Stop{ServiceMin: 5}, WeightKg: 1},
+			{Stop: Stop{WindowEnd: departure.Add(12 * time.Minute)}, WeightKg: 1},
+		},
+		Vehicles:  []Vehicle{{CapacityKg: 10}},
+		Departure: departure,
+	}
+	plan, err := SolveFleet(problem)
+	require.NoError(t, err)
+	requireValidPlan(t, problem, plan)
+	require.Zero(t, plan.LateStops)
+	require.Equal(t, 2, plan.Trips[0].Order[0])
+}
+
+func TestSolveFleetDeterministic(t *testing.T) {
+	problem := randomFleet(11, 40, 7, 100, 80, 80, 60)
+	first, err := SolveFleet(problem)
+	require.NoError(t, err)
+	requireValidPlan(t, problem, first)
+	for i := 0; i < 3; i++ {
+		again, err := SolveFleet(problem)
+		require.NoError(t, err)
+		require.Equal(t, first, again)
+	}
+
+	// another seed may find another plan, but never an invalid one
+	problem.Seed = 2
+	other, err := SolveFleet(problem)
+	require.NoError(t, err)
+	requireValidPlan(t, problem, other)
+	require.InDelta(t, first.DistanceKm, other.DistanceKm, first.DistanceKm*0.15)
+}
+
+func TestSolveFleetInvalid(t *testing.T) {
+	problem := randomFleet(1, 3, 1, 10)
+	problem.Matrix.DistanceKm = problem.Matrix.DistanceKm[:3]
+	_, err := SolveFleet(problem)
+	require.ErrorIs(t, err, ErrMatrixSize)
+
+	problem = randomFleet(1, 3, 1, 10, 0)
+	_, err = SolveFleet(problem)
+	require.ErrorIs(t, err, ErrCapacity)
+
+	problem = randomFleet(1, 3, 1, 10)
+	problem.Deliveries[1].WeightKg = -1
+	_, err = SolveFleet(problem)
+	require.ErrorIs(t, err, ErrWeight)
+
+	problem = randomFleet(1, 3, 1, 10)
+	problem.Deliveries[0].WindowStart = departure.Add(time.Hour)
+	problem.Deliveries[0].WindowEnd = departure
+	_, err = SolveFleet(problem)
+	require.ErrorIs(t, err, ErrWindow)
+}
+
+func TestWithReturn(t *testing.T) {
+	matrix := Matrix{
+		DistanceKm:  [][]float64{{0, 1, 2}, {3, 0, 4}, {5, 6, 0}},
+		DurationMin: [][]float64{{0, 10, 20}, {30, 0, 40}, {50, 60, 0}},
+	}
+	extended := withReturn(matrix)
+	require.Equal(t, [][]float64{{0, 1, 2, 0}, {3, 0, 4, 3}, {5, 6, 0, 5}, {0, 1, 2, 0}}, extended.DistanceKm)
+	require.Equal(t, []float64{50, 60, 0, 50}, extended.DurationMin[2])
+}
+
+func BenchmarkSolve(b *testing.B) {
+	random := rand.New(rand.NewSource(1))
+	points := make([]eta.Point, 41)
+	for i := range points {
+		points[i] = eta.Point{Lat: 6.4 + random.Float64()*0.3, Lng: 3.2 + random.Float64()*0.3}
+	}
+	problem := Problem{Matrix: BuildMatrix(points, Haversine(40)), Stops: make([]Stop, 40), Departure: departure}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Solve(problem); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSolveFleet(b *testing.B) {
+	for _, size := range []struct {
+		name       string
+		deliveries int
+		vehicles   int
+	}{
+		{"20x2", 20, 2},
+		{"100x8", 100, 8},
+	} {
+		capacities := make([]float64, size.vehicles)
+		for i := range capacities {
+			capacities[i] = float64(size.deliveries) * 10 / float64(size.vehicles) * 1.2
+		}
+		problem := randomFleet(1, size.deliveries, 10, capacities...)
+
+		b.Run(size.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := SolveFleet(problem); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package optimize
+
+import "github.com/joekings2k/logistics-eta/eta"
+
+// Matrix holds the travel distance and time between every pair of nodes of a
+// Problem, indexed [from][to]. It doesn't need to be symmetric.
+type Matrix struct {
+	DistanceKm  [][]float64
+	DurationMin [][]float64
+}
+
+// LegFunc returns the travel distance and time from one point to another.
+type LegFunc func(from, to eta.Point) (distanceKm, durationMin float64)
+
+// Haversine returns a LegFunc that travels in straight lines at speedKmh.
+func Haversine(speedKmh float64) LegFunc {
+	return func(from, to eta.Point) (float64, float64) {
+		distanceKm := eta.HaversineKm(from, to)
+		return distanceKm, distanceKm / speedKmh * 60
+	}
+}
+
+// BuildMatrix measures every leg between points with leg.
+func BuildMatrix(points []eta.Point, leg LegFunc) Matrix {
+	matrix := Matrix{
+		DistanceKm:  make([][]float64, len(points)),
+		DurationMin: make([][]float64, len(points)),
+	}
+	for i, from := range points {
+		matrix.DistanceKm[i] = make([]float64, len(points))
+		matrix.DurationMin[i] = make([]float64, len(points))
+		for j, to := range points {
+			if i == j {
+				continue
+			}
+			matrix.DistanceKm[i][j], matrix.DurationMin[i][j] = leg(from, to)
+		}
+	}
+	return matrix
+}
+
+func (matrix Matrix) size() int {
+	return len(matrix.DistanceKm)
+}
+
+func (matrix Matrix) valid() bool {
+	n := matrix.size()
+	if len(matrix.DurationMin) != n {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if len(matrix.DistanceKm[i]) != n || len(matrix.DurationMin[i]) != n {
+			return false
+		}
+	}
+	return true
+}
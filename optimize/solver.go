@@ -0,0 +1,314 @@
+// Package optimize orders the stops of a multi-drop route. Solve builds
+// tours with nearest-neighbour construction from several first stops and
+// improves each with 2-opt and Or-opt moves until no move helps, which lands
+// close to the optimum for the tens of stops a driver makes on a run.
+package optimize
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// maxPasses bounds the improvement passes over the whole tour.
+	maxPasses = 50
+	// maxSegment is the longest run of stops an Or-opt move relocates.
+	maxSegment = 3
+	// maxStarts bounds how many first stops, nearest to the start first,
+	// construction is tried from.
+	maxStarts = 8
+	// epsilon absorbs floating point noise when comparing tours.
+	epsilon = 1e-9
+)
+
+var (
+	ErrMatrixSize = errors.New("matrix must cover the start, every stop and the fixed end")
+	ErrWindow     = errors.New("stop window must end after it starts")
+	ErrOrder      = errors.New("order must visit every stop exactly once")
+)
+
+// Stop is a stop to visit, with the time spent there and the window it has
+// to be served in. A zero WindowStart or WindowEnd leaves that side open.
+type Stop struct {
+	ServiceMin  float64
+	WindowStart time.Time
+	WindowEnd   time.Time
+}
+
+// Problem describes a tour that leaves the start at Departure and visits
+// every stop once. The matrix covers the start as node 0 and the stops as
+// nodes 1 to len(Stops), followed by the end point when FixedEnd is set.
+// Without a fixed end the tour ends at its last stop.
+type Problem struct {
+	Matrix    Matrix
+	Stops     []Stop
+	FixedEnd  bool
+	Departure time.Time
+}
+
+// Solution is a visiting order, as indexes into Problem.Stops, and the tour
+// it makes. Stops served after their window closes add to LateMin, and a
+// solver always prefers being less late over driving less.
+type Solution struct {
+	Order       []int
+	DistanceKm  float64
+	DurationMin float64
+	LateMin     float64
+	LateStops   int
+}
+
+// Feasible reports whether every stop is served within its window.
+func (solution Solution) Feasible() bool {
+	return solution.LateStops == 0
+}
+
+// Better reports whether solution is less late than other, or as late and
+// shorter.
+func (solution Solution) Better(other Solution) bool {
+	if math.Abs(solution.LateMin-other.LateMin) > epsilon {
+		return solution.LateMin < other.LateMin
+	}
+	return solution.DistanceKm < other.DistanceKm-epsilon
+}
+
+type solver struct {
+	problem Problem
+	end     int
+	// window bounds in minutes after departure
+	opens  []float64
+	closes []float64
+}
+
+// Solve returns a near-optimal visiting order for problem. It is
+// deterministic: the same problem always gives the same order.
+func Solve(problem Problem) (Solution, error) {
+	s, err := newSolver(problem)
+	if err != nil {
+		return Solution{}, err
+	}
+
+	best := s.improve(s.nearestNeighbour(-1))
+	for _, first := range s.firstStops() {
+		if candidate := s.improve(s.nearestNeighbour(first)); candidate.Better(best) {
+			best = candidate
+		}
+	}
+	return best, nil
+}
+
+// Evaluate returns the tour problem makes when its stops are visited in
+// order, which must list every stop once.
+func Evaluate(problem Problem, order []int) (Solution, error) {
+	s, err := newSolver(problem)
+	if err != nil {
+		return Solution{}, err
+	}
+	if !isPermutation(order, len(problem.Stops)) {
+		return Solution{}, ErrOrder
+	}
+	solution := s.evaluate(order)
+	solution.Order = append([]int(nil), order...)
+	return solution, nil
+}
+
+func newSolver(problem Problem) (*solver, error) {
+	nodes := len(problem.Stops) + 1
+	if problem.FixedEnd {
+		nodes++
+	}
+	if problem.Matrix.size() != nodes || !problem.Matrix.valid() {
+		return nil, fmt.Errorf("%w: want %d nodes", ErrMatrixSize, nodes)
+	}
+
+	s := &solver{
+		problem: problem,
+		end:     nodes - 1,
+		opens:   make([]float64, len(problem.Stops)),
+		closes:  make([]float64, len(problem.Stops)),
+	}
+	for i, stop := range problem.Stops {
+		s.opens[i] = math.Inf(-1)
+		s.closes[i] = math.Inf(1)
+		if !stop.WindowStart.IsZero() {
+			s.opens[i] = stop.WindowStart.Sub(problem.Departure).Minutes()
+		}
+		if !stop.WindowEnd.IsZero() {
+			s.closes[i] = stop.WindowEnd.Sub(problem.Departure).Minutes()
+		}
+		if s.closes[i] <= s.opens[i] {
+			return nil, fmt.Errorf("%w: stop %d", ErrWindow, i)
+		}
+	}
+	return s, nil
+}
+
+// improve applies 2-opt and Or-opt moves to order until neither helps.
+func (s *solver) improve(order []int) Solution {
+	best := s.evaluate(order)
+	for pass := 0; pass < maxPasses; pass++ {
+		improved := false
+		if s.twoOpt(order, &best) {
+			improved = true
+		}
+		if s.orOpt(order, &best) {
+			improved = true
+		}
+		if !improved {
+			break
+		}
+	}
+	best.Order = order
+	return best
+}
+
+// firstStops returns the maxStarts stops closest to the start, nearest
+// first, ties broken by index.
+func (s *solver) firstStops() []int {
+	stops := make([]int, len(s.problem.Stops))
+	for i := range stops {
+		stops[i] = i
+	}
+	distances := s.problem.Matrix.DistanceKm[0]
+	sort.SliceStable(stops, func(i, j int) bool {
+		return distances[stops[i]+1] < distances[stops[j]+1]
+	})
+	if len(stops) > maxStarts {
+		stops = stops[:maxStarts]
+	}
+	return stops
+}
+
+// nearestNeighbour drives from the start to first, or to the closest stop
+// when first is negative, and then always on to the closest stop not visited
+// yet.
+func (s *solver) nearestNeighbour(first int) []int {
+	n := len(s.problem.Stops)
+	order := make([]int, 0, n)
+	visited := make([]bool, n)
+	from := 0
+	if first >= 0 {
+		visited[first] = true
+		order = append(order, first)
+		from = first + 1
+	}
+	for len(order) < n {
+		next := -1
+		for stop := 0; stop < n; stop++ {
+			if visited[stop] {
+				continue
+			}
+			if next < 0 || s.problem.Matrix.DistanceKm[from][stop+1] < s.problem.Matrix.DistanceKm[from][next+1] {
+				next = stop
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		from = next + 1
+	}
+	return order
+}
+
+// twoOpt reverses every stretch of the tour whose reversal makes it better.
+func (s *solver) twoOpt(order []int, best *Solution) bool {
+	improved := false
+	for i := 0; i < len(order)-1; i++ {
+		for j := i + 1; j < len(order); j++ {
+			reverse(order[i : j+1])
+			if candidate := s.evaluate(order); candidate.Better(*best) {
+				*best = candidate
+				improved = true
+				continue
+			}
+			reverse(order[i : j+1])
+		}
+	}
+	return improved
+}
+
+// orOpt moves runs of up to maxSegment consecutive stops, in either
+// direction, to wherever in the tour makes it better.
+func (s *solver) orOpt(order []int, best *Solution) bool {
+	improved := false
+	candidate := make([]int, len(order))
+	segment := make([]int, 0, maxSegment)
+	rest := make([]int, 0, len(order))
+	for length := 1; length <= maxSegment && length < len(order); length++ {
+		for i := 0; i+length <= len(order); i++ {
+			segment = append(segment[:0], order[i:i+length]...)
+			rest = append(append(rest[:0], order[:i]...), order[i+length:]...)
+			for at := 0; at <= len(rest); at++ {
+				for _, reversed := range []bool{false, true} {
+					if (at == i && !reversed) || (length == 1 && reversed) {
+						continue
+					}
+					n := copy(candidate, rest[:at])
+					n += copy(candidate[n:], segment)
+					copy(candidate[n:], rest[at:])
+					if reversed {
+						reverse(candidate[at : at+length])
+					}
+					if evaluated := s.evaluate(candidate); evaluated.Better(*best) {
+						*best = evaluated
+						copy(order, candidate)
+						improved = true
+						segment = append(segment[:0], order[i:i+length]...)
+						rest = append(append(rest[:0], order[:i]...), order[i+length:]...)
+					}
+				}
+			}
+		}
+	}
+	return improved
+}
+
+// evaluate drives the tour, waiting at stops reached before their window
+// opens.
+func (s *solver) evaluate(order []int) Solution {
+	matrix := s.problem.Matrix
+	var solution Solution
+	at := 0.0
+	from := 0
+	for _, stop := range order {
+		node := stop + 1
+		solution.DistanceKm += matrix.DistanceKm[from][node]
+		at += matrix.DurationMin[from][node]
+		if at < s.opens[stop] {
+			at = s.opens[stop]
+		}
+		if late := at - s.closes[stop]; late > epsilon {
+			solution.LateMin += late
+			solution.LateStops++
+		}
+		at += s.problem.Stops[stop].ServiceMin
+		from = node
+	}
+	if s.problem.FixedEnd {
+		solution.DistanceKm += matrix.DistanceKm[from][s.end]
+		at += matrix.DurationMin[from][s.end]
+	}
+	solution.DurationMin = at
+	return solution
+}
+
+func reverse(order []int) {
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
+
+func isPermutation(order []int, n int) bool {
+	if len(order) != n {
+		return false
+	}
+	seen := make([]bool, n)
+	for _, stop := range order {
+		if stop < 0 || stop >= n || seen[stop] {
+			return false
+		}
+		seen[stop] = true
+	}
+	return true
+}
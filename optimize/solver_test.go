@@ -0,0 +1,230 @@
+package optimize
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/stretchr/testify/require"
+)
+
+var departure = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+// lineProblem places the stops the given kilometres east of the start, with
+// an optional fixed end, travelling at 60 km/h.
+func lineProblem(kms []float64, end *float64) Problem {
+	points := []eta.Point{{}}
+	stops := make([]Stop, 0, len(kms))
+	for _, km := range kms {
+		points = append(points, eta.Point{Lng: km / 111.195})
+		stops = append(stops, Stop{})
+	}
+	if end != nil {
+		points = append(points, eta.Point{Lng: *end / 111.195})
+	}
+	return Problem{
+		Matrix:    BuildMatrix(points, Haversine(60)),
+		Stops:     stops,
+		FixedEnd:  end != nil,
+		Departure: departure,
+	}
+}
+
+func TestSolveLine(t *testing.T) {
+	solution, err := Solve(lineProblem([]float64{7, 2, 9, 4, 1}, nil))
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 1, 3, 0, 2}, solution.Order)
+	require.InDelta(t, 9, solution.DistanceKm, 0.01)
+	require.InDelta(t, 9, solution.DurationMin, 0.01)
+	require.True(t, solution.Feasible())
+}
+
+func TestSolveFixedEnd(t *testing.T) {
+	// back to the start: out to one side and back, then the other
+	end := 0.0
+	solution, err := Solve(lineProblem([]float64{-3, 5, -1, 2}, &end))
+	require.NoError(t, err)
+	require.InDelta(t, 16, solution.DistanceKm, 0.01)
+
+	// ending far west means clearing the east first
+	end = -10
+	solution, err = Solve(lineProblem([]float64{-3, 5, -1, 2}, &end))
+	require.NoError(t, err)
+	require.InDelta(t, 20, solution.DistanceKm, 0.01)
+	require.Equal(t, 0, solution.Order[len(solution.Order)-1])
+}
+
+func TestSolveTimeWindows(t *testing.T) {
+	problem := lineProblem([]float64{1, 2, 10}, nil)
+	for i := range problem.Stops {
+		problem.Stops[i].ServiceMin = 5
+	}
+	// the far stop has to be served within the first quarter hour
+	problem.Stops[2].WindowEnd = departure.Add(15 * time.Minute)
+	// and the nearest one can't be served before the second hour
+	problem.Stops[0].WindowStart = departure.Add(time.Hour)
+
+	solution, err := Solve(problem)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1, 0}, solution.Order)
+	require.True(t, solution.Feasible())
+	// waiting for the last window to open counts towards the duration
+	require.InDelta(t, 65, solution.DurationMin, 0.01)
+}
+
+func TestSolveLateStops(t *testing.T) {
+	problem := lineProblem([]float64{30, 60}, nil)
+	problem.Stops[0].WindowEnd = departure.Add(10 * time.Minute)
+
+	solution, err := Solve(problem)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, solution.Order)
+	require.False(t, solution.Feasible())
+	require.Equal(t, 1, solution.LateStops)
+	require.InDelta(t, 20, solution.LateMin, 0.01)
+}
+
+func TestSolveSuppliedMatrix(t *testing.T) {
+	// one-way streets: going 1 -> 2 is cheap, 2 -> 1 is not
+	matrix := Matrix{
+		DistanceKm: [][]float64{
+			{0, 5, 1},
+			{5, 0, 1},
+			{1, 20, 0},
+		},
+	}
+	matrix.DurationMin = matrix.DistanceKm
+
+	solution, err := Solve(Problem{Matrix: matrix, Stops: make([]Stop, 2), Departure: departure})
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, solution.Order)
+	require.Equal(t, 6.0, solution.DistanceKm)
+}
+
+// The local search is a heuristic, so it may miss the optimum now and then,
+// but never by much.
+func TestSolveNearBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+	var totalGap float64
+	const rounds = 30
+	for round := 0; round < rounds; round++ {
+		points := make([]eta.Point, 8)
+		for i := range points {
+			points[i] = eta.Point{Lat: 6.4 + random.Float64()*0.3, Lng: 3.2 + random.Float64()*0.3}
+		}
+		problem := Problem{
+			Matrix:    BuildMatrix(points, Haversine(40)),
+			Stops:     make([]Stop, len(points)-1),
+			Departure: departure,
+		}
+
+		solution, err := Solve(problem)
+		require.NoError(t, err)
+		optimum := bruteForceKm(problem)
+		gap := solution.DistanceKm/optimum - 1
+		t.Logf("round %d: %.2f km against %.2f km", round, solution.DistanceKm, optimum)
+		require.GreaterOrEqual(t, gap, -1e-9)
+		require.Less(t, gap, 0.1, "round %d", round)
+		totalGap += gap
+	}
+	require.Less(t, totalGap/rounds, 0.02)
+}
+
+func TestSolveDeterministic(t *testing.T) {
+	random := rand.New(rand.NewSource(11))
+	points := make([]eta.Point, 41)
+	for i := range points {
+		points[i] = eta.Point{Lat: 6.4 + random.Float64()*0.3, Lng: 3.2 + random.Float64()*0.3}
+	}
+	problem := Problem{
+		Matrix:    BuildMatrix(points, Haversine(40)),
+		Stops:     make([]Stop, len(points)-1),
+		Departure: departure,
+	}
+
+	first, err := Solve(problem)
+	require.NoError(t, err)
+	second, err := Solve(problem)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	seen := make(map[int]bool)
+	for _, stop := range first.Order {
+		seen[stop] = true
+	}
+	require.Len(t, seen, len(problem.Stops))
+}
+
+func TestSolveNoStops(t *testing.T) {
+	end := 4.0
+	solution, err := Solve(lineProblem(nil, &end))
+	require.NoError(t, err)
+	require.Empty(t, solution.Order)
+	require.InDelta(t, 4, solution.DistanceKm, 0.01)
+}
+
+func TestSolveInvalid(t *testing.T) {
+	problem := lineProblem([]float64{1, 2}, nil)
+	problem.FixedEnd = true
+	_, err := Solve(problem)
+	require.ErrorIs(t, err, ErrMatrixSize)
+
+	problem = lineProblem([]float64{1, 2}, nil)
+	problem.Matrix.DurationMin = problem.Matrix.DurationMin[:2]
+	_, err = Solve(problem)
+	require.ErrorIs(t, err, ErrMatrixSize)
+
+	problem = lineProblem([]float64{1, 2}, nil)
+	problem.Stops[1].WindowStart = departure.Add(time.Hour)
+	problem.Stops[1].WindowEnd = departure
+	_, err = Solve(problem)
+	require.ErrorIs(t, err, ErrWindow)
+}
+
+func TestEvaluate(t *testing.T) {
+	problem := lineProblem([]float64{7, 2, 9, 4, 1}, nil)
+	given, err := Evaluate(problem, []int{0, 1, 2, 3, 4})
+	require.NoError(t, err)
+	require.InDelta(t, 7+5+7+5+3, given.DistanceKm, 0.01)
+
+	solution, err := Solve(problem)
+	require.NoError(t, err)
+	require.True(t, solution.Better(given))
+	require.False(t, given.Better(solution))
+
+	_, err = Evaluate(problem, []int{0, 1, 2, 3})
+	require.ErrorIs(t, err, ErrOrder)
+	_, err = Evaluate(problem, []int{0, 1, 2, 3, 3})
+	require.ErrorIs(t, err, ErrOrder)
+}
+
+// bruteForceKm tries every order of the stops of an open tour.
+func bruteForceKm(problem Problem) float64 {
+	order := make([]int, len(problem.Stops))
+	for i := range order {
+		order[i] = i
+	}
+	best := math.Inf(1)
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(order) {
+			var km float64
+			from := 0
+			for _, stop := range order {
+				km += problem.Matrix.DistanceKm[from][stop+1]
+				from = stop + 1
+			}
+			best = math.Min(best, km)
+			return
+		}
+		for i := k; i < len(order); i++ {
+			order[k], order[i] = order[i], order[k]
+			permute(k + 1)
+			order[k], order[i] = order[i], order[k]
+		}
+	}
+	permute(0)
+	return best
+}
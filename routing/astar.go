@@ -0,0 +1,173 @@
+package routing
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+// accessSpeedKmh is the speed assumed between a point and the road node it
+// snaps to, over driveways, yards and parking lots.
+const accessSpeedKmh = 15
+
+var (
+	ErrNotCovered = errors.New("point is not near any road of the network")
+	ErrNoPath     = errors.New("no road leads from the origin to the destination")
+)
+
+// Path is the fastest way by road between two points.
+type Path struct {
+	DistanceKm  float64 `json:"distance_km"`
+	DurationMin float64 `json:"duration_min"`
+	// Points are the origin, the road nodes driven through and the
+	// destination.
+	Points []eta.Point `json:"points"`
+}
+
+// Estimate rounds the path's distance and duration like eta estimates are.
+func (path Path) Estimate() eta.Estimate {
+	return eta.Estimate{
+		DistanceKm:  math.Round(path.DistanceKm*100) / 100,
+		DurationMin: math.Round(path.DurationMin*10) / 10,
+	}
+}
+
+// Route finds the fastest path from origin to destination for a vehicle of
+// class, over roads driven at their free-flow speed capped by MaxSpeedsKmh.
+// Both points snap to their nearest road node, which fails with
+// ErrNotCovered if it's further than MaxSnapKm.
+func (graph *Graph) Route(origin, destination eta.Point, class eta.VehicleClass) (Path, error) {
+	from, fromKm, ok := graph.nearest(origin)
+	if !ok {
+		return Path{}, ErrNotCovered
+	}
+	to, toKm, ok := graph.nearest(destination)
+	if !ok {
+		return Path{}, ErrNotCovered
+	}
+
+	capKmh := float64(maxEdgeSpeedKmh)
+	if speed, ok := MaxSpeedsKmh[class]; ok {
+		capKmh = speed
+	}
+	s := graph.searches.Get().(*search)
+	defer graph.searches.Put(s)
+	seconds, ok := s.run(graph, from, to, capKmh)
+	if !ok {
+		return Path{}, ErrNoPath
+	}
+
+	nodes := []uint32{to}
+	distanceKm := fromKm + toKm
+	for node := to; node != from; node = s.prev[node] {
+		distanceKm += float64(graph.lengthM[s.via[node]]) / 1000
+		nodes = append(nodes, s.prev[node])
+	}
+	path := Path{
+		DistanceKm:  distanceKm,
+		DurationMin: seconds/60 + (fromKm+toKm)/accessSpeedKmh*60,
+		Points:      make([]eta.Point, 0, len(nodes)+2),
+	}
+	path.Points = append(path.Points, origin)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		path.Points = append(path.Points, graph.point(nodes[i]))
+	}
+	path.Points = append(path.Points, destination)
+	return path, nil
+}
+
+// search holds the per node state of an A* search, reused from one search
+// to the next. A node's state belongs to the current search only if its
+// stamp is the search's.
+type search struct {
+	seconds []float64
+	prev    []uint32
+	via     []uint32
+	stamp   []uint32
+	current uint32
+	open    openSet
+}
+
+func newSearch(nodes int) *search {
+	return &search{
+		seconds: make([]float64, nodes),
+		prev:    make([]uint32, nodes),
+		via:     make([]uint32, nodes),
+		stamp:   make([]uint32, nodes),
+	}
+}
+
+// run searches the fastest path from one node to another, leaving how each
+// node on it was reached in prev and via, and returns its driving time in
+// seconds.
+func (s *search) run(graph *Graph, from, to uint32, capKmh float64) (float64, bool) {
+	s.current++
+	if s.current == 0 {
+		clear(s.stamp)
+		s.current = 1
+	}
+	s.open = s.open[:0]
+
+	// the remaining time can't be less than the straight line at top speed
+	target := graph.point(to)
+	topMps := math.Min(graph.topSpeedKmh, capKmh) / 3.6
+	estimate := func(node uint32) float64 {
+		return eta.HaversineKm(graph.point(node), target) * 1000 / topMps
+	}
+
+	s.reach(from, from, 0, 0)
+	heap.Push(&s.open, entry{node: from, estimate: estimate(from)})
+	for s.open.Len() > 0 {
+		next := heap.Pop(&s.open).(entry)
+		if next.seconds > s.seconds[next.node] {
+			continue
+		}
+		if next.node == to {
+			return next.seconds, true
+		}
+		for e := graph.first[next.node]; e < graph.first[next.node+1]; e++ {
+			node := graph.target[e]
+			speedMps := math.Min(float64(graph.speedKmh[e]), capKmh) / 3.6
+			seconds := next.seconds + float64(graph.lengthM[e])/speedMps
+			if s.stamp[node] == s.current && seconds >= s.seconds[node] {
+				continue
+			}
+			s.reach(node, next.node, e, seconds)
+			heap.Push(&s.open, entry{node: node, seconds: seconds, estimate: seconds + estimate(node)})
+		}
+	}
+	return 0, false
+}
+
+func (s *search) reach(node, prev, via uint32, seconds float64) {
+	s.stamp[node] = s.current
+	s.seconds[node] = seconds
+	s.prev[node] = prev
+	s.via[node] = via
+}
+
+type entry struct {
+	node     uint32
+	seconds  float64
+	estimate float64
+}
+
+// openSet is a min-heap of the nodes to visit by estimated total time.
+type openSet []entry
+
+func (set openSet) Len() int           { return len(set) }
+func (set openSet) Less(i, j int) bool { return set[i].estimate < set[j].estimate }
+func (set openSet) Swap(i, j int)      { set[i], set[j] = set[j], set[i] }
+
+func (set *openSet) Push(x any) {
+	*set = append(*set, x.(entry))
+}
+
+func (set *openSet) Pop() any {
+	old := *set
+	last := old[len(old)-1]
+	*set = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,199 @@
+package routing
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/stretchr/testify/require"
+)
+
+func requirePathThrough(t *testing.T, path Path, through ...eta.Point) {
+	require.Len(t, path.Points, len(through)+2)
+	for i, point := range through {
+		require.Equal(t, roundPoint(point), roundPoint(path.Points[i+1]))
+	}
+}
+
+func TestRoute(t *testing.T) {
+	graph, points := testNetwork(t)
+
+	// east takes the motorway: 1 km of links at 60 and 4 km at 100
+	path, err := graph.Route(points[1], points[2], eta.ClassCar)
+	require.NoError(t, err)
+	requirePathThrough(t, path, points[1], points[3], points[4], points[2])
+	require.Equal(t, points[1], path.Points[0])
+	require.Equal(t, points[2], path.Points[len(path.Points)-1])
+	require.InDelta(t, 5, path.DistanceKm, 0.01)
+	require.InDelta(t, 1+2.4, path.DurationMin, 0.01)
+
+	// trucks are slower on the motorway
+	path, err = graph.Route(points[1], points[2], eta.ClassTruck)
+	require.NoError(t, err)
+	require.InDelta(t, 1+3, path.DurationMin, 0.01)
+
+	// the motorway is one way, so west is along the street at 25
+	path, err = graph.Route(points[2], points[1], eta.ClassCar)
+	require.NoError(t, err)
+	requirePathThrough(t, path, points[2], points[6], points[1])
+	require.InDelta(t, 4, path.DistanceKm, 0.01)
+	require.InDelta(t, 9.6, path.DurationMin, 0.01)
+}
+
+func TestRouteSnapping(t *testing.T) {
+	graph, points := testNetwork(t)
+
+	// 100 m from home, walked at the access speed, and 200 m from node 6
+	origin := offset(home, -0.1, 0)
+	destination := offset(points[6], 0.2, 0)
+	path, err := graph.Route(origin, destination, eta.ClassCar)
+	require.NoError(t, err)
+	require.Equal(t, origin, path.Points[0])
+	require.Equal(t, destination, path.Points[len(path.Points)-1])
+	requirePathThrough(t, path, points[1], points[6])
+	require.InDelta(t, 2.3, path.DistanceKm, 0.01)
+	require.InDelta(t, 2.0/25*60+0.3/accessSpeedKmh*60, path.DurationMin, 0.01)
+
+	// both points on the same node
+	path, err = graph.Route(origin, home, eta.ClassCar)
+	require.NoError(t, err)
+	requirePathThrough(t, path, points[1])
+	require.InDelta(t, 0.1, path.DistanceKm, 0.01)
+}
+
+func TestRouteErrors(t *testing.T) {
+	graph, points := testNetwork(t)
+
+	_, err := graph.Route(offset(home, -3, 0), points[2], eta.ClassCar)
+	require.ErrorIs(t, err, ErrNotCovered)
+	_, err = graph.Route(points[2], offset(points[2], 0, 20), eta.ClassCar)
+	require.ErrorIs(t, err, ErrNotCovered)
+
+	// there's no way back from the end of the one-way street
+	_, err = graph.Route(points[9], points[1], eta.ClassCar)
+	require.ErrorIs(t, err, ErrNoPath)
+	_, err = graph.Route(points[1], points[9], eta.ClassCar)
+	require.NoError(t, err)
+}
+
+// randomGrid is a size by size grid of streets 200 m apart with random road
+// classes, some of them one way.
+func randomGrid(t testing.TB, seed int64, size int) (*Graph, []eta.Point) {
+	random := rand.New(rand.NewSource(seed))
+	classes := []string{"primary", "secondary", "tertiary", "residential", "service"}
+	builder := NewBuilder()
+	points := make([]eta.Point, 0, size*size)
+	id := func(row, col int) int64 { return int64(row*size + col) }
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			points = append(points, offset(home, float64(row)*0.2, float64(col)*0.2))
+			for _, next := range [][2]int{{row + 1, col}, {row, col + 1}} {
+				if next[0] == size || next[1] == size {
+					continue
+				}
+				tags := map[string]string{"highway": classes[random.Intn(len(classes))]}
+				if random.Intn(5) == 0 {
+					tags["oneway"] = "yes"
+				}
+				builder.AddWay([]int64{id(row, col), id(next[0], next[1])}, tags)
+			}
+		}
+	}
+	for i, point := range points {
+		builder.AddNode(int64(i), point.Lat, point.Lng)
+	}
+	graph, err := builder.Build()
+	require.NoError(t, err)
+	return graph, points
+}
+
+// dijkstra returns the fastest time in seconds from one node to every other,
+// the plain way.
+func dijkstra(graph *Graph, from uint32, capKmh float64) []float64 {
+	seconds := make([]float64, graph.NodeCount())
+	done := make([]bool, graph.NodeCount())
+	for i := range seconds {
+		seconds[i] = math.Inf(1)
+	}
+	seconds[from] = 0
+	for {
+		node := -1
+		for i := range seconds {
+			if !done[i] && !math.IsInf(seconds[i], 1) && (node < 0 || seconds[i] < seconds[node]) {
+				node = i
+			}
+		}
+		if node < 0 {
+			return seconds
+		}
+		done[node] = true
+		for e := graph.first[node]; e < graph.first[node+1]; e++ {
+			speedMps := math.Min(float64(graph.speedKmh[e]), capKmh) / 3.6
+			seconds[graph.target[e]] = math.Min(seconds[graph.target[e]], seconds[node]+float64(graph.lengthM[e])/speedMps)
+		}
+	}
+}
+
+func TestRouteFastest(t *testing.T) {
+	graph, points := randomGrid(t, 1, 12)
+	random := rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		from := random.Intn(len(points))
+		node, _, ok := graph.nearest(points[from])
+		require.True(t, ok)
+		expected := dijkstra(graph, node, MaxSpeedsKmh[eta.ClassTruck])
+		for to := range points {
+			node, _, ok := graph.nearest(points[to])
+			require.True(t, ok)
+			path, err := graph.Route(points[from], points[to], eta.ClassTruck)
+			if math.IsInf(expected[node], 1) {
+				require.ErrorIs(t, err, ErrNoPath)
+				continue
+			}
+			require.NoError(t, err)
+			// up to a metre is lost snapping to the float32 coordinates
+			require.InDelta(t, expected[node]/60, path.DurationMin, 1e-3)
+			require.GreaterOrEqual(t, path.DistanceKm, eta.HaversineKm(points[from], points[to])-1e-6)
+		}
+	}
+}
+
+func TestRouteConcurrent(t *testing.T) {
+	graph, points := randomGrid(t, 3, 10)
+	expected := make([]Path, len(points))
+	for i := range points {
+		expected[i], _ = graph.Route(points[0], points[i], eta.ClassCar)
+	}
+
+	results := make([][]Path, 8)
+	var wg sync.WaitGroup
+	for w := range results {
+		results[w] = make([]Path, len(points))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range points {
+				results[w][i], _ = graph.Route(points[0], points[i], eta.ClassCar)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, result := range results {
+		require.Equal(t, expected, result)
+	}
+}
+
+func BenchmarkRoute(b *testing.B) {
+	for _, size := range []int{50, 200} {
+		graph, points := randomGrid(b, 1, size)
+		b.Run(strconv.Itoa(size*size), func(b *testing.B) {
+			random := rand.New(rand.NewSource(1))
+			for i := 0; i < b.N; i++ {
+				graph.Route(points[random.Intn(len(points))], points[random.Intn(len(points))], eta.ClassCar)
+			}
+		})
+	}
+}
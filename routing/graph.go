@@ -0,0 +1,212 @@
+// Package routing answers fastest paths over a road network imported from an
+// OpenStreetMap extract. The network is held in memory as a compact graph
+// with the free-flow speed of every road, searched with A*.
+package routing
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+var ErrEmptyGraph = errors.New("road network has no routable roads")
+
+// Graph is a directed road network in compressed sparse row form: the edges
+// leaving node i are first[i] to first[i+1]. It's read-only once built, so
+// it can serve any number of searches at once.
+type Graph struct {
+	lat, lng []float32
+
+	first    []uint32
+	target   []uint32
+	lengthM  []float32
+	speedKmh []uint8
+
+	// topSpeedKmh is the fastest edge speed, which keeps the A* heuristic
+	// from overestimating.
+	topSpeedKmh float64
+	index       grid
+	searches    sync.Pool
+}
+
+// NodeCount returns the number of nodes of the graph.
+func (graph *Graph) NodeCount() int {
+	return len(graph.lat)
+}
+
+// EdgeCount returns the number of directed edges of the graph.
+func (graph *Graph) EdgeCount() int {
+	return len(graph.target)
+}
+
+func (graph *Graph) point(node uint32) eta.Point {
+	return eta.Point{Lat: float64(graph.lat[node]), Lng: float64(graph.lng[node])}
+}
+
+type way struct {
+	nodes     []int64
+	speedKmh  uint8
+	direction direction
+}
+
+type edge struct {
+	from, to uint32
+	lengthM  float32
+	speedKmh uint8
+}
+
+// Builder collects the ways and nodes of a road network, in any order, and
+// builds its Graph. Only nodes used by a routable way are kept.
+type Builder struct {
+	ways  []way
+	nodes map[int64]uint32
+	found []bool
+	lat   []float32
+	lng   []float32
+}
+
+func NewBuilder() *Builder {
+	return &Builder{nodes: make(map[int64]uint32)}
+}
+
+// AddWay adds the road through nodeIDs described by the OSM tags of a way,
+// if motor vehicles can drive it, and reports whether it was added.
+func (builder *Builder) AddWay(nodeIDs []int64, tags map[string]string) bool {
+	speed, dir, ok := wayProfile(tags)
+	if !ok || len(nodeIDs) < 2 {
+		return false
+	}
+	builder.ways = append(builder.ways, way{
+		nodes:     append([]int64(nil), nodeIDs...),
+		speedKmh:  uint8(math.Max(math.Round(speed), 1)),
+		direction: dir,
+	})
+	for _, id := range nodeIDs {
+		if _, ok := builder.nodes[id]; !ok {
+			builder.nodes[id] = uint32(len(builder.found))
+			builder.found = append(builder.found, false)
+		}
+	}
+	return true
+}
+
+// AddNode sets where a node is. Nodes no way added so far goes through are
+// ignored, so ways have to be added first.
+func (builder *Builder) AddNode(id int64, lat, lng float64) {
+	i, ok := builder.nodes[id]
+	if !ok {
+		return
+	}
+	if missing := len(builder.found) - len(builder.lat); missing > 0 {
+		builder.lat = append(builder.lat, make([]float32, missing)...)
+		builder.lng = append(builder.lng, make([]float32, missing)...)
+	}
+	builder.lat[i] = float32(lat)
+	builder.lng[i] = float32(lng)
+	builder.found[i] = true
+}
+
+// Build returns the graph of the ways added so far. Segments that lead to a
+// node missing from the extract are dropped.
+func (builder *Builder) Build() (*Graph, error) {
+	// renumber the nodes that were found, in the order they were first used
+	index := make([]uint32, len(builder.found))
+	graph := &Graph{}
+	for i, found := range builder.found {
+		if !found {
+			continue
+		}
+		index[i] = uint32(len(graph.lat))
+		graph.lat = append(graph.lat, builder.lat[i])
+		graph.lng = append(graph.lng, builder.lng[i])
+	}
+
+	var edges []edge
+	for _, w := range builder.ways {
+		for i := 1; i < len(w.nodes); i++ {
+			from, to := builder.nodes[w.nodes[i-1]], builder.nodes[w.nodes[i]]
+			if !builder.found[from] || !builder.found[to] || from == to {
+				continue
+			}
+			from, to = index[from], index[to]
+			lengthM := float32(eta.HaversineKm(graph.point(from), graph.point(to)) * 1000)
+			if w.direction != backwardOnly {
+				edges = append(edges, edge{from: from, to: to, lengthM: lengthM, speedKmh: w.speedKmh})
+			}
+			if w.direction != forwardOnly {
+				edges = append(edges, edge{from: to, to: from, lengthM: lengthM, speedKmh: w.speedKmh})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return nil, ErrEmptyGraph
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+	graph.first = make([]uint32, len(graph.lat)+1)
+	graph.target = make([]uint32, len(edges))
+	graph.lengthM = make([]float32, len(edges))
+	graph.speedKmh = make([]uint8, len(edges))
+	for i, e := range edges {
+		graph.first[e.from+1]++
+		graph.target[i] = e.to
+		graph.lengthM[i] = e.lengthM
+		graph.speedKmh[i] = e.speedKmh
+		graph.topSpeedKmh = math.Max(graph.topSpeedKmh, float64(e.speedKmh))
+	}
+	for i := 1; i < len(graph.first); i++ {
+		graph.first[i] += graph.first[i-1]
+	}
+
+	graph.index = newGrid(graph, largestComponent(graph, edges))
+	nodes := len(graph.lat)
+	graph.searches.New = func() any { return newSearch(nodes) }
+	return graph, nil
+}
+
+// largestComponent returns the nodes of the largest set of roads connected
+// to each other, ignoring one-way restrictions. Snapping only to them keeps
+// a point from landing on an isolated stretch of road that leads nowhere.
+func largestComponent(graph *Graph, edges []edge) []uint32 {
+	parent := make([]uint32, len(graph.lat))
+	for i := range parent {
+		parent[i] = uint32(i)
+	}
+	find := func(node uint32) uint32 {
+		for parent[node] != node {
+			parent[node] = parent[parent[node]]
+			node = parent[node]
+		}
+		return node
+	}
+	for _, e := range edges {
+		if a, b := find(e.from), find(e.to); a != b {
+			parent[a] = b
+		}
+	}
+
+	sizes := make(map[uint32]int)
+	var largest uint32
+	for node := range parent {
+		root := find(uint32(node))
+		sizes[root]++
+		if sizes[root] > sizes[largest] {
+			largest = root
+		}
+	}
+	nodes := make([]uint32, 0, sizes[largest])
+	for node := range parent {
+		if find(uint32(node)) == largest {
+			nodes = append(nodes, uint32(node))
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,115 @@
+package routing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/stretchr/testify/require"
+)
+
+var home = eta.Point{Lat: 6.5, Lng: 3.3}
+
+// offset moves point by the given kilometres north and east.
+func offset(point eta.Point, northKm, eastKm float64) eta.Point {
+	return eta.Point{
+		Lat: point.Lat + northKm/kmPerDeg,
+		Lng: point.Lng + eastKm/(kmPerDeg*math.Cos(point.Lat*math.Pi/180)),
+	}
+}
+
+// testNetwork is a 4 km residential street from home going east, with a
+// one-way motorway running alongside it 500 m to the north, joined at both
+// ends by links. A one-way street leaves the east end and goes nowhere, and
+// a short service road south of home isn't joined to anything.
+//
+//	3 ======================== 4      (motorway, eastbound)
+//	|                          |
+//	1 ----------- 6 ---------- 2 ->- 9
+//	7 -- 8
+func testNetwork(t *testing.T) (*Graph, map[int64]eta.Point) {
+	points := map[int64]eta.Point{
+		1: home,
+		2: offset(home, 0, 4),
+		3: offset(home, 0.5, 0),
+		4: offset(home, 0.5, 4),
+		6: offset(home, 0, 2),
+		7: offset(home, -0.1, 0),
+		8: offset(home, -0.1, 0.3),
+		9: offset(home, 0, 4.5),
+	}
+
+	builder := NewBuilder()
+	require.True(t, builder.AddWay([]int64{1, 6, 2}, map[string]string{"highway": "residential"}))
+	require.True(t, builder.AddWay([]int64{3, 4}, map[string]string{"highway": "motorway"}))
+	require.True(t, builder.AddWay([]int64{1, 3}, map[string]string{"highway": "motorway_link", "oneway": "no"}))
+	require.True(t, builder.AddWay([]int64{4, 2}, map[string]string{"highway": "motorway_link", "oneway": "no"}))
+	require.True(t, builder.AddWay([]int64{2, 9}, map[string]string{"highway": "residential", "oneway": "yes"}))
+	require.True(t, builder.AddWay([]int64{7, 8}, map[string]string{"highway": "service"}))
+	for id, point := range points {
+		builder.AddNode(id, point.Lat, point.Lng)
+	}
+
+	graph, err := builder.Build()
+	require.NoError(t, err)
+	return graph, points
+}
+
+func TestBuild(t *testing.T) {
+	graph, _ := testNetwork(t)
+	require.Equal(t, 8, graph.NodeCount())
+	// two ways on the residential street, the links and the service road,
+	// one on the motorway and the one-way street
+	require.Equal(t, 2*2+2*2+2+1+1, graph.EdgeCount())
+	require.Equal(t, 100.0, graph.topSpeedKmh)
+
+	for node := 0; node < graph.NodeCount(); node++ {
+		for e := graph.first[node]; e < graph.first[node+1]; e++ {
+			require.NotEqual(t, uint32(node), graph.target[e])
+			require.Positive(t, graph.lengthM[e])
+		}
+	}
+}
+
+func TestBuildMissingNodes(t *testing.T) {
+	builder := NewBuilder()
+	require.True(t, builder.AddWay([]int64{1, 2, 3, 4}, map[string]string{"highway": "tertiary"}))
+	require.False(t, builder.AddWay([]int64{1, 5}, map[string]string{"highway": "footway"}))
+	require.False(t, builder.AddWay([]int64{1}, map[string]string{"highway": "tertiary"}))
+
+	// node 3 is outside the extract, cutting the way in two
+	builder.AddNode(1, home.Lat, home.Lng)
+	builder.AddNode(2, home.Lat, home.Lng+0.01)
+	builder.AddNode(4, home.Lat, home.Lng+0.03)
+	builder.AddNode(5, home.Lat, home.Lng+0.04)
+	graph, err := builder.Build()
+	require.NoError(t, err)
+	require.Equal(t, 3, graph.NodeCount())
+	require.Equal(t, 2, graph.EdgeCount())
+
+	_, err = NewBuilder().Build()
+	require.ErrorIs(t, err, ErrEmptyGraph)
+}
+
+func TestNearest(t *testing.T) {
+	graph, points := testNetwork(t)
+
+	node, km, ok := graph.nearest(offset(points[6], 0.2, 0.1))
+	require.True(t, ok)
+	require.Equal(t, roundPoint(points[6]), roundPoint(graph.point(node)))
+	require.InDelta(t, math.Hypot(0.2, 0.1), km, 1e-3)
+
+	// the service road is closer but leads nowhere
+	node, km, ok = graph.nearest(offset(home, -0.1, 0))
+	require.True(t, ok)
+	require.Equal(t, roundPoint(home), roundPoint(graph.point(node)))
+	require.InDelta(t, 0.1, km, 1e-3)
+
+	_, _, ok = graph.nearest(offset(home, -2, 0))
+	require.False(t, ok)
+}
+
+// roundPoint drops the precision lost storing coordinates as float32.
+func roundPoint(point eta.Point) eta.Point {
+	return eta.Point{Lat: math.Round(point.Lat*1e5) / 1e5, Lng: math.Round(point.Lng*1e5) / 1e5}
+}
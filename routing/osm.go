@@ -0,0 +1,60 @@
+package routing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/qedus/osmpbf"
+)
+
+// Import builds the road network of the OpenStreetMap PBF extract at path.
+// The file is read twice: once for the roads, then for the nodes they go
+// through, so only those nodes are ever held in memory.
+func Import(path string) (*Graph, error) {
+	builder := NewBuilder()
+	err := decodeFile(path, func(object any) {
+		if way, ok := object.(*osmpbf.Way); ok {
+			builder.AddWay(way.NodeIDs, way.Tags)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = decodeFile(path, func(object any) {
+		if node, ok := object.(*osmpbf.Node); ok {
+			builder.AddNode(node.ID, node.Lat, node.Lon)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return builder.Build()
+}
+
+// decodeFile hands every node, way and relation of a PBF file to fn, in
+// file order.
+func decodeFile(path string, fn func(object any)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := osmpbf.NewDecoder(file)
+	if err := decoder.Start(runtime.GOMAXPROCS(0)); err != nil {
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	for {
+		object, err := decoder.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", path, err)
+		}
+		fn(object)
+	}
+}
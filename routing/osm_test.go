@@ -0,0 +1,132 @@
+package routing
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/qedus/osmpbf/OSMPBF"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// writeBlock appends a file block holding message, uncompressed, to file.
+func writeBlock(t *testing.T, file *os.File, kind string, message proto.Message) {
+	data, err := proto.Marshal(message)
+	require.NoError(t, err)
+	blob, err := proto.Marshal(&OSMPBF.Blob{
+		RawSize: proto.Int32(int32(len(data))),
+		Data:    &OSMPBF.Blob_Raw{Raw: data},
+	})
+	require.NoError(t, err)
+	header, err := proto.Marshal(&OSMPBF.BlobHeader{Type: proto.String(kind), Datasize: proto.Int32(int32(len(blob)))})
+	require.NoError(t, err)
+
+	require.NoError(t, binary.Write(file, binary.BigEndian, uint32(len(header))))
+	_, err = file.Write(header)
+	require.NoError(t, err)
+	_, err = file.Write(blob)
+	require.NoError(t, err)
+}
+
+type testWay struct {
+	nodes []int64
+	tags  map[string]string
+}
+
+// writeExtract writes the nodes and ways to a PBF file the way extracts are
+// laid out, every node before the first way.
+func writeExtract(t *testing.T, nodes map[int64]eta.Point, ways []testWay) string {
+	path := filepath.Join(t.TempDir(), "extract.osm.pbf")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	writeBlock(t, file, "OSMHeader", &OSMPBF.HeaderBlock{RequiredFeatures: []string{"OsmSchema-V0.6"}})
+
+	table := []string{""}
+	stringID := func(s string) uint32 {
+		for i, existing := range table {
+			if existing == s {
+				return uint32(i)
+			}
+		}
+		table = append(table, s)
+		return uint32(len(table) - 1)
+	}
+
+	nodeGroup := &OSMPBF.PrimitiveGroup{}
+	for id, point := range nodes {
+		// in units of the default granularity of 100 nanodegrees
+		nodeGroup.Nodes = append(nodeGroup.Nodes, &OSMPBF.Node{
+			Id:  proto.Int64(id),
+			Lat: proto.Int64(int64(point.Lat * 1e7)),
+			Lon: proto.Int64(int64(point.Lng * 1e7)),
+		})
+	}
+	wayGroup := &OSMPBF.PrimitiveGroup{}
+	for i, way := range ways {
+		encoded := &OSMPBF.Way{Id: proto.Int64(int64(i + 1))}
+		var last int64
+		for _, node := range way.nodes {
+			encoded.Refs = append(encoded.Refs, node-last)
+			last = node
+		}
+		for key, value := range way.tags {
+			encoded.Keys = append(encoded.Keys, stringID(key))
+			encoded.Vals = append(encoded.Vals, stringID(value))
+		}
+		wayGroup.Ways = append(wayGroup.Ways, encoded)
+	}
+	writeBlock(t, file, "OSMData", &OSMPBF.PrimitiveBlock{
+		Stringtable:    &OSMPBF.StringTable{S: table},
+		Primitivegroup: []*OSMPBF.PrimitiveGroup{nodeGroup, wayGroup},
+	})
+	return path
+}
+
+func TestImport(t *testing.T) {
+	_, points := testNetwork(t)
+	// a footpath and the node of a shop, neither of them for driving
+	points[10] = offset(home, 0.3, 1)
+	points[11] = offset(home, 0.2, 2)
+	path := writeExtract(t, points, []testWay{
+		{[]int64{1, 6, 2}, map[string]string{"highway": "residential", "name": "Broad Street"}},
+		{[]int64{3, 4}, map[string]string{"highway": "motorway"}},
+		{[]int64{1, 3}, map[string]string{"highway": "motorway_link", "oneway": "no"}},
+		{[]int64{4, 2}, map[string]string{"highway": "motorway_link", "oneway": "no"}},
+		{[]int64{2, 9}, map[string]string{"highway": "residential", "oneway": "yes"}},
+		{[]int64{7, 8}, map[string]string{"highway": "service"}},
+		{[]int64{1, 10, 2}, map[string]string{"highway": "footway"}},
+	})
+
+	graph, err := Import(path)
+	require.NoError(t, err)
+	require.Equal(t, 8, graph.NodeCount())
+	require.Equal(t, 12, graph.EdgeCount())
+
+	route, err := graph.Route(points[1], points[2], eta.ClassCar)
+	require.NoError(t, err)
+	require.Len(t, route.Points, 6)
+	require.InDelta(t, 5, route.DistanceKm, 0.01)
+	require.InDelta(t, 3.4, route.DurationMin, 0.01)
+}
+
+func TestImportInvalid(t *testing.T) {
+	_, err := Import(filepath.Join(t.TempDir(), "missing.osm.pbf"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	path := filepath.Join(t.TempDir(), "invalid.osm.pbf")
+	require.NoError(t, os.WriteFile(path, []byte("not a pbf file"), 0o600))
+	_, err = Import(path)
+	require.Error(t, err)
+
+	// nothing to drive on
+	path = writeExtract(t, map[int64]eta.Point{1: home, 2: offset(home, 0, 1)}, []testWay{
+		{[]int64{1, 2}, map[string]string{"highway": "footway"}},
+	})
+	_, err = Import(path)
+	require.ErrorIs(t, err, ErrEmptyGraph)
+}
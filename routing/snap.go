@@ -0,0 +1,74 @@
+package routing
+
+import (
+	"math"
+
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+const (
+	// MaxSnapKm is how far a point may be from the nearest road node for the
+	// network to cover it.
+	MaxSnapKm = 1.0
+
+	cellDeg  = 0.005
+	kmPerDeg = 111.195
+)
+
+type cell struct {
+	row, col int32
+}
+
+func cellOf(lat, lng float64) cell {
+	return cell{row: int32(math.Floor(lat / cellDeg)), col: int32(math.Floor(lng / cellDeg))}
+}
+
+// grid buckets nodes by their cell of cellDeg by cellDeg degrees to find the
+// node nearest a point without going over every node.
+type grid struct {
+	cells map[cell][]uint32
+}
+
+func newGrid(graph *Graph, nodes []uint32) grid {
+	index := grid{cells: make(map[cell][]uint32)}
+	for _, node := range nodes {
+		c := cellOf(float64(graph.lat[node]), float64(graph.lng[node]))
+		index.cells[c] = append(index.cells[c], node)
+	}
+	return index
+}
+
+// nearest returns the indexed node closest to point and how far it is, or
+// false if there's none within MaxSnapKm.
+func (graph *Graph) nearest(point eta.Point) (uint32, float64, bool) {
+	// cells are narrower than they are tall away from the equator, so the
+	// ring search goes by their width
+	widthKm := cellDeg * kmPerDeg * math.Max(math.Cos(point.Lat*math.Pi/180), 0.1)
+	rings := int32(math.Ceil(MaxSnapKm / widthKm))
+
+	center := cellOf(point.Lat, point.Lng)
+	best, bestKm := uint32(0), math.Inf(1)
+	for r := int32(0); r <= rings; r++ {
+		for row := center.row - r; row <= center.row+r; row++ {
+			for col := center.col - r; col <= center.col+r; col++ {
+				// only the cells on the edge of the ring are new
+				if row != center.row-r && row != center.row+r && col != center.col-r && col != center.col+r {
+					continue
+				}
+				for _, node := range graph.index.cells[cell{row: row, col: col}] {
+					if km := eta.HaversineKm(point, graph.point(node)); km < bestKm {
+						best, bestKm = node, km
+					}
+				}
+			}
+		}
+		// nodes of further rings are at least r cells away
+		if bestKm <= float64(r)*widthKm {
+			break
+		}
+	}
+	if bestKm > MaxSnapKm {
+		return 0, 0, false
+	}
+	return best, bestKm, true
+}
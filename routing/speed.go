@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/joekings2k/logistics-eta/eta"
+)
+
+const (
+	mphToKmh = 1.609344
+	// maxEdgeSpeedKmh is the fastest speed an edge can store.
+	maxEdgeSpeedKmh = math.MaxUint8
+)
+
+// RoadSpeedsKmh is the free-flow speed of every routable OSM highway class,
+// used when a way has no usable maxspeed tag. They sit below the usual
+// limits since few roads are driven at their limit end to end.
+var RoadSpeedsKmh = map[string]float64{
+	"motorway":       100,
+	"motorway_link":  60,
+	"trunk":          80,
+	"trunk_link":     50,
+	"primary":        60,
+	"primary_link":   40,
+	"secondary":      50,
+	"secondary_link": 35,
+	"tertiary":       40,
+	"tertiary_link":  30,
+	"unclassified":   30,
+	"road":           30,
+	"residential":    25,
+	"living_street":  10,
+	"service":        15,
+}
+
+// MaxSpeedsKmh caps the speed of heavier vehicle classes whatever the road
+// allows. Classes not listed drive at the road speed.
+var MaxSpeedsKmh = map[eta.VehicleClass]float64{
+	eta.ClassVan:   90,
+	eta.ClassTruck: 80,
+}
+
+// direction tells which ways a way can be driven along its node order.
+type direction int
+
+const (
+	bothWays direction = iota
+	forwardOnly
+	backwardOnly
+)
+
+// wayProfile returns the speed of a way and the directions it can be driven
+// in, or false if it's not meant for motor vehicles.
+func wayProfile(tags map[string]string) (float64, direction, bool) {
+	speed, ok := RoadSpeedsKmh[tags["highway"]]
+	if !ok || tags["area"] == "yes" {
+		return 0, bothWays, false
+	}
+	for _, key := range []string{"access", "vehicle", "motor_vehicle", "motorcar"} {
+		switch tags[key] {
+		case "no", "private":
+			return 0, bothWays, false
+		}
+	}
+
+	if limit, ok := parseMaxSpeed(tags["maxspeed"]); ok {
+		speed = limit
+	}
+	return math.Min(speed, maxEdgeSpeedKmh), wayDirection(tags), true
+}
+
+// wayDirection reads the oneway tag, motorways and roundabouts being one way
+// unless tagged otherwise.
+func wayDirection(tags map[string]string) direction {
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		return forwardOnly
+	case "-1", "reverse":
+		return backwardOnly
+	case "no", "false", "0":
+		return bothWays
+	}
+	if tags["highway"] == "motorway" || tags["junction"] == "roundabout" {
+		return forwardOnly
+	}
+	return bothWays
+}
+
+// parseMaxSpeed reads a maxspeed tag such as "50", "50 km/h" or "30 mph".
+// Symbolic values like "none" or "walk" are ignored.
+func parseMaxSpeed(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	factor := 1.0
+	switch {
+	case strings.HasSuffix(value, "mph"):
+		factor = mphToKmh
+		value = strings.TrimSpace(strings.TrimSuffix(value, "mph"))
+	case strings.HasSuffix(value, "km/h"):
+		value = strings.TrimSpace(strings.TrimSuffix(value, "km/h"))
+	}
+
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	return speed * factor, true
+}
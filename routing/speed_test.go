@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWayProfile(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tags      map[string]string
+		speedKmh  float64
+		direction direction
+		routable  bool
+	}{
+		{"Residential", map[string]string{"highway": "residential"}, 25, bothWays, true},
+		{"MaxSpeed", map[string]string{"highway": "primary", "maxspeed": "70"}, 70, bothWays, true},
+		{"MaxSpeedMph", map[string]string{"highway": "primary", "maxspeed": "30 mph"}, 30 * mphToKmh, bothWays, true},
+		{"MaxSpeedNone", map[string]string{"highway": "motorway", "maxspeed": "none"}, 100, forwardOnly, true},
+		{"MotorwayBothWays", map[string]string{"highway": "motorway", "oneway": "no"}, 100, bothWays, true},
+		{"Roundabout", map[string]string{"highway": "tertiary", "junction": "roundabout"}, 40, forwardOnly, true},
+		{"Oneway", map[string]string{"highway": "secondary", "oneway": "yes"}, 50, forwardOnly, true},
+		{"OnewayReverse", map[string]string{"highway": "secondary", "oneway": "-1"}, 50, backwardOnly, true},
+		{"Footway", map[string]string{"highway": "footway"}, 0, bothWays, false},
+		{"NoHighway", map[string]string{"building": "yes"}, 0, bothWays, false},
+		{"Private", map[string]string{"highway": "service", "access": "private"}, 0, bothWays, false},
+		{"NoMotorVehicles", map[string]string{"highway": "residential", "motor_vehicle": "no"}, 0, bothWays, false},
+		{"Area", map[string]string{"highway": "service", "area": "yes"}, 0, bothWays, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			speed, dir, ok := wayProfile(tc.tags)
+			require.Equal(t, tc.routable, ok)
+			if !tc.routable {
+				return
+			}
+			require.InDelta(t, tc.speedKmh, speed, 1e-9)
+			require.Equal(t, tc.direction, dir)
+		})
+	}
+}
+
+func TestParseMaxSpeed(t *testing.T) {
+	for value, expected := range map[string]float64{
+		"50":       50,
+		"50 km/h":  50,
+		" 80km/h ": 80,
+		"20 mph":   20 * mphToKmh,
+	} {
+		speed, ok := parseMaxSpeed(value)
+		require.True(t, ok, value)
+		require.InDelta(t, expected, speed, 1e-9, value)
+	}
+
+	for _, value := range []string{"", "none", "walk", "signals", "-10", "0"} {
+		_, ok := parseMaxSpeed(value)
+		require.False(t, ok, value)
+	}
+}
@@ -0,0 +1,130 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+const minSecretKeySize = 32
+
+// JWTMaker issues JSON Web Tokens signed with HS256, RS256 or EdDSA.
+type JWTMaker struct {
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// jwtClaims carries a Payload in the registered JWT claims so partners can
+// read it with any JWT library.
+type jwtClaims struct {
+	Role util.Role `json:"role"`
+	Type TokenType `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+func NewJWTMaker(secretKey string) (Maker, error) {
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	}
+	key := []byte(secretKey)
+	return &JWTMaker{method: jwt.SigningMethodHS256, signKey: key, verifyKey: key}, nil
+}
+
+func NewRS256JWTMaker(privateKey *rsa.PrivateKey) (Maker, error) {
+	if privateKey == nil {
+		return nil, errors.New("rsa private key is required")
+	}
+	if privateKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("rsa key must be at least 2048 bits, got %d", privateKey.N.BitLen())
+	}
+	return &JWTMaker{method: jwt.SigningMethodRS256, signKey: privateKey, verifyKey: &privateKey.PublicKey}, nil
+}
+
+func NewEdDSAJWTMaker(privateKey ed25519.PrivateKey) (Maker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+	return &JWTMaker{
+		method:    jwt.SigningMethodEdDSA,
+		signKey:   privateKey,
+		verifyKey: privateKey.Public(),
+	}, nil
+}
+
+func (maker *JWTMaker) CreateToken(userID uuid.UUID, role util.Role, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, role, tokenType, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := jwtClaims{
+		Role: payload.Role,
+		Type: payload.Type,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        payload.ID.String(),
+			Subject:   payload.UserID.String(),
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(maker.method, claims).SignedString(maker.signKey)
+	return token, payload, err
+}
+
+func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (any, error) {
+		return maker.verifyKey, nil
+	}
+
+	// pinning the algorithm rejects "none" and keys reinterpreted under
+	// another algorithm
+	claims := &jwtClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, keyFunc,
+		jwt.WithValidMethods([]string{maker.method.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := claims.payload()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (claims *jwtClaims) payload() (*Payload, error) {
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	return &Payload{
+		ID:        id,
+		UserID:    userID,
+		Role:      claims.Role,
+		Type:      claims.Type,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiredAt: claims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,210 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTMakerHS256(t *testing.T) {
+	testMaker(t, func(t *testing.T) Maker {
+		maker, err := NewJWTMaker(util.RandomString(32))
+		require.NoError(t, err)
+		return maker
+	})
+}
+
+func TestJWTMakerRS256(t *testing.T) {
+	// generating rsa keys is slow, so the suite alternates between two
+	keys := []*rsa.PrivateKey{randomRSAKey(t), randomRSAKey(t)}
+	calls := 0
+	testMaker(t, func(t *testing.T) Maker {
+		maker, err := NewRS256JWTMaker(keys[calls%len(keys)])
+		require.NoError(t, err)
+		calls++
+		return maker
+	})
+}
+
+func TestJWTMakerEdDSA(t *testing.T) {
+	testMaker(t, func(t *testing.T) Maker {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		maker, err := NewEdDSAJWTMaker(privateKey)
+		require.NoError(t, err)
+		return maker
+	})
+}
+
+func TestJWTSecretKeyLength(t *testing.T) {
+	_, err := NewJWTMaker(util.RandomString(10))
+	require.Error(t, err)
+}
+
+func TestJWTWeakRSAKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = NewRS256JWTMaker(privateKey)
+	require.Error(t, err)
+}
+
+func TestJWTAlgorithmConfusion(t *testing.T) {
+	privateKey := randomRSAKey(t)
+	maker, err := NewRS256JWTMaker(privateKey)
+	require.NoError(t, err)
+
+	// an attacker who knows the public key signs an HS256 token with it
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+	claims := jwtClaims{
+		Role: util.RoleAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(publicPEM)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestNewMaker(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := x509.MarshalPKCS8PrivateKey(randomRSAKey(t))
+	require.NoError(t, err)
+	rsaFile := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, os.WriteFile(rsaFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaKey}), 0600))
+
+	_, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edKey, err := x509.MarshalPKCS8PrivateKey(edPrivateKey)
+	require.NoError(t, err)
+	edFile := filepath.Join(dir, "ed25519.pem")
+	require.NoError(t, os.WriteFile(edFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edKey}), 0600))
+
+	_, retiredKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	retiredPublicKey, err := x509.MarshalPKIXPublicKey(retiredKey.Public())
+	require.NoError(t, err)
+	retiredFile := filepath.Join(dir, "retired.pub.pem")
+	require.NoError(t, os.WriteFile(retiredFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: retiredPublicKey}), 0600))
+
+	symmetricKey := util.RandomString(32)
+
+	testCases := []struct {
+		name      string
+		config    util.Config
+		algorithm string
+		wantErr   bool
+	}{
+		{
+			name:   "DefaultPaseto",
+			config: util.Config{TokenSymmetricKey: symmetricKey},
+		},
+		{
+			name:      "JWTDefaultHS256",
+			config:    util.Config{TokenType: TypeJWT, TokenSymmetricKey: symmetricKey},
+			algorithm: AlgorithmHS256,
+		},
+		{
+			name:      "JWTRS256",
+			config:    util.Config{TokenType: TypeJWT, TokenAlgorithm: AlgorithmRS256, TokenPrivateKeyFile: rsaFile},
+			algorithm: AlgorithmRS256,
+		},
+		{
+			name:      "JWTEdDSA",
+			config:    util.Config{TokenType: TypeJWT, TokenAlgorithm: AlgorithmEdDSA, TokenPrivateKeyFile: edFile},
+			algorithm: AlgorithmEdDSA,
+		},
+		{
+			name:      "PasetoPublic",
+			config:    util.Config{TokenType: TypePasetoPublic, TokenPrivateKeyFile: edFile, TokenVerificationKeyFiles: []string{retiredFile}},
+			algorithm: pasetoPublicHeader,
+		},
+		{
+			name:    "PasetoPublicRSAKey",
+			config:  util.Config{TokenType: TypePasetoPublic, TokenPrivateKeyFile: rsaFile},
+			wantErr: true,
+		},
+		{
+			name:    "PasetoPublicMissingVerificationKey",
+			config:  util.Config{TokenType: TypePasetoPublic, TokenPrivateKeyFile: edFile, TokenVerificationKeyFiles: []string{filepath.Join(dir, "missing.pem")}},
+			wantErr: true,
+		},
+		{
+			name:    "WrongKeyType",
+			config:  util.Config{TokenType: TypeJWT, TokenAlgorithm: AlgorithmEdDSA, TokenPrivateKeyFile: rsaFile},
+			wantErr: true,
+		},
+		{
+			name:    "MissingKeyFile",
+			config:  util.Config{TokenType: TypeJWT, TokenAlgorithm: AlgorithmRS256, TokenPrivateKeyFile: filepath.Join(dir, "missing.pem")},
+			wantErr: true,
+		},
+		{
+			name:    "UnsupportedAlgorithm",
+			config:  util.Config{TokenType: TypeJWT, TokenAlgorithm: "none", TokenSymmetricKey: symmetricKey},
+			wantErr: true,
+		},
+		{
+			name:    "UnsupportedType",
+			config:  util.Config{TokenType: "macaroon", TokenSymmetricKey: symmetricKey},
+			wantErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewMaker(tc.config)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.algorithm == "" {
+				require.IsType(t, &PasetoMaker{}, maker)
+				return
+			}
+			if tc.algorithm == pasetoPublicHeader {
+				require.IsType(t, &PasetoPublicMaker{}, maker)
+				require.Len(t, maker.(PublicKeyProvider).PublicKeys(), 2)
+			} else {
+				require.IsType(t, &JWTMaker{}, maker)
+				require.Equal(t, tc.algorithm, maker.(*JWTMaker).method.Alg())
+			}
+
+			token, _, err := maker.CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(token)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func randomRSAKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return privateKey
+}
@@ -0,0 +1,99 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// PublicKey is a verification key published under its key id.
+type PublicKey struct {
+	KID string
+	Key ed25519.PublicKey
+}
+
+// PublicKeyProvider is implemented by makers whose tokens can be verified
+// with public keys alone.
+type PublicKeyProvider interface {
+	PublicKeys() []PublicKey
+}
+
+// KeyID derives a stable key id from an Ed25519 public key, so every service
+// computes the same id for the same key without coordination.
+func KeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// KeyRing holds the key new tokens are signed with and every public key
+// tokens may still be verified with. Keeping retired public keys on the ring
+// lets the signing key rotate without invalidating tokens already issued.
+type KeyRing struct {
+	signingKID string
+	signingKey ed25519.PrivateKey
+	publicKeys map[string]ed25519.PublicKey
+}
+
+func NewKeyRing(signingKey ed25519.PrivateKey, verificationKeys ...ed25519.PublicKey) (*KeyRing, error) {
+	if len(signingKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+	publicKey := signingKey.Public().(ed25519.PublicKey)
+
+	ring := &KeyRing{
+		signingKID: KeyID(publicKey),
+		signingKey: signingKey,
+		publicKeys: map[string]ed25519.PublicKey{KeyID(publicKey): publicKey},
+	}
+	for _, key := range verificationKeys {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+		}
+		ring.publicKeys[KeyID(key)] = key
+	}
+	return ring, nil
+}
+
+// NewVerificationKeyRing builds a ring that can only verify tokens, for
+// services that trust tokens issued elsewhere.
+func NewVerificationKeyRing(keys ...ed25519.PublicKey) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one ed25519 public key is required")
+	}
+	ring := &KeyRing{publicKeys: make(map[string]ed25519.PublicKey, len(keys))}
+	for _, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+		}
+		ring.publicKeys[KeyID(key)] = key
+	}
+	return ring, nil
+}
+
+func (ring *KeyRing) SigningKID() string {
+	return ring.signingKID
+}
+
+func (ring *KeyRing) publicKey(kid string) (ed25519.PublicKey, bool) {
+	key, ok := ring.publicKeys[kid]
+	return key, ok
+}
+
+// PublicKeys lists every verification key, the signing key's first when the
+// ring has one.
+func (ring *KeyRing) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, 0, len(ring.publicKeys))
+	for kid, key := range ring.publicKeys {
+		keys = append(keys, PublicKey{KID: kid, Key: key})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].KID == ring.signingKID || keys[j].KID == ring.signingKID {
+			return keys[i].KID == ring.signingKID
+		}
+		return keys[i].KID < keys[j].KID
+	})
+	return keys
+}
@@ -1,13 +1,116 @@
 package token
 
 import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
 )
 
+const (
+	TypePaseto       = "paseto"
+	TypePasetoPublic = "paseto_public"
+	TypeJWT          = "jwt"
+
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
 
 type Maker interface {
-	CreateToken(userID uuid.UUID, duration time.Duration) (string, error)
+	CreateToken(userID uuid.UUID, role util.Role, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 	VerifyToken(token string) (*Payload, error)
-}
\ No newline at end of file
+}
+
+// NewMaker builds the token maker selected by TOKEN_TYPE, defaulting to
+// symmetric PASETO. JWTs are signed with TOKEN_ALGORITHM, which defaults to
+// HS256; RS256, EdDSA and public PASETO read a PEM private key from
+// TOKEN_PRIVATE_KEY_FILE. Public PASETO also accepts tokens signed by the
+// retired keys in TOKEN_VERIFICATION_KEY_FILES.
+func NewMaker(config util.Config) (Maker, error) {
+	switch strings.ToLower(config.TokenType) {
+	case "", TypePaseto:
+		return NewPasetoMaker(config.TokenSymmetricKey)
+	case TypePasetoPublic:
+		ring, err := loadKeyRing(config.TokenPrivateKeyFile, config.TokenVerificationKeyFiles)
+		if err != nil {
+			return nil, err
+		}
+		return NewPasetoPublicMaker(ring)
+	case TypeJWT:
+		return newJWTMakerFromConfig(config)
+	default:
+		return nil, fmt.Errorf("unsupported token type %q", config.TokenType)
+	}
+}
+
+func newJWTMakerFromConfig(config util.Config) (Maker, error) {
+	switch config.TokenAlgorithm {
+	case "", AlgorithmHS256:
+		return NewJWTMaker(config.TokenSymmetricKey)
+	case AlgorithmRS256:
+		pem, err := os.ReadFile(config.TokenPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token private key: %w", err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse rsa private key: %w", err)
+		}
+		return NewRS256JWTMaker(privateKey)
+	case AlgorithmEdDSA:
+		privateKey, err := loadEd25519PrivateKey(config.TokenPrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewEdDSAJWTMaker(privateKey)
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", config.TokenAlgorithm)
+	}
+}
+
+func loadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read token private key: %w", err)
+	}
+	key, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse ed25519 private key: %w", err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unexpected ed25519 private key type %T", key)
+	}
+	return privateKey, nil
+}
+
+func loadKeyRing(privateKeyFile string, verificationKeyFiles []string) (*KeyRing, error) {
+	privateKey, err := loadEd25519PrivateKey(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationKeys := make([]ed25519.PublicKey, 0, len(verificationKeyFiles))
+	for _, path := range verificationKeyFiles {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token verification key: %w", err)
+		}
+		key, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse ed25519 public key %s: %w", path, err)
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unexpected ed25519 public key type %T", key)
+		}
+		verificationKeys = append(verificationKeys, publicKey)
+	}
+	return NewKeyRing(privateKey, verificationKeys...)
+}
@@ -0,0 +1,109 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+// testMaker runs the behaviour every Maker must share. newMaker must return a
+// fresh maker with its own key on every call.
+func testMaker(t *testing.T, newMaker func(t *testing.T) Maker) {
+	t.Run("OK", func(t *testing.T) {
+		maker := newMaker(t)
+		userID := uuid.New()
+		role := util.Role(util.RandomRole())
+		duration := time.Minute
+		issuedAt := time.Now()
+		expiredAt := issuedAt.Add(duration)
+
+		token, created, err := maker.CreateToken(userID, role, RefreshToken, duration)
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+		require.NotEmpty(t, created)
+
+		payload, err := maker.VerifyToken(token)
+		require.NoError(t, err)
+		require.NotEmpty(t, payload)
+
+		require.Equal(t, created.ID, payload.ID)
+		require.Equal(t, userID, payload.UserID)
+		require.Equal(t, role, payload.Role)
+		require.Equal(t, RefreshToken, payload.Type)
+		require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+		require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		maker := newMaker(t)
+		token, payload, err := maker.CreateToken(uuid.New(), util.RoleDriver, AccessToken, -time.Minute)
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+		require.NotEmpty(t, payload)
+
+		payload, err = maker.VerifyToken(token)
+		require.Error(t, err)
+		require.EqualError(t, err, ErrExpiredToken.Error())
+		require.Nil(t, payload)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		maker := newMaker(t)
+		payload, err := maker.VerifyToken("invalidToken")
+		require.Error(t, err)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	})
+
+	t.Run("TamperedToken", func(t *testing.T) {
+		maker := newMaker(t)
+		token, _, err := maker.CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+		require.NoError(t, err)
+
+		// flip one character in the middle, away from base64 padding bits
+		i := len(token) / 2
+		replacement := byte('A')
+		if token[i] == replacement {
+			replacement = 'B'
+		}
+		tampered := token[:i] + string(replacement) + token[i+1:]
+
+		payload, err := maker.VerifyToken(tampered)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	})
+
+	t.Run("AlgNoneToken", func(t *testing.T) {
+		maker := newMaker(t)
+		claims := jwtClaims{
+			Role: util.RoleAdmin,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				Subject:   uuid.NewString(),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(token, "."))
+
+		payload, err := maker.VerifyToken(token)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	})
+
+	t.Run("ForeignKey", func(t *testing.T) {
+		token, _, err := newMaker(t).CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+		require.NoError(t, err)
+
+		payload, err := newMaker(t).VerifyToken(token)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	})
+}
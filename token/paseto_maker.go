@@ -6,6 +6,7 @@ import (
 
 	"github.com/aead/chacha20poly1305"
 	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
 	"github.com/o1egl/paseto"
 )
 
@@ -28,13 +29,14 @@ func NewPasetoMaker(symmetricKey string ) (Maker, error) {
 }
 
 
-func (maker *PasetoMaker) CreateToken(userID uuid.UUID, duration time.Duration) (string, error) {
-	payload, err := NewPayload(userID, duration)
+func (maker *PasetoMaker) CreateToken(userID uuid.UUID, role util.Role, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 
 	}
-	return  maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	return token, payload, err
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error)  {
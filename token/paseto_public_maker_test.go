@@ -0,0 +1,176 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func newPasetoPublicMaker(t *testing.T, signingKey ed25519.PrivateKey, verificationKeys ...ed25519.PublicKey) Maker {
+	ring, err := NewKeyRing(signingKey, verificationKeys...)
+	require.NoError(t, err)
+	maker, err := NewPasetoPublicMaker(ring)
+	require.NoError(t, err)
+	return maker
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	testMaker(t, func(t *testing.T) Maker {
+		return newPasetoPublicMaker(t, randomEd25519Key(t))
+	})
+}
+
+func TestPasetoPublicVector(t *testing.T) {
+	// test vector 4-S-1 from the PASETO specification
+	secretKey, err := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	require.NoError(t, err)
+	message := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+	expected := "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+
+	require.Equal(t, expected, signPasetoPublic(ed25519.PrivateKey(secretKey), message, nil))
+}
+
+func TestPasetoPublicFooterCarriesKeyID(t *testing.T) {
+	signingKey := randomEd25519Key(t)
+	maker := newPasetoPublicMaker(t, signingKey)
+
+	token, _, err := maker.CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 4)
+	footer, err := base64.RawURLEncoding.DecodeString(parts[3])
+	require.NoError(t, err)
+
+	var f pasetoFooter
+	require.NoError(t, json.Unmarshal(footer, &f))
+	require.Equal(t, KeyID(signingKey.Public().(ed25519.PublicKey)), f.KID)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldKey := randomEd25519Key(t)
+	newKey := randomEd25519Key(t)
+	oldPublicKey := oldKey.Public().(ed25519.PublicKey)
+
+	oldToken, _, err := newPasetoPublicMaker(t, oldKey).CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// after rotation the retired key still verifies tokens it signed
+	rotated := newPasetoPublicMaker(t, newKey, oldPublicKey)
+	_, err = rotated.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, _, err := rotated.CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+	require.NoError(t, err)
+	_, err = rotated.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	// once the retired key is dropped from the ring its tokens are rejected
+	_, err = newPasetoPublicMaker(t, newKey).VerifyToken(oldToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	keys := rotated.(PublicKeyProvider).PublicKeys()
+	require.Len(t, keys, 2)
+	require.Equal(t, KeyID(newKey.Public().(ed25519.PublicKey)), keys[0].KID)
+	require.Equal(t, KeyID(oldPublicKey), keys[1].KID)
+	require.Equal(t, oldPublicKey, keys[1].Key)
+}
+
+func TestPasetoPublicForgedKeyID(t *testing.T) {
+	signingKey := randomEd25519Key(t)
+	otherKey := randomEd25519Key(t)
+	maker := newPasetoPublicMaker(t, signingKey, otherKey.Public().(ed25519.PublicKey))
+
+	token, _, err := maker.CreateToken(uuid.New(), util.RoleDriver, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// pointing the footer at another trusted key must not verify
+	footer, err := json.Marshal(pasetoFooter{KID: KeyID(otherKey.Public().(ed25519.PublicKey))})
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+	forged := strings.Join(parts[:3], ".") + "." + base64.RawURLEncoding.EncodeToString(footer)
+
+	payload, err := maker.VerifyToken(forged)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicRejectsMalformedTokens(t *testing.T) {
+	signingKey := randomEd25519Key(t)
+	maker := newPasetoPublicMaker(t, signingKey)
+
+	message, err := json.Marshal(Payload{ID: uuid.New(), UserID: uuid.New(), ExpiredAt: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	unknownFooter, err := json.Marshal(pasetoFooter{KID: "unknown"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{"WrongPurpose", strings.Replace(signPasetoPublic(signingKey, message, []byte(`{"kid":"x"}`)), "v4.public.", "v4.local.", 1)},
+		{"WrongVersion", strings.Replace(signPasetoPublic(signingKey, message, []byte(`{"kid":"x"}`)), "v4.", "v2.", 1)},
+		{"NoFooter", signPasetoPublic(signingKey, message, nil)},
+		{"UnknownKeyID", signPasetoPublic(signingKey, message, unknownFooter)},
+		{"FooterNotJSON", signPasetoPublic(signingKey, message, []byte("kid"))},
+		{"ShortBody", "v4.public." + base64.RawURLEncoding.EncodeToString([]byte("short")) + ".e30"},
+		{"BadBase64", "v4.public.***.e30"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := maker.VerifyToken(tc.token)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestPasetoPublicVerificationOnly(t *testing.T) {
+	signingKey := randomEd25519Key(t)
+	issuer := newPasetoPublicMaker(t, signingKey)
+
+	ring, err := NewVerificationKeyRing(signingKey.Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+	verifier, err := NewPasetoPublicMaker(ring)
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	token, _, err := issuer.CreateToken(userID, util.RoleCustomer, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := verifier.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, userID, payload.UserID)
+
+	_, _, err = verifier.CreateToken(userID, util.RoleCustomer, AccessToken, time.Minute)
+	require.ErrorIs(t, err, errNoSigningKey)
+}
+
+func TestKeyRingRejectsInvalidKeys(t *testing.T) {
+	_, err := NewKeyRing(ed25519.PrivateKey("short"))
+	require.Error(t, err)
+
+	_, err = NewKeyRing(randomEd25519Key(t), ed25519.PublicKey("short"))
+	require.Error(t, err)
+
+	_, err = NewVerificationKeyRing()
+	require.Error(t, err)
+}
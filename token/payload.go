@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/joekings2k/logistics-eta/util"
 )
 
 var( 
@@ -12,14 +13,25 @@ var(
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// TokenType tells access tokens, which authorize requests, from refresh
+// tokens, which are only good for renewing access tokens and logging out.
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+)
+
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	UserID    uuid.UUID `json:"user_id"`
+	Role      util.Role `json:"role"`
+	Type      TokenType `json:"type"`
 	IssuedAt  time.Time     `json:"issued_at"`
 	ExpiredAt time.Time    `json:"expired_at"`
 }
 
-func NewPayload(userID uuid.UUID, duration time.Duration) (*Payload, error) {
+func NewPayload(userID uuid.UUID, role util.Role, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -27,6 +39,8 @@ func NewPayload(userID uuid.UUID, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		UserID: 	userID,
+		Role: role,
+		Type: tokenType,
 		IssuedAt: time.Now(),
 		ExpiredAt: time.Now().Add(time.Duration(duration)),
 	}
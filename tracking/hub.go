@@ -0,0 +1,216 @@
+package tracking
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBufferSize is how many events a subscriber may fall behind before it
+// is evicted.
+const DefaultBufferSize = 64
+
+var (
+	ErrHubClosed    = errors.New("tracking hub is closed")
+	ErrSlowConsumer = errors.New("subscriber could not keep up with the event stream")
+)
+
+type EventType string
+
+const (
+	EventPosition  EventType = "position"
+	EventEta       EventType = "eta"
+	EventStatus    EventType = "status"
+	EventStop      EventType = "stop"
+	EventGeofence  EventType = "geofence"
+	EventDeviation EventType = "deviation"
+)
+
+// Event is a single update about a route. Data holds the API representation
+// of whatever changed.
+type Event struct {
+	Type     EventType `json:"type"`
+	RouteID  uuid.UUID `json:"route_id"`
+	DriverID uuid.UUID `json:"driver_id"`
+	Data     any       `json:"data"`
+	At       time.Time `json:"at"`
+}
+
+// Topic names a stream subscribers can follow.
+type Topic string
+
+func RouteTopic(routeID uuid.UUID) Topic {
+	return Topic("route:" + routeID.String())
+}
+
+func DriverTopic(driverID uuid.UUID) Topic {
+	return Topic("driver:" + driverID.String())
+}
+
+// Hub is an in-process publish/subscribe broker for route events. Every
+// subscriber gets its own buffer, and publishing never blocks: a subscriber
+// whose buffer is full is evicted instead of slowing everyone else down.
+type Hub struct {
+	mu          sync.RWMutex
+	bufferSize  int
+	subscribers map[Topic]map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{
+		bufferSize:  bufferSize,
+		subscribers: make(map[Topic]map[*Subscription]struct{}),
+	}
+}
+
+// Filter decides whether a subscriber gets an event, and may rewrite the event
+// it gets to leave out what it mustn't see. It runs while the event is being
+// published, so it must be quick and must not call the hub.
+type Filter func(Event) (Event, bool)
+
+// Subscription receives the events of one topic until it is closed, either by
+// the subscriber or by the hub.
+type Subscription struct {
+	hub     *Hub
+	topic   Topic
+	filter  Filter
+	events  chan Event
+	evicted bool
+}
+
+// Events is closed once the subscription ends.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Err reports why the hub ended the subscription. It is only meaningful once
+// Events has been closed.
+func (sub *Subscription) Err() error {
+	sub.hub.mu.RLock()
+	defer sub.hub.mu.RUnlock()
+	if sub.evicted {
+		return ErrSlowConsumer
+	}
+	if sub.hub.closed {
+		return ErrHubClosed
+	}
+	return nil
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.remove(sub)
+}
+
+func (hub *Hub) Subscribe(topic Topic) (*Subscription, error) {
+	return hub.SubscribeFiltered(topic, nil)
+}
+
+// SubscribeFiltered subscribes to the events of topic that filter lets
+// through, as filter rewrote them. A nil filter lets everything through.
+func (hub *Hub) SubscribeFiltered(topic Topic, filter Filter) (*Subscription, error) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.closed {
+		return nil, ErrHubClosed
+	}
+
+	sub := &Subscription{
+		hub:    hub,
+		topic:  topic,
+		filter: filter,
+		events: make(chan Event, hub.bufferSize),
+	}
+	if hub.subscribers[topic] == nil {
+		hub.subscribers[topic] = make(map[*Subscription]struct{})
+	}
+	hub.subscribers[topic][sub] = struct{}{}
+	return sub, nil
+}
+
+// Publish delivers event to the subscribers of its route and of its driver.
+func (hub *Hub) Publish(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	var slow []*Subscription
+	hub.mu.RLock()
+	for _, topic := range []Topic{RouteTopic(event.RouteID), DriverTopic(event.DriverID)} {
+		for sub := range hub.subscribers[topic] {
+			delivered, ok := event, true
+			if sub.filter != nil {
+				delivered, ok = sub.filter(event)
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case sub.events <- delivered:
+			default:
+				slow = append(slow, sub)
+			}
+		}
+	}
+	hub.mu.RUnlock()
+
+	if len(slow) == 0 {
+		return
+	}
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, sub := range slow {
+		if hub.remove(sub) {
+			sub.evicted = true
+		}
+	}
+}
+
+// SubscriberCount returns the number of live subscriptions to topic.
+func (hub *Hub) SubscriberCount(topic Topic) int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.subscribers[topic])
+}
+
+// Close ends every subscription and rejects new ones.
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.closed {
+		return
+	}
+	hub.closed = true
+	for _, subs := range hub.subscribers {
+		for sub := range subs {
+			hub.remove(sub)
+		}
+	}
+}
+
+// remove drops sub and closes its channel. The caller must hold the write
+// lock, which guarantees no Publish is sending on the channel. It reports
+// whether sub was still subscribed.
+func (hub *Hub) remove(sub *Subscription) bool {
+	subs, ok := hub.subscribers[sub.topic]
+	if !ok {
+		return false
+	}
+	if _, ok := subs[sub]; !ok {
+		return false
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(hub.subscribers, sub.topic)
+	}
+	close(sub.events)
+	return true
+}
@@ -0,0 +1,162 @@
+package tracking
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent(routeID, driverID uuid.UUID) Event {
+	return Event{
+		Type:     EventPosition,
+		RouteID:  routeID,
+		DriverID: driverID,
+		Data:     map[string]float64{"lat": 6.5, "lng": 3.4},
+	}
+}
+
+func TestPublishReachesRouteAndDriverSubscribers(t *testing.T) {
+	hub := NewHub(4)
+	routeID, driverID := uuid.New(), uuid.New()
+
+	routeSub, err := hub.Subscribe(RouteTopic(routeID))
+	require.NoError(t, err)
+	driverSub, err := hub.Subscribe(DriverTopic(driverID))
+	require.NoError(t, err)
+	otherSub, err := hub.Subscribe(RouteTopic(uuid.New()))
+	require.NoError(t, err)
+
+	hub.Publish(randomEvent(routeID, driverID))
+
+	for _, sub := range []*Subscription{routeSub, driverSub} {
+		select {
+		case event := <-sub.Events():
+			require.Equal(t, routeID, event.RouteID)
+			require.False(t, event.At.IsZero())
+		default:
+			t.Fatal("expected an event")
+		}
+	}
+	require.Empty(t, otherSub.Events())
+}
+
+func TestFilteredSubscription(t *testing.T) {
+	hub := NewHub(4)
+	routeID, driverID := uuid.New(), uuid.New()
+
+	// only ETA updates get through, without their data
+	sub, err := hub.SubscribeFiltered(RouteTopic(routeID), func(event Event) (Event, bool) {
+		event.Data = nil
+		return event, event.Type == EventEta
+	})
+	require.NoError(t, err)
+	all, err := hub.Subscribe(RouteTopic(routeID))
+	require.NoError(t, err)
+
+	position := randomEvent(routeID, driverID)
+	update := randomEvent(routeID, driverID)
+	update.Type = EventEta
+	hub.Publish(position)
+	hub.Publish(update)
+
+	require.Len(t, sub.Events(), 1)
+	event := <-sub.Events()
+	require.Equal(t, EventEta, event.Type)
+	require.Nil(t, event.Data)
+
+	// other subscribers still get every event as it was published
+	require.Len(t, all.Events(), 2)
+	require.NotNil(t, (<-all.Events()).Data)
+}
+
+func TestSlowConsumerIsEvicted(t *testing.T) {
+	hub := NewHub(2)
+	routeID, driverID := uuid.New(), uuid.New()
+
+	slow, err := hub.Subscribe(RouteTopic(routeID))
+	require.NoError(t, err)
+	fast, err := hub.Subscribe(RouteTopic(routeID))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(randomEvent(routeID, driverID))
+		if i < 2 {
+			<-fast.Events()
+		}
+	}
+
+	// the two buffered events are still delivered before the channel closes
+	require.Len(t, slow.Events(), 2)
+	<-slow.Events()
+	<-slow.Events()
+	_, ok := <-slow.Events()
+	require.False(t, ok)
+	require.ErrorIs(t, slow.Err(), ErrSlowConsumer)
+
+	// the fast subscriber is unaffected
+	require.Len(t, fast.Events(), 1)
+	require.NoError(t, fast.Err())
+	require.Equal(t, 1, hub.SubscriberCount(RouteTopic(routeID)))
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	hub := NewHub(1)
+	topic := RouteTopic(uuid.New())
+
+	sub, err := hub.Subscribe(topic)
+	require.NoError(t, err)
+	require.Equal(t, 1, hub.SubscriberCount(topic))
+
+	sub.Close()
+	sub.Close()
+	require.Zero(t, hub.SubscriberCount(topic))
+	_, ok := <-sub.Events()
+	require.False(t, ok)
+	require.NoError(t, sub.Err())
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(1)
+	sub, err := hub.Subscribe(DriverTopic(uuid.New()))
+	require.NoError(t, err)
+
+	hub.Close()
+	_, ok := <-sub.Events()
+	require.False(t, ok)
+	require.ErrorIs(t, sub.Err(), ErrHubClosed)
+
+	_, err = hub.Subscribe(DriverTopic(uuid.New()))
+	require.ErrorIs(t, err, ErrHubClosed)
+}
+
+func TestConcurrentPublishAndUnsubscribe(t *testing.T) {
+	hub := NewHub(1)
+	routeID, driverID := uuid.New(), uuid.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				hub.Publish(randomEvent(routeID, driverID))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				sub, err := hub.Subscribe(RouteTopic(routeID))
+				if err != nil {
+					return
+				}
+				time.Sleep(time.Microsecond)
+				sub.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Zero(t, hub.SubscriberCount(RouteTopic(routeID)))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/joekings2k/logistics-eta/db/sqlc"
+	"github.com/joekings2k/logistics-eta/eta"
+	"github.com/joekings2k/logistics-eta/util"
+)
+
+const (
+	// defaultModelWindow is how far back completed routes are trained on when
+	// ETA_MODEL_WINDOW isn't set.
+	defaultModelWindow = 26 * 7 * 24 * time.Hour
+	// maxCorrectionSamples bounds how many routes, the latest first, the
+	// model is trained on.
+	maxCorrectionSamples = 200000
+)
+
+// runTrainCommand trains the eta correction model on the routes completed
+// over the model window, prints how it does on the latest of them and saves
+// it to the file given, or ETA_MODEL_FILE.
+func runTrainCommand(store db.Store, config util.Config, args []string) error {
+	path := config.ETAModelFile
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("missing model file\n%s", usage)
+	}
+	location, err := time.LoadLocation(config.ETAProfileTimezone)
+	if err != nil {
+		return fmt.Errorf("cannot load time zone: %w", err)
+	}
+	window := config.ETAModelWindow
+	if window == 0 {
+		window = defaultModelWindow
+	}
+
+	rows, err := store.ListCorrectionSamples(context.Background(), db.ListCorrectionSamplesParams{
+		Since:      time.Now().Add(-window),
+		MaxSamples: maxCorrectionSamples,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot list completed routes: %w", err)
+	}
+	samples := make([]eta.CorrectionSample, 0, len(rows))
+	for _, row := range rows {
+		samples = append(samples, eta.CorrectionSample{
+			CorrectionFeatures: eta.CorrectionFeatures{
+				DistanceKm:   row.EstimatedDistanceKm,
+				Departure:    row.StartedAt,
+				DriverID:     row.DriverID.String(),
+				VehicleModel: row.VehicleModel,
+				Origin:       eta.Point{Lat: row.OriginLat, Lng: row.OriginLng},
+				Destination:  eta.Point{Lat: row.DestinationLat, Lng: row.DestinationLng},
+			},
+			BaselineMin: row.BaselineDurationMin,
+			PlannedMin:  row.EstimatedDurationMin,
+			ActualMin:   row.ActualDurationMin,
+		})
+	}
+
+	model, err := eta.TrainCorrectionModel(samples, location)
+	if errors.Is(err, eta.ErrTooFewSamples) {
+		return fmt.Errorf("%w: %d found, %d needed", err, len(samples), eta.MinTrainingSamples)
+	}
+	if err != nil {
+		return err
+	}
+	if err := model.Save(path); err != nil {
+		return fmt.Errorf("cannot save model: %w", err)
+	}
+
+	fmt.Printf("trained on %d routes, evaluated on the latest %d:\n", model.Samples, model.Evaluation.Corrected.Trips)
+	for _, metrics := range []struct {
+		name string
+		eta.ErrorMetrics
+	}{
+		{"free-flow", model.Evaluation.FreeFlow},
+		{"planned", model.Evaluation.Planned},
+		{"corrected", model.Evaluation.Corrected},
+	} {
+		fmt.Printf("  %-10s MAE %6.2f min  P90 %6.2f min\n", metrics.name, metrics.MAEMin, metrics.P90Min)
+	}
+	fmt.Println("saved model to", path)
+	return nil
+}
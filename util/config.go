@@ -13,8 +13,31 @@ type Config struct {
 	DBDriver       string `mapstructure:"DB_DRIVER"`
 	DBSource       string `mapstructure:"DB_SOURCE"`
 	ServerAddress  string `mapstructure:"SERVER_ADDRESS"`
+	MigrateOnStart bool `mapstructure:"MIGRATE_ON_START"`
 	TokenSymmetricKey string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenType string `mapstructure:"TOKEN_TYPE"`
+	TokenAlgorithm string `mapstructure:"TOKEN_ALGORITHM"`
+	TokenPrivateKeyFile string `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	TokenVerificationKeyFiles []string `mapstructure:"TOKEN_VERIFICATION_KEY_FILES"`
 	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	ETACircuityFactor float64 `mapstructure:"ETA_CIRCUITY_FACTOR"`
+	ETACarSpeedKmh float64 `mapstructure:"ETA_CAR_SPEED_KMH"`
+	ETAVanSpeedKmh float64 `mapstructure:"ETA_VAN_SPEED_KMH"`
+	ETATruckSpeedKmh float64 `mapstructure:"ETA_TRUCK_SPEED_KMH"`
+	ETASpeedWindow time.Duration `mapstructure:"ETA_SPEED_WINDOW"`
+	ETAProfileTimezone string `mapstructure:"ETA_PROFILE_TIMEZONE"`
+	ETAProfileWindow time.Duration `mapstructure:"ETA_PROFILE_WINDOW"`
+	ETAModelFile string `mapstructure:"ETA_MODEL_FILE"`
+	ETAModelWindow time.Duration `mapstructure:"ETA_MODEL_WINDOW"`
+	RoutingGraphFile string `mapstructure:"ROUTING_GRAPH_FILE"`
+	GeofenceRadiusM float64 `mapstructure:"GEOFENCE_RADIUS_M"`
+	GeofenceExitMarginM float64 `mapstructure:"GEOFENCE_EXIT_MARGIN_M"`
+	GeofenceDwell time.Duration `mapstructure:"GEOFENCE_DWELL"`
+	DeviationThresholdM float64 `mapstructure:"DEVIATION_THRESHOLD_M"`
+	DeviationDwell time.Duration `mapstructure:"DEVIATION_DWELL"`
+	StreamBufferSize int `mapstructure:"STREAM_BUFFER_SIZE"`
+	StreamHeartbeatInterval time.Duration `mapstructure:"STREAM_HEARTBEAT_INTERVAL"`
 }
 
 func LoadConfig(path string) (config Config, err error){
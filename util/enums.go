@@ -2,6 +2,8 @@ package util
 
 type Role string
 type RouteStatus string
+type StopStatus string
+type GeofencePlace string
 
 const (
 	RoleAdmin    Role = "admin"
@@ -16,6 +18,19 @@ const (
 	RouteCancelled  RouteStatus = "cancelled"
 )
 
+const (
+	StopPending   StopStatus = "pending"
+	StopArrived   StopStatus = "arrived"
+	StopCompleted StopStatus = "completed"
+	StopFailed    StopStatus = "failed"
+)
+
+const (
+	GeofenceOrigin      GeofencePlace = "origin"
+	GeofenceStop        GeofencePlace = "stop"
+	GeofenceDestination GeofencePlace = "destination"
+)
+
 func (role Role) IsValid() bool {
 	switch role {
 	case RoleAdmin, RoleDriver, RoleCustomer:
@@ -23,4 +38,22 @@ func (role Role) IsValid() bool {
 	default:
 		return false
 	}
-};
\ No newline at end of file
+}
+
+func (status RouteStatus) IsValid() bool {
+	switch status {
+	case RoutePending, RouteInProgress, RouteCompleted, RouteCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (status StopStatus) IsValid() bool {
+	switch status {
+	case StopPending, StopArrived, StopCompleted, StopFailed:
+		return true
+	default:
+		return false
+	}
+}
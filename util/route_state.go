@@ -0,0 +1,37 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidRouteTransition = errors.New("invalid route status transition")
+
+// routeTransitions lists, for every non-terminal status, the statuses a route
+// may move to next. Completed and cancelled routes are terminal.
+var routeTransitions = map[RouteStatus][]RouteStatus{
+	RoutePending:    {RouteInProgress, RouteCancelled},
+	RouteInProgress: {RouteCompleted, RouteCancelled},
+}
+
+func (status RouteStatus) IsTerminal() bool {
+	return status == RouteCompleted || status == RouteCancelled
+}
+
+func (status RouteStatus) CanTransitionTo(next RouteStatus) bool {
+	for _, allowed := range routeTransitions[status] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRouteTransition returns ErrInvalidRouteTransition, wrapped with the
+// offending statuses, when a route is not allowed to move from one status to the other.
+func ValidateRouteTransition(from, to RouteStatus) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidRouteTransition, from, to)
+	}
+	return nil
+}
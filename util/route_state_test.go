@@ -0,0 +1,45 @@
+package util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRouteTransition(t *testing.T) {
+	testCases := []struct {
+		from  RouteStatus
+		to    RouteStatus
+		valid bool
+	}{
+		{RoutePending, RouteInProgress, true},
+		{RoutePending, RouteCancelled, true},
+		{RouteInProgress, RouteCompleted, true},
+		{RouteInProgress, RouteCancelled, true},
+		{RoutePending, RouteCompleted, false},
+		{RoutePending, RoutePending, false},
+		{RouteInProgress, RoutePending, false},
+		{RouteCompleted, RouteInProgress, false},
+		{RouteCompleted, RouteCancelled, false},
+		{RouteCancelled, RoutePending, false},
+		{RouteStatus("lost"), RouteInProgress, false},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateRouteTransition(tc.from, tc.to)
+		if tc.valid {
+			require.NoError(t, err, "%s -> %s", tc.from, tc.to)
+			continue
+		}
+		require.Error(t, err, "%s -> %s", tc.from, tc.to)
+		require.True(t, errors.Is(err, ErrInvalidRouteTransition))
+	}
+}
+
+func TestRouteStatusIsTerminal(t *testing.T) {
+	require.False(t, RoutePending.IsTerminal())
+	require.False(t, RouteInProgress.IsTerminal())
+	require.True(t, RouteCompleted.IsTerminal())
+	require.True(t, RouteCancelled.IsTerminal())
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidStopTransition = errors.New("invalid stop status transition")
+
+// stopTransitions lists, for every non-terminal status, the statuses a stop
+// may move to next. A driver may complete or fail a stop without marking the
+// arrival first. Completed and failed stops are terminal.
+var stopTransitions = map[StopStatus][]StopStatus{
+	StopPending: {StopArrived, StopCompleted, StopFailed},
+	StopArrived: {StopCompleted, StopFailed},
+}
+
+func (status StopStatus) IsTerminal() bool {
+	return status == StopCompleted || status == StopFailed
+}
+
+func (status StopStatus) CanTransitionTo(next StopStatus) bool {
+	for _, allowed := range stopTransitions[status] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateStopTransition returns ErrInvalidStopTransition, wrapped with the
+// offending statuses, when a stop is not allowed to move from one status to the other.
+func ValidateStopTransition(from, to StopStatus) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStopTransition, from, to)
+	}
+	return nil
+}
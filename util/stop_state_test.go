@@ -0,0 +1,44 @@
+package util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStopTransition(t *testing.T) {
+	testCases := []struct {
+		from  StopStatus
+		to    StopStatus
+		valid bool
+	}{
+		{StopPending, StopArrived, true},
+		{StopPending, StopCompleted, true},
+		{StopPending, StopFailed, true},
+		{StopArrived, StopCompleted, true},
+		{StopArrived, StopFailed, true},
+		{StopPending, StopPending, false},
+		{StopArrived, StopPending, false},
+		{StopCompleted, StopFailed, false},
+		{StopFailed, StopArrived, false},
+		{StopStatus("skipped"), StopArrived, false},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateStopTransition(tc.from, tc.to)
+		if tc.valid {
+			require.NoError(t, err, "%s -> %s", tc.from, tc.to)
+			continue
+		}
+		require.Error(t, err, "%s -> %s", tc.from, tc.to)
+		require.True(t, errors.Is(err, ErrInvalidStopTransition))
+	}
+}
+
+func TestStopStatusIsTerminal(t *testing.T) {
+	require.False(t, StopPending.IsTerminal())
+	require.False(t, StopArrived.IsTerminal())
+	require.True(t, StopCompleted.IsTerminal())
+	require.True(t, StopFailed.IsTerminal())
+}